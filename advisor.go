@@ -0,0 +1,212 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+// - MARK: Advisor section.
+
+// Thresholds used by `Advisor`.
+const (
+	// cADVSHARDRATE is failed CAS per operation
+	// above which sharding is recommended.
+	cADVSHARDRATE = 0.5
+	// cADVYIELDRATE is yields per operation above
+	// which spinning goroutines starve each other.
+	cADVYIELDRATE = 0.01
+	// cADVSCHEDLATENCY is median scheduling latency
+	// above which runnable goroutines queue up.
+	cADVSCHEDLATENCY = 100 * time.Microsecond
+)
+
+// AdviceKind is kind of recommendation.
+type AdviceKind int
+
+const (
+	// AdviseNone means contention is acceptable.
+	AdviseNone AdviceKind = iota
+	// AdviseShard recommends striping the ring
+	// into `Advice.Shards` rings.
+	AdviseShard
+	// AdviseReduceProcs recommends lowering
+	// GOMAXPROCS to `Advice.GOMAXPROCS`.
+	AdviseReduceProcs
+)
+
+// String returns name of `k`.
+func (k AdviceKind) String() string {
+	switch k {
+	case AdviseShard:
+		return "shard"
+	case AdviseReduceProcs:
+		return "reduce-procs"
+	}
+	return "none"
+}
+
+// Advice is a structured recommendation for a ring.
+type Advice struct {
+	Ring         int           // index of ring passed to `NewAdvisor`
+	Kind         AdviceKind    // recommendation
+	Shards       int           // suggested shard count
+	GOMAXPROCS   int           // suggested GOMAXPROCS
+	Ops          uint64        // completed operations in window
+	RetryRate    float64       // failed CAS per operation
+	YieldRate    float64       // yields per operation
+	SchedLatency time.Duration // median scheduling latency in window
+	Reason       string        // human readable explanation
+}
+
+// advisorSample is a snapshot of ring counters.
+type advisorSample struct {
+	ops, casfail, yields uint64
+}
+
+// Advisor correlates CAS retry rates of rings with
+// runtime scheduler statistics and suggests shard
+// counts or GOMAXPROCS adjustments. It is not safe
+// for concurrent use.
+type Advisor struct {
+	rings  []*Ring
+	last   []advisorSample
+	hist   []metrics.Sample
+	counts []uint64 // latency histogram at previous call
+}
+
+// NewAdvisor allocates and initializes a new
+// `Advisor` observing `rings`.
+func NewAdvisor(rings ...*Ring) *Advisor {
+	a := &Advisor{
+		rings: rings,
+		last:  make([]advisorSample, len(rings)),
+		hist:  []metrics.Sample{{Name: "/sched/latencies:seconds"}},
+	}
+	for i, r := range rings {
+		a.last[i] = sampleRing(r)
+	}
+	a.schedLatency()
+	return a
+}
+
+// Advise returns one recommendation per ring for
+// the window since previous call.
+func (a *Advisor) Advise() []Advice {
+	var (
+		procs   int           = runtime.GOMAXPROCS(0)
+		latency time.Duration = a.schedLatency()
+		advice  []Advice      = make([]Advice, len(a.rings))
+	)
+	for i, r := range a.rings {
+		cur := sampleRing(r)
+		adv := Advice{
+			Ring:         i,
+			Ops:          cur.ops - a.last[i].ops,
+			SchedLatency: latency,
+			GOMAXPROCS:   procs,
+			Shards:       1,
+		}
+		if adv.Ops > 0 {
+			adv.RetryRate = float64(cur.casfail-a.last[i].casfail) / float64(adv.Ops)
+			adv.YieldRate = float64(cur.yields-a.last[i].yields) / float64(adv.Ops)
+		}
+		a.last[i] = cur
+		switch {
+		case adv.YieldRate > cADVYIELDRATE && latency > cADVSCHEDLATENCY && procs > runtime.NumCPU():
+			adv.Kind = AdviseReduceProcs
+			adv.GOMAXPROCS = runtime.NumCPU()
+			adv.Reason = fmt.Sprintf("spinning goroutines yield %.3f/op while median scheduling latency is %v; GOMAXPROCS(%d) exceeds NumCPU(%d)", adv.YieldRate, latency, procs, runtime.NumCPU())
+		case adv.RetryRate > cADVSHARDRATE && procs > 1:
+			adv.Kind = AdviseShard
+			adv.Shards = int(roundP2(uint64(adv.RetryRate) + 2))
+			if adv.Shards > procs {
+				adv.Shards = procs
+			}
+			adv.Reason = fmt.Sprintf("%.2f failed CAS per operation; cursor contention caps throughput", adv.RetryRate)
+		default:
+			adv.Reason = "contention within bounds"
+		}
+		advice[i] = adv
+	}
+	return advice
+}
+
+// schedLatency returns median scheduling latency
+// of the window since previous call. The runtime
+// histogram is cumulative since start, so the
+// previous one is subtracted.
+func (a *Advisor) schedLatency() time.Duration {
+	metrics.Read(a.hist)
+	if a.hist[0].Value.Kind() != metrics.KindFloat64Histogram {
+		return 0
+	}
+	h := a.hist[0].Value.Float64Histogram()
+	if len(a.counts) != len(h.Counts) {
+		a.counts = make([]uint64, len(h.Counts))
+	}
+	median := histMedian(h, a.counts)
+	// `metrics.Read` reuses histogram memory.
+	copy(a.counts, h.Counts)
+	return median
+}
+
+// histMedian returns median of histogram `h`
+// less counts `prev`.
+func histMedian(h *metrics.Float64Histogram, prev []uint64) time.Duration {
+	var (
+		total uint64
+		acc   uint64
+	)
+	for i, c := range h.Counts {
+		total += c - prev[i]
+	}
+	for i, c := range h.Counts {
+		acc += c - prev[i]
+		if acc*2 >= total && total > 0 {
+			// upper bound, lower one for +Inf bucket
+			bound := h.Buckets[i+1]
+			if math.IsInf(bound, 1) {
+				bound = h.Buckets[i]
+			}
+			return time.Duration(bound * float64(time.Second))
+		}
+	}
+	return 0
+}
+
+// sampleRing returns current counters of `r`.
+func sampleRing(r *Ring) advisorSample {
+	return advisorSample{
+		ops:     r.writeIndex() + r.readIndex(),
+		casfail: atomic.LoadUint64(&r.casfail),
+		yields:  atomic.LoadUint64(&r.yields),
+	}
+}
@@ -0,0 +1,79 @@
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"testing"
+	"time"
+)
+
+func TestAdvisorShard(t *testing.T) {
+	var (
+		r *Ring    = NewRing(8)
+		a *Advisor = NewAdvisor(r)
+	)
+	for i := 0; i < 4; i++ {
+		r.Push(i)
+	}
+	// simulate contention: 3 failed CAS per op
+	r.casfail += 12
+	adv := a.Advise()[0]
+	if adv.Ops != 4 || adv.RetryRate != 3 {
+		t.Fatalf("assertion failed, ops(%d), rate(%v).", adv.Ops, adv.RetryRate)
+	}
+	if runtime.GOMAXPROCS(0) > 1 && (adv.Kind != AdviseShard || adv.Shards < 2) {
+		t.Fatalf("assertion failed, expected shard advice, got %+v.", adv)
+	}
+	// window resets between calls
+	if adv = a.Advise()[0]; adv.Kind != AdviseNone || adv.Ops != 0 {
+		t.Fatalf("assertion failed, expected no advice, got %+v.", adv)
+	}
+}
+
+func TestAdvisorLatencyWindow(t *testing.T) {
+	var (
+		h *metrics.Float64Histogram = &metrics.Float64Histogram{
+			Buckets: []float64{0, 1e-6, 1e-3, math.Inf(1)},
+			Counts:  []uint64{0, 10, 100},
+		}
+		prev []uint64 = make([]uint64, 3)
+	)
+	// slow history dominates the cumulative median
+	if m := histMedian(h, prev); m != time.Millisecond {
+		t.Fatalf("assertion failed, expected 1ms, got %v.", m)
+	}
+	// a fast window following it does not
+	copy(prev, h.Counts)
+	h.Counts[0] += 5
+	if m := histMedian(h, prev); m != time.Microsecond {
+		t.Fatalf("assertion failed, expected 1µs, got %v.", m)
+	}
+	if m := histMedian(h, h.Counts); m != 0 {
+		t.Fatalf("assertion failed, expected 0 for empty window, got %v.", m)
+	}
+}
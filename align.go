@@ -0,0 +1,74 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"errors"
+	"math/bits"
+	"reflect"
+)
+
+// Tagging schemes of the package steal low-order
+// bits of pointers and need their targets to be
+// aligned accordingly.
+const (
+	// TagBits is number of low-order pointer bits
+	// used as tags, see `RDCSSCtx` and `KCSSCtx`.
+	TagBits = 1
+	// TagAlign is minimum alignment of values
+	// stored in tagged words.
+	TagAlign = 1 << TagBits
+	// DWAlign is alignment `DWCAS` needs to use
+	// hardware instead of striped locks.
+	DWAlign = 16
+)
+
+var (
+	// ErrMisaligned is returned when a value does
+	// not have `TagBits` zero low-order bits.
+	ErrMisaligned = errors.New("lfring: value not tag aligned")
+)
+
+// - MARK: Alignment section.
+
+// NewDW returns a zeroed word pair aligned to
+// `DWAlign`, so `DWCAS` always uses hardware when
+// the CPU supports it.
+func NewDW() *[2]uintptr {
+	buf := make([]uintptr, 2+DWAlign*8/bits.UintSize)
+	for i := range buf {
+		if addressOf(&buf[i])&(DWAlign-1) == 0 {
+			return (*[2]uintptr)(buf[i : i+2])
+		}
+	}
+	panic("lfring: unreachable")
+}
+
+// addressOf returns address of pointer `p`. The
+// result is only compared or masked; it is never
+// converted back.
+func addressOf(p interface{}) uintptr {
+	return reflect.ValueOf(p).Pointer()
+}
@@ -0,0 +1,108 @@
+//go:build 386 || arm || mips || mipsle
+// +build 386 arm mips mipsle
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/mitghi/lfring/epoch"
+)
+
+// - MARK: Test section.
+
+// TestAlign32 asserts that words accessed by
+// 64-bit atomics are 8-byte aligned on 32-bit
+// platforms, where only the first word of an
+// allocation is and a misaligned access panics.
+// Run it with e.g. `GOARCH=386 go test`.
+func TestAlign32(t *testing.T) {
+	var (
+		r   Ring
+		a   Audit
+		c   FakeClock
+		fi  FanIn
+		fo  FanOut
+		q   IntrusiveRing
+		m   Mux
+		p   Pool
+		rz  Resizable
+		sn  Sentinel
+		st  Stack
+		sg  Stage
+		sk  Sticky
+		sub Subscriber
+		sq  Sequencer
+		rs  ringStats
+	)
+	for _, c := range []struct {
+		name string
+		off  uintptr
+	}{
+		{"Ring.wri", unsafe.Offsetof(r.wri)},
+		{"Ring.ticket", unsafe.Offsetof(r.ticket)},
+		{"Ring.serving", unsafe.Offsetof(r.serving)},
+		{"Ring.rdi", unsafe.Offsetof(r.rdi)},
+		{"Ring.count", unsafe.Offsetof(r.count)},
+		{"Ring.casfail", unsafe.Offsetof(r.casfail)},
+		{"Ring.yields", unsafe.Offsetof(r.yields)},
+		{"Audit.written", unsafe.Offsetof(a.written)},
+		{"Audit.failed", unsafe.Offsetof(a.failed)},
+		{"FakeClock.off", unsafe.Offsetof(c.off)},
+		{"FanIn.merged", unsafe.Offsetof(fi.merged)},
+		{"FanIn.stalled", unsafe.Offsetof(fi.stalled)},
+		{"FanOut.stats", unsafe.Offsetof(fo.stats)},
+		{"IntrusiveRing.cancelled", unsafe.Offsetof(q.cancelled)},
+		{"Mux.next", unsafe.Offsetof(m.next)},
+		{"Pool.gets", unsafe.Offsetof(p.gets)},
+		{"Pool.puts", unsafe.Offsetof(p.puts)},
+		{"Pool.misses", unsafe.Offsetof(p.misses)},
+		{"Pool.rejected", unsafe.Offsetof(p.rejected)},
+		{"Resizable.resizes", unsafe.Offsetof(rz.resizes)},
+		{"Sentinel.samples", unsafe.Offsetof(sn.samples)},
+		{"Sentinel.anomalies", unsafe.Offsetof(sn.anomalies)},
+		{"Stack.count", unsafe.Offsetof(st.count)},
+		{"Stack.elims", unsafe.Offsetof(st.elims)},
+		{"Stage.processed", unsafe.Offsetof(sg.processed)},
+		{"Stage.panics", unsafe.Offsetof(sg.panics)},
+		{"Sticky.moved", unsafe.Offsetof(sk.moved)},
+		{"Subscriber.cursor", unsafe.Offsetof(sub.cursor)},
+		{"Sequencer.cursor", unsafe.Offsetof(sq.cursor)},
+		{"Sequencer.cached", unsafe.Offsetof(sq.cached)},
+		{"Sequencer.pubd", unsafe.Offsetof(sq.pubd)},
+		{"ringStats.full", unsafe.Offsetof(rs.full)},
+		{"ringStats.maxlen", unsafe.Offsetof(rs.maxlen)},
+	} {
+		if c.off%8 != 0 {
+			t.Fatalf("assertion failed, %s at offset %d.", c.name, c.off)
+		}
+	}
+	if p := uintptr(unsafe.Pointer(epoch.Default)); p%8 != 0 {
+		t.Fatalf("assertion failed, default epoch at %#x.", p)
+	}
+}
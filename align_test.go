@@ -0,0 +1,82 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"testing"
+	"unsafe"
+)
+
+func TestAllocAligned(t *testing.T) {
+	for _, align := range []uintptr{0, 1, 2, 8, 16, 64, 4096} {
+		for _, size := range []uintptr{1, 7, 16, 100} {
+			p := AllocAligned(size, align)
+			if !TagSafe(p) || (align > 0 && uintptr(p)&(align-1) != 0) {
+				t.Fatalf("assertion failed, %p not aligned to %d.", p, align)
+			}
+			b := unsafe.Slice((*byte)(p), size)
+			for i := range b {
+				if b[i] != 0 {
+					t.Fatal("inconsistent state, memory not zeroed.")
+				}
+				b[i] = 0xff
+			}
+		}
+	}
+	if dw := NewDW(); uintptr(unsafe.Pointer(dw))&(DWAlign-1) != 0 || !DWCAS(dw, [2]uintptr{}, [2]uintptr{1, 2}) {
+		t.Fatal("assertion failed, expected aligned word pair.")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("assertion failed, expected panic on invalid alignment.")
+		}
+	}()
+	AllocAligned(8, 3)
+}
+
+func TestTagSafe(t *testing.T) {
+	var (
+		buf  [4]byte
+		ctl  uint64
+		slot unsafe.Pointer
+	)
+	odd := unsafe.Pointer(&buf[1])
+	if uintptr(odd)&1 == 0 {
+		odd = unsafe.Pointer(&buf[0])
+	}
+	if TagSafe(odd) || !TagSafe(nil) {
+		t.Fatal("assertion failed, expected odd pointer to be unsafe.")
+	}
+	if ok, err := RDCSSCtx(context.Background(), &ctl, 0, &slot, nil, odd); ok || err != ErrMisaligned || slot != nil {
+		t.Fatalf("assertion failed, expected ErrMisaligned, got %v, %v.", ok, err)
+	}
+	if ok, err := KCSSCtx(context.Background(), &slot, nil, odd, nil, nil); ok || err != ErrMisaligned || slot != nil {
+		t.Fatalf("assertion failed, expected ErrMisaligned, got %v, %v.", ok, err)
+	}
+}
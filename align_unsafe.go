@@ -0,0 +1,60 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "unsafe"
+
+// - MARK: Alignment section.
+
+// TagSafe returns whether `p` can be stored in a
+// tagged word, i.e. its `TagBits` low-order bits
+// are zero. Go allocations are, but pointers into
+// byte arrays, from cgo or custom allocators may
+// not be.
+func TagSafe(p unsafe.Pointer) bool {
+	return uintptr(p)&(TagAlign-1) == 0
+}
+
+// AllocAligned returns `size` bytes of zeroed
+// memory whose address is a multiple of `align`,
+// a power of two. Alignments below `TagAlign` are
+// raised to it, so the result is always
+// `TagSafe`. The memory is garbage collected, but
+// not scanned: it must not hold Go pointers.
+func AllocAligned(size, align uintptr) unsafe.Pointer {
+	if align&(align-1) != 0 {
+		panic("lfring: alignment is not a power of two")
+	}
+	if align < TagAlign {
+		align = TagAlign
+	}
+	// interior pointers keep whole buffer alive.
+	buf := make([]uint64, (size+align+7)/8)
+	base := uintptr(unsafe.Pointer(&buf[0]))
+	return unsafe.Add(unsafe.Pointer(&buf[0]), (align-base&(align-1))&(align-1))
+}
@@ -0,0 +1,78 @@
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "testing"
+
+// - MARK: Allocation section.
+
+// assertNoAllocs fails when `fn` allocates.
+func assertNoAllocs(t *testing.T, name string, fn func()) {
+	t.Helper()
+	if allocs := testing.AllocsPerRun(1000, fn); allocs > 0 {
+		t.Fatalf("assertion failed, %s allocates %v times per run.", name, allocs)
+	}
+}
+
+func TestRingZeroAllocs(t *testing.T) {
+	var (
+		// pointer ring carries pointers, value
+		// ring carries small structs stored
+		// inline in slots (pre-boxed by caller).
+		items map[string]interface{} = map[string]interface{}{
+			"pointer": &tstnode{uid: "p"},
+			"value":   tstnode{uid: "v", value: 1},
+		}
+		dst   []interface{} = make([]interface{}, 4)
+		visit func(interface{}) bool
+	)
+	visit = func(interface{}) bool { return true }
+	for kind, item := range items {
+		r := NewRing(8)
+		assertNoAllocs(t, kind+"/Push+Pop", func() {
+			r.Push(item)
+			r.Pop()
+		})
+		assertNoAllocs(t, kind+"/TryPop", func() {
+			r.Push(item)
+			r.TryPop(8)
+		})
+		assertNoAllocs(t, kind+"/PopInto", func() {
+			r.Push(item)
+			r.Push(item)
+			r.PopInto(dst)
+		})
+		assertNoAllocs(t, kind+"/PushBatch", func() {
+			dst[0], dst[1] = item, item
+			r.PushBatch(dst[:2])
+			r.PopInto(dst)
+		})
+		assertNoAllocs(t, kind+"/Consume", func() {
+			r.Push(item)
+			r.Push(item)
+			r.Consume(4, visit)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"fmt"
+	"math/bits"
+	"reflect"
+	"runtime"
+)
+
+// - MARK: Arch section.
+
+// Arch reports platform constants the package
+// relies on, e.g. to audit a deployment.
+type Arch struct {
+	GOARCH        string  // target architecture
+	WordSize      int     // bits per word
+	TagBits       int     // low-order pointer bits used as tags
+	MaxTag        uintptr // largest tag value
+	PointerMask   uintptr // clears tag bits of a pointer
+	CacheLineSize int     // padding granularity, see `CacheLineSize`
+	DWCAS         string  // implementation backing `DWCAS`
+}
+
+// ArchInfo returns constants of running platform.
+func ArchInfo() Arch {
+	return Arch{
+		GOARCH:        runtime.GOARCH,
+		WordSize:      bits.UintSize,
+		TagBits:       TagBits,
+		MaxTag:        TagAlign - 1,
+		PointerMask:   ^uintptr(TagAlign - 1),
+		CacheLineSize: CacheLineSize,
+		DWCAS:         dwcasImpl(),
+	}
+}
+
+// dwcasImpl names implementation backing `DWCAS`
+// on aligned words.
+func dwcasImpl() string {
+	switch {
+	case raceEnabled, puregoEnabled:
+	case runtime.GOARCH == "amd64" && CPU.HasCX16:
+		return "cmpxchg16b"
+	case runtime.GOARCH == "arm64" && CPU.HasLSE:
+		return "casp"
+	case runtime.GOARCH == "arm64":
+		return "ldaxp/stlxp"
+	}
+	return "striped locks"
+}
+
+func init() {
+	// fail fast rather than corrupt memory.
+	if err := checkArch(); err != nil {
+		panic(err)
+	}
+}
+
+// checkArch verifies assumptions of the package
+// about word size, layout and allocator
+// alignment.
+func checkArch() error {
+	if bits.UintSize != 32 && bits.UintSize != 64 {
+		return fmt.Errorf("lfring: unsupported word size %d on %s", bits.UintSize, runtime.GOARCH)
+	}
+	if reflect.TypeOf(uintptr(0)).Size() != reflect.TypeOf((*int)(nil)).Size() {
+		return fmt.Errorf("lfring: pointers do not fit words on %s", runtime.GOARCH)
+	}
+	// 64-bit atomics need 8-byte alignment, which
+	// 32-bit platforms only grant to the first
+	// word of an allocation; cursors rely on pads.
+	rt := reflect.TypeOf((*Ring)(nil)).Elem()
+	for _, name := range []string{"wri", "rdi", "count", "casfail"} {
+		if f, _ := rt.FieldByName(name); f.Offset%8 != 0 {
+			return fmt.Errorf("lfring: misaligned ring cursor at offset %d on %s", f.Offset, runtime.GOARCH)
+		}
+	}
+	if err := checkTags(); err != nil {
+		return err
+	}
+	if CacheLineSize%8 != 0 {
+		return fmt.Errorf("lfring: cache line size %d is not a multiple of 8", CacheLineSize)
+	}
+	return nil
+}
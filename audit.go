@@ -0,0 +1,225 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"encoding/json"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults
+const (
+	// cAUDITBATCH is maximum number of records
+	// written to a sink at once.
+	cAUDITBATCH = 256
+)
+
+// - MARK: Audit section.
+
+// AuditOp is an operation recorded by `Audit`.
+type AuditOp uint8
+
+// Audited operations.
+const (
+	AuditPush AuditOp = iota // item pushed
+	AuditPop                 // item popped
+	AuditDrop                // push rejected or discarded
+)
+
+// String returns name of operation.
+func (o AuditOp) String() string {
+	switch o {
+	case AuditPush:
+		return "push"
+	case AuditPop:
+		return "pop"
+	case AuditDrop:
+		return "drop"
+	}
+	return "invalid"
+}
+
+// MarshalText implements `encoding.TextMarshaler`
+// interface.
+func (o AuditOp) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// AuditRecord is an entry of the audit trail.
+type AuditRecord struct {
+	Op    AuditOp   // operation
+	Seq   uint64    // ring position
+	Token uint64    // caller token, e.g. producer id
+	At    time.Time // operation time
+}
+
+// AuditSink persists audit records, e.g. to an
+// append-only file. It must not retain `recs`.
+type AuditSink interface {
+	WriteAudit(recs []AuditRecord) error
+}
+
+// AuditFunc adapts a function to `AuditSink`.
+type AuditFunc func(recs []AuditRecord) error
+
+// WriteAudit implements `AuditSink` interface.
+func (f AuditFunc) WriteAudit(recs []AuditRecord) error {
+	return f(recs)
+}
+
+// JSONAuditSink returns a sink writing records to
+// `w` as JSON lines.
+func JSONAuditSink(w io.Writer) AuditSink {
+	enc := json.NewEncoder(w)
+	return AuditFunc(func(recs []AuditRecord) error {
+		for i := range recs {
+			if err := enc.Encode(&recs[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Audit keeps an append-only trail of operations
+// on a ring, for environments which must account
+// for every message: who pushed or popped which
+// position and when. Operations record into a
+// secondary ring and are written to a sink
+// asynchronously by `Run`, so a slow sink only
+// delays producers once the log is full, and no
+// record is ever dropped. Operations which bypass
+// `Audit` are not recorded.
+type Audit struct {
+	// 64bit aligned
+	written uint64 // records written to sink
+	failed  uint64 // failed sink writes
+	ring    *Ring
+	log     *Ring         // pending records
+	sink    AuditSink     // destination of records
+	batch   []AuditRecord // records being written
+}
+
+// NewAudit allocates and initializes a new `Audit`
+// of `ring` writing to `sink`, buffering up to
+// `capacity` records.
+func NewAudit(ring *Ring, sink AuditSink, capacity uint64) *Audit {
+	return &Audit{
+		ring:  ring,
+		log:   NewRing(capacity, WithPolicy(Block), WithMode(MPSC)),
+		sink:  sink,
+		batch: make([]AuditRecord, 0, cAUDITBATCH),
+	}
+}
+
+// Ring returns audited ring.
+func (a *Audit) Ring() *Ring {
+	return a.ring
+}
+
+// Push pushes `data` like `Ring.Push` on behalf
+// of `token` and records the outcome.
+func (a *Audit) Push(data interface{}, token uint64) bool {
+	pos, ok := a.ring.pushPos(data)
+	if ok {
+		a.record(AuditPush, pos, token)
+		return true
+	}
+	a.record(AuditDrop, pos, token)
+	return a.ring.dropped()
+}
+
+// Pop pops like `Ring.Pop` on behalf of `token`
+// and records the popped position.
+func (a *Audit) Pop(token uint64) (interface{}, bool) {
+	data, pos, ok := a.ring.popPos()
+	if ok {
+		a.record(AuditPop, pos, token)
+	}
+	return data, ok
+}
+
+// Flush writes up to `cAUDITBATCH` pending records
+// to sink and returns their number. Records of a
+// failed write are retried by next flush. It must
+// not be called concurrently with itself or
+// `Run`.
+func (a *Audit) Flush() (int, error) {
+	for len(a.batch) < cAUDITBATCH {
+		v, ok := a.log.Pop()
+		if !ok {
+			break
+		}
+		a.batch = append(a.batch, v.(AuditRecord))
+	}
+	if len(a.batch) == 0 {
+		return 0, nil
+	}
+	if err := a.sink.WriteAudit(a.batch); err != nil {
+		atomic.AddUint64(&a.failed, 1)
+		return 0, err
+	}
+	n := len(a.batch)
+	a.batch = a.batch[:0]
+	atomic.AddUint64(&a.written, uint64(n))
+	return n, nil
+}
+
+// Run flushes records until `stop` is closed and
+// then flushes remaining ones, giving up on the
+// first failed write.
+func (a *Audit) Run(stop <-chan struct{}) {
+	for idle := 0; ; {
+		select {
+		case <-stop:
+			for {
+				if n, err := a.Flush(); n == 0 || err != nil {
+					return
+				}
+			}
+		default:
+		}
+		if n, _ := a.Flush(); n > 0 {
+			idle = 0
+			continue
+		}
+		a.log.pause(idle)
+		idle++
+	}
+}
+
+// Stats returns written and pending records and
+// failed sink writes.
+func (a *Audit) Stats() (written, pending, failed uint64) {
+	return atomic.LoadUint64(&a.written), a.log.Len(), atomic.LoadUint64(&a.failed)
+}
+
+// record appends a record to the log, waiting
+// while it is full.
+func (a *Audit) record(op AuditOp, seq, token uint64) {
+	a.log.Push(AuditRecord{Op: op, Seq: seq, Token: token, At: a.ring.clock.Now()})
+}
@@ -0,0 +1,115 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestAudit(t *testing.T) {
+	var (
+		buf  bytes.Buffer
+		fail bool = true
+		sink AuditSink
+		a    *Audit
+	)
+	out := JSONAuditSink(&buf)
+	sink = AuditFunc(func(recs []AuditRecord) error {
+		if fail {
+			fail = false
+			return errors.New("sink unavailable")
+		}
+		return out.WriteAudit(recs)
+	})
+	a = NewAudit(NewRing(2), sink, 8)
+	a.Push("a", 1)
+	a.Push("b", 2)
+	a.Push("c", 3)
+	if v, ok := a.Pop(7); !ok || v.(string) != "a" {
+		t.Fatalf("assertion failed, expected a, got %v.", v)
+	}
+	// failed batch is kept and retried
+	if _, err := a.Flush(); err == nil {
+		t.Fatal("assertion failed, expected sink error.")
+	}
+	if n, err := a.Flush(); n != 4 || err != nil {
+		t.Fatalf("assertion failed, flushed(%d), err(%v).", n, err)
+	}
+	if w, p, f := a.Stats(); w != 4 || p != 0 || f != 1 {
+		t.Fatalf("assertion failed, written(%d), pending(%d), failed(%d).", w, p, f)
+	}
+	var want = []string{`"Op":"push","Seq":0,"Token":1`, `"Op":"push","Seq":1,"Token":2`, `"Op":"drop","Seq":2,"Token":3`, `"Op":"pop","Seq":0,"Token":7`}
+	for i, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if !strings.Contains(line, want[i]) {
+			t.Fatalf("assertion failed, expected %s in %s.", want[i], line)
+		}
+	}
+}
+
+func TestAuditRun(t *testing.T) {
+	const count = 1000
+	var (
+		mu   sync.Mutex
+		recs []AuditRecord
+		a    *Audit = NewAudit(NewRing(16), AuditFunc(func(b []AuditRecord) error {
+			mu.Lock()
+			recs = append(recs, b...)
+			mu.Unlock()
+			return nil
+		}), 4)
+		stop chan struct{} = make(chan struct{})
+		done chan struct{} = make(chan struct{})
+	)
+	go func() {
+		a.Run(stop)
+		close(done)
+	}()
+	for i := 0; i < count; i++ {
+		for !a.Push(i, 1) {
+			a.Pop(2)
+		}
+	}
+	close(stop)
+	<-done
+	mu.Lock()
+	defer mu.Unlock()
+	var ops [3]int
+	for _, rec := range recs {
+		ops[rec.Op]++
+	}
+	if ops[AuditPush] != count || ops[AuditPop] != ops[AuditDrop] {
+		t.Fatalf("assertion failed, ops(%v).", ops)
+	}
+	if _, err := json.Marshal(recs[0]); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"math/bits"
+	"runtime"
+	"time"
+)
+
+// Defaults
+const (
+	// cBACKOFFYIELDS is number of yields performed
+	// by CAS retry loops of ring once spinning
+	// reached its cap.
+	cBACKOFFYIELDS = 8
+	// cBACKOFFSLEEP caps jittered sleeps of CAS
+	// retry loops of ring under sustained
+	// contention.
+	cBACKOFFSLEEP = 50 * time.Microsecond
+	// cBACKOFFMINSLEEP is first sleep of a backoff.
+	cBACKOFFMINSLEEP = time.Microsecond
+	// cBACKOFFBATCH is number of failed attempts
+	// between context checks of `RetryCtx`.
+	cBACKOFFBATCH = 16
+)
+
+// casBackoff backs off context aware multi-word
+// operations, see `RDCSSCtx` and `KCSSCtx`.
+var casBackoff Backoff = Backoff{Cap: cDEFBACKOFFCAP, Yields: cBACKOFFYIELDS, Sleep: cBACKOFFSLEEP}
+
+// - MARK: Backoff section.
+
+// Backoff is an exponential backoff for CAS retry
+// loops. Attempt `n` spins for `min(2^n, Cap)`
+// iterations until the cap is reached, then yields
+// control to scheduler for `Yields` attempts and
+// finally sleeps for a random duration below an
+// exponentially growing bound capped at `Sleep`
+// (full jitter). Jitter keeps contending goroutines
+// from retrying in lockstep, which a fixed yield
+// threshold does not. Without `Sleep`, it keeps
+// yielding. It implements `WaitStrategy`.
+type Backoff struct {
+	Cap    int           // spin cap
+	Yields int           // yielding attempts after spinning
+	Sleep  time.Duration // sleep cap, zero disables sleeping
+}
+
+// Wait implements `WaitStrategy` interface.
+func (b Backoff) Wait(n int) {
+	var spins int = bits.Len(uint(b.Cap)) // attempts spent spinning
+	switch {
+	case n < spins:
+		backoff(uint(n), b.Cap)
+	case n < spins+b.Yields || b.Sleep <= 0:
+		runtime.Gosched()
+	default:
+		k := uint(n - spins - b.Yields)
+		d := b.Sleep
+		if k < 30 && cBACKOFFMINSLEEP<<k < d {
+			d = cBACKOFFMINSLEEP << k
+		}
+		time.Sleep(time.Duration(rnd.Int64N(int64(d)) + 1))
+	}
+}
+
+// Retry calls `op` until it returns true, backing
+// off between attempts, e.g. around `RDCSS` based
+// updates. It returns number of failed attempts.
+func (b Backoff) Retry(op func() bool) int {
+	var n int
+	for !op() {
+		b.Wait(n)
+		n++
+	}
+	return n
+}
+
+// RetryCtx is `Retry` which gives up once `ctx`
+// is done. Context is checked before the first
+// attempt and after every batch of failed
+// attempts, so a cancelled operation stops
+// within a bounded number of retries. It returns
+// number of failed attempts and `ctx.Err()` when
+// it gave up.
+func (b Backoff) RetryCtx(ctx context.Context, op func() bool) (int, error) {
+	var n int
+	for {
+		if n%cBACKOFFBATCH == 0 {
+			if err := ctx.Err(); err != nil {
+				return n, err
+			}
+		}
+		if op() {
+			return n, nil
+		}
+		b.Wait(n)
+		n++
+	}
+}
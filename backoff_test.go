@@ -0,0 +1,82 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoffPhases(t *testing.T) {
+	var (
+		b     Backoff = Backoff{Cap: 8, Yields: 2, Sleep: 2 * time.Millisecond}
+		start time.Time
+	)
+	// spinning and yielding phases never sleep
+	start = time.Now()
+	for n := 0; n < 6; n++ {
+		b.Wait(n)
+	}
+	if d := time.Since(start); d > 10*time.Millisecond {
+		t.Fatalf("assertion failed, spinning phases took %v.", d)
+	}
+	// sleeps are jittered below the cap
+	start = time.Now()
+	for n := 6; n < 26; n++ {
+		b.Wait(n)
+	}
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Fatalf("assertion failed, sleeping phase took %v.", d)
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	var (
+		b       Backoff = Backoff{Cap: 16, Yields: 4, Sleep: 10 * time.Microsecond}
+		counter uint64
+		wg      sync.WaitGroup
+	)
+	if n := b.Retry(func() bool { return true }); n != 0 {
+		t.Fatalf("assertion failed, n(%d)!=0.", n)
+	}
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				b.Retry(func() bool {
+					v := atomic.LoadUint64(&counter)
+					return atomic.CompareAndSwapUint64(&counter, v, v+1)
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if counter != 4000 {
+		t.Fatalf("assertion failed, counter(%d)!=4000.", counter)
+	}
+}
@@ -23,10 +23,21 @@
  */
 
 // Package lfring provides Lock-Free Multi-Reader, Multi-Writer Ring Buffer implementation.
+//
+// Building with the `purego` tag compiles the
+// package without unsafe while keeping its API,
+// except where a signature carries an
+// `unsafe.Pointer`: `TagSafe`, `AllocAligned`,
+// `RDCSSCtx`, `KCSSCtx`, `RingDescriptor` and
+// the `epoch` and `hazard` packages take typed
+// pointers, slices or interfaces instead.
+// `Tagged` words and `DWCAS` fall back to locks,
+// `SlotRing` keeps sequences apart from records,
+// and prefetching is a no-op. Features needing
+// shared memory, i.e. `MmapRing`, io_uring, perf
+// buffers and package shm, return errors.
 package lfring
 
-import "unsafe"
-
 // Defaults
 const (
 	// ui64MASK is maximum int value
@@ -37,16 +48,63 @@ const (
 	// cWRSCHDTHRESHOLD is writer's spin threshold
 	// before yielding control with `runtime.Gosched()`.
 	cWRSCHDTHRESHOLD = 1000
+	// cRDLOCK is read-index bit held by a consumer
+	// with exclusive access to the head.
+	cRDLOCK = uint64(1) << 63
+	// cWRCLOSED is write-index bit of a closed
+	// ring which rejects further pushes.
+	cWRCLOSED = uint64(1) << 63
 )
 
 // - MARK: Struct section.
 
-// Ring is a aligned struct with size of 64 bytes
-// used to implement ring buffer. Note that ring
-// capacity is always rounded to next power of 2.
+// CacheLinePad is a cache line sized padding used
+// to keep frequently written fields on separate
+// cache lines and avoid false sharing.
+type CacheLinePad struct {
+	_ [CacheLineSize]byte
+}
+
+// Ring is a aligned struct used to implement
+// bounded MPMC ring buffer with per-slot sequence
+// numbers (D. Vyukov). Cursors written by
+// producers and consumers live on separate cache
+// lines. Note that ring capacity is always
+// rounded to next power of 2.
 type Ring struct {
 	// 64bit aligned
-	nodes                  []unsafe.Pointer // storage with capacity `size`, pow2
-	wri, rdi, maxrdi, size uint64           // write, read, max-read and size (mask) indexes
-	count                  uint64           // occupancy counter
+	_       CacheLinePad
+	wri     uint64 // write index
+	ticket  uint64 // producer ticket (fairness)
+	serving uint64 // ticket being served (fairness)
+	_       CacheLinePad
+	rdi     uint64 // read index
+	_       CacheLinePad
+	count   uint64 // occupancy counter
+	_       CacheLinePad
+	casfail uint64 // failed CAS attempts (slow path)
+	yields  uint64 // yields to scheduler
+	_       CacheLinePad
+	// read-mostly
+	size     uint64         // size (mask)
+	nodes    []interface{}  // storage with capacity `size`, pow2
+	seqs     []uint64       // per-slot sequence numbers
+	fair     FairnessPolicy // producer fairness policy
+	slow     bool           // hooks or single sides enabled, see `hook`
+	sentinel *Sentinel      // sentinel mode, nil when disabled
+	wmark    *Watermark     // event-time watermark, nil when disabled
+	wait     WaitStrategy   // waiting between failed attempts
+	signal   Signaler       // parking wait strategy, or nil
+	// options, see `Option`
+	mode   Mode       // producer/consumer cardinality
+	order  Ordering   // cursor store ordering of single sides
+	policy Policy     // full ring behavior of `Push`
+	shift  uint       // log2 of sequence stride
+	stats  *ringStats // statistics, nil when disabled
+	maxcap uint64     // capacity limit (construction)
+	tracer *Tracer    // event hooks, nil when disabled
+	lat    *latency   // time-in-queue, nil when disabled
+	ttl    *expiry    // item deadlines, nil when disabled
+	clock  Clock      // time source of time dependent features
+	press  *Pressure  // backpressure levels, nil when disabled
 }
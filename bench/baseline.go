@@ -0,0 +1,74 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package bench
+
+import (
+	"embed"
+	"errors"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrNoBaseline is returned for architectures
+	// without recorded baseline.
+	ErrNoBaseline = errors.New("lfring: no benchmark baseline")
+)
+
+// baselines are recorded outputs of
+// `go test -bench . -benchmem -count 5` of
+// package lfring, one file per GOARCH.
+//
+//go:embed baselines/*.txt
+var baselines embed.FS
+
+// - MARK: Baseline section.
+
+// Baseline returns recorded benchmark results of
+// lfring for `goarch`, e.g. `runtime.GOARCH`.
+// Its `Config` names the recording hardware;
+// compare against it with `Compare` to spot
+// regressions of an lfring build, keeping in mind
+// that different hardware shifts all results.
+func Baseline(goarch string) (*Set, error) {
+	f, err := baselines.Open("baselines/" + goarch + ".txt")
+	if err != nil {
+		return nil, ErrNoBaseline
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Arches returns architectures with recorded
+// baselines.
+func Arches() []string {
+	var arches []string
+	entries, _ := baselines.ReadDir("baselines")
+	for _, e := range entries {
+		arches = append(arches, strings.TrimSuffix(e.Name(), ".txt"))
+	}
+	sort.Strings(arches)
+	return arches
+}
@@ -0,0 +1,207 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+// Package bench parses and compares results of
+// `go test -bench` in the manner of `benchstat`
+// and ships recorded baselines, so users of
+// lfring can detect performance regressions
+// across Go versions and hardware.
+package bench
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNoResults is returned when input holds no
+	// benchmark results.
+	ErrNoResults = errors.New("lfring: no benchmark results")
+)
+
+// - MARK: Result section.
+
+// Result is one line of benchmark output.
+type Result struct {
+	Name    string             // name without GOMAXPROCS suffix
+	Procs   int                // GOMAXPROCS, 1 when not reported
+	N       int                // iterations
+	Metrics map[string]float64 // value per unit, e.g. "ns/op"
+}
+
+// Set is a parsed benchmark output.
+type Set struct {
+	Config  map[string]string // e.g. goos, goarch, cpu
+	Results []Result
+}
+
+// Parse reads benchmark output of `go test -bench`
+// from `r`. Configuration lines such as `goarch:`
+// are collected, other lines are ignored.
+func Parse(r io.Reader) (*Set, error) {
+	var (
+		s  *Set           = &Set{Config: map[string]string{}}
+		sc *bufio.Scanner = bufio.NewScanner(r)
+	)
+	for sc.Scan() {
+		line := sc.Text()
+		if res, ok := parseResult(line); ok {
+			s.Results = append(s.Results, res)
+			continue
+		}
+		if i := strings.Index(line, ": "); i > 0 && !strings.ContainsAny(line[:i], " \t") {
+			s.Config[line[:i]] = strings.TrimSpace(line[i+2:])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(s.Results) == 0 {
+		return nil, ErrNoResults
+	}
+	return s, nil
+}
+
+// parseResult parses a result line, e.g.
+// `BenchmarkRingPushPop-8 100 61.5 ns/op 8 B/op`.
+func parseResult(line string) (Result, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || len(fields)%2 != 0 || !strings.HasPrefix(fields[0], "Benchmark") {
+		return Result{}, false
+	}
+	n, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Result{}, false
+	}
+	res := Result{Name: fields[0], Procs: 1, N: n, Metrics: map[string]float64{}}
+	if i := strings.LastIndexByte(res.Name, '-'); i > 0 {
+		if procs, err := strconv.Atoi(res.Name[i+1:]); err == nil {
+			res.Name, res.Procs = res.Name[:i], procs
+		}
+	}
+	for i := 2; i < len(fields); i += 2 {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return Result{}, false
+		}
+		res.Metrics[fields[i+1]] = v
+	}
+	return res, true
+}
+
+// - MARK: Stat section.
+
+// Stat summarizes samples of a benchmark.
+type Stat struct {
+	Median float64
+	Min    float64
+	Max    float64
+	N      int // number of samples
+}
+
+// Summarize returns statistics of metric `unit`
+// per benchmark name.
+func (s *Set) Summarize(unit string) map[string]Stat {
+	var (
+		samples map[string][]float64 = map[string][]float64{}
+		stats   map[string]Stat      = map[string]Stat{}
+	)
+	for _, r := range s.Results {
+		if v, ok := r.Metrics[unit]; ok {
+			samples[r.Name] = append(samples[r.Name], v)
+		}
+	}
+	for name, vs := range samples {
+		sort.Float64s(vs)
+		st := Stat{Min: vs[0], Max: vs[len(vs)-1], N: len(vs)}
+		if len(vs)%2 == 1 {
+			st.Median = vs[len(vs)/2]
+		} else {
+			st.Median = (vs[len(vs)/2-1] + vs[len(vs)/2]) / 2
+		}
+		stats[name] = st
+	}
+	return stats
+}
+
+// - MARK: Compare section.
+
+// Delta is the change of a benchmark between two
+// sets. Lower values are assumed to be better,
+// as for time, bytes and allocations per op.
+type Delta struct {
+	Name        string
+	Unit        string
+	Old         Stat
+	New         Stat
+	Change      float64 // relative change of medians, 0.1 is 10% worse
+	Significant bool    // sample ranges do not overlap
+	Regression  bool    // significant and worse than threshold
+}
+
+// Compare compares metric `unit` of benchmarks
+// present in both `old` and `cur`, sorted by
+// name. A change is significant when sample
+// ranges do not overlap, a conservative stand-in
+// for the test of `benchstat`; run benchmarks
+// with `-count` of 5 or more. Significant changes
+// worse than `threshold`, e.g. 0.05 for 5%, are
+// regressions.
+func Compare(old, cur *Set, unit string, threshold float64) []Delta {
+	var (
+		prev   map[string]Stat = old.Summarize(unit)
+		next   map[string]Stat = cur.Summarize(unit)
+		deltas []Delta
+	)
+	for name, o := range prev {
+		n, ok := next[name]
+		if !ok {
+			continue
+		}
+		d := Delta{Name: name, Unit: unit, Old: o, New: n}
+		if o.Median != 0 {
+			d.Change = (n.Median - o.Median) / o.Median
+		}
+		d.Significant = n.Min > o.Max || n.Max < o.Min
+		d.Regression = d.Significant && d.Change > threshold
+		deltas = append(deltas, d)
+	}
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i].Name < deltas[j].Name })
+	return deltas
+}
+
+// Regressions returns regressions of `deltas`.
+func Regressions(deltas []Delta) []Delta {
+	var regs []Delta
+	for _, d := range deltas {
+		if d.Regression {
+			regs = append(regs, d)
+		}
+	}
+	return regs
+}
@@ -0,0 +1,110 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package bench
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	oldOutput = `goos: linux
+goarch: amd64
+pkg: github.com/mitghi/lfring
+BenchmarkRingPushPop-8   	 100	  60.0 ns/op	  8 B/op	  1 allocs/op
+BenchmarkRingPushPop-8   	 100	  61.0 ns/op	  8 B/op	  1 allocs/op
+BenchmarkRingPushPop-8   	 100	  62.0 ns/op	  8 B/op	  1 allocs/op
+BenchmarkRingFairness/ticket-8	10	40.0 ns/op	0.06 cv
+BenchmarkRingFairness/ticket-8	10	42.0 ns/op	0.05 cv
+PASS
+`
+	newOutput = `BenchmarkRingPushPop-8   	 100	  70.0 ns/op	  8 B/op	  1 allocs/op
+BenchmarkRingPushPop-8   	 100	  71.0 ns/op	  8 B/op	  1 allocs/op
+BenchmarkRingPushPop-8   	 100	  72.0 ns/op	  8 B/op	  1 allocs/op
+BenchmarkRingFairness/ticket-8	10	41.0 ns/op	0.06 cv
+BenchmarkRingFairness/ticket-8	10	45.0 ns/op	0.05 cv
+`
+)
+
+func TestParse(t *testing.T) {
+	s, err := Parse(strings.NewReader(oldOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Results) != 5 || s.Config["goarch"] != "amd64" {
+		t.Fatalf("assertion failed, unexpected set %+v.", s)
+	}
+	r := s.Results[3]
+	if r.Name != "BenchmarkRingFairness/ticket" || r.Procs != 8 || r.N != 10 || r.Metrics["cv"] != 0.06 {
+		t.Fatalf("assertion failed, unexpected result %+v.", r)
+	}
+	st := s.Summarize("ns/op")["BenchmarkRingPushPop"]
+	if st.Median != 61 || st.Min != 60 || st.Max != 62 || st.N != 3 {
+		t.Fatalf("assertion failed, unexpected stat %+v.", st)
+	}
+	if _, err := Parse(strings.NewReader("PASS\n")); err != ErrNoResults {
+		t.Fatalf("assertion failed, expected ErrNoResults, got %v.", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	old, _ := Parse(strings.NewReader(oldOutput))
+	cur, _ := Parse(strings.NewReader(newOutput))
+	deltas := Compare(old, cur, "ns/op", 0.05)
+	if len(deltas) != 2 {
+		t.Fatalf("assertion failed, expected 2 deltas, got %d.", len(deltas))
+	}
+	// overlapping ranges are noise
+	if d := deltas[0]; d.Name != "BenchmarkRingFairness/ticket" || d.Significant || d.Regression {
+		t.Fatalf("assertion failed, unexpected delta %+v.", d)
+	}
+	regs := Regressions(deltas)
+	if len(regs) != 1 || regs[0].Name != "BenchmarkRingPushPop" || regs[0].Change < 0.16 || regs[0].Change > 0.17 {
+		t.Fatalf("assertion failed, unexpected regressions %+v.", regs)
+	}
+	if len(Regressions(Compare(old, cur, "B/op", 0.05))) != 0 {
+		t.Fatal("assertion failed, expected no allocation regression.")
+	}
+	// improvements are not regressions
+	if len(Regressions(Compare(cur, old, "ns/op", 0.05))) != 0 {
+		t.Fatal("assertion failed, expected no regression.")
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	if arches := Arches(); len(arches) == 0 || arches[0] != "amd64" {
+		t.Fatalf("assertion failed, unexpected arches %v.", arches)
+	}
+	s, err := Baseline("amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Config["goarch"] != "amd64" || s.Summarize("ns/op")["BenchmarkRingPushPop"].N == 0 {
+		t.Fatal("assertion failed, expected recorded push/pop baseline.")
+	}
+	if _, err := Baseline("mips"); err != ErrNoBaseline {
+		t.Fatalf("assertion failed, expected ErrNoBaseline, got %v.", err)
+	}
+}
@@ -0,0 +1,198 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Defaults
+const (
+	// cBRIDGEIDLE is the longest sleep of an idle
+	// bridge pump.
+	cBRIDGEIDLE = time.Millisecond
+)
+
+// - MARK: Bridge section.
+
+// ChanBridge exposes a ring as channel endpoints
+// backed by pump goroutines, so the ring drops into
+// select-based code. Pumps are started on first
+// use of an endpoint and stopped by `Close`.
+type ChanBridge struct {
+	ring  *Ring
+	send  chan interface{}
+	recv  chan interface{}
+	ready chan struct{}
+	stop  chan struct{}
+	once  [3]sync.Once
+	wg    sync.WaitGroup
+	done  sync.Once
+}
+
+// Bridge returns a `ChanBridge` of ring whose
+// channels have `buffer` capacity.
+func (r *Ring) Bridge(buffer int) *ChanBridge {
+	return &ChanBridge{
+		ring:  r,
+		send:  make(chan interface{}, buffer),
+		recv:  make(chan interface{}, buffer),
+		ready: make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+	}
+}
+
+// SendChan returns a channel whose values are
+// pushed to ring, waiting while it is full.
+func (b *ChanBridge) SendChan() chan<- interface{} {
+	b.start(0, b.pumpSend)
+	return b.send
+}
+
+// RecvChan returns a channel receiving values
+// popped from ring. It is closed by `Close`.
+func (b *ChanBridge) RecvChan() <-chan interface{} {
+	b.start(1, b.pumpRecv)
+	return b.recv
+}
+
+// ReadyChan returns a channel which is signaled
+// when ring holds items. Signals are coalesced; a
+// receiver should drain the ring with `Pop`.
+func (b *ChanBridge) ReadyChan() <-chan struct{} {
+	b.start(2, b.pumpReady)
+	return b.ready
+}
+
+// Close stops pumps and waits for them to exit.
+// Values sent but not yet pushed are pushed
+// before the send pump exits, while the ring has
+// room.
+func (b *ChanBridge) Close() {
+	b.done.Do(func() {
+		close(b.stop)
+		b.wg.Wait()
+	})
+}
+
+// start runs pump `fn` once.
+func (b *ChanBridge) start(i int, fn func()) {
+	b.once[i].Do(func() {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			fn()
+		}()
+	})
+}
+
+// pumpSend pushes values of send channel.
+func (b *ChanBridge) pumpSend() {
+	for {
+		select {
+		case v := <-b.send:
+			for i := 0; !b.ring.Push(v); i++ {
+				if !b.idle(i) {
+					return
+				}
+			}
+		case <-b.stop:
+			// flush buffered values
+			for {
+				select {
+				case v := <-b.send:
+					if !b.ring.Push(v) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// pumpRecv moves popped values to recv channel.
+func (b *ChanBridge) pumpRecv() {
+	defer close(b.recv)
+	for i := 0; ; {
+		v, ok := b.ring.Pop()
+		if !ok {
+			if !b.idle(i) {
+				return
+			}
+			i++
+			continue
+		}
+		i = 0
+		select {
+		case b.recv <- v:
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// pumpReady signals ready channel while ring
+// holds items.
+func (b *ChanBridge) pumpReady() {
+	for i := 0; ; i++ {
+		if !b.ring.IsEmpty() {
+			select {
+			case b.ready <- struct{}{}:
+			default:
+			}
+		}
+		if !b.idle(i) {
+			return
+		}
+	}
+}
+
+// idle waits according to number of idle rounds
+// `i`: spinning first, then yielding and finally
+// sleeping up to `cBRIDGEIDLE`. It returns false
+// once bridge is closed.
+func (b *ChanBridge) idle(i int) bool {
+	select {
+	case <-b.stop:
+		return false
+	default:
+	}
+	switch {
+	case i < cRDSCHDTHRESHOLD:
+	case i < 2*cRDSCHDTHRESHOLD:
+		runtime.Gosched()
+	default:
+		d := time.Duration(i-2*cRDSCHDTHRESHOLD+1) * time.Microsecond
+		if d > cBRIDGEIDLE {
+			d = cBRIDGEIDLE
+		}
+		time.Sleep(d)
+	}
+	return true
+}
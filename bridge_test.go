@@ -0,0 +1,78 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChanBridge(t *testing.T) {
+	const items = 1000
+	var (
+		r *Ring       = NewRing(16)
+		b *ChanBridge = r.Bridge(4)
+	)
+	defer b.Close()
+	go func() {
+		send := b.SendChan()
+		for i := 0; i < items; i++ {
+			send <- i
+		}
+	}()
+	recv := b.RecvChan()
+	for i := 0; i < items; i++ {
+		select {
+		case v := <-recv:
+			if v.(int) != i {
+				t.Fatalf("assertion failed, expected %d, got %v.", i, v)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("assertion failed, timed out.")
+		}
+	}
+}
+
+func TestChanBridgeReady(t *testing.T) {
+	var (
+		r *Ring       = NewRing(4)
+		b *ChanBridge = r.Bridge(0)
+	)
+	ready := b.ReadyChan()
+	r.Push(1)
+	select {
+	case <-ready:
+	case <-time.After(5 * time.Second):
+		t.Fatal("assertion failed, ready not signaled.")
+	}
+	if v, ok := r.Pop(); !ok || v.(int) != 1 {
+		t.Fatalf("assertion failed, expected 1, got %v.", v)
+	}
+	recv := b.RecvChan()
+	b.Close()
+	if _, ok := <-recv; ok {
+		t.Fatal("assertion failed, expected closed recv channel.")
+	}
+}
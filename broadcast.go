@@ -0,0 +1,457 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// - MARK: Broadcast section.
+
+// Broadcast is a ring where every subscriber sees
+// every item (pub/sub fan-out) instead of competing
+// for it. Each subscriber owns a read cursor and a
+// slot is only reused once all subscribers have
+// passed it; hence the slowest subscriber applies
+// backpressure to producers. It is built on
+// `Sequencer` with subscriber cursors as gating
+// sequences.
+type Broadcast struct {
+	seq   *Sequencer
+	nodes []interface{}
+	subs  atomic.Pointer[[]*Subscriber] // copy-on-write
+}
+
+// Subscriber is a read cursor of a `Broadcast`.
+// A subscriber must be used by a single
+// goroutine; `Lag`, `Offset` and `Drop` may be
+// called from any goroutine.
+type Subscriber struct {
+	// 64bit aligned
+	cursor  Sequence // committed offset
+	bc      *Broadcast
+	name    string
+	barrier *Barrier
+	dropped uint32   // drop request
+	filter  Filter   // evaluated by producers
+	match   []uint64 // bitmap of matching slots
+}
+
+// Filter reports whether a subscriber is
+// interested in `item`. It is called by
+// producers and must be safe for concurrent
+// use.
+type Filter func(item interface{}) bool
+
+// NewBroadcast allocates and initializes a new
+// `Broadcast` and returns a pointer to it. Note,
+// `capacity` is always rounded to nearest power
+// of two.
+func NewBroadcast(capacity uint64) *Broadcast {
+	b := &Broadcast{seq: NewSequencer(capacity)}
+	b.nodes = make([]interface{}, b.seq.Cap())
+	subs := make([]*Subscriber, 0)
+	b.subs.Store(&subs)
+	return b
+}
+
+// Cap returns capacity of broadcast ring.
+func (b *Broadcast) Cap() uint64 {
+	return b.seq.Cap()
+}
+
+// Push publishes `data` to all subscribers and
+// returns true when successfull. False is returned
+// when the slowest subscriber is a full ring
+// behind. Without subscribers, items are
+// published and overwritten freely.
+func (b *Broadcast) Push(data interface{}) bool {
+	pos, ok := b.seq.TryNext(1)
+	if !ok {
+		return false
+	}
+	idx := b.seq.Index(pos)
+	b.nodes[idx] = data
+	// filters run before publishing, so a match
+	// is marked by the time subscribers see it.
+	for _, s := range b.Subscribers() {
+		if s.filter != nil && s.filter(data) {
+			s.mark(idx)
+		}
+	}
+	b.seq.Publish(pos, 1)
+	return true
+}
+
+// Subscribe registers a new anonymous subscriber
+// which receives items pushed from now on.
+func (b *Broadcast) Subscribe() *Subscriber {
+	return b.subscribe("")
+}
+
+// SubscribeFilter registers a new anonymous
+// subscriber which only receives items matching
+// `filter`. Producers evaluate `filter` and mark
+// matching slots in a bitmap, so subscriber skips
+// uninteresting items 64 at a time instead of
+// visiting each one of them. It still has to
+// poll to release skipped slots to producers.
+func (b *Broadcast) SubscribeFilter(filter Filter) *Subscriber {
+	s := &Subscriber{
+		filter: filter,
+		match:  make([]uint64, (b.seq.Cap()+63)/64),
+	}
+	return b.register(s)
+}
+
+// SubscribeKeys registers a new anonymous
+// subscriber which only receives items whose
+// key, as returned by `key`, is one of `keys`.
+func (b *Broadcast) SubscribeKeys(key KeyFunc, keys ...interface{}) *Subscriber {
+	set := make(map[interface{}]struct{}, len(keys))
+	for _, k := range keys {
+		set[k] = struct{}{}
+	}
+	return b.SubscribeFilter(func(item interface{}) bool {
+		_, ok := set[key(item)]
+		return ok
+	})
+}
+
+// Named returns the subscriber registered as
+// `name`, registering a new one when there is
+// none. This lets a restarted consumer resume
+// from its committed offset, turning the ring
+// into a minimal in-process event log.
+func (b *Broadcast) Named(name string) *Subscriber {
+	if s := b.Lookup(name); s != nil {
+		return s
+	}
+	return b.subscribe(name)
+}
+
+// Lookup returns the subscriber registered as
+// `name` or nil.
+func (b *Broadcast) Lookup(name string) *Subscriber {
+	for _, s := range b.Subscribers() {
+		if s.name == name && name != "" {
+			return s
+		}
+	}
+	return nil
+}
+
+// Subscribers returns registered subscribers.
+// The returned slice must not be modified.
+func (b *Broadcast) Subscribers() []*Subscriber {
+	return *b.subs.Load()
+}
+
+// Lagging returns subscribers which are more
+// than `threshold` items behind producers.
+func (b *Broadcast) Lagging(threshold uint64) []*Subscriber {
+	var lagging []*Subscriber
+	for _, s := range b.Subscribers() {
+		if s.Lag() > threshold {
+			lagging = append(lagging, s)
+		}
+	}
+	return lagging
+}
+
+// subscribe registers a new subscriber named
+// `name`. Concurrent registrations of the same
+// name are resolved in favour of the first one.
+func (b *Broadcast) subscribe(name string) *Subscriber {
+	return b.register(&Subscriber{name: name})
+}
+
+// register adds subscriber `s` to subscribers.
+func (b *Broadcast) register(s *Subscriber) *Subscriber {
+	name := s.name
+	s.bc, s.barrier = b, b.seq.NewBarrier()
+	s.cursor.Set(b.seq.Cursor())
+	for {
+		old := b.subs.Load()
+		for _, o := range *old {
+			if name != "" && o.name == name {
+				return o
+			}
+		}
+		next := make([]*Subscriber, 0, len(*old)+1)
+		next = append(append(next, *old...), s)
+		if b.subs.CompareAndSwap(old, &next) {
+			break
+		}
+	}
+	b.seq.AddGating(&s.cursor)
+	// producers may have passed initial cursor
+	// before registration; claims made after it
+	// are gated, so restart from current cursor.
+	s.cursor.Set(b.seq.Cursor())
+	return s
+}
+
+// Unsubscribe unregisters subscriber `s`, which
+// no longer holds back producers. It must be
+// called by the goroutine using `s`; use `Drop`
+// from other goroutines.
+func (b *Broadcast) Unsubscribe(s *Subscriber) {
+	for {
+		old := b.subs.Load()
+		next := make([]*Subscriber, 0, len(*old))
+		for _, o := range *old {
+			if o != s {
+				next = append(next, o)
+			}
+		}
+		if b.subs.CompareAndSwap(old, &next) {
+			break
+		}
+	}
+	b.seq.RemoveGating(&s.cursor)
+}
+
+// Name returns subscriber name.
+func (s *Subscriber) Name() string {
+	return s.name
+}
+
+// Offset returns committed offset, i.e. the
+// sequence of next item to be read.
+func (s *Subscriber) Offset() uint64 {
+	return s.cursor.Get()
+}
+
+// Lag returns number of items claimed by
+// producers which subscriber has not read yet.
+func (s *Subscriber) Lag() uint64 {
+	var (
+		off uint64 = s.cursor.Get()
+		cur uint64 = s.bc.seq.Cursor()
+	)
+	if cur < off {
+		return 0
+	}
+	return cur - off
+}
+
+// Reset skips pending items and moves committed
+// offset to the head of the ring. A filtered
+// subscriber only skips published items, since
+// producers may still mark claimed slots.
+func (s *Subscriber) Reset() {
+	if s.filter == nil {
+		s.cursor.Set(s.bc.seq.Cursor())
+		return
+	}
+	pos := s.cursor.Get()
+	end, ok := s.barrier.TryWaitFor(pos)
+	if !ok {
+		return
+	}
+	for ; pos < end; pos++ {
+		s.unmark(s.bc.seq.Index(pos))
+	}
+	s.cursor.Set(end)
+}
+
+// Drop requests removal of a slow subscriber.
+// Its owner unsubscribes on next `Pop` or
+// `Consume`, which return nothing from then on.
+// Until then, subscriber keeps holding back
+// producers, so it never reads a slot which is
+// being overwritten.
+func (s *Subscriber) Drop() {
+	atomic.StoreUint32(&s.dropped, 1)
+}
+
+// Dropped returns whether subscriber is dropped.
+func (s *Subscriber) Dropped() bool {
+	return atomic.LoadUint32(&s.dropped) != 0
+}
+
+// checkDropped unsubscribes `s` once dropped.
+func (s *Subscriber) checkDropped() bool {
+	if atomic.LoadUint32(&s.dropped) == 0 {
+		return false
+	}
+	if atomic.CompareAndSwapUint32(&s.dropped, 1, 2) {
+		s.bc.Unsubscribe(s)
+	}
+	return true
+}
+
+// Pop returns next item with a boolean indicating
+// success status. It returns immediately when no
+// item is available.
+func (s *Subscriber) Pop() (interface{}, bool) {
+	if s.checkDropped() {
+		return nil, false
+	}
+	if s.filter != nil {
+		return s.popFiltered()
+	}
+	pos := s.cursor.Get()
+	if !s.bc.seq.IsAvailable(pos) {
+		return nil, false
+	}
+	data := s.bc.nodes[s.bc.seq.Index(pos)]
+	s.cursor.Set(pos + 1)
+	return data, true
+}
+
+// Consume visits up to `max` available items in
+// order and passes each one to `fn`. Visiting
+// stops early when `fn` returns false; the item
+// passed to that call is consumed nonetheless.
+// Cursor is committed once, also when `fn`
+// panics. It returns the number of consumed
+// items.
+func (s *Subscriber) Consume(max int, fn func(interface{}) bool) int {
+	if s.checkDropped() {
+		return 0
+	}
+	if s.filter != nil {
+		return s.consumeFiltered(max, fn)
+	}
+	pos := s.cursor.Get()
+	end, ok := s.barrier.TryWaitFor(pos)
+	if !ok || max <= 0 {
+		return 0
+	}
+	if end-pos > uint64(max) {
+		end = pos + uint64(max)
+	}
+	n := pos
+	// commit even when `fn` panics, so the
+	// offending item is not redelivered.
+	defer func() { s.cursor.Set(n) }()
+	for n < end {
+		data := s.bc.nodes[s.bc.seq.Index(n)]
+		n++
+		if !fn(data) {
+			break
+		}
+	}
+	return int(n - pos)
+}
+
+// - MARK: Filter section.
+
+// popFiltered returns next matching item and
+// commits offset past skipped ones.
+func (s *Subscriber) popFiltered() (interface{}, bool) {
+	pos := s.cursor.Get()
+	end, ok := s.barrier.TryWaitFor(pos)
+	if !ok {
+		return nil, false
+	}
+	pos = s.next(pos, end)
+	if pos == end {
+		s.cursor.Set(end)
+		return nil, false
+	}
+	idx := s.bc.seq.Index(pos)
+	data := s.bc.nodes[idx]
+	s.unmark(idx)
+	s.cursor.Set(pos + 1)
+	return data, true
+}
+
+// consumeFiltered is `Consume` of a filtered
+// subscriber; `max` bounds matching items.
+func (s *Subscriber) consumeFiltered(max int, fn func(interface{}) bool) int {
+	pos := s.cursor.Get()
+	end, ok := s.barrier.TryWaitFor(pos)
+	if !ok || max <= 0 {
+		return 0
+	}
+	var (
+		n    int
+		done bool
+	)
+	defer func() { s.cursor.Set(pos) }()
+	for n < max && !done {
+		if pos = s.next(pos, end); pos == end {
+			break
+		}
+		idx := s.bc.seq.Index(pos)
+		data := s.bc.nodes[idx]
+		s.unmark(idx)
+		pos++
+		n++
+		done = !fn(data)
+	}
+	return n
+}
+
+// next returns position of first matching slot
+// in [pos, end), or `end` when there is none.
+func (s *Subscriber) next(pos, end uint64) uint64 {
+	size := s.bc.seq.Cap()
+	for pos < end {
+		idx := s.bc.seq.Index(pos)
+		w := atomic.LoadUint64(&s.match[idx>>6]) >> (idx & 63)
+		if w != 0 {
+			pos += uint64(bits.TrailingZeros64(w))
+			if pos > end {
+				return end
+			}
+			return pos
+		}
+		step := 64 - idx&63
+		if step > size-idx {
+			step = size - idx
+		}
+		pos += step
+	}
+	return end
+}
+
+// mark sets match bit of slot `idx`.
+func (s *Subscriber) mark(idx uint64) {
+	addr, bit := &s.match[idx>>6], uint64(1)<<(idx&63)
+	for {
+		old := atomic.LoadUint64(addr)
+		if atomic.CompareAndSwapUint64(addr, old, old|bit) {
+			return
+		}
+	}
+}
+
+// unmark clears match bit of slot `idx`. The
+// slot is not reused before subscriber commits
+// its offset past it.
+func (s *Subscriber) unmark(idx uint64) {
+	addr, bit := &s.match[idx>>6], uint64(1)<<(idx&63)
+	for {
+		old := atomic.LoadUint64(addr)
+		if old&bit == 0 || atomic.CompareAndSwapUint64(addr, old, old&^bit) {
+			return
+		}
+	}
+}
@@ -0,0 +1,244 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestBroadcastSerial(t *testing.T) {
+	var (
+		b    *Broadcast = NewBroadcast(4)
+		a    *Subscriber
+		c    *Subscriber
+		seen []int
+	)
+	// no subscribers; items are overwritten
+	for i := 0; i < 8; i++ {
+		if !b.Push(-1) {
+			t.Fatal("inconsistent state, unable to push.")
+		}
+	}
+	a, c = b.Subscribe(), b.Subscribe()
+	if _, ok := a.Pop(); ok {
+		t.Fatal("assertion failed, subscriber saw item pushed before subscription.")
+	}
+	for i := 0; i < 4; i++ {
+		b.Push(i)
+	}
+	// slowest subscriber holds back producers
+	if b.Push(4) {
+		t.Fatal("assertion failed, producer overtook subscriber.")
+	}
+	for i := 0; i < 4; i++ {
+		if v, ok := a.Pop(); !ok || v.(int) != i {
+			t.Fatalf("assertion failed, expected %d, got %v.", i, v)
+		}
+	}
+	if b.Push(4) {
+		t.Fatal("assertion failed, producer overtook subscriber.")
+	}
+	n := c.Consume(8, func(v interface{}) bool {
+		seen = append(seen, v.(int))
+		return len(seen) < 2
+	})
+	if n != 2 || seen[0] != 0 || seen[1] != 1 {
+		t.Fatalf("assertion failed, n(%d), seen(%v).", n, seen)
+	}
+	b.Unsubscribe(c)
+	if !b.Push(4) {
+		t.Fatal("inconsistent state, unable to push after unsubscribe.")
+	}
+	if v, ok := a.Pop(); !ok || v.(int) != 4 {
+		t.Fatalf("assertion failed, expected 4, got %v.", v)
+	}
+}
+
+func TestBroadcastConsumePanic(t *testing.T) {
+	var (
+		b *Broadcast = NewBroadcast(4)
+		s *Subscriber
+	)
+	s = b.Subscribe()
+	for i := 0; i < 3; i++ {
+		b.Push(i)
+	}
+	func() {
+		defer func() { recover() }()
+		s.Consume(4, func(v interface{}) bool {
+			if v.(int) == 1 {
+				panic("subscriber")
+			}
+			return true
+		})
+	}()
+	// offending item is not redelivered
+	if v, ok := s.Pop(); !ok || v.(int) != 2 {
+		t.Fatalf("assertion failed, expected 2, got %v.", v)
+	}
+}
+
+func TestBroadcastConcurrent(t *testing.T) {
+	const (
+		subscribers = 3
+		items       = 5000
+	)
+	var (
+		b    *Broadcast = NewBroadcast(64)
+		subs []*Subscriber
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < subscribers; i++ {
+		subs = append(subs, b.Subscribe())
+	}
+	for _, s := range subs {
+		wg.Add(1)
+		go func(s *Subscriber) {
+			defer wg.Done()
+			for exp := 0; exp < items; {
+				n := s.Consume(16, func(v interface{}) bool {
+					if v.(int) != exp {
+						t.Errorf("assertion failed, expected %d, got %v.", exp, v)
+					}
+					exp++
+					return true
+				})
+				if n == 0 {
+					runtime.Gosched()
+				}
+			}
+		}(s)
+	}
+	for i := 0; i < items; i++ {
+		for !b.Push(i) {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+}
+
+func TestBroadcastNamed(t *testing.T) {
+	var (
+		b       *Broadcast = NewBroadcast(8)
+		journal *Subscriber
+		audit   *Subscriber
+	)
+	journal, audit = b.Named("journal"), b.Named("audit")
+	if b.Named("journal") != journal || b.Lookup("replica") != nil || len(b.Subscribers()) != 2 {
+		t.Fatal("assertion failed, expected named subscribers to be registered once.")
+	}
+	for i := 0; i < 6; i++ {
+		b.Push(i)
+	}
+	journal.Consume(4, func(interface{}) bool { return true })
+	if journal.Offset() != 4 || journal.Lag() != 2 || audit.Lag() != 6 {
+		t.Fatalf("assertion failed, journal lag(%d), audit lag(%d).", journal.Lag(), audit.Lag())
+	}
+	if lagging := b.Lagging(3); len(lagging) != 1 || lagging[0].Name() != "audit" {
+		t.Fatalf("assertion failed, lagging(%v).", lagging)
+	}
+	// slow audit holds back producers until dropped
+	b.Push(6)
+	b.Push(7)
+	if b.Push(8) {
+		t.Fatal("assertion failed, producer overtook subscriber.")
+	}
+	audit.Drop()
+	if b.Push(8) {
+		t.Fatal("assertion failed, producer overtook subscriber before drop was observed.")
+	}
+	if _, ok := audit.Pop(); ok || !audit.Dropped() || b.Lookup("audit") != nil {
+		t.Fatal("assertion failed, expected dropped subscriber to be unregistered.")
+	}
+	if !b.Push(8) {
+		t.Fatal("inconsistent state, unable to push after drop.")
+	}
+	// restarted consumer resumes at committed offset
+	if v, ok := b.Named("journal").Pop(); !ok || v.(int) != 4 {
+		t.Fatalf("assertion failed, expected 4, got %v.", v)
+	}
+	journal.Reset()
+	if journal.Lag() != 0 {
+		t.Fatalf("assertion failed, lag(%d)!=0.", journal.Lag())
+	}
+}
+
+func TestBroadcastFilter(t *testing.T) {
+	var (
+		b     *Broadcast  = NewBroadcast(256)
+		all   *Subscriber = b.Subscribe()
+		odd   *Subscriber = b.SubscribeFilter(func(v interface{}) bool { return v.(int)%2 == 1 })
+		keyed *Subscriber = b.SubscribeKeys(func(v interface{}) interface{} { return v.(int) % 100 }, 7, 42)
+	)
+	for lap := 0; lap < 4; lap++ {
+		for i := 0; i < 200; i++ {
+			if !b.Push(lap*200 + i) {
+				t.Fatal("inconsistent state, unable to push.")
+			}
+		}
+		all.Consume(200, func(interface{}) bool { return true })
+		var got []int
+		for {
+			v, ok := keyed.Pop()
+			if !ok {
+				break
+			}
+			got = append(got, v.(int))
+		}
+		base := lap * 200
+		if want := []int{base + 7, base + 42, base + 107, base + 142}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("assertion failed, expected %v, got %v.", want, got)
+		}
+		if keyed.Lag() != 0 {
+			t.Fatalf("assertion failed, lag(%d)!=0.", keyed.Lag())
+		}
+		n := odd.Consume(1000, func(v interface{}) bool {
+			if v.(int)%2 != 1 {
+				t.Fatalf("assertion failed, unexpected item %v.", v)
+			}
+			return true
+		})
+		if n != 100 || odd.Lag() != 0 {
+			t.Fatalf("assertion failed, consumed(%d), lag(%d).", n, odd.Lag())
+		}
+	}
+	// reset clears pending marks, so they are not
+	// mistaken for matches on next lap.
+	for i := 0; i < 200; i++ {
+		b.Push(i)
+	}
+	all.Reset()
+	odd.Reset()
+	keyed.Reset()
+	for i := 0; i < 200; i++ {
+		b.Push(2 * i)
+	}
+	if _, ok := odd.Pop(); ok || odd.Lag() != 0 {
+		t.Fatal("assertion failed, expected no matches after reset.")
+	}
+}
@@ -0,0 +1,511 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"runtime"
+	"sync/atomic"
+)
+
+// cMSGHDR is size of a message frame header: a
+// little endian uint32 length followed by the
+// crc32c of the payload.
+const cMSGHDR = 8
+
+var (
+	// ErrMsgSize is returned when a message exceeds
+	// the size limit of a ring.
+	ErrMsgSize = errors.New("lfring: message exceeds size limit")
+	// ErrMsgCorrupt is returned when a message frame
+	// fails validation.
+	ErrMsgCorrupt = errors.New("lfring: corrupt message frame")
+)
+
+// crctab is the Castagnoli table of checksums.
+var crctab = crc32.MakeTable(crc32.Castagnoli)
+
+// - MARK: ByteRing section.
+
+// ByteRing is a single-producer, single-consumer
+// byte ring which implements `io.Writer` on the
+// producer side and `io.Reader` on the consumer
+// side over a contiguous buffer. Head and tail
+// are free running indices updated with atomic
+// stores only, hence it acts as a lock-free pipe,
+// e.g. between a network reader goroutine and a
+// parser. Writes block while the ring is full and
+// reads block while it is empty, spinning before
+// yielding control to scheduler.
+type ByteRing struct {
+	_      CacheLinePad
+	head   uint64 // write index
+	skip   uint64 // index of padding skipped by `Reserve`
+	_      CacheLinePad
+	tail   uint64 // read index
+	_      CacheLinePad
+	closed uint32 // writer closed
+	_      CacheLinePad
+	size   uint64
+	buf    []byte
+	maxmsg uint64   // message size limit, 0 means default
+	flush  *Flusher // write-behind flusher, nil when disabled
+}
+
+// NewByteRing allocates and initializes a new
+// `ByteRing` and returns a pointer to it. Note,
+// `capacity` is always rounded to nearest power
+// of two.
+func NewByteRing(capacity uint64) *ByteRing {
+	b := &ByteRing{size: roundP2(capacity), skip: ui64NMASK}
+	b.buf = make([]byte, b.size)
+	return b
+}
+
+// Len returns number of unread bytes.
+func (b *ByteRing) Len() uint64 {
+	return atomic.LoadUint64(&b.head) - atomic.LoadUint64(&b.tail)
+}
+
+// Cap returns capacity of ring.
+func (b *ByteRing) Cap() uint64 {
+	return b.size
+}
+
+// Close closes the producer side. Readers drain
+// remaining bytes and then get `io.EOF`; further
+// writes fail with `io.ErrClosedPipe`.
+func (b *ByteRing) Close() error {
+	atomic.StoreUint32(&b.closed, 1)
+	return nil
+}
+
+// Write writes all of `p`, blocking while ring is
+// full. It implements `io.Writer`.
+func (b *ByteRing) Write(p []byte) (int, error) {
+	var (
+		n int
+		i int
+	)
+	for n < len(p) {
+		if atomic.LoadUint32(&b.closed) != 0 {
+			return n, io.ErrClosedPipe
+		}
+		w := copy(b.writable(), p[n:])
+		if w == 0 {
+			spin(&i)
+			continue
+		}
+		b.commitWrite(w)
+		n += w
+	}
+	return n, nil
+}
+
+// Read reads up to `len(p)` bytes, blocking until
+// at least one byte is available. It returns
+// `io.EOF` once the ring is closed and drained. It
+// implements `io.Reader`.
+func (b *ByteRing) Read(p []byte) (int, error) {
+	var i int
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		// check closed before loading head, so
+		// bytes written before `Close` are seen.
+		closed := atomic.LoadUint32(&b.closed) != 0
+		if n := b.readInto(p); n > 0 {
+			return n, nil
+		}
+		if closed {
+			return 0, io.EOF
+		}
+		spin(&i)
+	}
+}
+
+// ReadFrom reads from `r` directly into ring
+// memory until `io.EOF` or an error, blocking
+// while ring is full. It implements
+// `io.ReaderFrom`.
+func (b *ByteRing) ReadFrom(r io.Reader) (int64, error) {
+	var (
+		n int64
+		i int
+	)
+	for {
+		if atomic.LoadUint32(&b.closed) != 0 {
+			return n, io.ErrClosedPipe
+		}
+		buf := b.writable()
+		if len(buf) == 0 {
+			spin(&i)
+			continue
+		}
+		m, err := r.Read(buf)
+		if m > 0 {
+			b.commitWrite(m)
+			n += int64(m)
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// WriteTo writes ring contents directly from ring
+// memory to `w` until ring is closed and drained
+// or an error occurs. It implements `io.WriterTo`.
+func (b *ByteRing) WriteTo(w io.Writer) (int64, error) {
+	var (
+		n int64
+		i int
+	)
+	for {
+		closed := atomic.LoadUint32(&b.closed) != 0
+		buf := b.readable()
+		if len(buf) == 0 {
+			if closed {
+				return n, nil
+			}
+			spin(&i)
+			continue
+		}
+		m, err := w.Write(buf)
+		b.commitRead(m)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// readInto copies available bytes into `p`,
+// handling wrap around, and returns the number
+// of copied bytes.
+func (b *ByteRing) readInto(p []byte) int {
+	var n int
+	for n < len(p) {
+		m := copy(p[n:], b.readable())
+		if m == 0 {
+			break
+		}
+		b.commitRead(m)
+		n += m
+	}
+	return n
+}
+
+// writable returns the contiguous free region
+// following head.
+func (b *ByteRing) writable() []byte {
+	var (
+		head  uint64 = atomic.LoadUint64(&b.head)
+		free  uint64 = b.size - (head - atomic.LoadUint64(&b.tail))
+		start uint64 = head & (b.size - 1)
+	)
+	if start+free > b.size {
+		free = b.size - start
+	}
+	return b.buf[start : start+free]
+}
+
+// readable returns the contiguous unread region
+// following tail. Padding left by `Reserve` at
+// the end of buffer is released on the way.
+func (b *ByteRing) readable() []byte {
+	for {
+		var (
+			tail  uint64 = atomic.LoadUint64(&b.tail)
+			head  uint64 = atomic.LoadUint64(&b.head)
+			skip  uint64 = atomic.LoadUint64(&b.skip)
+			used  uint64 = head - tail
+			start uint64 = tail & (b.size - 1)
+		)
+		if skip >= tail && skip < head {
+			if skip == tail {
+				// padding runs up to end of buffer
+				b.commitRead(int(b.size - start))
+				continue
+			}
+			used = skip - tail
+		}
+		if start+used > b.size {
+			used = b.size - start
+		}
+		return b.buf[start : start+used]
+	}
+}
+
+// - MARK: Reservation section.
+
+// Reserve returns a contiguous region of `n` bytes
+// of ring memory to be filled by producer and
+// published with `Commit`, so data is serialized
+// into the ring without intermediate buffers. When
+// the region would wrap around, the rest of the
+// buffer is skipped (bip-buffer style). It returns
+// nil when there is no room, it never blocks.
+func (b *ByteRing) Reserve(n int) []byte {
+	var (
+		head  uint64 = atomic.LoadUint64(&b.head)
+		free  uint64 = b.size - (head - atomic.LoadUint64(&b.tail))
+		start uint64 = head & (b.size - 1)
+		want  uint64 = uint64(n)
+	)
+	if n <= 0 || want > b.size {
+		return nil
+	}
+	if start+want <= b.size {
+		if want > free {
+			return nil
+		}
+		return b.buf[start : start+want]
+	}
+	// skip the tail end of buffer and reserve
+	// from its start.
+	pad := b.size - start
+	if pad+want > free {
+		return nil
+	}
+	atomic.StoreUint64(&b.skip, head)
+	b.commitWrite(int(pad))
+	return b.buf[:want]
+}
+
+// Commit publishes first `n` bytes of region
+// returned by last `Reserve`.
+func (b *ByteRing) Commit(n int) {
+	b.commitWrite(n)
+}
+
+// Acquire returns the contiguous region of unread
+// bytes, which consumer may process in place and
+// release with `Release`. It returns an empty
+// slice when there is nothing to read.
+func (b *ByteRing) Acquire() []byte {
+	return b.readable()
+}
+
+// Release releases first `n` bytes of region
+// returned by last `Acquire`.
+func (b *ByteRing) Release(n int) {
+	b.commitRead(n)
+}
+
+// commitWrite publishes `n` written bytes and
+// wakes flusher at its watermark.
+func (b *ByteRing) commitWrite(n int) {
+	atomic.StoreUint64(&b.head, atomic.LoadUint64(&b.head)+uint64(n))
+	if b.flush != nil && b.Len() >= b.flush.mark {
+		b.flush.wake()
+	}
+}
+
+// commitRead releases `n` read bytes.
+func (b *ByteRing) commitRead(n int) {
+	atomic.StoreUint64(&b.tail, atomic.LoadUint64(&b.tail)+uint64(n))
+}
+
+// spin counts a busy spin in `i` and yields
+// control to scheduler every `cRDSCHDTHRESHOLD`
+// spins.
+func spin(i *int) {
+	*i++
+	if *i == cRDSCHDTHRESHOLD {
+		runtime.Gosched()
+		*i = 0
+	}
+}
+
+// - MARK: Message section.
+
+// SetMaxMsg limits payload size of messages
+// written with `WriteMsg` and accepted by
+// `ReadMsg`, so a corrupt length can not stall a
+// reader. The default, and upper bound, is the
+// largest message fitting the ring. It must be
+// called before ring is shared.
+func (b *ByteRing) SetMaxMsg(n int) {
+	b.maxmsg = uint64(n)
+}
+
+// MaxMsg returns message size limit.
+func (b *ByteRing) MaxMsg() int {
+	if b.maxmsg == 0 || b.maxmsg > b.size-cMSGHDR {
+		return int(b.size - cMSGHDR)
+	}
+	return int(b.maxmsg)
+}
+
+// WriteMsg writes `p` as a single length-prefixed
+// and checksummed message, blocking while ring
+// has no room for it. A frame is published at
+// once, so readers never see part of it. Messages
+// must not be mixed with plain writes or
+// reservations.
+func (b *ByteRing) WriteMsg(p []byte) error {
+	_, err := b.writeMsg(p, true)
+	return err
+}
+
+// ReadMsg appends next message to `dst` and
+// returns the result, blocking until one is
+// available. It returns `io.EOF` once the ring
+// is closed and drained, and `ErrMsgCorrupt`
+// when a frame fails validation; a frame with a
+// bad checksum is consumed, a bad length leaves
+// the ring unusable.
+func (b *ByteRing) ReadMsg(dst []byte) ([]byte, error) {
+	out, _, err := b.readMsg(dst, true)
+	return out, err
+}
+
+// Messages returns a non-blocking `RecordRing`
+// view of message framing, e.g. to carry values
+// with `NewMessageRing`.
+func (b *ByteRing) Messages() RecordRing {
+	return byteRecords{b}
+}
+
+// byteRecords adapts `ByteRing` messages to
+// `RecordRing` interface.
+type byteRecords struct {
+	b *ByteRing
+}
+
+// Push implements `RecordRing` interface.
+func (r byteRecords) Push(p []byte) bool {
+	ok, _ := r.b.writeMsg(p, false)
+	return ok
+}
+
+// Pop implements `RecordRing` interface. Frames
+// with a bad checksum are dropped; a bad length
+// consumes nothing, so it reports no record
+// rather than retrying the same header.
+func (r byteRecords) Pop(dst []byte) ([]byte, bool) {
+	for {
+		out, ok, err := r.b.readMsg(dst, false)
+		if err != ErrMsgCorrupt || !ok {
+			return out, ok
+		}
+	}
+}
+
+// writeMsg writes message `p` and returns false
+// when `block` is unset and there is no room.
+func (b *ByteRing) writeMsg(p []byte, block bool) (bool, error) {
+	if len(p) > b.MaxMsg() {
+		return false, ErrMsgSize
+	}
+	var (
+		need uint64 = cMSGHDR + uint64(len(p))
+		hdr  [cMSGHDR]byte
+		i    int
+	)
+	for b.size-b.Len() < need {
+		if atomic.LoadUint32(&b.closed) != 0 {
+			return false, io.ErrClosedPipe
+		}
+		if !block {
+			return false, nil
+		}
+		spin(&i)
+	}
+	if atomic.LoadUint32(&b.closed) != 0 {
+		return false, io.ErrClosedPipe
+	}
+	binary.LittleEndian.PutUint32(hdr[0:], uint32(len(p)))
+	binary.LittleEndian.PutUint32(hdr[4:], crc32.Checksum(p, crctab))
+	head := atomic.LoadUint64(&b.head)
+	b.copyIn(head, hdr[:])
+	b.copyIn(head+cMSGHDR, p)
+	b.commitWrite(int(need))
+	return true, nil
+}
+
+// readMsg reads next message into `dst` and
+// returns false when `block` is unset and there
+// is none.
+func (b *ByteRing) readMsg(dst []byte, block bool) ([]byte, bool, error) {
+	var (
+		hdr [cMSGHDR]byte
+		i   int
+	)
+	for {
+		closed := atomic.LoadUint32(&b.closed) != 0
+		if b.Len() >= cMSGHDR {
+			break
+		}
+		if closed {
+			return dst, false, io.EOF
+		}
+		if !block {
+			return dst, false, nil
+		}
+		spin(&i)
+	}
+	tail := atomic.LoadUint64(&b.tail)
+	b.copyOut(hdr[:], tail)
+	n := uint64(binary.LittleEndian.Uint32(hdr[0:]))
+	// frames are published whole, so a header
+	// implies its payload.
+	if n > uint64(b.MaxMsg()) || b.Len() < cMSGHDR+n {
+		return dst, false, ErrMsgCorrupt
+	}
+	off := len(dst)
+	if uint64(cap(dst)-off) < n {
+		grown := make([]byte, off, off+int(n))
+		copy(grown, dst)
+		dst = grown
+	}
+	dst = dst[:off+int(n)]
+	b.copyOut(dst[off:], tail+cMSGHDR)
+	b.commitRead(int(cMSGHDR + n))
+	if crc32.Checksum(dst[off:], crctab) != binary.LittleEndian.Uint32(hdr[4:]) {
+		return dst[:off], true, ErrMsgCorrupt
+	}
+	return dst, true, nil
+}
+
+// copyIn copies `src` to index `pos`.
+func (b *ByteRing) copyIn(pos uint64, src []byte) {
+	n := copy(b.buf[pos&(b.size-1):], src)
+	copy(b.buf, src[n:])
+}
+
+// copyOut copies data at index `pos` into `dst`.
+func (b *ByteRing) copyOut(dst []byte, pos uint64) {
+	n := copy(dst, b.buf[pos&(b.size-1):])
+	copy(dst[n:], b.buf)
+}
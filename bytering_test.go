@@ -0,0 +1,333 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestByteRingSerial(t *testing.T) {
+	var (
+		b   *ByteRing = NewByteRing(5)
+		buf []byte    = make([]byte, 16)
+	)
+	if b.Cap() != 8 {
+		t.Fatalf("assertion failed, cap(%d)!=8.", b.Cap())
+	}
+	// wrap around the end of buffer
+	for _, chunk := range []string{"abcde", "fgh", "ijklm"} {
+		if n, err := b.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("assertion failed, n(%d), err(%v).", n, err)
+		}
+		n, err := b.Read(buf)
+		if err != nil || string(buf[:n]) != chunk {
+			t.Fatalf("assertion failed, expected %q, got %q.", chunk, buf[:n])
+		}
+	}
+	b.Write([]byte("xyz"))
+	b.Close()
+	if _, err := b.Write([]byte("late")); err != io.ErrClosedPipe {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	if n, err := b.Read(buf); n != 3 || err != nil {
+		t.Fatalf("assertion failed, expected remaining bytes, n(%d), err(%v).", n, err)
+	}
+	if _, err := b.Read(buf); err != io.EOF {
+		t.Fatalf("assertion failed, expected EOF, got %v.", err)
+	}
+}
+
+func TestByteRingPipe(t *testing.T) {
+	var (
+		b    *ByteRing = NewByteRing(64)
+		src  []byte    = make([]byte, 1<<16)
+		dst  bytes.Buffer
+		done chan error = make(chan error, 1)
+	)
+	rand.New(rand.NewSource(1)).Read(src)
+	go func() {
+		// odd sized writes exercise wrap around
+		for off := 0; off < len(src); off += 37 {
+			end := off + 37
+			if end > len(src) {
+				end = len(src)
+			}
+			if _, err := b.Write(src[off:end]); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- b.Close()
+	}()
+	if _, err := io.Copy(&dst, b); err != nil {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	if !bytes.Equal(dst.Bytes(), src) {
+		t.Fatal("assertion failed, corrupted stream.")
+	}
+}
+
+func TestByteRingReadFrom(t *testing.T) {
+	var (
+		b    *ByteRing = NewByteRing(128)
+		src  []byte    = bytes.Repeat([]byte("lfring"), 1000)
+		dst  bytes.Buffer
+		done chan int64 = make(chan int64, 1)
+	)
+	go func() {
+		n, _ := b.ReadFrom(bytes.NewReader(src))
+		b.Close()
+		done <- n
+	}()
+	n, err := b.WriteTo(&dst)
+	if err != nil || n != int64(len(src)) || <-done != n {
+		t.Fatalf("assertion failed, n(%d), err(%v).", n, err)
+	}
+	if !bytes.Equal(dst.Bytes(), src) {
+		t.Fatal("assertion failed, corrupted stream.")
+	}
+}
+
+func TestByteRingReserve(t *testing.T) {
+	var b *ByteRing = NewByteRing(16)
+	if b.Reserve(17) != nil || b.Reserve(0) != nil {
+		t.Fatal("assertion failed, reserved invalid size.")
+	}
+	buf := b.Reserve(10)
+	copy(buf, "0123456789")
+	b.Commit(10)
+	if got := b.Acquire(); string(got) != "0123456789" {
+		t.Fatalf("assertion failed, acquired %q.", got)
+	}
+	b.Release(8)
+	// 6 bytes left at the end; a 7 byte region
+	// wraps, skipping them.
+	buf = b.Reserve(7)
+	if len(buf) != 7 || &buf[0] != &b.buf[0] {
+		t.Fatal("assertion failed, expected region at start of buffer.")
+	}
+	copy(buf, "abcdefg")
+	b.Commit(7)
+	if b.Reserve(8) != nil {
+		t.Fatal("assertion failed, reserved beyond free space.")
+	}
+	if got := b.Acquire(); string(got) != "89" {
+		t.Fatalf("assertion failed, acquired %q.", got)
+	}
+	b.Release(2)
+	if got := b.Acquire(); string(got) != "abcdefg" {
+		t.Fatalf("assertion failed, acquired %q after padding.", got)
+	}
+	b.Release(7)
+	if b.Len() != 0 || len(b.Acquire()) != 0 {
+		t.Fatalf("assertion failed, len(%d)!=0.", b.Len())
+	}
+}
+
+func TestByteRingReserveConcurrent(t *testing.T) {
+	const items = 20000
+	var (
+		b    *ByteRing     = NewByteRing(256)
+		done chan struct{} = make(chan struct{})
+	)
+	// length-prefixed records, each written in
+	// place and read in place.
+	go func() {
+		defer close(done)
+		for i := 0; i < items; i++ {
+			n := 1 + i%50
+			var buf []byte
+			for buf = b.Reserve(n + 1); buf == nil; buf = b.Reserve(n + 1) {
+				runtime.Gosched()
+			}
+			buf[0] = byte(n)
+			for j := 1; j <= n; j++ {
+				buf[j] = byte(i)
+			}
+			b.Commit(n + 1)
+		}
+	}()
+	for i := 0; i < items; {
+		buf := b.Acquire()
+		if len(buf) == 0 {
+			runtime.Gosched()
+			continue
+		}
+		n := int(buf[0])
+		if n != 1+i%50 || len(buf) < n+1 {
+			t.Fatalf("assertion failed, record %d split or corrupted.", i)
+		}
+		for _, c := range buf[1 : n+1] {
+			if c != byte(i) {
+				t.Fatalf("assertion failed, record %d corrupted.", i)
+			}
+		}
+		b.Release(n + 1)
+		i++
+	}
+	<-done
+}
+
+func TestByteRingMsg(t *testing.T) {
+	var (
+		b   *ByteRing = NewByteRing(64)
+		buf []byte
+		err error
+	)
+	if b.MaxMsg() != 56 {
+		t.Fatalf("assertion failed, max msg(%d)!=56.", b.MaxMsg())
+	}
+	if err = b.WriteMsg(make([]byte, 57)); err != ErrMsgSize {
+		t.Fatalf("assertion failed, expected ErrMsgSize, got %v.", err)
+	}
+	// frames wrap around buffer end
+	for i := 0; i < 20; i++ {
+		msg := bytes.Repeat([]byte{byte(i)}, i%30)
+		if err = b.WriteMsg(msg); err != nil {
+			t.Fatal(err)
+		}
+		if buf, err = b.ReadMsg(buf[:0]); err != nil || !bytes.Equal(buf, msg) {
+			t.Fatalf("assertion failed, expected %v, got %v (%v).", msg, buf, err)
+		}
+	}
+	if ok := b.Messages().Push(make([]byte, 40)); !ok {
+		t.Fatal("inconsistent state, unable to push.")
+	}
+	if ok := b.Messages().Push(make([]byte, 40)); ok {
+		t.Fatal("assertion failed, pushed to a full ring.")
+	}
+	if _, ok := b.Messages().Pop(nil); !ok || b.Len() != 0 {
+		t.Fatal("assertion failed, expected message.")
+	}
+	// a damaged payload is detected and consumed
+	b.WriteMsg([]byte("payload"))
+	b.buf[(b.tail+cMSGHDR)&(b.size-1)] ^= 0xff
+	if _, err = b.ReadMsg(nil); err != ErrMsgCorrupt || b.Len() != 0 {
+		t.Fatalf("assertion failed, expected ErrMsgCorrupt, got %v.", err)
+	}
+	// length beyond limit is rejected
+	b.SetMaxMsg(4)
+	b.SetMaxMsg(0)
+	b.WriteMsg([]byte("too long"))
+	b.SetMaxMsg(4)
+	if _, err = b.ReadMsg(nil); err != ErrMsgCorrupt {
+		t.Fatalf("assertion failed, expected ErrMsgCorrupt, got %v.", err)
+	}
+	b.Close()
+	b = NewByteRing(64)
+	b.Close()
+	if _, err = b.ReadMsg(nil); err != io.EOF {
+		t.Fatalf("assertion failed, expected EOF, got %v.", err)
+	}
+}
+
+func TestByteRingMsgCorruptLength(t *testing.T) {
+	var (
+		b *ByteRing  = NewByteRing(64)
+		r RecordRing = b.Messages()
+	)
+	// a bad checksum is skipped in favour of the
+	// next frame
+	r.Push([]byte("first"))
+	r.Push([]byte("second"))
+	b.buf[(b.tail+cMSGHDR)&(b.size-1)] ^= 0xff
+	if v, ok := r.Pop(nil); !ok || string(v) != "second" {
+		t.Fatalf("assertion failed, expected second, got %q.", v)
+	}
+	// a bad length consumes nothing and must not
+	// stall the reader
+	r.Push([]byte("payload"))
+	b.buf[b.tail&(b.size-1)] = 0xff
+	done := make(chan bool, 1)
+	go func() {
+		_, ok := r.Pop(nil)
+		done <- ok
+	}()
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("assertion failed, popped a frame with a bad length.")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("assertion failed, pop spins on a bad length.")
+	}
+	if b.Len() != cMSGHDR+7 {
+		t.Fatalf("inconsistent state, expected %d bytes, got %d.", cMSGHDR+7, b.Len())
+	}
+}
+
+func TestByteRingMsgPipe(t *testing.T) {
+	const count = 5000
+	var (
+		b    *ByteRing    = NewByteRing(256)
+		m    *MessageRing = NewMessageRing(b.Messages(), JSONCodec{})
+		done chan error   = make(chan error, 1)
+	)
+	go func() {
+		for i := 0; i < count; i++ {
+			if err := b.WriteMsg(bytes.Repeat([]byte{byte(i)}, i%100)); err != nil {
+				done <- err
+				return
+			}
+		}
+		b.Close()
+		done <- nil
+	}()
+	var buf []byte
+	for i := 0; ; i++ {
+		var err error
+		buf, err = b.ReadMsg(buf[:0])
+		if err == io.EOF {
+			if i != count {
+				t.Fatalf("assertion failed, read %d messages.", i)
+			}
+			break
+		}
+		if err != nil || len(buf) != i%100 || (len(buf) > 0 && buf[0] != byte(i)) {
+			t.Fatalf("assertion failed, message %d: %v (%v).", i, buf, err)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := m.Push(map[string]interface{}{"k": "v"}); ok {
+		t.Fatal("assertion failed, closed ring accepted a message.")
+	}
+	m = NewMessageRing(NewByteRing(256).Messages(), JSONCodec{})
+	if ok, err := m.Push(map[string]interface{}{"k": "v"}); !ok || err != nil {
+		t.Fatalf("inconsistent state, unable to push (%v).", err)
+	}
+	if v, ok, err := m.Pop(); !ok || err != nil || v.(map[string]interface{})["k"] != "v" {
+		t.Fatalf("assertion failed, got %v (%v).", v, err)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// DefaultClock is the system clock.
+	DefaultClock Clock = systemClock{}
+)
+
+// - MARK: Clock section.
+
+// Clock is the time source of time dependent
+// features: latency recording, deadlines, tracer
+// events, stage retries and audit records. Tests
+// inject a `FakeClock` to run them instantly and
+// deterministically. Idle waits, e.g. of wait
+// strategies, always use real time.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock reads the system clock.
+type systemClock struct{}
+
+// Now implements `Clock` interface.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// WithClock sets time source of ring and of
+// stages and joins reading from it.
+func WithClock(c Clock) Option {
+	return func(r *Ring) { r.clock = c }
+}
+
+// Clock returns time source of ring.
+func (r *Ring) Clock() Clock {
+	return r.clock
+}
+
+// FakeClock is a `Clock` which only moves when
+// told to, for tests. It is safe for concurrent
+// use.
+type FakeClock struct {
+	// 64bit aligned
+	off  int64 // ns since base
+	base time.Time
+}
+
+// NewFakeClock allocates and initializes a new
+// `FakeClock` reading `start`.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{base: start}
+}
+
+// Now implements `Clock` interface.
+func (c *FakeClock) Now() time.Time {
+	return c.base.Add(time.Duration(atomic.LoadInt64(&c.off)))
+}
+
+// Advance moves clock forward by `d`.
+func (c *FakeClock) Advance(d time.Duration) {
+	atomic.AddInt64(&c.off, int64(d))
+}
+
+// Set moves clock to `t`, e.g. backwards to
+// test clock steps.
+func (c *FakeClock) Set(t time.Time) {
+	atomic.StoreInt64(&c.off, int64(t.Sub(c.base)))
+}
@@ -0,0 +1,80 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// - MARK: Test section.
+
+func TestFakeClock(t *testing.T) {
+	var (
+		start time.Time  = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		c     *FakeClock = NewFakeClock(start)
+		r     *Ring      = NewRing(4, WithTTL(nil), WithClock(c))
+	)
+	if !c.Now().Equal(start) || r.Clock() != c || NewRing(2).Clock() != DefaultClock {
+		t.Fatal("assertion failed, clock not applied.")
+	}
+	// deadlines follow virtual time only
+	r.PushTTL(0, time.Minute)
+	r.PushTTL(1, time.Hour)
+	c.Advance(time.Minute)
+	if v, ok := r.Pop(); !ok || v.(int) != 1 || r.Stats().Expired != 1 {
+		t.Fatalf("assertion failed, expected 1, got %v.", v)
+	}
+	c.Set(start)
+	if !c.Now().Equal(start) {
+		t.Fatal("assertion failed, clock not set.")
+	}
+}
+
+func TestStageFakeClock(t *testing.T) {
+	var (
+		c     *FakeClock = NewFakeClock(time.Now())
+		in    *Ring      = NewRing(4, WithClock(c))
+		calls int
+		stage *Stage = NewStage("retry", in, func(item interface{}) (interface{}, error) {
+			if calls++; calls == 1 {
+				return nil, errors.New("transient")
+			}
+			return item, nil
+		}, nil, nil)
+	)
+	stage.SetRetryPolicy(RetryPolicy{MaxAttempts: 2, Backoff: func(int) time.Duration { return time.Hour }})
+	in.Push(0)
+	stage.Step(1)
+	if stage.Step(1) != 0 || stage.Pending() != 1 {
+		t.Fatal("assertion failed, retry redelivered before backoff.")
+	}
+	// an hour passes instantly
+	c.Advance(time.Hour + time.Second)
+	if stage.Step(1) != 1 || calls != 2 || stage.Retried() != 1 {
+		t.Fatalf("assertion failed, calls(%d), retried(%d).", calls, stage.Retried())
+	}
+}
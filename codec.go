@@ -0,0 +1,230 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+var (
+	// ErrCodecType is returned when a codec can not
+	// encode values of a given type.
+	ErrCodecType = errors.New("lfring: unsupported codec value type")
+)
+
+// - MARK: Codec section.
+
+// Codec converts values to and from byte records,
+// so byte based rings such as `MmapRing` or shm
+// rings can carry structured messages. Codecs
+// must be safe for concurrent use.
+type Codec interface {
+	// Encode returns encoding of `v`.
+	Encode(v interface{}) ([]byte, error)
+	// Decode returns value encoded in `p`. `p` is
+	// only valid during the call.
+	Decode(p []byte) (interface{}, error)
+}
+
+var (
+	// RawCodec carries `[]byte` and `string` values
+	// as they are; decoded values are `[]byte`.
+	RawCodec Codec = rawCodec{}
+)
+
+// rawCodec is the codec behind `RawCodec`.
+type rawCodec struct{}
+
+// Encode implements `Codec` interface.
+func (rawCodec) Encode(v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, ErrCodecType
+}
+
+// Decode implements `Codec` interface.
+func (rawCodec) Decode(p []byte) (interface{}, error) {
+	return append([]byte(nil), p...), nil
+}
+
+// GobCodec encodes values with `encoding/gob`.
+// Every record is self-describing, so it can be
+// decoded by another process. With `New`, records
+// are decoded into values it returns, which must
+// be pointers; otherwise values are carried as
+// interfaces and their types must be registered
+// with `gob.Register`.
+type GobCodec struct {
+	New func() interface{}
+}
+
+// Encode implements `Codec` interface.
+func (c GobCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if c.New == nil {
+		// encode as interface to carry type name
+		if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode implements `Codec` interface.
+func (c GobCodec) Decode(p []byte) (interface{}, error) {
+	dec := gob.NewDecoder(bytes.NewReader(p))
+	if c.New == nil {
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	v := c.New()
+	if err := dec.Decode(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// JSONCodec encodes values with `encoding/json`.
+// With `New`, records are decoded into values it
+// returns, which must be pointers; otherwise they
+// decode to maps, slices and basic types.
+type JSONCodec struct {
+	New func() interface{}
+}
+
+// Encode implements `Codec` interface.
+func (c JSONCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Decode implements `Codec` interface.
+func (c JSONCodec) Decode(p []byte) (interface{}, error) {
+	if c.New == nil {
+		var v interface{}
+		if err := json.Unmarshal(p, &v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	v := c.New()
+	if reflect.ValueOf(v).Kind() != reflect.Ptr {
+		return nil, ErrCodecType
+	}
+	if err := json.Unmarshal(p, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// - MARK: MessageRing section.
+
+// RecordRing is a ring of byte records, e.g.
+// `MmapRing` or a shm ring.
+type RecordRing interface {
+	// Push appends a copy of record `p` and
+	// returns false when there is no room.
+	Push(p []byte) bool
+	// Pop appends next record to `dst` and returns
+	// the result, or false when ring is empty.
+	Pop(dst []byte) ([]byte, bool)
+}
+
+// MessageRing carries values encoded by a `Codec`
+// over a `RecordRing`. Records pass through its
+// `Transform` chain at the ring boundary, see
+// `NewMessageRing`. It is as safe for
+// concurrent use as the underlying ring, except
+// `Pop` which reuses a scratch buffer and must be
+// called by a single goroutine.
+type MessageRing struct {
+	ring       RecordRing
+	codec      Codec
+	transforms []Transform
+	scratch    []byte
+}
+
+// NewMessageRing returns a `MessageRing` carrying
+// values encoded by `codec` over `ring`. Encoded
+// records are transformed by `transforms` in
+// order on push and restored in reverse order
+// on pop, e.g. compressed then encrypted.
+func NewMessageRing(ring RecordRing, codec Codec, transforms ...Transform) *MessageRing {
+	return &MessageRing{ring: ring, codec: codec, transforms: transforms}
+}
+
+// Push encodes and appends `v`. It returns false
+// when ring is full or rejects the record, e.g.
+// as too large, and an error when `v` can not be
+// encoded or transformed.
+func (m *MessageRing) Push(v interface{}) (bool, error) {
+	p, err := m.codec.Encode(v)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range m.transforms {
+		if p, err = t.Encode(p); err != nil {
+			return false, err
+		}
+	}
+	return m.ring.Push(p), nil
+}
+
+// Pop removes and decodes next value. It returns
+// false when ring is empty and an error when the
+// record can not be restored or decoded; the
+// record is consumed nonetheless.
+func (m *MessageRing) Pop() (interface{}, bool, error) {
+	var err error
+	p, ok := m.ring.Pop(m.scratch[:0])
+	if !ok {
+		return nil, false, nil
+	}
+	m.scratch = p
+	for i := len(m.transforms) - 1; i >= 0; i-- {
+		if p, err = m.transforms[i].Decode(p); err != nil {
+			return nil, true, err
+		}
+	}
+	v, err := m.codec.Decode(p)
+	if err != nil {
+		return nil, true, err
+	}
+	return v, true, nil
+}
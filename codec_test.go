@@ -0,0 +1,72 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"encoding/gob"
+	"reflect"
+	"testing"
+)
+
+// codecPoint is a structured test message.
+type codecPoint struct {
+	X, Y int
+	Tag  string
+}
+
+func init() {
+	gob.Register(codecPoint{})
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	var (
+		point codecPoint = codecPoint{X: 1, Y: -2, Tag: "p"}
+		cases            = []struct {
+			codec Codec
+			in    interface{}
+			out   interface{}
+		}{
+			{RawCodec, []byte("raw"), []byte("raw")},
+			{RawCodec, "str", []byte("str")},
+			{GobCodec{}, point, point},
+			{GobCodec{New: func() interface{} { return new(codecPoint) }}, point, &point},
+			{JSONCodec{}, map[string]interface{}{"a": 1.5}, map[string]interface{}{"a": 1.5}},
+			{JSONCodec{New: func() interface{} { return new(codecPoint) }}, point, &point},
+		}
+	)
+	for i, c := range cases {
+		p, err := c.codec.Encode(c.in)
+		if err != nil {
+			t.Fatalf("assertion failed, case %d: %v.", i, err)
+		}
+		v, err := c.codec.Decode(p)
+		if err != nil || !reflect.DeepEqual(v, c.out) {
+			t.Fatalf("assertion failed, case %d: expected %v, got %v (%v).", i, c.out, v, err)
+		}
+	}
+	if _, err := RawCodec.Encode(1); err != ErrCodecType {
+		t.Fatalf("assertion failed, expected ErrCodecType, got %v.", err)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+)
+
+// - MARK: ConfigCell section.
+
+// ConfigCell holds a hot-reloadable configuration,
+// typically a pointer to an immutable struct.
+// Readers always get a consistent snapshot, the
+// value together with its version, without locks
+// (`purego` builds take a read lock); writers
+// replace it as a whole. Versions start
+// at one and increase by one per replacement, so
+// `CompareAndSwap` guards read-modify-write
+// reloads against lost updates. With
+// `CompareAndSwapGuarded`, a swap additionally
+// depends on an external guard word by RDCSS,
+// e.g. the epoch of the controller issuing the
+// reload, so a deposed controller can not install
+// stale configuration.
+type ConfigCell struct {
+	cell Tagged[configEntry] // current entry
+}
+
+// configEntry is an immutable versioned value.
+// Entries are never reused, so pointer identity
+// implies version.
+type configEntry struct {
+	value   interface{}
+	version uint64
+}
+
+// NewConfigCell allocates and initializes a new
+// `ConfigCell` holding `v` at version one and
+// returns a pointer to it.
+func NewConfigCell(v interface{}) *ConfigCell {
+	c := &ConfigCell{}
+	c.cell.Store(&configEntry{value: v, version: 1})
+	return c
+}
+
+// Load returns current value and its version.
+func (c *ConfigCell) Load() (interface{}, uint64) {
+	e := c.load()
+	return e.value, e.version
+}
+
+// Version returns current version.
+func (c *ConfigCell) Version() uint64 {
+	return c.load().version
+}
+
+// Store replaces value by `v` unconditionally and
+// returns its version.
+func (c *ConfigCell) Store(v interface{}) uint64 {
+	for {
+		e := c.load()
+		n := &configEntry{value: v, version: e.version + 1}
+		if c.cell.CompareAndSwap(e, n) {
+			return n.version
+		}
+	}
+}
+
+// CompareAndSwap replaces value by `v` iff current
+// version is `version` and returns whether it
+// did.
+func (c *ConfigCell) CompareAndSwap(version uint64, v interface{}) bool {
+	for {
+		e := c.load()
+		if e.version != version {
+			return false
+		}
+		n := &configEntry{value: v, version: version + 1}
+		if c.cell.CompareAndSwap(e, n) {
+			return true
+		}
+	}
+}
+
+// CompareAndSwapGuarded is `CompareAndSwap` which
+// also requires `*guard == expect` at the instant
+// of the swap, see `RDCSSCtx`.
+func (c *ConfigCell) CompareAndSwapGuarded(guard *uint64, expect uint64, version uint64, v interface{}) bool {
+	for {
+		e := c.load()
+		if e.version != version || atomic.LoadUint64(guard) != expect {
+			return false
+		}
+		n := &configEntry{value: v, version: version + 1}
+		if ok, _ := c.cell.rdcssTry(guard, expect, e, n); ok {
+			return true
+		}
+		// a competitor held or replaced the entry,
+		// or guard changed; recheck.
+	}
+}
+
+// load returns current entry, waiting for a
+// guarded swap in progress to complete.
+func (c *ConfigCell) load() *configEntry {
+	return c.cell.Load()
+}
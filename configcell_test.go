@@ -0,0 +1,109 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// - MARK: Test section.
+
+// cellConfig is an immutable test configuration
+// whose fields must always agree.
+type cellConfig struct {
+	A, B int
+}
+
+func TestConfigCell(t *testing.T) {
+	var c *ConfigCell = NewConfigCell(&cellConfig{1, 1})
+	v, ver := c.Load()
+	if v.(*cellConfig).A != 1 || ver != 1 {
+		t.Fatalf("assertion failed, unexpected initial %v@%d.", v, ver)
+	}
+	if c.Store(&cellConfig{2, 2}) != 2 || c.Version() != 2 {
+		t.Fatal("assertion failed, expected version 2.")
+	}
+	if c.CompareAndSwap(1, &cellConfig{3, 3}) {
+		t.Fatal("assertion failed, swapped stale version.")
+	}
+	if !c.CompareAndSwap(2, &cellConfig{3, 3}) || c.Version() != 3 {
+		t.Fatal("assertion failed, expected swap to version 3.")
+	}
+	var epoch uint64 = 7
+	if c.CompareAndSwapGuarded(&epoch, 6, 3, &cellConfig{4, 4}) {
+		t.Fatal("assertion failed, swapped with stale guard.")
+	}
+	if !c.CompareAndSwapGuarded(&epoch, 7, 3, &cellConfig{4, 4}) {
+		t.Fatal("assertion failed, expected guarded swap.")
+	}
+	if v, ver := c.Load(); v.(*cellConfig).A != 4 || ver != 4 {
+		t.Fatalf("assertion failed, unexpected %v@%d.", v, ver)
+	}
+}
+
+func TestConfigCellConcurrent(t *testing.T) {
+	const writes = 2000
+	var (
+		c     *ConfigCell = NewConfigCell(&cellConfig{0, 0})
+		wg    sync.WaitGroup
+		epoch uint64
+		done  uint32
+	)
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < writes; {
+				v, ver := c.Load()
+				n := v.(*cellConfig).A + 1
+				if c.CompareAndSwapGuarded(&epoch, 0, ver, &cellConfig{n, n}) {
+					i++
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for atomic.LoadUint32(&done) == 0 {
+			v, ver := c.Load()
+			if cfg := v.(*cellConfig); cfg.A != cfg.B || uint64(cfg.A)+1 != ver {
+				t.Errorf("assertion failed, inconsistent snapshot %+v@%d.", cfg, ver)
+				return
+			}
+		}
+	}()
+	for c.Version() < 2*writes+1 {
+		runtime.Gosched()
+	}
+	atomic.StoreUint32(&done, 1)
+	wg.Wait()
+	if v, _ := c.Load(); v.(*cellConfig).A != 2*writes {
+		t.Fatalf("assertion failed, lost updates, got %d.", v.(*cellConfig).A)
+	}
+}
@@ -0,0 +1,184 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "sync/atomic"
+
+// - MARK: Consume section.
+
+// Consume visits up to `max` available items in
+// order and passes each one to `fn`. Visiting stops
+// early when `fn` returns false; the item passed to
+// that call is consumed nonetheless. Read-index is
+// committed once for all visited items, no
+// intermediate slice is allocated. It returns the
+// number of consumed items; expired items are
+// reaped instead of visited, see `WithTTL`.
+//
+// Exclusive access to the head is obtained by
+// setting lock bit of read-index, which blocks
+// competing consumers until the commit. Therefore
+// `fn` should be short. Producers are never
+// blocked; visited slots are released one by one.
+// A panic of `fn` is propagated after the commit.
+func (r *Ring) Consume(max int, fn func(interface{}) bool) int {
+	var (
+		i   int
+		c   int    // items passed to `fn`
+		n   uint64 // released slots
+		pos uint64
+	)
+	if max <= 0 {
+		return 0
+	}
+	for {
+		pos = atomic.LoadUint64(&r.rdi)
+		if pos&cRDLOCK == 0 {
+			if atomic.LoadUint64(r.seq(pos)) != pos+1 {
+				// head not published; empty.
+				if r.stats != nil {
+					atomic.AddUint64(&r.stats.empty, 1)
+				}
+				return 0
+			}
+			// acquire head.
+			if atomic.CompareAndSwapUint64(&r.rdi, pos, pos|cRDLOCK) {
+				break
+			}
+			r.casFailed()
+		}
+		r.block(i, pos)
+		i++
+	}
+	// commit read-index once and unlock; when
+	// `fn` panics, the offending item counts as
+	// consumed so the ring stays usable.
+	defer func() {
+		atomic.StoreUint64(&r.rdi, pos+n)
+		if n > 0 {
+			r.freed()
+		}
+		if r.press != nil {
+			r.press.update(r.Len())
+		}
+	}()
+	for c < max && atomic.LoadUint64(r.seq(pos+n)) == pos+n+1 {
+		r.prefetchAhead(pos + n)
+		r.age(pos + n)
+		if r.ttl != nil && r.expired(pos+n) {
+			r.reap(pos + n)
+			n++
+			continue
+		}
+		item := r.take(pos + n)
+		if r.tracer != nil {
+			r.tracer.pop(pos + n)
+		}
+		n++
+		c++
+		if !fn(item) {
+			break
+		}
+	}
+	return c
+}
+
+// PopIf pops head only when `pred` passes for it
+// and returns it with true. Otherwise head stays
+// in place for the next consumer and false is
+// returned, as on an empty ring; gated consumers
+// can then wait on an external condition per
+// item. Like `Consume`, head is locked while
+// `pred` runs, which blocks competing consumers,
+// so `pred` should be short. It sees an item at
+// most once per call; expired items are reaped
+// beforehand. When `pred` panics, head is left
+// in place and the panic is propagated.
+func (r *Ring) PopIf(pred func(interface{}) bool) (interface{}, bool) {
+	var (
+		i    int
+		pos  uint64
+		next uint64 // read-index stored on unlock
+	)
+	for {
+		pos = atomic.LoadUint64(&r.rdi)
+		if pos&cRDLOCK == 0 {
+			if atomic.LoadUint64(r.seq(pos)) != pos+1 {
+				// head not published; empty.
+				if r.stats != nil {
+					atomic.AddUint64(&r.stats.empty, 1)
+				}
+				return nil, false
+			}
+			// acquire head.
+			if atomic.CompareAndSwapUint64(&r.rdi, pos, pos|cRDLOCK) {
+				if r.ttl == nil || !r.expired(pos) {
+					break
+				}
+				r.reap(pos)
+				atomic.StoreUint64(&r.rdi, pos+1)
+				continue
+			}
+			r.casFailed()
+		}
+		r.block(i, pos)
+		i++
+	}
+	next = pos
+	defer func() {
+		atomic.StoreUint64(&r.rdi, next)
+	}()
+	if !pred(r.nodes[pos&(r.size-1)]) {
+		return nil, false
+	}
+	r.age(pos)
+	data := r.take(pos)
+	next = pos + 1
+	r.freed()
+	if r.press != nil {
+		r.press.update(r.Len())
+	}
+	if r.tracer != nil {
+		r.tracer.pop(pos)
+	}
+	return data, true
+}
+
+// Reset discards buffered items and returns their
+// number, e.g. to flush a ring on reconnect
+// without reconstructing it. Like `Consume`, it
+// locks the head by lock bit of read-index rather
+// than swapping cursors with `RDCSS`, so slots are
+// released one by one and producers are never
+// blocked. The discarded items are a prefix of
+// ring contents up to the first slot not yet
+// published; at most one lap is discarded, so
+// concurrent producers can not keep it running.
+// Discarded items count as pops in `Stats`.
+func (r *Ring) Reset() int {
+	return r.Consume(int(r.size), func(interface{}) bool {
+		return true
+	})
+}
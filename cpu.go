@@ -0,0 +1,63 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+import "math/bits"
+
+// - MARK: CPU section.
+
+// CPUFeatures describes features of the running
+// CPU which select implementations of hot paths
+// at init, so one binary runs the best available
+// path on every machine.
+type CPUFeatures struct {
+	// HasCX16 reports `CMPXCHG16B` on amd64,
+	// backing `DWCAS` and `DWLoad`.
+	HasCX16 bool
+	// HasLSE reports large system extension
+	// atomics on arm64; `DWCAS` uses `CASP`
+	// instead of an exclusive pair loop.
+	HasLSE bool
+	// CacheLineSize is detected cache line size
+	// in bytes, or `CacheLineSize` when unknown.
+	// It sizes slot padding of `WithPadding`.
+	CacheLineSize int
+}
+
+// CPU holds features detected at init. It must
+// be treated as read-only.
+var CPU CPUFeatures
+
+func init() {
+	CPU = detectCPU()
+	if CPU.CacheLineSize <= 0 || CPU.CacheLineSize&(CPU.CacheLineSize-1) != 0 {
+		CPU.CacheLineSize = CacheLineSize
+	}
+}
+
+// seqPadShift returns log2 of slot sequences per
+// cache line.
+func (c CPUFeatures) seqPadShift() uint {
+	return uint(bits.TrailingZeros(uint(c.CacheLineSize / 8)))
+}
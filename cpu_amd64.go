@@ -0,0 +1,45 @@
+//go:build amd64
+// +build amd64
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+// detectCPU queries `CPUID` leaf 1 for
+// `CMPXCHG16B` and `CLFLUSH` line size.
+func detectCPU() (c CPUFeatures) {
+	if maxleaf, _, _, _ := cpuid(0, 0); maxleaf < 1 {
+		return c
+	}
+	_, ebx, ecx, edx := cpuid(1, 0)
+	c.HasCX16 = ecx&(1<<13) != 0
+	if edx&(1<<19) != 0 {
+		// CLFLUSH line size in 8-byte units
+		c.CacheLineSize = int((ebx>>8)&0xff) * 8
+	}
+	return c
+}
+
+//go:noescape
+func cpuid(leaf, sub uint32) (eax, ebx, ecx, edx uint32)
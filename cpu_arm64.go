@@ -0,0 +1,70 @@
+//go:build arm64
+// +build arm64
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+import (
+	"encoding/binary"
+	"os"
+	"runtime"
+)
+
+const (
+	// cATHWCAP is auxiliary vector tag of
+	// hardware capabilities.
+	cATHWCAP = 16
+	// cHWCAPATOMICS is hardware capability bit
+	// of LSE atomics.
+	cHWCAPATOMICS = 1 << 8
+)
+
+// detectCPU reads LSE support from auxiliary
+// vector on Linux; Apple silicon always has it.
+func detectCPU() (c CPUFeatures) {
+	switch runtime.GOOS {
+	case "darwin", "ios":
+		c.HasLSE = true
+		c.CacheLineSize = 128
+	case "linux", "android":
+		c.HasLSE = hwcap()&cHWCAPATOMICS != 0
+	}
+	return c
+}
+
+// hwcap returns `AT_HWCAP` of auxiliary vector,
+// or zero when it is unreadable.
+func hwcap() uint64 {
+	auxv, err := os.ReadFile("/proc/self/auxv")
+	if err != nil {
+		return 0
+	}
+	for i := 0; i+16 <= len(auxv); i += 16 {
+		if binary.LittleEndian.Uint64(auxv[i:]) == cATHWCAP {
+			return binary.LittleEndian.Uint64(auxv[i+8:])
+		}
+	}
+	return 0
+}
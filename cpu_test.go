@@ -0,0 +1,78 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"math/bits"
+	"runtime"
+	"testing"
+)
+
+func TestCPUFeatures(t *testing.T) {
+	if n := CPU.CacheLineSize; n <= 0 || n&(n-1) != 0 {
+		t.Fatalf("assertion failed, cache line size(%d).", n)
+	}
+	if 8<<CPU.seqPadShift() != CPU.CacheLineSize {
+		t.Fatalf("assertion failed, pad shift(%d).", CPU.seqPadShift())
+	}
+	if runtime.GOARCH != "arm64" && CPU.HasLSE {
+		t.Fatal("assertion failed, LSE reported on foreign architecture.")
+	}
+	if runtime.GOARCH != "amd64" && CPU.HasCX16 {
+		t.Fatal("assertion failed, CX16 reported on foreign architecture.")
+	}
+}
+
+// TestDWCASFeatureless runs `DWCAS` with detected
+// features disabled, exercising fallback paths.
+func TestDWCASFeatureless(t *testing.T) {
+	saved := CPU
+	defer func() { CPU = saved }()
+	CPU.HasCX16, CPU.HasLSE = false, false
+	aligned, _ := alignedPair()
+	*aligned = [2]uintptr{1, 2}
+	if DWCAS(aligned, [2]uintptr{1, 3}, [2]uintptr{4, 5}) || !DWCAS(aligned, [2]uintptr{1, 2}, [2]uintptr{4, 5}) {
+		t.Fatal("assertion failed, fallback DWCAS.")
+	}
+	if v := DWLoad(aligned); v != [2]uintptr{4, 5} {
+		t.Fatalf("inconsistent state, got %v.", v)
+	}
+}
+
+func TestArchInfo(t *testing.T) {
+	a := ArchInfo()
+	if a.GOARCH != runtime.GOARCH || a.WordSize != bits.UintSize {
+		t.Fatalf("assertion failed, arch(%+v).", a)
+	}
+	if a.MaxTag&a.PointerMask != 0 || a.MaxTag|a.PointerMask != ^uintptr(0) || a.MaxTag != 1<<a.TagBits-1 {
+		t.Fatalf("assertion failed, tag masks(%#x, %#x).", a.MaxTag, a.PointerMask)
+	}
+	if a.DWCAS == "" {
+		t.Fatal("assertion failed, expected DWCAS implementation.")
+	}
+	if err := checkArch(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+)
+
+// - MARK: Deque section.
+
+// Deque is a Chase–Lev work-stealing deque: its
+// owner pushes and pops at the bottom end, LIFO,
+// while thieves steal from the top end, FIFO,
+// e.g. tasks of a scheduler worker. Only thieves
+// and an owner popping the last item compete,
+// on a single CAS of the top index. Buffer grows
+// on demand and is never shrunk. Items are boxed
+// so thieves can load a slot atomically while
+// the owner reuses it; a push allocates.
+type Deque struct {
+	_      CacheLinePad
+	top    int64 // steal end, advanced by CAS
+	_      CacheLinePad
+	bottom int64 // owner end
+	_      CacheLinePad
+	buf    atomic.Pointer[dequeBuf]
+}
+
+// dequeBuf is a circular buffer of boxed items.
+type dequeBuf struct {
+	mask  int64
+	slots []atomic.Pointer[interface{}] // boxed items
+}
+
+// NewDeque allocates and initializes a new `Deque`
+// with initial capacity `capacity`, rounded to
+// power of two.
+func NewDeque(capacity uint64) *Deque {
+	if capacity < 2 {
+		capacity = 2
+	}
+	d := &Deque{}
+	d.buf.Store(newDequeBuf(int64(roundP2(capacity))))
+	return d
+}
+
+// newDequeBuf returns a buffer of `n` slots.
+func newDequeBuf(n int64) *dequeBuf {
+	return &dequeBuf{mask: n - 1, slots: make([]atomic.Pointer[interface{}], n)}
+}
+
+// Push appends `data` at bottom end, growing the
+// buffer when full. Only the owner may push.
+func (d *Deque) Push(data interface{}) {
+	var (
+		b int64     = atomic.LoadInt64(&d.bottom)
+		t int64     = atomic.LoadInt64(&d.top)
+		a *dequeBuf = d.buf.Load()
+	)
+	if b-t > a.mask {
+		a = d.grow(a, t, b)
+	}
+	a.slots[b&a.mask].Store(&data)
+	atomic.StoreInt64(&d.bottom, b+1)
+}
+
+// Pop removes the item at bottom end, the most
+// recently pushed one, and returns false when
+// deque is empty. Only the owner may pop.
+func (d *Deque) Pop() (interface{}, bool) {
+	var (
+		b int64     = atomic.LoadInt64(&d.bottom) - 1
+		a *dequeBuf = d.buf.Load()
+	)
+	// reserve bottom before reading top, so
+	// thieves see the reservation.
+	atomic.StoreInt64(&d.bottom, b)
+	t := atomic.LoadInt64(&d.top)
+	if t > b {
+		// empty, restore bottom.
+		atomic.StoreInt64(&d.bottom, b+1)
+		return nil, false
+	}
+	p := a.slots[b&a.mask].Load()
+	if t == b {
+		// last item, race thieves for it.
+		ok := atomic.CompareAndSwapInt64(&d.top, t, t+1)
+		atomic.StoreInt64(&d.bottom, b+1)
+		if !ok {
+			return nil, false
+		}
+		return *p, true
+	}
+	// no thief reaches `b` while top is below.
+	a.slots[b&a.mask].Store(nil)
+	return *p, true
+}
+
+// Steal removes the item at top end, the least
+// recently pushed one, and returns false when
+// deque is empty. Any goroutine may steal.
+func (d *Deque) Steal() (interface{}, bool) {
+	for {
+		var (
+			t int64 = atomic.LoadInt64(&d.top)
+			b int64 = atomic.LoadInt64(&d.bottom)
+		)
+		if t >= b {
+			return nil, false
+		}
+		a := d.buf.Load()
+		p := a.slots[t&a.mask].Load()
+		if atomic.CompareAndSwapInt64(&d.top, t, t+1) {
+			return *p, true
+		}
+		// lost to another thief or the owner.
+	}
+}
+
+// Len returns number of items; it is approximate
+// while deque is in use.
+func (d *Deque) Len() int {
+	n := atomic.LoadInt64(&d.bottom) - atomic.LoadInt64(&d.top)
+	if n < 0 {
+		return 0
+	}
+	return int(n)
+}
+
+// grow replaces full buffer `a` holding
+// positions [t, b) by one of twice its size.
+// Thieves still reading `a` find the same items.
+func (d *Deque) grow(a *dequeBuf, t, b int64) *dequeBuf {
+	n := newDequeBuf(2 * (a.mask + 1))
+	for i := t; i < b; i++ {
+		n.slots[i&n.mask].Store(a.slots[i&a.mask].Load())
+	}
+	d.buf.Store(n)
+	return n
+}
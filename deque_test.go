@@ -0,0 +1,112 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestDeque(t *testing.T) {
+	var d *Deque = NewDeque(2)
+	if _, ok := d.Pop(); ok {
+		t.Fatal("inconsistent state, popped from empty deque.")
+	}
+	// grows past initial capacity
+	for i := 0; i < 10; i++ {
+		d.Push(i)
+	}
+	if d.Len() != 10 {
+		t.Fatalf("assertion failed, len(%d)!=10.", d.Len())
+	}
+	if v, ok := d.Steal(); !ok || v.(int) != 0 {
+		t.Fatalf("assertion failed, expected 0 stolen, got %v.", v)
+	}
+	for i := 9; i > 0; i-- {
+		if v, ok := d.Pop(); !ok || v.(int) != i {
+			t.Fatalf("assertion failed, expected %d, got %v.", i, v)
+		}
+	}
+	if _, ok := d.Steal(); ok || d.Len() != 0 {
+		t.Fatal("inconsistent state, stole from empty deque.")
+	}
+}
+
+func TestDequeSteal(t *testing.T) {
+	const (
+		thieves = 3
+		count   = 20000
+	)
+	var (
+		d    *Deque = NewDeque(4)
+		wg   sync.WaitGroup
+		done int32
+		seen []int32 = make([]int32, count)
+	)
+	take := func(v interface{}) {
+		if atomic.AddInt32(&seen[v.(int)], 1) != 1 {
+			t.Errorf("assertion failed, item %v taken twice.", v)
+		}
+	}
+	for i := 0; i < thieves; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&done) == 0 || d.Len() > 0 {
+				if v, ok := d.Steal(); ok {
+					take(v)
+					continue
+				}
+				runtime.Gosched()
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		d.Push(i)
+		if i%3 == 0 {
+			if v, ok := d.Pop(); ok {
+				take(v)
+			}
+		}
+	}
+	for {
+		v, ok := d.Pop()
+		if !ok {
+			break
+		}
+		take(v)
+	}
+	atomic.StoreInt32(&done, 1)
+	wg.Wait()
+	for v, n := range seen {
+		if n != 1 {
+			t.Fatalf("assertion failed, item %d taken %d times.", v, n)
+		}
+	}
+}
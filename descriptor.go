@@ -0,0 +1,158 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+)
+
+var (
+	// ErrDescriptorLayout is returned when slot
+	// storage of a descriptor is inconsistent.
+	ErrDescriptorLayout = errors.New("lfring: invalid descriptor layout")
+)
+
+// - MARK: Descriptor section.
+
+const (
+	// DescSingleProducer flags a descriptor whose
+	// write index is advanced by plain stores.
+	DescSingleProducer = uint32(cSINGLEPROD)
+	// DescSingleConsumer flags a descriptor whose
+	// read index is advanced by plain stores.
+	DescSingleConsumer = uint32(cSINGLECONS)
+)
+
+// Descriptor returns descriptor of ring `r`. Ring
+// must outlive the descriptor. Descriptor
+// operations bypass ring hooks, i.e. signals,
+// watermark, sentinel and statistics.
+func (r *Ring) Descriptor() RingDescriptor {
+	d := RingDescriptor{
+		Write: &r.wri,
+		Read:  &r.rdi,
+		Count: &r.count,
+		Mask:  r.size - 1,
+		Shift: r.shift,
+		Flags: uint32(r.mode),
+	}
+	d.setStorage(r.nodes, r.seqs)
+	return d
+}
+
+// InitDescriptor initializes `d` over storage
+// owned by caller: `nodes` slots with a power of
+// two length, one sequence per slot in `seqs`
+// and zeroed cursors. Storage must not move
+// while the descriptor is in use.
+func InitDescriptor(d *RingDescriptor, nodes []interface{}, seqs []uint64, write, read, count *uint64, flags uint32) error {
+	n := uint64(len(nodes))
+	if n == 0 || n&(n-1) != 0 || uint64(len(seqs)) != n {
+		return ErrDescriptorLayout
+	}
+	for i := range seqs {
+		seqs[i] = uint64(i)
+		nodes[i] = nil
+	}
+	*write, *read, *count = 0, 0, 0
+	*d = RingDescriptor{
+		Write: write,
+		Read:  read,
+		Count: count,
+		Mask:  n - 1,
+		Flags: flags,
+	}
+	d.setStorage(nodes, seqs)
+	return nil
+}
+
+// Cap returns capacity.
+func (d *RingDescriptor) Cap() uint64 {
+	return d.Mask + 1
+}
+
+// Len returns number of items.
+func (d *RingDescriptor) Len() uint64 {
+	return atomic.LoadUint64(d.Count)
+}
+
+// Push writes `data` to next empty slot and
+// returns false when full or closed.
+func (d *RingDescriptor) Push(data interface{}) bool {
+	for {
+		pos := atomic.LoadUint64(d.Write)
+		if pos&cWRCLOSED != 0 {
+			return false
+		}
+		dif := int64(atomic.LoadUint64(d.seq(pos)) - pos)
+		if dif < 0 {
+			return false
+		}
+		if dif == 0 && d.claim(d.Write, pos, DescSingleProducer) {
+			*d.node(pos) = data
+			atomic.StoreUint64(d.seq(pos), pos+1)
+			atomic.AddUint64(d.Count, 1)
+			return true
+		}
+	}
+}
+
+// Pop takes next value and returns false when
+// empty.
+func (d *RingDescriptor) Pop() (interface{}, bool) {
+	for i := 1; ; i++ {
+		pos := atomic.LoadUint64(d.Read)
+		if pos&cRDLOCK == 0 {
+			dif := int64(atomic.LoadUint64(d.seq(pos)) - (pos + 1))
+			if dif < 0 {
+				return nil, false
+			}
+			if dif == 0 && d.claim(d.Read, pos, DescSingleConsumer) {
+				data := *d.node(pos)
+				*d.node(pos) = nil
+				atomic.StoreUint64(d.seq(pos), pos+d.Mask+1)
+				atomic.AddUint64(d.Count, ui64NMASK)
+				return data, true
+			}
+		}
+		if i%cRDSCHDTHRESHOLD == 0 {
+			// head locked by `Consume`
+			runtime.Gosched()
+		}
+	}
+}
+
+// claim advances `cursor` from `pos` by one with
+// a store when `single` flag is set and a CAS
+// otherwise.
+func (d *RingDescriptor) claim(cursor *uint64, pos uint64, single uint32) bool {
+	if d.Flags&single != 0 {
+		atomic.StoreUint64(cursor, pos+1)
+		return true
+	}
+	return atomic.CompareAndSwapUint64(cursor, pos, pos+1)
+}
@@ -0,0 +1,66 @@
+//go:build purego
+// +build purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+// - MARK: Descriptor section.
+
+// RingDescriptor is a compact view of ring state:
+// cursors, slot bases, mask and flags. It can be
+// embedded in user structs which own the storage
+// and driven with its methods, so a ring becomes
+// an intrusive building block. Descriptors of
+// the same storage interoperate with each other
+// and with the `Ring` they were taken from.
+// Slots are held as slices; default builds hold
+// `unsafe.Pointer` bases instead.
+type RingDescriptor struct {
+	Write *uint64       // write index, closed bit
+	Read  *uint64       // read index, lock bit
+	Count *uint64       // occupancy counter
+	Nodes []interface{} // slots
+	Seqs  []uint64      // sequences, `Shift` strided
+	Mask  uint64        // capacity - 1
+	Shift uint          // log2 of sequence stride
+	Flags uint32        // `Desc*` flags
+}
+
+// setStorage points `d` at `nodes` and `seqs`.
+func (d *RingDescriptor) setStorage(nodes []interface{}, seqs []uint64) {
+	d.Nodes = nodes
+	d.Seqs = seqs
+}
+
+// seq returns sequence of slot of position `pos`.
+func (d *RingDescriptor) seq(pos uint64) *uint64 {
+	return &d.Seqs[(pos&d.Mask)<<d.Shift]
+}
+
+// node returns slot of position `pos`.
+func (d *RingDescriptor) node(pos uint64) *interface{} {
+	return &d.Nodes[pos&d.Mask]
+}
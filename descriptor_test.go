@@ -0,0 +1,104 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// tstembed embeds a ring descriptor over its own
+// storage.
+type tstembed struct {
+	name  string
+	write uint64
+	read  uint64
+	count uint64
+	nodes [8]interface{}
+	seqs  [8]uint64
+	RingDescriptor
+}
+
+func TestRingDescriptor(t *testing.T) {
+	var (
+		r *Ring          = NewRing(4, WithPadding())
+		d RingDescriptor = r.Descriptor()
+	)
+	// descriptor and ring interoperate
+	d.Push(1)
+	r.Push(2)
+	if v, ok := r.Pop(); !ok || v.(int) != 1 {
+		t.Fatalf("assertion failed, expected 1, got %v.", v)
+	}
+	if v, ok := d.Pop(); !ok || v.(int) != 2 || d.Len() != 0 {
+		t.Fatalf("assertion failed, expected 2, got %v.", v)
+	}
+	if _, ok := d.Pop(); ok {
+		t.Fatal("inconsistent state, popped from empty descriptor.")
+	}
+	r.close()
+	if d.Push(3) {
+		t.Fatal("assertion failed, pushed into closed ring.")
+	}
+}
+
+func TestRingDescriptorEmbedded(t *testing.T) {
+	const n = 1000
+	var (
+		e  *tstembed = &tstembed{name: "embedded"}
+		wg sync.WaitGroup
+	)
+	if InitDescriptor(&e.RingDescriptor, e.nodes[:6], e.seqs[:6], &e.write, &e.read, &e.count, 0) != ErrDescriptorLayout {
+		t.Fatal("assertion failed, expected layout error.")
+	}
+	if err := InitDescriptor(&e.RingDescriptor, e.nodes[:], e.seqs[:], &e.write, &e.read, &e.count, DescSingleConsumer); err != nil {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	for p := 0; p < 2; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				for !e.Push(i) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	sum := 0
+	for got := 0; got < 2*n; {
+		if v, ok := e.Pop(); ok {
+			sum += v.(int)
+			got++
+			continue
+		}
+		runtime.Gosched()
+	}
+	wg.Wait()
+	if sum != n*(n-1) || e.Cap() != 8 || e.Len() != 0 {
+		t.Fatalf("assertion failed, sum(%d), len(%d).", sum, e.Len())
+	}
+}
@@ -0,0 +1,90 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// - MARK: DWCAS section.
+
+// cDWLOCKS is number of striped locks used by
+// the fallback path, pow2.
+const cDWLOCKS = 64
+
+// dwlocks are spinlocks guarding double words
+// which can not be swapped by hardware.
+var dwlocks [cDWLOCKS]struct {
+	state uint32
+	_     [60]byte // pad to 64 bytes
+}
+
+// dwlock returns the striped lock guarding `addr`.
+func dwlock(addr *[2]uintptr) *uint32 {
+	return &dwlocks[(addressOf(addr)>>4)&(cDWLOCKS-1)].state
+}
+
+// dwcasLocked is the fallback path of `DWCAS`. An
+// address is always routed to the same path, hence
+// both paths never operate on the same word.
+func dwcasLocked(addr *[2]uintptr, old, new [2]uintptr) bool {
+	var (
+		l  *uint32 = dwlock(addr)
+		ok bool
+		i  int
+	)
+	for !atomic.CompareAndSwapUint32(l, 0, 1) {
+		i++
+		if i == cWRSCHDTHRESHOLD {
+			runtime.Gosched()
+			i = 0
+		}
+	}
+	if *addr == old {
+		*addr = new
+		ok = true
+	}
+	atomic.StoreUint32(l, 0)
+	return ok
+}
+
+// dwloadLocked is the fallback path of `DWLoad`.
+func dwloadLocked(addr *[2]uintptr) (v [2]uintptr) {
+	var (
+		l *uint32 = dwlock(addr)
+		i int
+	)
+	for !atomic.CompareAndSwapUint32(l, 0, 1) {
+		i++
+		if i == cRDSCHDTHRESHOLD {
+			runtime.Gosched()
+			i = 0
+		}
+	}
+	v = *addr
+	atomic.StoreUint32(l, 0)
+	return v
+}
@@ -0,0 +1,57 @@
+//go:build amd64 && !race && !purego
+// +build amd64,!race,!purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "unsafe"
+
+// DWCAS atomically swaps the adjacent word pair at
+// `addr` with `new` iff it equals `old` and returns
+// true on success. It is backed by `CMPXCHG16B` when
+// the CPU supports it and `addr` is 16-byte aligned
+// and by striped locks otherwise.
+func DWCAS(addr *[2]uintptr, old, new [2]uintptr) bool {
+	if uintptr(unsafe.Pointer(addr))&15 != 0 || !CPU.HasCX16 {
+		return dwcasLocked(addr, old, new)
+	}
+	return cmpxchg16b(addr, old, new)
+}
+
+// DWLoad atomically loads the adjacent word pair
+// at `addr`.
+func DWLoad(addr *[2]uintptr) [2]uintptr {
+	if uintptr(unsafe.Pointer(addr))&15 != 0 || !CPU.HasCX16 {
+		return dwloadLocked(addr)
+	}
+	return load16b(addr)
+}
+
+//go:noescape
+func cmpxchg16b(addr *[2]uintptr, old, new [2]uintptr) bool
+
+//go:noescape
+func load16b(addr *[2]uintptr) [2]uintptr
@@ -0,0 +1,29 @@
+//go:build amd64 && !race && !purego
+// +build amd64,!race,!purego
+
+#include "textflag.h"
+
+// func cmpxchg16b(addr *[2]uintptr, old, new [2]uintptr) bool
+TEXT ·cmpxchg16b(SB), NOSPLIT, $0-41
+	MOVQ addr+0(FP), DI
+	MOVQ old_0+8(FP), AX
+	MOVQ old_1+16(FP), DX
+	MOVQ new_0+24(FP), BX
+	MOVQ new_1+32(FP), CX
+	LOCK
+	CMPXCHG16B (DI)
+	SETEQ ret+40(FP)
+	RET
+
+// func load16b(addr *[2]uintptr) [2]uintptr
+TEXT ·load16b(SB), NOSPLIT, $0-24
+	MOVQ addr+0(FP), DI
+	XORQ AX, AX
+	XORQ DX, DX
+	XORQ BX, BX
+	XORQ CX, CX
+	LOCK
+	CMPXCHG16B (DI)
+	MOVQ AX, ret_0+8(FP)
+	MOVQ DX, ret_1+16(FP)
+	RET
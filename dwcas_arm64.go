@@ -0,0 +1,69 @@
+//go:build arm64 && !race && !purego
+// +build arm64,!race,!purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+import "unsafe"
+
+// DWCAS atomically swaps the adjacent word pair at
+// `addr` with `new` iff it equals `old` and returns
+// true on success. It is backed by `CASP` when
+// LSE atomics are available, by an exclusive pair
+// loop otherwise and by striped locks when `addr`
+// is not 16-byte aligned.
+func DWCAS(addr *[2]uintptr, old, new [2]uintptr) bool {
+	if uintptr(unsafe.Pointer(addr))&15 != 0 {
+		return dwcasLocked(addr, old, new)
+	}
+	if CPU.HasLSE {
+		return casp(addr, old, new)
+	}
+	return casxp(addr, old, new)
+}
+
+// DWLoad atomically loads the adjacent word pair
+// at `addr`.
+func DWLoad(addr *[2]uintptr) [2]uintptr {
+	if uintptr(unsafe.Pointer(addr))&15 != 0 {
+		return dwloadLocked(addr)
+	}
+	if CPU.HasLSE {
+		return loadp(addr)
+	}
+	return loadxp(addr)
+}
+
+//go:noescape
+func casp(addr *[2]uintptr, old, new [2]uintptr) bool
+
+//go:noescape
+func casxp(addr *[2]uintptr, old, new [2]uintptr) bool
+
+//go:noescape
+func loadp(addr *[2]uintptr) [2]uintptr
+
+//go:noescape
+func loadxp(addr *[2]uintptr) [2]uintptr
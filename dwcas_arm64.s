@@ -0,0 +1,75 @@
+//go:build arm64 && !race && !purego
+// +build arm64,!race,!purego
+
+#include "textflag.h"
+
+// func casp(addr *[2]uintptr, old, new [2]uintptr) bool
+TEXT ·casp(SB), NOSPLIT, $0-41
+	MOVD addr+0(FP), R8
+	MOVD old_0+8(FP), R0
+	MOVD old_1+16(FP), R1
+	MOVD new_0+24(FP), R2
+	MOVD new_1+32(FP), R3
+	MOVD R0, R4
+	MOVD R1, R5
+	DMB $0xb
+	CASPD (R0, R1), (R8), (R2, R3)
+	DMB $0xb
+	CMP R0, R4
+	CSET EQ, R6
+	CMP R1, R5
+	CSET EQ, R7
+	AND R6, R7
+	MOVB R7, ret+40(FP)
+	RET
+
+// func casxp(addr *[2]uintptr, old, new [2]uintptr) bool
+TEXT ·casxp(SB), NOSPLIT, $0-41
+	MOVD addr+0(FP), R8
+	MOVD old_0+8(FP), R0
+	MOVD old_1+16(FP), R1
+	MOVD new_0+24(FP), R2
+	MOVD new_1+32(FP), R3
+again:
+	LDAXP (R8), (R4, R5)
+	CMP R0, R4
+	BNE fail
+	CMP R1, R5
+	BNE fail
+	STLXP (R2, R3), (R8), R6
+	CBNZ R6, again
+	MOVD $1, R7
+	MOVB R7, ret+40(FP)
+	RET
+fail:
+	// store loaded pair back; success proves
+	// the pair was read atomically.
+	STLXP (R4, R5), (R8), R6
+	CBNZ R6, again
+	MOVB ZR, ret+40(FP)
+	RET
+
+// func loadp(addr *[2]uintptr) [2]uintptr
+TEXT ·loadp(SB), NOSPLIT, $0-24
+	MOVD addr+0(FP), R8
+	MOVD ZR, R0
+	MOVD ZR, R1
+	MOVD ZR, R2
+	MOVD ZR, R3
+	DMB $0xb
+	CASPD (R0, R1), (R8), (R2, R3)
+	DMB $0xb
+	MOVD R0, ret_0+8(FP)
+	MOVD R1, ret_1+16(FP)
+	RET
+
+// func loadxp(addr *[2]uintptr) [2]uintptr
+TEXT ·loadxp(SB), NOSPLIT, $0-24
+	MOVD addr+0(FP), R8
+again:
+	LDAXP (R8), (R0, R1)
+	STLXP (R0, R1), (R8), R2
+	CBNZ R2, again
+	MOVD R0, ret_0+8(FP)
+	MOVD R1, ret_1+16(FP)
+	RET
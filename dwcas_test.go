@@ -0,0 +1,112 @@
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// alignedPair returns a 16-byte aligned and a
+// misaligned word pair.
+func alignedPair() (aligned, misaligned *[2]uintptr) {
+	buf := new([4]uintptr)
+	if addressOf(buf)&15 == 0 {
+		return (*[2]uintptr)(buf[0:2]), (*[2]uintptr)(buf[1:3])
+	}
+	return (*[2]uintptr)(buf[1:3]), (*[2]uintptr)(buf[0:2])
+}
+
+func TestDWCAS(t *testing.T) {
+	aligned, misaligned := alignedPair()
+	for _, addr := range []*[2]uintptr{aligned, misaligned} {
+		*addr = [2]uintptr{1, 2}
+		if DWCAS(addr, [2]uintptr{1, 3}, [2]uintptr{4, 5}) {
+			t.Fatal("assertion failed, expected false.")
+		}
+		if !DWCAS(addr, [2]uintptr{1, 2}, [2]uintptr{4, 5}) {
+			t.Fatal("assertion failed, expected true.")
+		}
+		if v := DWLoad(addr); v != [2]uintptr{4, 5} {
+			t.Fatalf("inconsistent state, got %v.", v)
+		}
+	}
+}
+
+func TestDWCASConcurrent(t *testing.T) {
+	const n = 2000
+	aligned, misaligned := alignedPair()
+	for _, addr := range []*[2]uintptr{aligned, misaligned} {
+		var wg sync.WaitGroup
+		*addr = [2]uintptr{}
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < n; i++ {
+					for {
+						old := DWLoad(addr)
+						if DWCAS(addr, old, [2]uintptr{old[0] + 1, old[1] + 2}) {
+							break
+						}
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if v := DWLoad(addr); v != [2]uintptr{4 * n, 8 * n} {
+			t.Fatalf("assertion failed, got %v.", v)
+		}
+	}
+}
+
+// TestDWCASHandoff publishes plain writes through
+// `DWCAS`, which the race detector must see as
+// synchronization when run with `-race`.
+func TestDWCASHandoff(t *testing.T) {
+	var (
+		addr    *[2]uintptr = NewDW()
+		payload int
+		done    chan struct{} = make(chan struct{})
+	)
+	if (raceEnabled || puregoEnabled) && ArchInfo().DWCAS != "striped locks" {
+		t.Fatalf("assertion failed, lock based build uses %s.", ArchInfo().DWCAS)
+	}
+	go func() {
+		defer close(done)
+		for DWLoad(addr) != [2]uintptr{1, 1} {
+			runtime.Gosched()
+		}
+		if payload != 42 {
+			t.Error("assertion failed, handoff lost payload.")
+		}
+	}()
+	payload = 42
+	if !DWCAS(addr, [2]uintptr{}, [2]uintptr{1, 1}) {
+		t.Fatal("assertion failed, expected true.")
+	}
+	<-done
+}
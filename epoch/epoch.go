@@ -0,0 +1,199 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+// Package epoch provides epoch-based memory reclamation (EBR)
+// for manually recycled objects.
+package epoch
+
+import (
+	"sync/atomic"
+)
+
+// Defaults
+const (
+	// cLIMBOS is number of limbo lists per guard.
+	cLIMBOS = 3
+	// cADVANCETHRESHOLD is number of retired
+	// pointers in a limbo list which triggers
+	// an attempt to advance global epoch.
+	cADVANCETHRESHOLD = 64
+)
+
+// - MARK: Struct section.
+
+// limbo is a list of pointers retired during
+// `epoch`.
+type limbo struct {
+	epoch uint64
+	items []retired
+}
+
+// Guard is a participant of an `Epoch`. Guards
+// are owned exclusively between `Enter` and
+// `Exit`; they are recycled afterwards.
+type Guard struct {
+	local uint64         // epoch<<1 | active
+	owned uint32         // ownership flag
+	next  *Guard         // next guard in epoch
+	ep    *Epoch         // owning epoch
+	limbo [cLIMBOS]limbo // deferred free lists
+}
+
+// Epoch is a global epoch counter along with its
+// registered guards. Pointers retired during epoch
+// `e` are reclaimed once global epoch reaches
+// `e+2`, when no guard can still observe them.
+type Epoch struct {
+	global uint64                // global epoch
+	head   atomic.Pointer[Guard] // guards are never unlinked
+}
+
+// Default is the epoch used by `lfring`.
+var Default = &defaultEpoch
+
+// defaultEpoch backs `Default`. Unlike a pointer
+// to a literal, which the compiler may lay out
+// statically at word alignment, a variable is
+// 64-bit aligned on 32-bit platforms.
+var defaultEpoch Epoch
+
+// - MARK: Epoch section.
+
+// New allocates and initializes a new `Epoch`
+// and returns a pointer to it.
+func New() *Epoch {
+	return &Epoch{}
+}
+
+// Enter registers caller in current epoch and
+// returns its guard. Pointers loaded afterwards
+// remain valid until `Exit`.
+func (e *Epoch) Enter() *Guard {
+	g := e.acquire()
+	for {
+		global := atomic.LoadUint64(&e.global)
+		atomic.StoreUint64(&g.local, global<<1|1)
+		// epoch may have advanced before the
+		// store became visible; republish.
+		if atomic.LoadUint64(&e.global) == global {
+			return g
+		}
+	}
+}
+
+// Load returns current global epoch.
+func (e *Epoch) Load() uint64 {
+	return atomic.LoadUint64(&e.global)
+}
+
+// acquire returns a guard owned by the caller,
+// reusing released guards before allocating.
+func (e *Epoch) acquire() *Guard {
+	for g := e.head.Load(); g != nil; g = g.next {
+		if atomic.LoadUint32(&g.owned) == 0 && atomic.CompareAndSwapUint32(&g.owned, 0, 1) {
+			return g
+		}
+	}
+	g := &Guard{owned: 1, ep: e}
+	for i := range g.limbo {
+		g.limbo[i].items = make([]retired, 0, cADVANCETHRESHOLD)
+	}
+	for {
+		head := e.head.Load()
+		g.next = head
+		if e.head.CompareAndSwap(head, g) {
+			return g
+		}
+	}
+}
+
+// tryAdvance increments global epoch when every
+// active guard has observed it.
+func (e *Epoch) tryAdvance() bool {
+	global := atomic.LoadUint64(&e.global)
+	for g := e.head.Load(); g != nil; g = g.next {
+		local := atomic.LoadUint64(&g.local)
+		if local&1 == 1 && local>>1 != global {
+			return false
+		}
+	}
+	return atomic.CompareAndSwapUint64(&e.global, global, global+1)
+}
+
+// - MARK: Guard section.
+
+// Exit leaves the epoch and recycles the guard.
+// Retired pointers stay with the guard and are
+// reclaimed by its next owner.
+func (g *Guard) Exit() {
+	atomic.StoreUint64(&g.local, 0)
+	atomic.StoreUint32(&g.owned, 0)
+}
+
+// retire schedules `rt` for reclamation once no
+// guard can observe it, see `Retire`.
+func (g *Guard) retire(rt retired) {
+	var (
+		global uint64 = atomic.LoadUint64(&g.ep.global)
+		l      *limbo = &g.limbo[global%cLIMBOS]
+	)
+	if l.epoch != global {
+		// list belongs to epoch `global-3`
+		// or older, safe to reclaim.
+		g.reclaim(l)
+		l.epoch = global
+	}
+	l.items = append(l.items, rt)
+	if len(l.items) >= cADVANCETHRESHOLD {
+		g.ep.tryAdvance()
+		g.Collect()
+	}
+}
+
+// Collect reclaims every limbo list retired two
+// or more epochs ago and returns number of
+// reclaimed pointers.
+func (g *Guard) Collect() int {
+	var (
+		global uint64 = atomic.LoadUint64(&g.ep.global)
+		n      int
+	)
+	for i := range g.limbo {
+		if l := &g.limbo[i]; len(l.items) > 0 && l.epoch+2 <= global {
+			n += g.reclaim(l)
+		}
+	}
+	return n
+}
+
+// reclaim frees every pointer in `l`.
+func (g *Guard) reclaim(l *limbo) int {
+	n := len(l.items)
+	for i, rt := range l.items {
+		rt.release()
+		l.items[i] = retired{}
+	}
+	l.items = l.items[:0]
+	return n
+}
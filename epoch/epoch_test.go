@@ -0,0 +1,63 @@
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package epoch
+
+import (
+	"testing"
+)
+
+func TestEpochReclaim(t *testing.T) {
+	var (
+		e      *Epoch = New()
+		freed  int
+		a      int
+		reader *Guard
+		writer *Guard
+	)
+	reader = e.Enter()
+	writer = e.Enter()
+	retireFunc(writer, &a, func() { freed++ })
+	// reader pins epoch 0; global may advance
+	// once but not twice.
+	e.tryAdvance()
+	if e.tryAdvance() {
+		t.Fatal("assertion failed, advanced past active reader.")
+	}
+	if writer.Collect() != 0 || freed != 0 {
+		t.Fatal("assertion failed, reclaimed pointer observable by reader.")
+	}
+	reader.Exit()
+	writer.Exit()
+	for i := 0; i < 2; i++ {
+		if !e.tryAdvance() {
+			t.Fatal("assertion failed, expected epoch to advance.")
+		}
+	}
+	writer = e.Enter()
+	if writer.Collect() != 1 || freed != 1 {
+		t.Fatalf("assertion failed, freed(%d)!=1.", freed)
+	}
+	writer.Exit()
+}
@@ -0,0 +1,49 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package epoch
+
+import "unsafe"
+
+// - MARK: Retire section.
+
+// retired is a pointer awaiting reclamation.
+type retired struct {
+	ptr  unsafe.Pointer
+	free func(unsafe.Pointer)
+}
+
+// release frees the pointer.
+func (rt retired) release() {
+	rt.free(rt.ptr)
+}
+
+// Retire schedules `p` for reclamation with
+// `free` once no guard can observe it.
+func (g *Guard) Retire(p unsafe.Pointer, free func(unsafe.Pointer)) {
+	g.retire(retired{ptr: p, free: free})
+}
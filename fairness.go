@@ -0,0 +1,113 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+)
+
+// - MARK: Fairness section.
+
+// Defaults chosen from `BenchmarkRingFairness`
+// (4 producers, 64 slots, single consumer). Ticket
+// mode yields a coefficient of variation of ~0.1
+// against ~1.1 for plain CAS, but a preempted
+// ticket holder stalls every producer, so Push is
+// no longer lock-free. It is therefore opt-in and
+// the default only caps the post-CAS backoff.
+const (
+	// cDEFBACKOFFCAP is default cap of spins
+	// performed after a failed write index CAS.
+	cDEFBACKOFFCAP = 64
+)
+
+// FairnessPolicy trades producer throughput
+// for inter-producer fairness under saturation.
+type FairnessPolicy struct {
+	// Ticket serializes producers in arrival
+	// order using a ticket lock on the write
+	// index acquisition. It bounds starvation
+	// at the cost of throughput.
+	Ticket bool
+	// BackoffCap caps the exponential spin
+	// performed after a failed write index
+	// CAS; sustained failures then yield and
+	// sleep with jitter, see `Backoff`. Zero
+	// disables backoff.
+	BackoffCap int
+}
+
+// DefaultFairness is the policy used by `NewRing`.
+var DefaultFairness = FairnessPolicy{BackoffCap: cDEFBACKOFFCAP}
+
+// SetFairness sets producer fairness policy. It
+// must be called before ring is shared among
+// goroutines.
+func (r *Ring) SetFairness(p FairnessPolicy) {
+	r.fair = p
+	r.hook()
+}
+
+// Fairness returns ring's fairness policy.
+func (r *Ring) Fairness() FairnessPolicy {
+	return r.fair
+}
+
+// acquireTicket takes a ticket and waits until
+// it is being served.
+func (r *Ring) acquireTicket() {
+	var (
+		ticket uint64 = atomic.AddUint64(&r.ticket, 1) - 1
+		i      int
+	)
+	for atomic.LoadUint64(&r.serving) != ticket {
+		r.block(i, ticket)
+		i++
+	}
+}
+
+// Waiting returns number of producers queued for
+// write access, including the one being served.
+// It is zero unless `Ticket` fairness is set.
+func (r *Ring) Waiting() uint64 {
+	serving := atomic.LoadUint64(&r.serving)
+	return atomic.LoadUint64(&r.ticket) - serving
+}
+
+// releaseTicket passes write access to the next
+// ticket holder.
+func (r *Ring) releaseTicket() {
+	atomic.AddUint64(&r.serving, 1)
+}
+
+// backoff spins for `min(2^n, cap)` iterations.
+func backoff(n uint, cap int) {
+	var spins int = 1 << n
+	if n > 30 || spins > cap {
+		spins = cap
+	}
+	for i := 0; i < spins; i++ {
+	}
+}
@@ -0,0 +1,128 @@
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// - MARK: Bench section.
+
+// variation returns coefficient of variation
+// of `counts`; zero means perfectly fair.
+func variation(counts []uint64) float64 {
+	var (
+		sum, mean, sq float64
+	)
+	for _, c := range counts {
+		sum += float64(c)
+	}
+	mean = sum / float64(len(counts))
+	if mean == 0 {
+		return 0
+	}
+	for _, c := range counts {
+		sq += (float64(c) - mean) * (float64(c) - mean)
+	}
+	return math.Sqrt(sq/float64(len(counts))) / mean
+}
+
+// saturate runs producers against a small ring
+// drained by a single consumer until `n` items
+// have been pushed and returns pushes per producer.
+func saturate(r *Ring, producers int, n uint64) []uint64 {
+	var (
+		wg     sync.WaitGroup
+		total  uint64
+		done   uint32
+		counts []uint64 = make([]uint64, producers)
+	)
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for atomic.LoadUint32(&done) == 0 {
+				if r.Push(p) {
+					counts[p]++
+					if atomic.AddUint64(&total, 1) >= n {
+						atomic.StoreUint32(&done, 1)
+					}
+				}
+			}
+		}(p)
+	}
+	for atomic.LoadUint32(&done) == 0 {
+		if _, ok := r.Pop(); !ok {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+	return counts
+}
+
+func benchmarkFairness(b *testing.B, p FairnessPolicy) {
+	var (
+		producers int = 4
+		cv        float64
+	)
+	for i := 0; i < b.N; i++ {
+		r := NewRing(64)
+		r.SetFairness(p)
+		cv += variation(saturate(r, producers, 2000))
+	}
+	b.ReportMetric(cv/float64(b.N), "cv")
+}
+
+func BenchmarkRingFairness(b *testing.B) {
+	b.Run("cas", func(b *testing.B) {
+		benchmarkFairness(b, FairnessPolicy{})
+	})
+	b.Run("backoff", func(b *testing.B) {
+		benchmarkFairness(b, DefaultFairness)
+	})
+	b.Run("ticket", func(b *testing.B) {
+		benchmarkFairness(b, FairnessPolicy{Ticket: true, BackoffCap: cDEFBACKOFFCAP})
+	})
+}
+
+// - MARK: Test section.
+
+func TestRingTicketFairness(t *testing.T) {
+	r := NewRing(64)
+	r.SetFairness(FairnessPolicy{Ticket: true})
+	counts := saturate(r, 4, 4000)
+	for p, c := range counts {
+		if c == 0 {
+			t.Fatalf("assertion failed, producer %d starved.", p)
+		}
+	}
+	if r.ticket != r.serving {
+		t.Fatalf("inconsistent state, ticket(%d)!=serving(%d).", r.ticket, r.serving)
+	}
+}
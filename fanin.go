@@ -0,0 +1,134 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+)
+
+// - MARK: FanIn section.
+
+// FanIn merges input rings into one output ring,
+// e.g. per-shard rings back into one processing
+// loop. Inputs are drained round robin, at most
+// `quantum` items of a ring per round, so a busy
+// ring can not starve the others. Items of each
+// input keep their order; an item refused by a
+// full output is held back and retried before
+// its successors. A fan-in must be run by a
+// single goroutine.
+type FanIn struct {
+	// 64bit aligned
+	merged  uint64 // forwarded items
+	stalled uint64 // pushes refused by output
+	in      []*Ring
+	out     *Ring
+	quantum int
+	held    []interface{} // items refused by output
+	isheld  []bool
+}
+
+// NewFanIn allocates and initializes a new `FanIn`
+// merging `in` into `out` and returns a pointer
+// to it. A `quantum` below one is one.
+func NewFanIn(out *Ring, quantum int, in ...*Ring) *FanIn {
+	if quantum < 1 {
+		quantum = 1
+	}
+	return &FanIn{in: in, out: out, quantum: quantum, held: make([]interface{}, len(in)), isheld: make([]bool, len(in))}
+}
+
+// Step performs up to `rounds` rounds over input
+// rings and returns number of forwarded items. It
+// stops early once inputs are empty or output is
+// full.
+func (f *FanIn) Step(rounds int) int {
+	var n int
+	for r := 0; r < rounds; r++ {
+		var (
+			moved int  // items forwarded this round
+			full  bool // output refused an item
+		)
+		for i := range f.in {
+			k, ok := f.drain(i)
+			moved += k
+			full = full || !ok
+		}
+		n += moved
+		if moved == 0 || full {
+			break
+		}
+	}
+	atomic.AddUint64(&f.merged, uint64(n))
+	return n
+}
+
+// Run merges items until `stop` is closed,
+// waiting on output ring according to its wait
+// strategy while inputs are empty or output is
+// full.
+func (f *FanIn) Run(stop <-chan struct{}) {
+	for idle := 0; ; {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if f.Step(cRDSCHDTHRESHOLD) > 0 {
+			idle = 0
+			continue
+		}
+		f.out.pause(idle)
+		idle++
+	}
+}
+
+// Stats returns number of forwarded items and of
+// pushes refused by a full output.
+func (f *FanIn) Stats() (merged, stalled uint64) {
+	return atomic.LoadUint64(&f.merged), atomic.LoadUint64(&f.stalled)
+}
+
+// drain forwards up to a quantum of items of
+// input `i` and returns their number and false
+// when output refused an item.
+func (f *FanIn) drain(i int) (int, bool) {
+	var n int
+	for ; n < f.quantum; n++ {
+		if !f.isheld[i] {
+			v, ok := f.in[i].Pop()
+			if !ok {
+				break
+			}
+			f.held[i], f.isheld[i] = v, true
+		}
+		if !f.out.Push(f.held[i]) {
+			atomic.AddUint64(&f.stalled, 1)
+			return n, false
+		}
+		f.held[i], f.isheld[i] = nil, false
+	}
+	return n, true
+}
@@ -0,0 +1,111 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestFanIn(t *testing.T) {
+	var (
+		a   *Ring  = NewRing(16)
+		b   *Ring  = NewRing(16)
+		out *Ring  = NewRing(4)
+		f   *FanIn = NewFanIn(out, 2, a, b)
+	)
+	for i := 0; i < 6; i++ {
+		a.Push(i)
+	}
+	b.Push(100)
+	b.Push(101)
+	b.Push(102)
+	var got []int
+	for len(got) < 9 {
+		if f.Step(8) == 0 && out.Len() == 0 {
+			t.Fatal("inconsistent state, fan-in stuck.")
+		}
+		for {
+			v, ok := out.Pop()
+			if !ok {
+				break
+			}
+			got = append(got, v.(int))
+		}
+	}
+	// quantum of two per ring and round, order
+	// kept per source across a full output.
+	want := []int{0, 1, 100, 101, 2, 3, 102, 4, 5}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("assertion failed, expected %v, got %v.", want, got)
+		}
+	}
+	if merged, stalled := f.Stats(); merged != 9 || stalled == 0 {
+		t.Fatalf("inconsistent state, unexpected stats %d, %d.", merged, stalled)
+	}
+}
+
+func TestFanInRun(t *testing.T) {
+	var (
+		in   []*Ring
+		out  *Ring = NewRing(1024)
+		stop       = make(chan struct{})
+		done       = make(chan struct{})
+	)
+	for i := 0; i < 4; i++ {
+		in = append(in, NewRing(64))
+	}
+	f := NewFanIn(out, 4, in...)
+	go func() {
+		f.Run(stop)
+		close(done)
+	}()
+	for k := 0; k < 50; k++ {
+		for i, r := range in {
+			for !r.Push(i*1000 + k) {
+				runtime.Gosched()
+			}
+		}
+	}
+	var last [4]int = [4]int{-1, -1, -1, -1}
+	for n := 0; n < 200; {
+		v, ok := out.Pop()
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		src, k := v.(int)/1000, v.(int)%1000
+		if k != last[src]+1 {
+			t.Fatalf("assertion failed, source %d out of order: %d after %d.", src, k, last[src])
+		}
+		last[src] = k
+		n++
+	}
+	close(stop)
+	<-done
+}
@@ -0,0 +1,185 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+)
+
+// - MARK: FanOut section.
+
+// RouteFunc returns index of the output ring of
+// an item, e.g. a hash of its key modulo number
+// of outputs.
+type RouteFunc func(item interface{}) int
+
+// FanOutStats are counters of a `FanOut`.
+type FanOutStats struct {
+	Routed   uint64 // items pushed to their output
+	Dropped  uint64 // items discarded by `DropNewest`
+	Evicted  uint64 // items evicted by `DropOldest`
+	Stalled  uint64 // pushes refused under `Reject`
+	Unrouted uint64 // items routed out of range, discarded
+}
+
+// FanOut pops items of one ring and routes each
+// to one of several output rings, the
+// partitioning half of a sharded pipeline, see
+// `FanIn`. What happens when an output is full
+// depends on the `Policy` of its route:
+//
+//   - `Reject` holds the item back and stops the
+//     step, so the input is not consumed further
+//     until the output has room. It is the
+//     default and loses nothing.
+//   - `DropNewest` discards the item.
+//   - `DropOldest` evicts the oldest item of the
+//     output to make room.
+//   - `Block` waits for room using wait strategy
+//     of the output.
+//
+// A fan-out must be run by a single goroutine.
+type FanOut struct {
+	// 64bit aligned
+	stats    FanOutStats
+	in       *Ring
+	out      []*Ring
+	route    RouteFunc
+	policies []Policy
+	held     interface{} // item refused by its output
+	isheld   bool
+}
+
+// NewFanOut allocates and initializes a new
+// `FanOut` routing items of `in` by `route` to
+// `out` and returns a pointer to it.
+func NewFanOut(in *Ring, route RouteFunc, out ...*Ring) *FanOut {
+	return &FanOut{in: in, out: out, route: route, policies: make([]Policy, len(out))}
+}
+
+// SetPolicy sets policy of route `i`. It must be
+// called before fan-out is run.
+func (f *FanOut) SetPolicy(i int, p Policy) {
+	f.policies[i] = p
+}
+
+// Step routes up to `max` items and returns their
+// number, including discarded ones. It stops early
+// once input is empty or an output under `Reject`
+// is full.
+func (f *FanOut) Step(max int) int {
+	var n int
+	for ; n < max; n++ {
+		if !f.isheld {
+			v, ok := f.in.Pop()
+			if !ok {
+				break
+			}
+			f.held, f.isheld = v, true
+		}
+		if !f.forward(f.held) {
+			atomic.AddUint64(&f.stats.Stalled, 1)
+			break
+		}
+		f.held, f.isheld = nil, false
+	}
+	return n
+}
+
+// Run routes items until `stop` is closed,
+// waiting on input ring according to its wait
+// strategy while it is empty or routing stalls.
+func (f *FanOut) Run(stop <-chan struct{}) {
+	for idle := 0; ; {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if f.Step(cRDSCHDTHRESHOLD) > 0 {
+			idle = 0
+			continue
+		}
+		f.in.pause(idle)
+		idle++
+	}
+}
+
+// Stats returns a snapshot of fan-out counters.
+func (f *FanOut) Stats() FanOutStats {
+	return FanOutStats{
+		Routed:   atomic.LoadUint64(&f.stats.Routed),
+		Dropped:  atomic.LoadUint64(&f.stats.Dropped),
+		Evicted:  atomic.LoadUint64(&f.stats.Evicted),
+		Stalled:  atomic.LoadUint64(&f.stats.Stalled),
+		Unrouted: atomic.LoadUint64(&f.stats.Unrouted),
+	}
+}
+
+// forward pushes `item` to its output applying
+// route policy and returns false when it must be
+// held back.
+func (f *FanOut) forward(item interface{}) bool {
+	i := f.route(item)
+	if i < 0 || i >= len(f.out) {
+		atomic.AddUint64(&f.stats.Unrouted, 1)
+		return true
+	}
+	out := f.out[i]
+	for n := 0; !out.Push(item); n++ {
+		switch f.policies[i] {
+		case DropNewest:
+			atomic.AddUint64(&f.stats.Dropped, 1)
+			return true
+		case DropOldest:
+			if _, ok := out.Pop(); ok {
+				atomic.AddUint64(&f.stats.Evicted, 1)
+			}
+		case Block:
+			out.pause(n)
+		default:
+			return false
+		}
+	}
+	atomic.AddUint64(&f.stats.Routed, 1)
+	return true
+}
+
+// PushEach pushes `item` to each of `rings`, like
+// `FanOut` with every route taken, and returns
+// indices of rings which refused it, e.g. being
+// full or closed, so callers can apply a fallback
+// per destination. It returns nil, without
+// allocating, when every ring accepted the item.
+// Deliveries are independent; a refusal does not
+// undo pushes to other rings.
+func PushEach(item interface{}, rings ...*Ring) (failed []int) {
+	for i, r := range rings {
+		if !r.Push(item) {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,129 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"testing"
+	"time"
+)
+
+// - MARK: Test section.
+
+func TestFanOut(t *testing.T) {
+	var (
+		in  *Ring = NewRing(16)
+		out       = []*Ring{NewRing(2), NewRing(2), NewRing(2)}
+		mod       = func(item interface{}) int { return item.(int) % 4 }
+		f         = NewFanOut(in, mod, out...)
+	)
+	f.SetPolicy(1, DropNewest)
+	f.SetPolicy(2, DropOldest)
+	// 3 is out of range, 0 rejects when full.
+	for _, v := range []int{1, 5, 9, 2, 6, 10, 3, 0, 4, 8} {
+		in.Push(v)
+	}
+	if n := f.Step(16); n != 9 {
+		t.Fatalf("assertion failed, expected 9 routed items, got %d.", n)
+	}
+	if v, _ := out[2].Pop(); v != 6 {
+		t.Fatalf("assertion failed, expected oldest evicted, got %v.", v)
+	}
+	if out[1].Len() != 2 || out[0].Len() != 2 || in.Len() != 0 {
+		t.Fatal("inconsistent state, unexpected ring lengths.")
+	}
+	want := FanOutStats{Routed: 7, Dropped: 1, Evicted: 1, Stalled: 1, Unrouted: 1}
+	if s := f.Stats(); s != want {
+		t.Fatalf("assertion failed, expected %+v, got %+v.", want, s)
+	}
+	// held item is routed once output has room.
+	out[0].Pop()
+	if n := f.Step(16); n != 1 || out[0].Len() != 2 {
+		t.Fatal("assertion failed, expected held item routed.")
+	}
+	if v, _ := out[0].Pop(); v != 4 {
+		t.Fatalf("assertion failed, expected order kept, got %v.", v)
+	}
+}
+
+func TestFanOutBlock(t *testing.T) {
+	var (
+		in   *Ring = NewRing(4)
+		out  *Ring = NewRing(2)
+		f          = NewFanOut(in, func(interface{}) int { return 0 }, out)
+		stop       = make(chan struct{})
+		done       = make(chan struct{})
+	)
+	f.SetPolicy(0, Block)
+	for i := 1; i <= 3; i++ {
+		in.Push(i)
+	}
+	go func() {
+		f.Run(stop)
+		close(done)
+	}()
+	for i := 1; i <= 3; i++ {
+		var (
+			v  interface{}
+			ok bool
+		)
+		for !ok {
+			time.Sleep(time.Millisecond)
+			v, ok = out.Pop()
+		}
+		if v != i {
+			t.Fatalf("assertion failed, expected %d, got %v.", i, v)
+		}
+	}
+	close(stop)
+	<-done
+	if s := f.Stats(); s.Routed != 3 || s.Stalled != 0 {
+		t.Fatalf("inconsistent state, unexpected stats %+v.", s)
+	}
+}
+
+func TestPushEach(t *testing.T) {
+	var (
+		a    *Ring = NewRing(2)
+		full *Ring = NewRing(2)
+		b    *Ring = NewRing(2)
+	)
+	full.Push(1)
+	full.Push(2)
+	if failed := PushEach("x", a, full, b); len(failed) != 1 || failed[0] != 1 {
+		t.Fatalf("assertion failed, expected [1], got %v.", failed)
+	}
+	if failed := PushEach("y", a, b); failed != nil {
+		t.Fatalf("assertion failed, expected none, got %v.", failed)
+	}
+	for _, r := range []*Ring{a, b} {
+		if v, _ := r.Pop(); v != "x" {
+			t.Fatalf("assertion failed, expected x, got %v.", v)
+		}
+	}
+	a.Push("w")
+	if failed := PushEach("z", a, full, b); len(failed) != 2 || failed[0] != 0 || failed[1] != 1 {
+		t.Fatalf("assertion failed, expected [0 1], got %v.", failed)
+	}
+}
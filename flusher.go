@@ -0,0 +1,168 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// - MARK: Flusher section.
+
+// Flusher drains a `ByteRing` into a writer,
+// making the ring a write-behind buffer for files
+// and sockets: producers write to memory while
+// the flusher writes out accumulated bytes, both
+// segments of a wrapped region in turn, once they
+// reach a watermark or an interval elapses. A
+// failed write closes the ring, so producers get
+// `io.ErrClosedPipe` instead of blocking on a
+// ring nobody drains.
+type Flusher struct {
+	ring     *ByteRing
+	w        io.Writer
+	mark     uint64        // watermark in bytes
+	interval time.Duration // flush interval, 0 none
+	kick     chan struct{} // watermark reached or flush requested
+	done     chan struct{} // closed on exit
+	mu       sync.Mutex    // guards err and written
+	err      error         // first write error
+	written  uint64        // bytes written out
+}
+
+// FlushTo starts a `Flusher` which writes bytes of
+// ring to `w` whenever at least `watermark` bytes,
+// at most capacity, are buffered, and every
+// `interval` unless zero. It becomes the consumer
+// of ring; readers must not be used alongside,
+// and it must be called before ring is shared.
+func (b *ByteRing) FlushTo(w io.Writer, watermark uint64, interval time.Duration) *Flusher {
+	if watermark == 0 || watermark > b.size {
+		watermark = b.size
+	}
+	f := &Flusher{
+		ring:     b,
+		w:        w,
+		mark:     watermark,
+		interval: interval,
+		kick:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	b.flush = f
+	go f.run()
+	return f
+}
+
+// Flush requests writing out buffered bytes
+// without waiting for the watermark.
+func (f *Flusher) Flush() {
+	f.wake()
+}
+
+// Close closes ring, waits until remaining bytes
+// are written out and returns the first write
+// error.
+func (f *Flusher) Close() error {
+	f.ring.Close()
+	f.wake()
+	<-f.done
+	return f.Err()
+}
+
+// Err returns the first write error.
+func (f *Flusher) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// Written returns number of bytes written out.
+func (f *Flusher) Written() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.written
+}
+
+// wake wakes flusher unless a wakeup is pending.
+func (f *Flusher) wake() {
+	select {
+	case f.kick <- struct{}{}:
+	default:
+	}
+}
+
+// run writes out ring until it is closed and
+// drained or a write fails.
+func (f *Flusher) run() {
+	var tick <-chan time.Time
+	defer close(f.done)
+	if f.interval > 0 {
+		t := time.NewTicker(f.interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-f.kick:
+		case <-tick:
+		}
+		// check closed before draining, so bytes
+		// written before `Close` are flushed.
+		closed := atomic.LoadUint32(&f.ring.closed) != 0
+		if err := f.drain(); err != nil {
+			f.mu.Lock()
+			f.err = err
+			f.mu.Unlock()
+			f.ring.Close()
+			return
+		}
+		if closed {
+			return
+		}
+	}
+}
+
+// drain writes out all readable bytes.
+func (f *Flusher) drain() error {
+	for {
+		buf := f.ring.readable()
+		if len(buf) == 0 {
+			return nil
+		}
+		n, err := f.w.Write(buf)
+		f.ring.commitRead(n)
+		f.mu.Lock()
+		f.written += uint64(n)
+		f.mu.Unlock()
+		if err == nil && n < len(buf) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,120 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// - MARK: Test section.
+
+// syncBuffer is a `bytes.Buffer` safe for
+// concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Len()
+}
+
+func TestFlusherWatermark(t *testing.T) {
+	var (
+		out syncBuffer
+		b   *ByteRing = NewByteRing(16)
+		f   *Flusher  = b.FlushTo(&out, 8, 0)
+	)
+	b.Write([]byte("abc"))
+	time.Sleep(10 * time.Millisecond)
+	if out.Len() != 0 {
+		t.Fatal("assertion failed, flushed below watermark.")
+	}
+	// wraps around end of buffer
+	for i := 0; i < 10; i++ {
+		b.Write([]byte("defghij"))
+	}
+	f.Flush()
+	b.Write([]byte("!"))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "abc" + string(bytes.Repeat([]byte("defghij"), 10)) + "!"
+	if out.buf.String() != want || f.Written() != uint64(len(want)) {
+		t.Fatalf("assertion failed, got %q.", out.buf.String())
+	}
+}
+
+func TestFlusherInterval(t *testing.T) {
+	var (
+		out syncBuffer
+		b   *ByteRing = NewByteRing(64)
+		f   *Flusher  = b.FlushTo(&out, 0, time.Millisecond)
+	)
+	defer f.Close()
+	b.Write([]byte("tick"))
+	for deadline := time.Now().Add(5 * time.Second); out.Len() != 4; {
+		if time.Now().After(deadline) {
+			t.Fatal("assertion failed, interval flush missing.")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// failWriter fails every write.
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestFlusherError(t *testing.T) {
+	var (
+		b *ByteRing = NewByteRing(8)
+		f *Flusher  = b.FlushTo(failWriter{}, 4, 0)
+	)
+	// producer is released, not blocked forever
+	for {
+		if _, err := b.Write([]byte("data")); err == io.ErrClosedPipe {
+			break
+		}
+	}
+	if err := f.Close(); err == nil || err.Error() != "disk full" {
+		t.Fatalf("assertion failed, expected write error, got %v.", err)
+	}
+}
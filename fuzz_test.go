@@ -0,0 +1,303 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// Defaults
+const (
+	// cFUZZMAXITEMS caps items pushed per input of
+	// concurrent fuzzer.
+	cFUZZMAXITEMS = 512
+)
+
+// fuzzConfigs are ring configurations selected by
+// first byte of fuzz input, with limits on
+// producers and consumers of their mode.
+var fuzzConfigs []struct {
+	opts       []Option
+	prod, cons int
+} = []struct {
+	opts       []Option
+	prod, cons int
+}{
+	{nil, 4, 4},
+	{[]Option{WithPadding()}, 4, 4},
+	{[]Option{WithFairness(FairnessPolicy{Ticket: true})}, 4, 4},
+	{[]Option{WithMode(MPSC)}, 4, 1},
+	{[]Option{WithMode(SPSC)}, 1, 1},
+}
+
+// fuzzByte returns byte `i` of `data` or zero.
+func fuzzByte(data []byte, i int) byte {
+	if i < len(data) {
+		return data[i]
+	}
+	return 0
+}
+
+// - MARK: Test section.
+
+// FuzzRingConcurrent drives producers and
+// consumers mixing single and batch operations,
+// as chosen by input, and verifies that no item
+// is lost or duplicated, that each consumer sees
+// items of a producer in order and that popped
+// and buffered items add up to pushed ones.
+func FuzzRingConcurrent(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 2, 40, 40, 0, 1, 2, 3})
+	f.Add([]byte{1, 0, 4, 4, 255, 7, 9, 3})
+	f.Add([]byte{2, 3, 3, 1, 100, 100, 100})
+	f.Add([]byte{3, 2, 4, 1, 64, 64, 64, 64, 1})
+	f.Add([]byte{4, 4, 1, 1, 200, 2, 3})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var (
+			cfg   = fuzzConfigs[int(fuzzByte(data, 0))%len(fuzzConfigs)]
+			r     = NewRing(2<<(fuzzByte(data, 1)%5), cfg.opts...)
+			nprod = 1 + int(fuzzByte(data, 2))%cfg.prod
+			ncons = 1 + int(fuzzByte(data, 3))%cfg.cons
+			quota = make([]int, nprod)
+			ops   []byte
+			total int
+		)
+		for i := 0; i < nprod; i++ {
+			n := int(fuzzByte(data, 4+i))
+			if total+n > cFUZZMAXITEMS {
+				n = cFUZZMAXITEMS - total
+			}
+			quota[i], total = n, total+n
+		}
+		// bytes past quotas select operations.
+		if ops = []byte{0}; len(data) > 4+nprod {
+			ops = data[4+nprod:]
+		}
+		var (
+			wg       sync.WaitGroup
+			consumed int64
+			seen     = make([][]uint64, ncons)
+		)
+		for p := 0; p < nprod; p++ {
+			wg.Add(1)
+			go func(p int) {
+				defer wg.Done()
+				var batch []interface{}
+				for i, k := 0, 0; i < quota[p]; k++ {
+					op := ops[(p+k)%len(ops)]
+					if op&1 == 0 {
+						if r.Push(uint64(p)<<32 | uint64(i)) {
+							i++
+						} else {
+							runtime.Gosched()
+						}
+						continue
+					}
+					batch = batch[:0]
+					for j := 0; j < int(op>>1)%4+1 && i+j < quota[p]; j++ {
+						batch = append(batch, uint64(p)<<32|uint64(i+j))
+					}
+					n := r.PushBatch(batch)
+					if i += n; n == 0 {
+						runtime.Gosched()
+					}
+				}
+			}(p)
+		}
+		for c := 0; c < ncons; c++ {
+			wg.Add(1)
+			go func(c int) {
+				defer wg.Done()
+				var (
+					dst  [4]interface{}
+					keep = func(v interface{}) bool {
+						seen[c] = append(seen[c], v.(uint64))
+						return true
+					}
+				)
+				for k := 0; atomic.LoadInt64(&consumed) < int64(total); k++ {
+					var n int
+					switch op := ops[(c+k)%len(ops)]; op % 4 {
+					case 0:
+						if v, ok := r.Pop(); ok {
+							keep(v)
+							n = 1
+						}
+					case 1:
+						if v, ok := r.TryPop(4); ok {
+							keep(v)
+							n = 1
+						}
+					case 2:
+						n = r.PopInto(dst[:int(op>>2)%4+1])
+						for _, v := range dst[:n] {
+							keep(v)
+						}
+					case 3:
+						n = r.Consume(int(op>>2)%4+1, keep)
+					}
+					if atomic.AddInt64(&consumed, int64(n)); n == 0 {
+						runtime.Gosched()
+					}
+				}
+			}(c)
+		}
+		wg.Wait()
+		counts := make([]map[uint64]int, nprod)
+		for p := range counts {
+			counts[p] = make(map[uint64]int)
+		}
+		for c := range seen {
+			last := make([]int64, nprod)
+			for p := range last {
+				last[p] = -1
+			}
+			for _, v := range seen[c] {
+				p, i := int(v>>32), int64(v&0xffffffff)
+				if p >= nprod || i >= int64(quota[p]) {
+					t.Fatalf("assertion failed, foreign item %x.", v)
+				}
+				if i <= last[p] {
+					t.Fatalf("assertion failed, consumer %d saw item %d of producer %d after %d.", c, i, p, last[p])
+				}
+				last[p] = i
+				counts[p][uint64(i)]++
+			}
+		}
+		for p := range counts {
+			for i := 0; i < quota[p]; i++ {
+				if n := counts[p][uint64(i)]; n != 1 {
+					t.Fatalf("assertion failed, item %d of producer %d popped %d times.", i, p, n)
+				}
+			}
+		}
+		if s := r.Stats(); s.Pushes != uint64(total) || s.Pops != uint64(total) || r.Len() != 0 {
+			t.Fatalf("inconsistent state, pushed %d, stats(%+v).", total, s)
+		}
+	})
+}
+
+// FuzzRingSequential applies operations chosen by
+// input to a ring and to a FIFO model and
+// verifies that results and contents agree.
+func FuzzRingSequential(f *testing.F) {
+	f.Add([]byte{0, 1, 0, 0, 0, 1, 1, 1})
+	f.Add([]byte{1, 3, 2, 10, 6, 14, 3, 7, 4})
+	f.Add([]byte{2, 4, 13, 13, 13, 9, 0, 5})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var (
+			cfg   = fuzzConfigs[int(fuzzByte(data, 0))%len(fuzzConfigs)]
+			r     = NewRing(2<<(fuzzByte(data, 1)%4), cfg.opts...)
+			size  = int(r.Cap())
+			model []int
+			next  int
+			dst   [4]interface{}
+		)
+		pop := func(v interface{}) {
+			if len(model) == 0 || v.(int) != model[0] {
+				t.Fatalf("assertion failed, popped %v, model %v.", v, model)
+			}
+			model = model[1:]
+		}
+		for i := 2; i < len(data); i++ {
+			op := data[i]
+			k := int(op>>3)%4 + 1
+			switch op % 6 {
+			case 0:
+				if ok := r.Push(next); ok != (len(model) < size) {
+					t.Fatalf("assertion failed, push returned %v at length %d.", ok, len(model))
+				} else if ok {
+					model = append(model, next)
+				}
+				next++
+			case 1:
+				v, ok := r.Pop()
+				if ok != (len(model) > 0) {
+					t.Fatalf("assertion failed, pop returned %v at length %d.", ok, len(model))
+				} else if ok {
+					pop(v)
+				}
+			case 2:
+				var src []interface{}
+				for j := 0; j < k; j++ {
+					src = append(src, next+j)
+				}
+				want := size - len(model)
+				if want > k {
+					want = k
+				}
+				if n := r.PushBatch(src); n != want {
+					t.Fatalf("assertion failed, pushed %d of %d, expected %d.", n, k, want)
+				}
+				for j := 0; j < want; j++ {
+					model = append(model, next+j)
+				}
+				next += k
+			case 3:
+				want := len(model)
+				if want > k {
+					want = k
+				}
+				n := r.PopInto(dst[:k])
+				if n != want {
+					t.Fatalf("assertion failed, popped %d of %d, expected %d.", n, k, want)
+				}
+				for _, v := range dst[:n] {
+					pop(v)
+				}
+			case 4:
+				// stop after first item of odd value.
+				want := 0
+				for want < k && want < len(model) {
+					want++
+					if model[want-1]&1 != 0 {
+						break
+					}
+				}
+				n := r.Consume(k, func(v interface{}) bool {
+					pop(v)
+					return v.(int)&1 == 0
+				})
+				if n != want {
+					t.Fatalf("assertion failed, consumed %d, expected %d.", n, want)
+				}
+			case 5:
+				v, ok := r.PopIf(func(v interface{}) bool { return v.(int)%3 != 0 })
+				switch {
+				case ok != (len(model) > 0 && model[0]%3 != 0):
+					t.Fatalf("assertion failed, conditional pop returned %v, model %v.", ok, model)
+				case ok:
+					pop(v)
+				}
+			}
+			if r.Len() != uint64(len(model)) {
+				t.Fatalf("inconsistent state, length %d, model %v.", r.Len(), model)
+			}
+		}
+	})
+}
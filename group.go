@@ -0,0 +1,199 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+)
+
+// - MARK: Group section.
+
+// Group is a set of competing consumers of a ring
+// which tracks in-flight items per member. When a
+// member leaves, its unacknowledged items are
+// redelivered to remaining members, so consumers
+// can be restarted one by one without losing
+// work.
+type Group struct {
+	ring      *Ring                     // source ring
+	redeliver *Unbounded                // items of departed members
+	maxflight int                       // in-flight limit per member
+	members   atomic.Pointer[[]*Member] // copy-on-write
+}
+
+// Member is a consumer of a `Group`. `Fetch` and
+// `Ack` must be called by a single goroutine,
+// `Leave` may be called from any goroutine.
+type Member struct {
+	name     string
+	group    *Group
+	slots    []atomic.Pointer[Delivery] // in-flight deliveries
+	next     int                        // next slot to probe
+	departed uint32                     // departure flag
+}
+
+// Delivery is an item fetched by a member which
+// must be acknowledged once processed.
+type Delivery struct {
+	Item   interface{} // payload
+	member *Member
+	slot   int
+}
+
+// NewGroup allocates and initializes a new `Group`
+// consuming from `ring` where each member holds
+// at most `maxflight` unacknowledged items.
+func NewGroup(ring *Ring, maxflight int) *Group {
+	if maxflight < 1 {
+		maxflight = 1
+	}
+	// departed members may hold more than ring
+	// capacity in flight, so redelivery is
+	// unbounded and `Leave` never waits.
+	g := &Group{ring: ring, maxflight: maxflight, redeliver: NewUnboundedSize(uint64(maxflight))}
+	members := make([]*Member, 0)
+	g.members.Store(&members)
+	return g
+}
+
+// Join adds a new member named `name`.
+func (g *Group) Join(name string) *Member {
+	m := &Member{name: name, group: g, slots: make([]atomic.Pointer[Delivery], g.maxflight)}
+	for {
+		old := g.members.Load()
+		members := append(append(make([]*Member, 0), *old...), m)
+		if g.members.CompareAndSwap(old, &members) {
+			return m
+		}
+	}
+}
+
+// Members returns current members.
+func (g *Group) Members() []*Member {
+	return *g.members.Load()
+}
+
+// Leave removes `m` from group and redelivers its
+// unacknowledged items. It returns the number of
+// redelivered items.
+func (g *Group) Leave(m *Member) int {
+	var n int
+	if !atomic.CompareAndSwapUint32(&m.departed, 0, 1) {
+		return 0
+	}
+	for {
+		old := g.members.Load()
+		members := make([]*Member, 0)
+		for _, o := range *old {
+			if o != m {
+				members = append(members, o)
+			}
+		}
+		if g.members.CompareAndSwap(old, &members) {
+			break
+		}
+	}
+	for i := range m.slots {
+		if d := m.slots[i].Swap(nil); d != nil {
+			g.requeue(d.Item)
+			n++
+		}
+	}
+	return n
+}
+
+// requeue pushes `item` to redelivery queue.
+func (g *Group) requeue(item interface{}) {
+	g.redeliver.Push(item)
+}
+
+// Name returns member name.
+func (m *Member) Name() string {
+	return m.name
+}
+
+// Fetch returns next item, preferring items
+// redelivered from departed members. It returns
+// false when no item is available, the in-flight
+// limit is reached or the member has left.
+func (m *Member) Fetch() (*Delivery, bool) {
+	if atomic.LoadUint32(&m.departed) == 1 {
+		return nil, false
+	}
+	slot := m.freeSlot()
+	if slot < 0 {
+		return nil, false
+	}
+	item, ok := m.group.redeliver.Pop()
+	if !ok {
+		if item, ok = m.group.ring.Pop(); !ok {
+			return nil, false
+		}
+	}
+	d := &Delivery{Item: item, member: m, slot: slot}
+	m.slots[slot].Store(d)
+	if atomic.LoadUint32(&m.departed) == 1 {
+		// raced with `Leave`; whoever takes the
+		// delivery out of the slot requeues it.
+		if m.slots[slot].CompareAndSwap(d, nil) {
+			m.group.requeue(item)
+		}
+		return nil, false
+	}
+	return d, true
+}
+
+// Ack acknowledges `d` and returns false when it
+// was already redelivered because member left.
+func (m *Member) Ack(d *Delivery) bool {
+	if d.member != m {
+		return false
+	}
+	return m.slots[d.slot].CompareAndSwap(d, nil)
+}
+
+// InFlight returns number of unacknowledged items.
+func (m *Member) InFlight() int {
+	var n int
+	for i := range m.slots {
+		if m.slots[i].Load() != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// freeSlot returns index of a free in-flight slot
+// or -1 when all are taken.
+func (m *Member) freeSlot() int {
+	for i := 0; i < len(m.slots); i++ {
+		slot := (m.next + i) % len(m.slots)
+		if m.slots[slot].Load() == nil {
+			m.next = slot + 1
+			return slot
+		}
+	}
+	return -1
+}
@@ -0,0 +1,120 @@
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "testing"
+
+func TestGroupRebalance(t *testing.T) {
+	var (
+		r    *Ring  = NewRing(8)
+		g    *Group = NewGroup(r, 4)
+		a, b *Member
+		got  map[int]bool
+	)
+	got = make(map[int]bool)
+	for i := 0; i < 6; i++ {
+		r.Push(i)
+	}
+	a, b = g.Join("a"), g.Join("b")
+	// `a` processes 0 and 1, acks only 0.
+	d0, _ := a.Fetch()
+	d1, _ := a.Fetch()
+	if !a.Ack(d0) || a.InFlight() != 1 {
+		t.Fatal("assertion failed, expected one in-flight item.")
+	}
+	got[d0.Item.(int)] = true
+	if n := g.Leave(a); n != 1 || len(g.Members()) != 1 {
+		t.Fatalf("assertion failed, redelivered(%d)!=1.", n)
+	}
+	if a.Ack(d1) {
+		t.Fatal("assertion failed, acked redelivered item.")
+	}
+	if _, ok := a.Fetch(); ok {
+		t.Fatal("assertion failed, departed member fetched.")
+	}
+	// `b` receives the redelivered item first.
+	d, ok := b.Fetch()
+	if !ok || d.Item.(int) != d1.Item.(int) {
+		t.Fatalf("assertion failed, expected redelivery of %v.", d1.Item)
+	}
+	got[d.Item.(int)] = b.Ack(d)
+	for {
+		d, ok := b.Fetch()
+		if !ok {
+			break
+		}
+		got[d.Item.(int)] = b.Ack(d)
+	}
+	if len(got) != 6 {
+		t.Fatalf("assertion failed, delivered %v.", got)
+	}
+}
+
+func TestGroupInFlightLimit(t *testing.T) {
+	var (
+		r *Ring  = NewRing(8)
+		g *Group = NewGroup(r, 2)
+		m *Member
+	)
+	for i := 0; i < 4; i++ {
+		r.Push(i)
+	}
+	m = g.Join("m")
+	d, _ := m.Fetch()
+	m.Fetch()
+	if _, ok := m.Fetch(); ok {
+		t.Fatal("assertion failed, exceeded in-flight limit.")
+	}
+	m.Ack(d)
+	if _, ok := m.Fetch(); !ok {
+		t.Fatal("assertion failed, expected free slot after ack.")
+	}
+}
+
+func TestGroupLeaveBeyondCapacity(t *testing.T) {
+	var (
+		r *Ring  = NewRing(2)
+		g *Group = NewGroup(r, 6)
+		m *Member
+		n int
+	)
+	m = g.Join("m")
+	// member holds three times ring capacity
+	for i := 0; i < 6; i++ {
+		r.Push(i)
+		if _, ok := m.Fetch(); !ok {
+			t.Fatal("inconsistent state, unable to fetch.")
+		}
+	}
+	if n = g.Leave(m); n != 6 {
+		t.Fatalf("assertion failed, redelivered(%d)!=6.", n)
+	}
+	m = g.Join("n")
+	for i := 0; i < 6; i++ {
+		if d, ok := m.Fetch(); !ok || d.Item.(int) != i {
+			t.Fatalf("assertion failed, expected redelivery of %d.", i)
+		}
+	}
+}
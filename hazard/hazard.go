@@ -0,0 +1,150 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+// Package hazard provides hazard pointers for safe memory
+// reclamation of manually recycled objects.
+package hazard
+
+import "sync/atomic"
+
+// Defaults
+const (
+	// Slots is number of hazard pointers per record.
+	Slots = 2
+	// cRETIRETHRESHOLD is number of retired
+	// pointers per record which triggers a scan.
+	cRETIRETHRESHOLD = 64
+)
+
+// - MARK: Struct section.
+
+// Record holds hazard pointers of a single
+// goroutine. Records are owned exclusively
+// between `Acquire` and `Release`.
+type Record struct {
+	hp      [Slots]atomic.Uintptr // published hazard addresses
+	active  uint32                // ownership flag
+	next    *Record               // next record in domain
+	retired []retired             // pointers retired by owner
+}
+
+// Domain is a set of records whose hazard
+// pointers are consulted before reclamation.
+type Domain struct {
+	head atomic.Pointer[Record] // records are never unlinked
+}
+
+// Default is the domain used by `lfring`.
+var Default = &Domain{}
+
+// - MARK: Domain section.
+
+// NewDomain allocates and initializes a new
+// `Domain` and returns a pointer to it.
+func NewDomain() *Domain {
+	return &Domain{}
+}
+
+// Acquire returns a record owned by the caller,
+// reusing inactive records before allocating.
+func (d *Domain) Acquire() *Record {
+	for r := d.head.Load(); r != nil; r = r.next {
+		if atomic.LoadUint32(&r.active) == 0 && atomic.CompareAndSwapUint32(&r.active, 0, 1) {
+			return r
+		}
+	}
+	r := &Record{active: 1, retired: make([]retired, 0, cRETIRETHRESHOLD)}
+	for {
+		head := d.head.Load()
+		r.next = head
+		if d.head.CompareAndSwap(head, r) {
+			return r
+		}
+	}
+}
+
+// Release clears hazard pointers of `r` and
+// gives up its ownership. Pending retired
+// pointers stay with the record and are
+// reclaimed by its next owner.
+func (d *Domain) Release(r *Record) {
+	for i := range r.hp {
+		r.hp[i].Store(0)
+	}
+	atomic.StoreUint32(&r.active, 0)
+}
+
+// Clear clears slot `i`.
+func (r *Record) Clear(i int) {
+	r.hp[i].Store(0)
+}
+
+// retire schedules `rt` for reclamation once no
+// record holds it as hazard pointer, see `Retire`.
+func (d *Domain) retire(r *Record, rt retired) {
+	r.retired = append(r.retired, rt)
+	if len(r.retired) >= cRETIRETHRESHOLD {
+		d.Scan(r)
+	}
+}
+
+// Scan reclaims retired pointers of `r` which
+// are not protected by any record. It does not
+// allocate.
+func (d *Domain) Scan(r *Record) int {
+	var (
+		n    int
+		kept []retired = r.retired[:0]
+	)
+	for _, rt := range r.retired {
+		if d.protected(rt.address()) {
+			kept = append(kept, rt)
+			continue
+		}
+		rt.release()
+		n++
+	}
+	// drop references of reclaimed entries
+	for i := len(kept); i < len(r.retired); i++ {
+		r.retired[i] = retired{}
+	}
+	r.retired = kept
+	return n
+}
+
+// protected returns whether any record holds
+// address `p`.
+func (d *Domain) protected(p uintptr) bool {
+	if p == 0 {
+		return false
+	}
+	for r := d.head.Load(); r != nil; r = r.next {
+		for i := range r.hp {
+			if r.hp[i].Load() == p {
+				return true
+			}
+		}
+	}
+	return false
+}
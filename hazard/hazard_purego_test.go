@@ -0,0 +1,64 @@
+//go:build purego
+// +build purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package hazard
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestRetireProtected(t *testing.T) {
+	var (
+		d      *Domain = NewDomain()
+		owner  *Record = d.Acquire()
+		reader *Record = d.Acquire()
+		freed  map[*int]bool
+		slot   atomic.Pointer[int]
+		a, b   int
+	)
+	freed = make(map[*int]bool)
+	free := func(p interface{}) { freed[p.(*int)] = true }
+	slot.Store(&a)
+	if Protect(reader, 0, &slot) != &a {
+		t.Fatal("assertion failed, unexpected protected value.")
+	}
+	slot.Store(&b)
+	d.Retire(owner, &a, free)
+	d.Retire(owner, &b, free)
+	if n := d.Scan(owner); n != 1 || freed[&a] || !freed[&b] {
+		t.Fatalf("assertion failed, reclaimed protected pointer (n=%d).", n)
+	}
+	reader.Clear(0)
+	if n := d.Scan(owner); n != 1 || !freed[&a] {
+		t.Fatalf("assertion failed, expected reclamation (n=%d).", n)
+	}
+	d.Release(reader)
+	if d.Acquire() != reader {
+		t.Fatal("assertion failed, expected record reuse.")
+	}
+}
@@ -0,0 +1,64 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package hazard
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestRetireProtected(t *testing.T) {
+	var (
+		d      *Domain = NewDomain()
+		owner  *Record = d.Acquire()
+		reader *Record = d.Acquire()
+		freed  map[unsafe.Pointer]bool
+		slot   unsafe.Pointer
+		a, b   int
+	)
+	freed = make(map[unsafe.Pointer]bool)
+	free := func(p unsafe.Pointer) { freed[p] = true }
+	slot = unsafe.Pointer(&a)
+	if reader.Protect(0, &slot) != unsafe.Pointer(&a) {
+		t.Fatal("assertion failed, unexpected protected value.")
+	}
+	slot = unsafe.Pointer(&b)
+	d.Retire(owner, unsafe.Pointer(&a), free)
+	d.Retire(owner, unsafe.Pointer(&b), free)
+	if n := d.Scan(owner); n != 1 || freed[unsafe.Pointer(&a)] || !freed[unsafe.Pointer(&b)] {
+		t.Fatalf("assertion failed, reclaimed protected pointer (n=%d).", n)
+	}
+	reader.Clear(0)
+	if n := d.Scan(owner); n != 1 || !freed[unsafe.Pointer(&a)] {
+		t.Fatalf("assertion failed, expected reclamation (n=%d).", n)
+	}
+	d.Release(reader)
+	if d.Acquire() != reader {
+		t.Fatal("assertion failed, expected record reuse.")
+	}
+}
@@ -0,0 +1,93 @@
+//go:build purego
+// +build purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package hazard
+
+import (
+	"reflect"
+	"sync/atomic"
+)
+
+// - MARK: Protect section.
+
+// retired is a pointer awaiting reclamation.
+type retired struct {
+	ptr  interface{}
+	free func(interface{})
+}
+
+// address returns address of the pointer.
+func (rt retired) address() uintptr {
+	return address(rt.ptr)
+}
+
+// release frees the pointer.
+func (rt retired) release() {
+	rt.free(rt.ptr)
+}
+
+// Protect publishes the value loaded from `addr`
+// in slot `i` of `r` and returns it. The value is
+// safe to dereference until the slot is cleared.
+// Without `unsafe` it is a function over typed
+// pointers, unlike the `Record` method of default
+// builds.
+func Protect[T any](r *Record, i int, addr *atomic.Pointer[T]) *T {
+	for {
+		p := addr.Load()
+		r.hp[i].Store(address(p))
+		// validate; `addr` may have changed
+		// before `p` became visible.
+		if addr.Load() == p {
+			return p
+		}
+	}
+}
+
+// Set publishes pointer `p` in slot `i`. Caller
+// must validate `p` is still reachable afterwards.
+func (r *Record) Set(i int, p interface{}) {
+	r.hp[i].Store(address(p))
+}
+
+// Retire schedules pointer `p` for reclamation
+// with `free` once no record holds it as hazard
+// pointer.
+func (d *Domain) Retire(r *Record, p interface{}, free func(interface{})) {
+	d.retire(r, retired{ptr: p, free: free})
+}
+
+// address returns address of pointer `p`, or
+// zero for nil. Retired entries keep their
+// pointers reachable, so an address cannot be
+// reused while it is compared.
+func address(p interface{}) uintptr {
+	if p == nil {
+		return 0
+	}
+	return reflect.ValueOf(p).Pointer()
+}
@@ -0,0 +1,74 @@
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"math/bits"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// - MARK: Test section.
+
+// TestInlinable compiles the package with `-gcflags=-m`
+// and asserts that hot-path helpers are inlined, so
+// call overhead does not creep back into Push/Pop.
+func TestInlinable(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping compiler diagnostics in short mode.")
+	}
+	if bits.UintSize != 64 {
+		t.Skip("skipping on 32-bit platform, 64-bit atomics are calls.")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not available.")
+	}
+	out, err := exec.Command(gobin, "build", "-gcflags=-m", ".").CombinedOutput()
+	if err != nil {
+		t.Fatalf("assertion failed, build error: %v\n%s", err, out)
+	}
+	diag := string(out)
+	for _, fn := range []string{
+		"(*Ring).publish",
+		"(*Ring).take",
+		"(*Ring).casFailed",
+		"(*Ring).seq",
+		"(*Ring).claimWrite",
+		"(*Ring).claimRead",
+		"(*Ring).readIndex",
+		"(*Ring).Len",
+	} {
+		if !strings.Contains(diag, "can inline "+fn+"\n") {
+			t.Fatalf("assertion failed, %s is not inlinable.", fn)
+		}
+	}
+	for _, fn := range []string{"(*Ring).publish", "(*Ring).take", "(*Ring).claimWrite", "(*Ring).claimRead"} {
+		if !strings.Contains(diag, "inlining call to "+fn+"\n") {
+			t.Fatalf("assertion failed, %s is not inlined into fast path.", fn)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+//go:build interleave && !purego
+// +build interleave,!purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"unsafe"
+)
+
+// - MARK: Test section.
+
+func TestInterleaveRDCSS(t *testing.T) {
+	var (
+		o  unsafe.Pointer = unsafe.Pointer(new(int))
+		n1 unsafe.Pointer = unsafe.Pointer(new(int))
+		n2 unsafe.Pointer = unsafe.Pointer(new(int))
+	)
+	n := explore(t, func() ([]func(), func() error) {
+		var (
+			a1       uint64
+			a2       unsafe.Pointer = o
+			ok1, ok2 bool
+			seen     []unsafe.Pointer
+		)
+		return []func(){
+			func() { ok1 = rdcss(&a1, 0, &a2, o, n1) },
+			func() { ok2 = rdcss(&a1, 0, &a2, o, n2) },
+			func() {
+				atomic.StoreUint64(&a1, 1)
+				yieldPoint()
+				seen = append(seen, atomic.LoadPointer(&a2))
+			},
+		}, func() error {
+			final := atomic.LoadPointer(&a2)
+			switch {
+			case ok1 && ok2:
+				return fmt.Errorf("both swaps succeeded")
+			case ok1 && final != n1, ok2 && final != n2:
+				return fmt.Errorf("successful swap not visible")
+			case !ok1 && !ok2 && final != o:
+				return fmt.Errorf("failed swaps changed data")
+			}
+			for _, p := range seen {
+				if p != o && p != n1 && p != n2 && !isDescriptor(p) {
+					return fmt.Errorf("reader observed foreign value")
+				}
+			}
+			return nil
+		}
+	})
+	t.Logf("explored %d schedules.", n)
+}
+
+func TestInterleaveKCSS(t *testing.T) {
+	var (
+		o *int = new(int)
+		n *int = new(int)
+	)
+	count := explore(t, func() ([]func(), func() error) {
+		var (
+			a   unsafe.Pointer = unsafe.Pointer(o)
+			ver uint64
+			ok  bool
+		)
+		return []func(){
+			func() { ok = kcss(&a, unsafe.Pointer(o), unsafe.Pointer(n), []*uint64{&ver}, []uint64{0}) },
+			func() { atomic.AddUint64(&ver, 1) },
+		}, func() error {
+			if final := atomic.LoadPointer(&a); ok != (final == unsafe.Pointer(n)) {
+				return fmt.Errorf("swap result %v disagrees with data", ok)
+			}
+			return nil
+		}
+	})
+	t.Logf("explored %d schedules.", count)
+}
+
+func TestInterleaveConfigCell(t *testing.T) {
+	count := explore(t, func() ([]func(), func() error) {
+		var (
+			c     *ConfigCell = NewConfigCell(1)
+			guard uint64
+			seen  []uint64
+			swaps int32
+		)
+		return []func(){
+			func() {
+				if c.CompareAndSwapGuarded(&guard, 0, 1, 2) {
+					atomic.AddInt32(&swaps, 1)
+				}
+			},
+			func() {
+				if c.CompareAndSwap(1, 3) {
+					atomic.AddInt32(&swaps, 1)
+				}
+			},
+			func() {
+				v, ver := c.Load()
+				if uint64(v.(int)) != ver && ver != 2 {
+					seen = append(seen, ver)
+				}
+			},
+		}, func() error {
+			if len(seen) != 0 {
+				return fmt.Errorf("inconsistent snapshots at versions %v", seen)
+			}
+			if swaps != 1 || c.Version() != 2 {
+				return fmt.Errorf("expected exactly one swap, got %d", swaps)
+			}
+			return nil
+		}
+	})
+	t.Logf("explored %d schedules.", count)
+}
@@ -0,0 +1,46 @@
+//go:build interleave
+// +build interleave
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+// - MARK: Interleave section.
+
+// interleaveHook, when set, is called at every
+// yield point, see `yieldPoint`. It is installed
+// by the schedule exploration harness of tests
+// built with tag `interleave` and must not be
+// changed while instrumented code runs.
+var interleaveHook func()
+
+// yieldPoint marks a window between two steps of
+// a concurrent protocol, e.g. between installing
+// and completing an RDCSS descriptor, where the
+// exploration harness may switch goroutines.
+func yieldPoint() {
+	if interleaveHook != nil {
+		interleaveHook()
+	}
+}
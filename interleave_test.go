@@ -0,0 +1,277 @@
+//go:build interleave
+// +build interleave
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Defaults
+const (
+	// cEXPLOREMAX bounds number of explored
+	// schedules per test.
+	cEXPLOREMAX = 200000
+	// cEXPLOREDEPTH is number of leading switches
+	// of a schedule which are branched on; later
+	// switches follow the default rotation.
+	cEXPLOREDEPTH = 10
+	// cEXPLORESTEPS bounds switches of a run,
+	// exceeding it indicates a livelock.
+	cEXPLORESTEPS = 10000
+	// cEXPLORESTALL is how long a thread may run
+	// without reaching a yield point.
+	cEXPLORESTALL = 5 * time.Second
+)
+
+// - MARK: Explorer section.
+
+// explorer runs threads, i.e. goroutines, one at
+// a time and switches between them only at yield
+// points, following a schedule: at every switch
+// the schedule picks one of the runnable threads
+// by its offset from a rotating default, so a
+// thread spinning on a yield point can not keep
+// the default schedule from finishing.
+// Enumerating schedules depth first explores
+// every interleaving of yield points, within a
+// depth bound, which stress tests hit only by
+// chance.
+type explorer struct {
+	mu      sync.Mutex
+	threads map[int64]*ethread // by goroutine id
+	events  chan *ethread      // thread yielded or finished
+}
+
+// ethread is a thread of an explorer.
+type ethread struct {
+	id   int
+	wake chan struct{}
+	done bool
+}
+
+// goid returns id of calling goroutine.
+func goid() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	id, _ := strconv.ParseInt(string(b[:bytes.IndexByte(b, ' ')]), 10, 64)
+	return id
+}
+
+// yield parks calling thread until it is
+// scheduled again. Goroutines not run by the
+// explorer pass through.
+func (e *explorer) yield() {
+	e.mu.Lock()
+	t := e.threads[goid()]
+	e.mu.Unlock()
+	if t == nil {
+		return
+	}
+	e.events <- t
+	<-t.wake
+}
+
+// run runs `fns` as threads following `schedule`
+// and returns the offsets taken and number of
+// runnable threads at every switch. Switches past
+// the end of `schedule` take offset zero.
+func (e *explorer) run(fns []func(), schedule []int) (taken, widths []int, err error) {
+	var (
+		threads []*ethread
+		started sync.WaitGroup
+	)
+	e.threads, e.events = map[int64]*ethread{}, make(chan *ethread)
+	for i, fn := range fns {
+		t := &ethread{id: i, wake: make(chan struct{})}
+		threads = append(threads, t)
+		started.Add(1)
+		go func(fn func()) {
+			e.mu.Lock()
+			e.threads[goid()] = t
+			e.mu.Unlock()
+			started.Done()
+			<-t.wake
+			fn()
+			t.done = true
+			e.events <- t
+		}(fn)
+	}
+	started.Wait()
+	for {
+		var runnable []*ethread
+		for _, t := range threads {
+			if !t.done {
+				runnable = append(runnable, t)
+			}
+		}
+		if len(runnable) == 0 {
+			return taken, widths, nil
+		}
+		if len(taken) == cEXPLORESTEPS {
+			return taken, widths, fmt.Errorf("threads livelocked")
+		}
+		offset := 0
+		if len(taken) < len(schedule) {
+			offset = schedule[len(taken)]
+		}
+		choice := (len(taken) + offset) % len(runnable)
+		taken, widths = append(taken, offset), append(widths, len(runnable))
+		runnable[choice].wake <- struct{}{}
+		select {
+		case <-e.events:
+		case <-time.After(cEXPLORESTALL):
+			// threads are leaked; the test fails.
+			return taken, widths, fmt.Errorf("thread %d runs without reaching a yield point", runnable[choice].id)
+		}
+	}
+}
+
+// explore runs every schedule of threads made by
+// `setup`, up to `cEXPLOREMAX`, calling `check`
+// once threads finished. It returns number of
+// explored schedules.
+func explore(t *testing.T, setup func() (fns []func(), check func() error)) int {
+	var (
+		e        *explorer = &explorer{}
+		schedule []int
+	)
+	interleaveHook = e.yield
+	defer func() { interleaveHook = nil }()
+	for n := 1; ; n++ {
+		fns, check := setup()
+		taken, widths, err := e.run(fns, schedule)
+		if err == nil {
+			err = check()
+		}
+		if err != nil {
+			t.Fatalf("assertion failed, schedule %v: %v.", taken, err)
+		}
+		// advance to next schedule, depth first.
+		i := len(taken) - 1
+		if i >= cEXPLOREDEPTH {
+			i = cEXPLOREDEPTH - 1
+		}
+		for i >= 0 && taken[i]+1 >= widths[i] {
+			i--
+		}
+		if i < 0 || n == cEXPLOREMAX {
+			return n
+		}
+		schedule = append(taken[:i:i], taken[i]+1)
+	}
+}
+
+// - MARK: Test section.
+
+func TestExplorer(t *testing.T) {
+	// two threads with one yield point each
+	// interleave in 6 ways.
+	var orders map[string]bool = map[string]bool{}
+	n := explore(t, func() ([]func(), func() error) {
+		var (
+			mu    sync.Mutex
+			order []byte
+		)
+		step := func(c byte) func() {
+			return func() {
+				mu.Lock()
+				order = append(order, c)
+				mu.Unlock()
+				yieldPoint()
+				mu.Lock()
+				order = append(order, c+'a'-'A')
+				mu.Unlock()
+			}
+		}
+		return []func(){step('A'), step('B')}, func() error {
+			orders[string(order)] = true
+			return nil
+		}
+	})
+	if n != 6 || len(orders) != 6 {
+		t.Fatalf("assertion failed, expected 6 schedules, got %d (%v).", n, orders)
+	}
+}
+
+func TestInterleaveRing(t *testing.T) {
+	count := explore(t, func() ([]func(), func() error) {
+		var (
+			r      *Ring = NewRing(2)
+			pushed [3]bool
+			popped []int
+		)
+		return []func(){
+			func() {
+				pushed[0] = r.Push(1)
+				pushed[1] = r.Push(2)
+			},
+			func() { pushed[2] = r.Push(10) },
+			func() {
+				for i := 0; i < 2; i++ {
+					if v, ok := r.Pop(); ok {
+						popped = append(popped, v.(int))
+					}
+				}
+			},
+		}, func() error {
+			for {
+				v, ok := r.Pop()
+				if !ok {
+					break
+				}
+				popped = append(popped, v.(int))
+			}
+			var want int
+			for _, ok := range pushed {
+				if ok {
+					want++
+				}
+			}
+			if len(popped) != want {
+				return fmt.Errorf("pushed %v, popped %v", pushed, popped)
+			}
+			last := 0
+			for _, v := range popped {
+				if v < 10 {
+					if v < last {
+						return fmt.Errorf("producer order violated in %v", popped)
+					}
+					last = v
+				}
+			}
+			return nil
+		}
+	})
+	t.Logf("explored %d schedules.", count)
+}
@@ -0,0 +1,248 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+)
+
+// - MARK: Node section.
+
+const (
+	// cNODEIDLE is state of a node outside rings.
+	cNODEIDLE uint32 = iota
+	// cNODEQUEUED is state of an enqueued node.
+	cNODEQUEUED
+	// cNODECANCELLED is state of an enqueued node
+	// which is skipped by consumers.
+	cNODECANCELLED
+)
+
+// Node is embedded by payload structs stored in an
+// `IntrusiveRing`. It records sequence of the last
+// enqueue and a state word which makes
+// cancellation a single CAS. A node is in at most
+// one ring at a time.
+type Node struct {
+	seq   uint64 // ring position of last enqueue
+	state uint32 // cNODE* state
+}
+
+// Intrusive is implemented by structs embedding
+// `Node`.
+type Intrusive interface {
+	RingNode() *Node
+}
+
+// RingNode implements `Intrusive`.
+func (n *Node) RingNode() *Node {
+	return n
+}
+
+// Seq returns ring position of last enqueue.
+func (n *Node) Seq() uint64 {
+	return atomic.LoadUint64(&n.seq)
+}
+
+// Queued returns whether node is enqueued and
+// not cancelled.
+func (n *Node) Queued() bool {
+	return atomic.LoadUint32(&n.state) == cNODEQUEUED
+}
+
+// Cancel cancels an enqueued node in constant time
+// and returns false when it is not enqueued or
+// already dequeued. Cancelled nodes keep their
+// slot until consumers skip them; the node can
+// not be enqueued again before.
+func (n *Node) Cancel() bool {
+	return atomic.CompareAndSwapUint32(&n.state, cNODEQUEUED, cNODECANCELLED)
+}
+
+// - MARK: IntrusiveRing section.
+
+// IntrusiveRing is a ring of payloads embedding
+// `Node`. Slots hold the payload pointer itself,
+// no wrapper is allocated per item.
+type IntrusiveRing struct {
+	// 64bit aligned
+	cancelled uint64 // skipped cancelled nodes
+	ring      *Ring
+}
+
+// NewIntrusiveRing allocates and initializes a new
+// `IntrusiveRing` and returns a pointer to it.
+// `opts` configure the underlying ring; policies
+// dropping items are not supported.
+func NewIntrusiveRing(capacity uint64, opts ...Option) *IntrusiveRing {
+	q := &IntrusiveRing{ring: NewRing(capacity, opts...)}
+	if q.ring.policy != Block {
+		q.ring.policy = Reject
+	}
+	return q
+}
+
+// Ring returns underlying ring.
+func (q *IntrusiveRing) Ring() *Ring {
+	return q.ring
+}
+
+// Len returns number of slots in use, including
+// cancelled nodes not yet skipped.
+func (q *IntrusiveRing) Len() uint64 {
+	return q.ring.Len()
+}
+
+// Cancelled returns number of skipped cancelled
+// nodes.
+func (q *IntrusiveRing) Cancelled() uint64 {
+	return atomic.LoadUint64(&q.cancelled)
+}
+
+// Push enqueues `item` and returns false when
+// ring is full or node is already enqueued.
+func (q *IntrusiveRing) Push(item Intrusive) bool {
+	n := item.RingNode()
+	if !atomic.CompareAndSwapUint32(&n.state, cNODEIDLE, cNODEQUEUED) {
+		return false
+	}
+	pos, ok := q.ring.acquireWrite()
+	if !ok {
+		atomic.StoreUint32(&n.state, cNODEIDLE)
+		return false
+	}
+	atomic.StoreUint64(&n.seq, pos)
+	q.ring.stamp(pos)
+	q.ring.publish(pos, item)
+	q.ring.published(pos, item)
+	return true
+}
+
+// Pop dequeues next node which is not cancelled
+// and returns false when ring is empty. Skipped
+// nodes become idle and may be enqueued again.
+func (q *IntrusiveRing) Pop() (Intrusive, bool) {
+	for {
+		v, ok := q.ring.Pop()
+		if !ok {
+			return nil, false
+		}
+		item := v.(Intrusive)
+		n := item.RingNode()
+		if atomic.CompareAndSwapUint32(&n.state, cNODEQUEUED, cNODEIDLE) {
+			return item, true
+		}
+		// cancelled; release node and skip.
+		atomic.StoreUint32(&n.state, cNODEIDLE)
+		atomic.AddUint64(&q.cancelled, 1)
+	}
+}
+
+// - MARK: MPSCList section.
+
+// Link is embedded by payload structs stored in
+// an `MPSCList`. Unlike `Node`, it links payloads
+// to each other, so the list needs no storage of
+// its own and is never full.
+type Link struct {
+	next *Link  // pushed before
+	item Linked // payload embedding link
+}
+
+// Linked is implemented by structs embedding
+// `Link`.
+type Linked interface {
+	ListLink() *Link
+}
+
+// ListLink implements `Linked`.
+func (n *Link) ListLink() *Link {
+	return n
+}
+
+// MPSCList is an unbounded intrusive list which
+// any goroutine pushes to and a single consumer
+// drains at once, e.g. a completion queue of
+// callbacks or deferred work where a bounded ring
+// could refuse a completion. Push is a single CAS
+// on the head; drain swaps the head out and
+// reverses the detached chain into push order.
+// A payload must not be pushed again before it
+// is drained.
+type MPSCList struct {
+	head atomic.Pointer[Link] // pushed last
+}
+
+// NewMPSCList allocates and initializes a new
+// `MPSCList` and returns a pointer to it.
+func NewMPSCList() *MPSCList {
+	return &MPSCList{}
+}
+
+// Push appends `item` and returns true when list
+// was empty, i.e. when the consumer may need a
+// wakeup. It does not allocate.
+func (l *MPSCList) Push(item Linked) bool {
+	n := item.ListLink()
+	n.item = item
+	for {
+		old := l.head.Load()
+		n.next = old
+		if l.head.CompareAndSwap(old, n) {
+			return old == nil
+		}
+	}
+}
+
+// Empty returns whether list is empty.
+func (l *MPSCList) Empty() bool {
+	return l.head.Load() == nil
+}
+
+// Drain detaches all pushed payloads and passes
+// them to `fn` in push order, per producer, and
+// returns their number. Payloads are unlinked
+// before `fn` is called, so `fn` may push them
+// again. It must be called by a single consumer.
+func (l *MPSCList) Drain(fn func(item Linked)) int {
+	var (
+		n    int
+		prev *Link
+		cur  *Link = l.head.Swap(nil)
+	)
+	for cur != nil {
+		next := cur.next
+		cur.next = prev
+		prev, cur = cur, next
+	}
+	for prev != nil {
+		next, item := prev.next, prev.item
+		prev.next, prev.item = nil, nil
+		fn(item)
+		prev = next
+		n++
+	}
+	return n
+}
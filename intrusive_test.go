@@ -0,0 +1,191 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+type tstjob struct {
+	Node
+	id int
+}
+
+func TestIntrusiveRing(t *testing.T) {
+	var (
+		q    *IntrusiveRing = NewIntrusiveRing(4)
+		jobs []*tstjob      = []*tstjob{{id: 0}, {id: 1}, {id: 2}}
+	)
+	for _, j := range jobs {
+		if !q.Push(j) {
+			t.Fatal("inconsistent state, unable to push.")
+		}
+	}
+	if q.Push(jobs[0]) {
+		t.Fatal("assertion failed, node enqueued twice.")
+	}
+	if jobs[2].Seq() != 2 || !jobs[1].Queued() {
+		t.Fatalf("assertion failed, seq(%d).", jobs[2].Seq())
+	}
+	if !jobs[1].Cancel() || jobs[1].Cancel() || jobs[1].Queued() {
+		t.Fatal("assertion failed, expected single cancellation.")
+	}
+	for _, want := range []int{0, 2} {
+		v, ok := q.Pop()
+		if !ok || v.(*tstjob).id != want {
+			t.Fatalf("assertion failed, expected %d, got %v.", want, v)
+		}
+	}
+	if _, ok := q.Pop(); ok || q.Cancelled() != 1 || q.Len() != 0 {
+		t.Fatalf("assertion failed, cancelled(%d).", q.Cancelled())
+	}
+	// dequeued and skipped nodes are reusable
+	if jobs[0].Cancel() || !q.Push(jobs[1]) || jobs[1].Seq() != 3 {
+		t.Fatal("assertion failed, expected idle nodes.")
+	}
+	assertNoAllocs(t, "IntrusiveRing", func() {
+		q.Push(jobs[0])
+		q.Pop()
+	})
+}
+
+func TestIntrusiveRingConcurrent(t *testing.T) {
+	const n = 2000
+	var (
+		q    *IntrusiveRing = NewIntrusiveRing(64)
+		jobs []*tstjob      = make([]*tstjob, n)
+		wg   sync.WaitGroup
+		got  int
+	)
+	for i := range jobs {
+		jobs[i] = &tstjob{id: i}
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, j := range jobs {
+			for !q.Push(j) {
+				runtime.Gosched()
+			}
+			if j.id%3 == 0 {
+				j.Cancel()
+			}
+		}
+	}()
+	for got+int(q.Cancelled()) < n {
+		if v, ok := q.Pop(); ok {
+			if v.(*tstjob).Queued() {
+				t.Fatal("assertion failed, dequeued node still queued.")
+			}
+			got++
+			continue
+		}
+		runtime.Gosched()
+	}
+	wg.Wait()
+	if got+int(q.Cancelled()) != n {
+		t.Fatalf("assertion failed, got(%d), cancelled(%d).", got, q.Cancelled())
+	}
+}
+
+type tstdone struct {
+	Link
+	id int
+}
+
+func TestMPSCList(t *testing.T) {
+	var (
+		l    *MPSCList  = NewMPSCList()
+		done []*tstdone = []*tstdone{{id: 0}, {id: 1}, {id: 2}}
+		got  []int
+	)
+	if !l.Empty() || l.Drain(func(Linked) {}) != 0 {
+		t.Fatal("assertion failed, expected empty list.")
+	}
+	for i, d := range done {
+		if first := l.Push(d); first != (i == 0) {
+			t.Fatalf("assertion failed, push %d reported empty(%v).", i, first)
+		}
+	}
+	n := l.Drain(func(item Linked) {
+		got = append(got, item.(*tstdone).id)
+		if item.(*tstdone).id == 1 {
+			// drained payloads may be pushed again.
+			l.Push(item)
+		}
+	})
+	if n != 3 || len(got) != 3 || got[0] != 0 || got[1] != 1 || got[2] != 2 {
+		t.Fatalf("assertion failed, expected push order, got %v.", got)
+	}
+	if l.Empty() || l.Drain(func(Linked) {}) != 1 || !l.Empty() {
+		t.Fatal("inconsistent state, expected repushed payload.")
+	}
+	assertNoAllocs(t, "MPSCList", func() {
+		l.Push(done[0])
+		l.Drain(func(Linked) {})
+	})
+}
+
+func TestMPSCListConcurrent(t *testing.T) {
+	const (
+		producers = 4
+		n         = 1000
+	)
+	var (
+		l    *MPSCList = NewMPSCList()
+		wg   sync.WaitGroup
+		last [producers]int
+		got  int
+	)
+	for p := 0; p < producers; p++ {
+		last[p] = -1
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				l.Push(&tstdone{id: p*n + i})
+			}
+		}(p)
+	}
+	for got < producers*n {
+		k := l.Drain(func(item Linked) {
+			id := item.(*tstdone).id
+			if p := id / n; id%n <= last[p] {
+				t.Fatalf("assertion failed, %d drained after %d.", id, last[p])
+			} else {
+				last[p] = id % n
+			}
+		})
+		if got += k; k == 0 {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+	if !l.Empty() {
+		t.Fatal("inconsistent state, expected empty list.")
+	}
+}
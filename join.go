@@ -0,0 +1,206 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// - MARK: Join section.
+
+// KeyFunc returns join key of an item. Keys must be
+// comparable.
+type KeyFunc func(item interface{}) interface{}
+
+// Pair is a matched pair emitted by `Join`.
+type Pair struct {
+	Left  interface{}
+	Right interface{}
+}
+
+// joinEntry is a buffered item awaiting matches.
+type joinEntry struct {
+	item interface{}
+	at   int64 // unix ns
+}
+
+// Join correlates items of two rings: items with
+// equal keys whose times lie within `window` of
+// each other are emitted as `Pair` to output ring.
+// Item time is its event time when `Timestamped`
+// and its arrival time otherwise. Items are kept
+// for `window` past the latest time seen, so an
+// item matches every counterpart arriving in time,
+// and are then evicted. A join must be run by a
+// single goroutine.
+type Join struct {
+	left    *Ring
+	right   *Ring
+	out     *Ring
+	key     KeyFunc
+	window  int64                       // ns
+	lbuf    map[interface{}][]joinEntry // buffered left items
+	rbuf    map[interface{}][]joinEntry // buffered right items
+	latest  int64                       // latest item time
+	swept   int64                       // time of last eviction
+	matched uint64                      // emitted pairs
+	expired uint64                      // evicted items
+}
+
+// NewJoin allocates and initializes a new `Join`
+// of `left` and `right` emitting to `out` and
+// returns a pointer to it.
+func NewJoin(left, right *Ring, key KeyFunc, window time.Duration, out *Ring) *Join {
+	return &Join{
+		left:   left,
+		right:  right,
+		out:    out,
+		key:    key,
+		window: int64(window),
+		lbuf:   make(map[interface{}][]joinEntry),
+		rbuf:   make(map[interface{}][]joinEntry),
+	}
+}
+
+// Step processes up to `max` items of each input
+// ring, evicts expired items and returns number
+// of processed items.
+func (j *Join) Step(max int) int {
+	var n int
+	for i := 0; i < max; i++ {
+		lv, lok := j.left.Pop()
+		if lok {
+			j.add(lv, j.lbuf, j.rbuf, true)
+			n++
+		}
+		rv, rok := j.right.Pop()
+		if rok {
+			j.add(rv, j.rbuf, j.lbuf, false)
+			n++
+		}
+		if !lok && !rok {
+			break
+		}
+	}
+	if j.latest-j.swept > j.window/4 {
+		j.evict()
+	}
+	return n
+}
+
+// Run processes items until `stop` is closed,
+// waiting on left ring according to its wait
+// strategy while both rings are empty.
+func (j *Join) Run(stop <-chan struct{}) {
+	for idle := 0; ; {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if j.Step(cRDSCHDTHRESHOLD) > 0 {
+			idle = 0
+			continue
+		}
+		j.left.pause(idle)
+		idle++
+	}
+}
+
+// Stats returns number of emitted pairs and of
+// items evicted from window.
+func (j *Join) Stats() (matched, expired uint64) {
+	return atomic.LoadUint64(&j.matched), atomic.LoadUint64(&j.expired)
+}
+
+// Buffered returns number of items awaiting
+// matches.
+func (j *Join) Buffered() int {
+	var n int
+	for _, buf := range []map[interface{}][]joinEntry{j.lbuf, j.rbuf} {
+		for _, entries := range buf {
+			n += len(entries)
+		}
+	}
+	return n
+}
+
+// add matches `item` against buffered items of
+// the other side and buffers it.
+func (j *Join) add(item interface{}, own, other map[interface{}][]joinEntry, isLeft bool) {
+	var (
+		at  int64 = j.itemTime(item)
+		key       = j.key(item)
+	)
+	if at > j.latest {
+		j.latest = at
+	}
+	for _, e := range other[key] {
+		if d := at - e.at; d > j.window || -d > j.window {
+			continue
+		}
+		p := &Pair{Left: item, Right: e.item}
+		if !isLeft {
+			p.Left, p.Right = e.item, item
+		}
+		for i := 0; !j.out.Push(p); i++ {
+			j.out.pause(i)
+		}
+		atomic.AddUint64(&j.matched, 1)
+	}
+	own[key] = append(own[key], joinEntry{item: item, at: at})
+}
+
+// evict drops items which can no longer match.
+func (j *Join) evict() {
+	var horizon int64 = j.latest - j.window
+	for _, buf := range []map[interface{}][]joinEntry{j.lbuf, j.rbuf} {
+		for key, entries := range buf {
+			kept := entries[:0]
+			for _, e := range entries {
+				if e.at >= horizon {
+					kept = append(kept, e)
+				}
+			}
+			atomic.AddUint64(&j.expired, uint64(len(entries)-len(kept)))
+			if len(kept) == 0 {
+				delete(buf, key)
+			} else {
+				buf[key] = kept
+			}
+		}
+	}
+	j.swept = j.latest
+}
+
+// itemTime returns event time of `item` or
+// current time of left ring.
+func (j *Join) itemTime(item interface{}) int64 {
+	if ts, ok := item.(Timestamped); ok {
+		return ts.EventTime().UnixNano()
+	}
+	return j.left.clock.Now().UnixNano()
+}
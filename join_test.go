@@ -0,0 +1,78 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"testing"
+	"time"
+)
+
+// joinEvent is a keyed event for join tests.
+type joinEvent struct {
+	key string
+	at  time.Time
+}
+
+// EventTime implements `Timestamped` interface.
+func (e joinEvent) EventTime() time.Time {
+	return e.at
+}
+
+func TestJoinWindow(t *testing.T) {
+	var (
+		left  *Ring     = NewRing(16)
+		right *Ring     = NewRing(16)
+		out   *Ring     = NewRing(16)
+		base  time.Time = time.Unix(1000, 0)
+		j     *Join
+	)
+	j = NewJoin(left, right, func(v interface{}) interface{} { return v.(joinEvent).key }, time.Second, out)
+	left.Push(joinEvent{"a", base})
+	left.Push(joinEvent{"b", base})
+	right.Push(joinEvent{"a", base.Add(500 * time.Millisecond)})
+	// outside window of left "b"
+	right.Push(joinEvent{"b", base.Add(1200 * time.Millisecond)})
+	j.Step(16)
+	if out.Len() != 1 {
+		t.Fatalf("assertion failed, emitted %d pairs.", out.Len())
+	}
+	v, _ := out.Pop()
+	p := v.(*Pair)
+	if p.Left.(joinEvent).key != "a" || !p.Right.(joinEvent).at.Equal(base.Add(500*time.Millisecond)) {
+		t.Fatalf("assertion failed, unexpected pair %+v.", p)
+	}
+	// late left "a" still matches buffered right
+	left.Push(joinEvent{"a", base.Add(time.Second)})
+	j.Step(16)
+	if v, ok := out.Pop(); !ok || v.(*Pair).Left.(joinEvent).at != base.Add(time.Second) {
+		t.Fatalf("assertion failed, expected late match, got %v.", v)
+	}
+	// advancing time evicts old items
+	left.Push(joinEvent{"c", base.Add(10 * time.Second)})
+	j.Step(16)
+	if matched, expired := j.Stats(); matched != 2 || expired != 5 || j.Buffered() != 1 {
+		t.Fatalf("assertion failed, matched(%d), expired(%d), buffered(%d).", matched, expired, j.Buffered())
+	}
+}
@@ -0,0 +1,116 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"sync/atomic"
+	"unsafe"
+)
+
+// - MARK: KCSS section.
+
+// kcss performs k-compare single-swap: `*a` is
+// set to `n` iff `*a == o` and `*addrs[i] == olds[i]`
+// holds for every `i`. It returns true when the
+// swap took place.
+//
+// Compared words must be monotonic version
+// counters. A descriptor is installed at `a` for
+// the duration of the operation, then the counters
+// are collected twice; since counters never go
+// back, two matching collects imply every counter
+// held its expected value at a common instant
+// while `a` was still owned, which is the
+// linearization point (Luchangco, Moir, Shavit).
+func kcss(a *unsafe.Pointer, o, n unsafe.Pointer, addrs []*uint64, olds []uint64) bool {
+	ok, _ := kcssTry(a, o, n, addrs, olds)
+	return ok
+}
+
+// KCSSCtx performs KCSS like `kcss`, retrying
+// with backoff while `a` is held by a competing
+// operation until `ctx` is done. It returns false
+// without retrying when a value does not match,
+// and `ctx.Err()` when it gave up. Values must be
+// `TagSafe`, otherwise `ErrMisaligned` is returned.
+func KCSSCtx(ctx context.Context, a *unsafe.Pointer, o, n unsafe.Pointer, addrs []*uint64, olds []uint64) (bool, error) {
+	if !TagSafe(o) || !TagSafe(n) {
+		return false, ErrMisaligned
+	}
+	var swapped bool
+	_, err := casBackoff.RetryCtx(ctx, func() bool {
+		ok, contended := kcssTry(a, o, n, addrs, olds)
+		swapped = ok
+		return !contended
+	})
+	return swapped, err
+}
+
+// kcssTry is `kcss` which also reports whether
+// it failed to acquire `a` while it was held by
+// a competitor or changed back.
+func kcssTry(a *unsafe.Pointer, o, n unsafe.Pointer, addrs []*uint64, olds []uint64) (ok, contended bool) {
+	if len(addrs) != len(olds) {
+		panic("lfring: kcss length mismatch")
+	}
+	var (
+		rc  reclaimer        = enterReclaim()
+		d   *rdcssDescriptor = acquireDescriptor()
+		tag unsafe.Pointer
+	)
+	d.a2, d.o2, d.n2 = a, o, n
+	tag = tagDescriptor(d)
+	// acquire `a`; competitors observe the
+	// tag and back off.
+	if !atomic.CompareAndSwapPointer(a, o, tag) {
+		releaseDescriptor(rc, d)
+		cur := atomic.LoadPointer(a)
+		return false, cur == o || isDescriptor(cur)
+	}
+	yieldPoint()
+	ok = collect(addrs, olds) && collect(addrs, olds)
+	if ok {
+		atomic.CompareAndSwapPointer(a, tag, n)
+	} else {
+		atomic.CompareAndSwapPointer(a, tag, o)
+	}
+	releaseDescriptor(rc, d)
+	return ok, false
+}
+
+// collect returns whether every word in `addrs`
+// holds the corresponding value in `olds`.
+func collect(addrs []*uint64, olds []uint64) bool {
+	for i, addr := range addrs {
+		if atomic.LoadUint64(addr) != olds[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestKCSS(t *testing.T) {
+	var (
+		v1, v2 uint64 = 3, 7
+		a, b   int
+		word   unsafe.Pointer = unsafe.Pointer(&a)
+		addrs  []*uint64      = []*uint64{&v1, &v2}
+	)
+	if kcss(&word, unsafe.Pointer(&a), unsafe.Pointer(&b), addrs, []uint64{3, 8}) {
+		t.Fatal("assertion failed, expected false on counter mismatch.")
+	}
+	if word != unsafe.Pointer(&a) {
+		t.Fatal("inconsistent state, word modified.")
+	}
+	if kcss(&word, unsafe.Pointer(&b), nil, addrs, []uint64{3, 7}) {
+		t.Fatal("assertion failed, expected false on word mismatch.")
+	}
+	if !kcss(&word, unsafe.Pointer(&a), unsafe.Pointer(&b), addrs, []uint64{3, 7}) {
+		t.Fatal("assertion failed, expected true.")
+	}
+	if word != unsafe.Pointer(&b) {
+		t.Fatal("inconsistent state, word not swapped.")
+	}
+}
+
+func TestKCSSCtx(t *testing.T) {
+	var (
+		v1, v2 uint64 = 3, 7
+		a, b   int
+		word   unsafe.Pointer = tagDescriptor(&rdcssDescriptor{})
+		addrs  []*uint64      = []*uint64{&v1, &v2}
+		held   unsafe.Pointer = word
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if ok, err := KCSSCtx(ctx, &word, unsafe.Pointer(&a), unsafe.Pointer(&b), addrs, []uint64{3, 7}); ok || err != context.DeadlineExceeded || word != held {
+		t.Fatalf("assertion failed, expected deadline, got %v, %v.", ok, err)
+	}
+	go func() {
+		time.Sleep(time.Millisecond)
+		atomic.StorePointer(&word, unsafe.Pointer(&a))
+	}()
+	if ok, err := KCSSCtx(context.Background(), &word, unsafe.Pointer(&a), unsafe.Pointer(&b), addrs, []uint64{3, 8}); ok || err != nil {
+		t.Fatalf("assertion failed, expected counter mismatch, got %v, %v.", ok, err)
+	}
+	if ok, err := KCSSCtx(context.Background(), &word, unsafe.Pointer(&a), unsafe.Pointer(&b), addrs, []uint64{3, 7}); !ok || err != nil {
+		t.Fatalf("assertion failed, expected swap, got %v, %v.", ok, err)
+	}
+}
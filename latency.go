@@ -0,0 +1,179 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// - MARK: Latency section.
+
+const (
+	// cLATSUBBITS is log2 of linear sub-buckets
+	// per power of two; relative bucket error is
+	// below 1/2^cLATSUBBITS.
+	cLATSUBBITS = 3
+	// cLATBUCKETS is number of histogram buckets.
+	cLATBUCKETS = (64 - cLATSUBBITS + 1) << cLATSUBBITS
+)
+
+// latency is the time-in-queue recorder of a ring.
+// Push stamps each slot before publishing it and
+// pop records the age of the slot before releasing
+// it into a log-linear (HDR-style) histogram of
+// atomic counters.
+type latency struct {
+	base    time.Time // monotonic time origin
+	stamps  []int64   // push time per slot, ns since base
+	max     int64     // maximum recorded ns
+	sum     uint64    // sum of recorded ns
+	buckets [cLATBUCKETS]uint64
+}
+
+// WithLatency enables time-in-queue recording, see
+// `LatencySnapshot`. It costs a clock read per
+// push and pop.
+func WithLatency() Option {
+	return func(r *Ring) { r.lat = &latency{} }
+}
+
+// stamp records push time of position `pos` and
+// clears its deadline; it must precede
+// publication.
+func (r *Ring) stamp(pos uint64) {
+	if r.lat != nil {
+		atomic.StoreInt64(&r.lat.stamps[pos&(r.size-1)], int64(r.clock.Now().Sub(r.lat.base)))
+	}
+	if r.ttl != nil {
+		atomic.StoreInt64(&r.ttl.deadlines[pos&(r.size-1)], 0)
+	}
+}
+
+// age records time-in-queue of position `pos`; it
+// must precede release of the slot.
+func (r *Ring) age(pos uint64) {
+	if r.lat != nil {
+		r.lat.record(int64(r.clock.Now().Sub(r.lat.base)) - atomic.LoadInt64(&r.lat.stamps[pos&(r.size-1)]))
+	}
+}
+
+// record adds duration `ns` to histogram.
+func (l *latency) record(ns int64) {
+	if ns < 0 {
+		ns = 0
+	}
+	atomic.AddUint64(&l.buckets[latBucket(uint64(ns))], 1)
+	atomic.AddUint64(&l.sum, uint64(ns))
+	for {
+		max := atomic.LoadInt64(&l.max)
+		if ns <= max || atomic.CompareAndSwapInt64(&l.max, max, ns) {
+			return
+		}
+	}
+}
+
+// latBucket returns bucket of `v`: values below
+// 2^cLATSUBBITS map linearly, larger ones by
+// exponent and top `cLATSUBBITS` mantissa bits.
+func latBucket(v uint64) int {
+	if v < 1<<cLATSUBBITS {
+		return int(v)
+	}
+	exp := bits.Len64(v) - cLATSUBBITS - 1
+	return (exp+1)<<cLATSUBBITS + int(v>>uint(exp))&(1<<cLATSUBBITS-1)
+}
+
+// latUpper returns largest value of bucket `b`.
+func latUpper(b int) uint64 {
+	if b < 1<<cLATSUBBITS {
+		return uint64(b)
+	}
+	exp := uint(b>>cLATSUBBITS - 1)
+	sub := uint64(b&(1<<cLATSUBBITS-1) | 1<<cLATSUBBITS)
+	return (sub+1)<<exp - 1
+}
+
+// LatencySnapshot is a copy of the time-in-queue
+// histogram of a ring.
+type LatencySnapshot struct {
+	Count   uint64        // recorded items
+	Sum     time.Duration // total time-in-queue
+	Max     time.Duration // maximum time-in-queue
+	buckets [cLATBUCKETS]uint64
+}
+
+// LatencySnapshot returns time-in-queue histogram
+// of items popped so far. It is empty unless
+// enabled by `WithLatency`. Concurrent pops may
+// be partially included.
+func (r *Ring) LatencySnapshot() (s LatencySnapshot) {
+	if r.lat == nil {
+		return s
+	}
+	return r.lat.snapshot()
+}
+
+// snapshot returns a copy of histogram.
+func (l *latency) snapshot() (s LatencySnapshot) {
+	for i := range s.buckets {
+		s.buckets[i] = atomic.LoadUint64(&l.buckets[i])
+		s.Count += s.buckets[i]
+	}
+	s.Sum = time.Duration(atomic.LoadUint64(&l.sum))
+	s.Max = time.Duration(atomic.LoadInt64(&l.max))
+	return s
+}
+
+// Mean returns mean time-in-queue.
+func (s *LatencySnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile returns upper bound of time-in-queue of
+// quantile `q` in [0, 1], e.g. 0.99 for p99.
+func (s *LatencySnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(s.Count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for b, n := range s.buckets {
+		if seen += n; seen >= rank {
+			if d := time.Duration(latUpper(b)); d < s.Max {
+				return d
+			}
+			return s.Max
+		}
+	}
+	return s.Max
+}
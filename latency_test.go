@@ -0,0 +1,69 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyBuckets(t *testing.T) {
+	for _, v := range []uint64{0, 1, 7, 8, 9, 15, 16, 17, 1000, 123456789, 1<<63 - 1} {
+		b := latBucket(v)
+		if b >= cLATBUCKETS || latUpper(b) < v || (b > 0 && latUpper(b-1) >= v) {
+			t.Fatalf("assertion failed, value %d in bucket %d up to %d.", v, b, latUpper(b))
+		}
+		// relative error bounded by sub-buckets
+		if v >= 1<<cLATSUBBITS && float64(latUpper(b)-v)/float64(v) > 1.0/(1<<cLATSUBBITS) {
+			t.Fatalf("assertion failed, bucket %d too wide for %d.", b, v)
+		}
+	}
+}
+
+func TestRingLatency(t *testing.T) {
+	var (
+		c   *FakeClock    = NewFakeClock(time.Now())
+		r   *Ring         = NewRing(8, WithLatency(), WithClock(c))
+		dst []interface{} = make([]interface{}, 2)
+	)
+	if s := NewRing(8).LatencySnapshot(); s.Count != 0 || s.Quantile(0.5) != 0 {
+		t.Fatal("assertion failed, latency recorded while disabled.")
+	}
+	r.Push(0)
+	c.Advance(20 * time.Millisecond)
+	r.Pop()
+	for i := 0; i < 4; i++ {
+		r.Push(i)
+	}
+	r.PopInto(dst)
+	r.Consume(8, func(interface{}) bool { return true })
+	s := r.LatencySnapshot()
+	if s.Count != 5 || s.Max != 20*time.Millisecond || s.Mean() != s.Max/5 {
+		t.Fatalf("assertion failed, count(%d), max(%v), mean(%v).", s.Count, s.Max, s.Mean())
+	}
+	if p50, p100 := s.Quantile(0.5), s.Quantile(1); p50 >= 20*time.Millisecond || p100 != s.Max {
+		t.Fatalf("assertion failed, p50(%v), p100(%v).", p50, p100)
+	}
+}
@@ -25,11 +25,25 @@
 package lfring
 
 import (
+	"errors"
+	"math/bits"
 	"runtime"
 	"sync/atomic"
-	"unsafe"
+)
+
+const (
+	// MaxCapacity is upper bound of ring capacity
+	// after rounding to power of two.
+	MaxCapacity uint64 = 1 << 40
+)
 
-	"github.com/mitghi/x/pointers"
+var (
+	// ErrCapacityZero is returned for a ring of
+	// zero capacity.
+	ErrCapacityZero = errors.New("lfring: zero capacity")
+	// ErrCapacityLimit is returned when rounded
+	// capacity exceeds the capacity limit.
+	ErrCapacityLimit = errors.New("lfring: capacity exceeds limit")
 )
 
 /**
@@ -45,15 +59,62 @@ import (
 // - MARK: Alloc/Init section.
 
 // NewRing allocates and initializes a new `Ring`
-// struct and returns a pointer to it. Note,
-// `capacity` is always rounded to nearest power
-// of two.
-func NewRing(capacity uint64) (r *Ring) {
-	r = &Ring{size: roundP2(capacity)}
-	r.nodes = make([]unsafe.Pointer, r.size)
+// struct configured by `opts` and returns a
+// pointer to it. Note, `capacity` is always
+// rounded to nearest power of two; see `Cap`.
+// It panics when capacity is invalid, use
+// `NewRingChecked` to handle the error.
+func NewRing(capacity uint64, opts ...Option) *Ring {
+	r, err := NewRingChecked(capacity, opts...)
+	if err != nil {
+		panic(err)
+	}
 	return r
 }
 
+// NewRingChecked is like `NewRing` but returns
+// an error when `capacity` is zero or, rounded
+// to power of two, exceeds `MaxCapacity` or the
+// limit set by `WithMaxCapacity`, or when options
+// conflict, see `WithOrdering`.
+func NewRingChecked(capacity uint64, opts ...Option) (*Ring, error) {
+	r := &Ring{fair: DefaultFairness, wait: DefaultWaitStrategy, maxcap: MaxCapacity, clock: DefaultClock}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if capacity == 0 {
+		return nil, ErrCapacityZero
+	}
+	if capacity > MaxCapacity || roundP2(capacity) > r.maxcap {
+		return nil, ErrCapacityLimit
+	}
+	if r.order == AcqRel && r.mode == MPMC {
+		return nil, ErrOrdering
+	}
+	r.size = roundP2(capacity)
+	r.nodes = make([]interface{}, r.size)
+	r.seqs = make([]uint64, r.size<<r.shift)
+	if r.lat != nil {
+		r.lat.base = r.clock.Now()
+		r.lat.stamps = make([]int64, r.size)
+	}
+	if r.ttl != nil {
+		r.ttl.base = r.clock.Now()
+		r.ttl.deadlines = make([]int64, r.size)
+	}
+	for i := uint64(0); i < r.size; i++ {
+		*r.seq(i) = i
+	}
+	r.hook()
+	return r, nil
+}
+
+// RoundCapacity returns effective capacity of a
+// ring constructed with `capacity`.
+func RoundCapacity(capacity uint64) uint64 {
+	return roundP2(capacity)
+}
+
 // - MARK: Ring section.
 
 // Len returns number of items in ring.
@@ -61,6 +122,19 @@ func (r *Ring) Len() uint64 {
 	return atomic.LoadUint64(&r.count)
 }
 
+// Cap returns effective capacity of ring, i.e.
+// requested capacity rounded to power of two.
+func (r *Ring) Cap() uint64 {
+	return r.size
+}
+
+// Footprint returns size of slot storage in
+// bytes.
+func (r *Ring) Footprint() uintptr {
+	// an interface value is a pair of words.
+	return uintptr(len(r.nodes))*2*bits.UintSize/8 + uintptr(len(r.seqs))*8
+}
+
 // IsFull returns whether ring is full.
 func (r *Ring) IsFull() bool {
 	return r.Len() == r.size
@@ -76,118 +150,254 @@ func (r *Ring) IsEmpty() bool {
 
 // Push atomically writes `data` to next empty
 // slot and returns true when successfull. Note,
-// when ring is full, false is returned; does
-// not overwrite old slots.
+// when ring is full, the result depends on its
+// `Policy`; by default false is returned.
+//
+// Slot `pos & mask` is writable for position
+// `pos` iff its sequence equals `pos`; after
+// writing, sequence is set to `pos + 1` which
+// publishes the slot to the consumer of `pos`.
+//
+// The uncontended attempt checks the single
+// `slow` flag, then runs a straight line of
+// atomics with `publish` inlined into it; hooks,
+// retries, fairness and backoff live out of line
+// in `pushSlow`. Leaf helpers must stay
+// inlinable, see `TestInlinable`.
 func (r *Ring) Push(data interface{}) bool {
+	pos := atomic.LoadUint64(&r.wri)
+	if r.slow || atomic.LoadUint64(r.seq(pos)) != pos || !r.claimWrite(pos, 1) {
+		return r.pushSlow(data)
+	}
+	r.publish(pos, data)
+	return true
+}
+
+// publish stores `data` in slot of acquired
+// position `pos` and publishes it.
+func (r *Ring) publish(pos uint64, data interface{}) {
+	// store data inline; sequence store orders
+	// the plain write, no holder is allocated.
+	r.nodes[pos&(r.size-1)] = data
+	yieldPoint()
+	atomic.StoreUint64(r.seq(pos), pos+1)
+	atomic.AddUint64(&r.count, 1)
+}
+
+// pushSlow is the contended or hooked path of
+// `Push`.
+func (r *Ring) pushSlow(data interface{}) bool {
+	if r.sentinel != nil {
+		r.sample()
+	}
+	if _, ok := r.pushPos(data); ok {
+		return true
+	}
+	return r.dropped()
+}
+
+// pushPos pushes `data` and returns its position.
+func (r *Ring) pushPos(data interface{}) (uint64, bool) {
+	pos, ok := r.acquireWrite()
+	if !ok {
+		return r.writeIndex(), false
+	}
+	r.stamp(pos)
+	r.publish(pos, data)
+	r.published(pos, data)
+	return pos, true
+}
+
+// dropped returns whether a failed push counts
+// as pushed, i.e. `DropNewest` discarded it.
+func (r *Ring) dropped() bool {
+	return r.policy == DropNewest && atomic.LoadUint64(&r.wri)&cWRCLOSED == 0
+}
+
+// acquireWrite acquires next writable position
+// and returns false when ring is full or closed.
+// Caller must publish the acquired position.
+func (r *Ring) acquireWrite() (uint64, bool) {
 	var (
-		mask    uint64 = r.size + 1
-		currwri uint64
-		i       int = 0
+		pos uint64
+		dif int64
+		n   int = 0
+		w   int = 0
 	)
+	if r.fair.Ticket {
+		r.acquireTicket()
+	}
 	for {
-		currwri = atomic.LoadUint64(&r.wri)
-		if ((currwri + 1) % mask) == (atomic.LoadUint64(&r.rdi) % mask) {
-			return false
-		}
-		// acquire current slot by pushing
-		// competitors forward; dedicated
-		// write access.
-		if atomic.CompareAndSwapUint64(&r.wri, currwri, currwri+1) {
-			break
+		pos = atomic.LoadUint64(&r.wri)
+		if pos&cWRCLOSED != 0 {
+			// closed rings behave as full.
+			if r.fair.Ticket {
+				r.releaseTicket()
+			}
+			if r.tracer != nil {
+				r.tracer.drop(pos &^ cWRCLOSED)
+			}
+			return 0, false
 		}
-	}
-	// put data pointer in the slot
-	if pointers.SetSliceSlot(unsafe.Pointer(&r.nodes), int(currwri%(mask-1)), pointers.ArchPTRSIZE, unsafe.Pointer(&data)) {
-		// update readers boundary
-		for !atomic.CompareAndSwapUint64(&r.maxrdi, currwri, currwri+1) {
-			i++
-			if i == cWRSCHDTHRESHOLD {
-				// yield control to scheduler
-				// and let competitors run.
-				runtime.Gosched()
-				i = 0
+		dif = int64(atomic.LoadUint64(r.seq(pos)) - pos)
+		if dif == 0 {
+			// acquire current slot by pushing
+			// competitors forward; dedicated
+			// write access.
+			if r.claimWrite(pos, 1) {
+				break
+			}
+			r.casFailed()
+			if r.fair.BackoffCap > 0 {
+				Backoff{Cap: r.fair.BackoffCap, Yields: cBACKOFFYIELDS, Sleep: cBACKOFFSLEEP}.Wait(n)
+				n++
 			}
+		} else if dif < 0 {
+			// slot still holds previous lap;
+			// ring is full.
+			switch r.policy {
+			case DropOldest:
+				// evict oldest item and retry.
+				if _, evicted, ok := r.popAt(); ok {
+					if r.stats != nil {
+						atomic.AddUint64(&r.stats.overwritten, 1)
+					}
+					if r.tracer != nil {
+						r.tracer.drop(evicted)
+					}
+				}
+				continue
+			case Block:
+				// wait for consumers and retry.
+				if w == 0 && r.stats != nil {
+					atomic.AddUint64(&r.stats.blocked, 1)
+				}
+				r.block(w, pos)
+				w++
+				continue
+			case DropNewest:
+				if r.stats != nil {
+					atomic.AddUint64(&r.stats.dropped, 1)
+				}
+			default:
+				if r.stats != nil {
+					atomic.AddUint64(&r.stats.full, 1)
+				}
+			}
+			if r.tracer != nil {
+				r.tracer.drop(pos)
+			}
+			if r.fair.Ticket {
+				r.releaseTicket()
+			}
+			return 0, false
 		}
-		atomic.AddUint64(&r.count, 1)
-		return true
+		// dif > 0: competitor acquired `pos`,
+		// reload write index.
+	}
+	if r.fair.Ticket {
+		r.releaseTicket()
+	}
+	return pos, true
+}
+
+// published runs hooks of ring after `data` was
+// published at `pos` by a slow path.
+func (r *Ring) published(pos uint64, data interface{}) {
+	if r.wmark != nil {
+		r.wmark.observe(data)
+	}
+	if r.stats != nil {
+		r.stats.observe(r.Len())
+	}
+	if r.press != nil {
+		r.press.update(r.Len())
+	}
+	if r.tracer != nil {
+		r.tracer.push(pos)
+	}
+	if r.signal != nil {
+		r.signal.Signal()
 	}
-	return false
 }
 
 // Pop atomically pops a value when available and
 // returns it with a boolean indicating success
-// status. This receiver method spins until
-// `(currdi % mask) == (maxrdi % mask)` holds
-// true. It returns immediately when ring is
-// empty.
+// status. This receiver method spins while
+// competitors acquire the head and returns
+// immediately when ring is empty. Like `Push`,
+// its fast path inlines `take` and defers hooks
+// and the rest to `popSlow`.
 func (r *Ring) Pop() (interface{}, bool) {
+	pos := atomic.LoadUint64(&r.rdi)
+	// locked read-index never matches a sequence
+	if r.slow || atomic.LoadUint64(r.seq(pos)) != pos+1 || !r.claimRead(pos, 1) {
+		return r.popSlow()
+	}
+	return r.take(pos), true
+}
+
+// popSlow is the contended or hooked path of
+// `Pop`.
+func (r *Ring) popSlow() (interface{}, bool) {
+	if r.sentinel != nil {
+		r.sample()
+	}
+	data, _, ok := r.popPos()
+	return data, ok
+}
+
+// popPos pops head like `Pop` and returns it with
+// its position.
+func (r *Ring) popPos() (interface{}, uint64, bool) {
+	data, pos, ok := r.popAt()
+	if ok {
+		r.freed()
+	}
+	if ok && r.press != nil {
+		r.press.update(r.Len())
+	}
+	if ok && r.tracer != nil {
+		r.tracer.pop(pos)
+	}
+	return data, pos, ok
+}
+
+// popAt pops head and returns it with its
+// position.
+func (r *Ring) popAt() (interface{}, uint64, bool) {
 	var (
-		mask    uint64         = r.size + 1               // capacity mask
-		entry   unsafe.Pointer = unsafe.Pointer(&r.nodes) // nodes pointer ( reference )
-		rdiptr  unsafe.Pointer = unsafe.Pointer(&r.rdi)   // read-index pointer
-		index   int                                       // linear index of current slot in `r.nodes`
-		i       int                                       // yield threshold
-		currdi  uint64                                    // current read-index
-		maxrdi  uint64                                    // read-index boundary
-		data    interface{}                               // data address  ( dereferenced data pointer )
-		dataptr unsafe.Pointer                            // data pointer  ( dereferenced slot pointer )
-		offset  unsafe.Pointer                            // slot offset   ( reference )
-		slotptr unsafe.Pointer                            // slot pointer  ( reference )
+		i   int    // failed attempts
+		pos uint64 // current read-index
+		dif int64  // sequence distance
 	)
 	for {
-		currdi = atomic.LoadUint64(&r.rdi)
-		maxrdi = atomic.LoadUint64(&r.maxrdi)
-		if (currdi % mask) == (maxrdi % mask) {
-			return nil, false
-		}
-		// calculate slot address
-		// load data pointer from slot address
-		// get and store data pointer from current slot
-		index = int(currdi % (mask - 1))
-		offset = pointers.OffsetSliceSlot(entry, index, pointers.ArchPTRSIZE)
-		dataptr = atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(offset)))
-		if dptr := (*interface{})(dataptr); dptr != nil {
-			data = *dptr
-		} else {
-			i++
-			continue
-		}
-		if pointers.HasTag(dataptr) {
-			// dataptr is `rdcssDescriptor` which
-			// indicates ongoing RDCSS operation
-			// on current slot.
-			i++
-			continue
-		}
-		slotptr = unsafe.Pointer(offset)
-		// swap slot value with nil iff read-index
-		// is unchanged. this op is performed in
-		// two atomic stages. when interrupted
-		// after first stage, the state remains
-		// valid and exclusive access still belongs
-		// to current thread, because `rdcssDescriptor`
-		// acts as a barrier and prevents other threads
-		// from performing operations.
-		if pointers.RDCSS(
-			(*unsafe.Pointer)(rdiptr),
-			(unsafe.Pointer)(unsafe.Pointer(uintptr(currdi))),
-			(*unsafe.Pointer)(unsafe.Pointer(slotptr)),
-			(unsafe.Pointer)(dataptr),
-			nil,
-		) {
-			if atomic.CompareAndSwapUint64(&r.rdi, currdi, currdi+1) {
-				atomic.AddUint64(&r.count, ui64NMASK)
-				// succesfull, return previously acquired data
-				return data, true
+		pos = atomic.LoadUint64(&r.rdi)
+		if pos&cRDLOCK == 0 {
+			dif = int64(atomic.LoadUint64(r.seq(pos)) - (pos + 1))
+			if dif == 0 {
+				if r.claimRead(pos, 1) {
+					// succesfull, take published data
+					r.age(pos)
+					if r.ttl != nil && r.expired(pos) {
+						r.reap(pos)
+						continue
+					}
+					return r.take(pos), pos, true
+				}
+				r.casFailed()
+			} else if dif < 0 {
+				// slot not yet published; empty.
+				if r.stats != nil {
+					atomic.AddUint64(&r.stats.empty, 1)
+				}
+				return nil, pos, false
 			}
 		}
+		// head acquired by a competitor or
+		// locked by `Consume`; wait.
+		r.block(i, pos)
 		i++
-		if i == cRDSCHDTHRESHOLD {
-			// busy spin; yield to scheduler
-			// and wait.
-			runtime.Gosched()
-			i = 0
-		}
 	}
 }
 
@@ -199,55 +409,39 @@ func (r *Ring) Pop() (interface{}, bool) {
 // when ring has large capacity.
 func (r *Ring) TryPop(maxwait int) (interface{}, bool) {
 	var (
-		mask          uint64         = r.size + 1
-		schdthreshold int            = int(maxwait / 4) // yield threshold
-		entry         unsafe.Pointer = unsafe.Pointer(&r.nodes)
-		rdiptr        unsafe.Pointer = unsafe.Pointer(&r.rdi)
+		schdthreshold int = int(maxwait / 4) // yield threshold
 		i             int
-		index         int
 		waitcnt       int
-		currdi        uint64
-		maxrdi        uint64
-		data          interface{}
-		dataptr       unsafe.Pointer
-		offset        unsafe.Pointer
-		slotptr       unsafe.Pointer
+		pos           uint64
+		dif           int64
 	)
 	for i < maxwait {
-		currdi = atomic.LoadUint64(&r.rdi)
-		maxrdi = atomic.LoadUint64(&r.maxrdi)
-		if (currdi % mask) == (maxrdi % mask) {
-			return nil, false
-		}
-		index = int(currdi % (mask - 1))
-		offset = pointers.OffsetSliceSlot(entry, index, pointers.ArchPTRSIZE)
-		dataptr = atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(offset)))
-		// NOTE
-		// . `interface{}` loses type information
-		//   when used with atomics.
-		if dptr := (*interface{})(dataptr); dptr != nil {
-			data = *dptr
-		} else {
-			i++
-			waitcnt++
-			continue
-		}
-		if pointers.HasTag(dataptr) {
-			i++
-			waitcnt++
-			continue
-		}
-		slotptr = unsafe.Pointer(offset)
-		if pointers.RDCSS(
-			(*unsafe.Pointer)(rdiptr),
-			(unsafe.Pointer)(unsafe.Pointer(uintptr(currdi))),
-			(*unsafe.Pointer)(unsafe.Pointer(slotptr)),
-			(unsafe.Pointer)(dataptr),
-			nil,
-		) {
-			if atomic.CompareAndSwapUint64(&r.rdi, currdi, currdi+1) {
-				atomic.AddUint64(&r.count, ui64NMASK)
-				return data, true
+		pos = atomic.LoadUint64(&r.rdi)
+		if pos&cRDLOCK == 0 {
+			dif = int64(atomic.LoadUint64(r.seq(pos)) - (pos + 1))
+			if dif == 0 {
+				if r.claimRead(pos, 1) {
+					r.age(pos)
+					if r.ttl != nil && r.expired(pos) {
+						r.reap(pos)
+						continue
+					}
+					data := r.take(pos)
+					r.freed()
+					if r.press != nil {
+						r.press.update(r.Len())
+					}
+					if r.tracer != nil {
+						r.tracer.pop(pos)
+					}
+					return data, true
+				}
+				r.casFailed()
+			} else if dif < 0 {
+				if r.stats != nil {
+					atomic.AddUint64(&r.stats.empty, 1)
+				}
+				return nil, false
 			}
 		}
 		i++
@@ -255,13 +449,235 @@ func (r *Ring) TryPop(maxwait int) (interface{}, bool) {
 		if waitcnt == schdthreshold {
 			// NOTE:
 			// . fast spinning cause starvation.
-			runtime.Gosched()
+			r.yield()
 			waitcnt = 0
 		}
 	}
 	return nil, false
 }
 
+// PopInto atomically pops up to `len(dst)` values
+// into `dst` with a single read-index update and
+// returns the number of popped values. It does not
+// allocate.
+func (r *Ring) PopInto(dst []interface{}) int {
+	return r.popN(dst, uint64(len(dst)), false)
+}
+
+// popN pops up to `n` values into `dst` with a
+// single read-index update; values are dropped
+// when `dst` is nil. When `exact` is set, it pops
+// either `n` values or none.
+func (r *Ring) popN(dst []interface{}, n uint64, exact bool) int {
+	var (
+		i   int
+		m   uint64
+		pos uint64
+	)
+	for {
+		pos = atomic.LoadUint64(&r.rdi)
+		if pos&cRDLOCK == 0 {
+			// count published slots from head
+			for m = 0; m < n && atomic.LoadUint64(r.seq(pos+m)) == pos+m+1; m++ {
+			}
+			if m == n || (m > 0 && !exact) {
+				if r.claimRead(pos, m) {
+					break
+				}
+				r.casFailed()
+			} else if int64(atomic.LoadUint64(r.seq(pos+m))-(pos+m+1)) < 0 {
+				// slot not yet published; short.
+				if r.stats != nil {
+					atomic.AddUint64(&r.stats.empty, 1)
+				}
+				return 0
+			}
+		}
+		r.block(i, pos)
+		i++
+	}
+	for i := uint64(0); i < m; i++ {
+		r.prefetchAhead(pos + i)
+		r.age(pos + i)
+		if v := r.take(pos + i); dst != nil {
+			dst[i] = v
+		}
+		if r.tracer != nil {
+			r.tracer.pop(pos + i)
+		}
+	}
+	if m > 0 {
+		r.freed()
+	}
+	if m > 0 && r.press != nil {
+		r.press.update(r.Len())
+	}
+	return int(m)
+}
+
+// PushBatch atomically writes up to `len(src)`
+// values with a single write-index update and
+// returns the number of pushed values. Like
+// `Push`, it ignores `Policy` of ring and pushes
+// as many values as fit.
+func (r *Ring) PushBatch(src []interface{}) int {
+	return r.pushN(src, false)
+}
+
+// pushN pushes up to `len(src)` values with a
+// single write-index update. When `exact` is set,
+// it pushes either all values or none.
+func (r *Ring) pushN(src []interface{}, exact bool) int {
+	var (
+		n   uint64 = uint64(len(src))
+		i   int
+		m   uint64
+		pos uint64
+	)
+	if n == 0 {
+		return 0
+	}
+	if r.fair.Ticket {
+		r.acquireTicket()
+	}
+	for {
+		pos = atomic.LoadUint64(&r.wri)
+		if pos&cWRCLOSED != 0 {
+			m = 0
+			break
+		}
+		// count free slots from tail
+		for m = 0; m < n && atomic.LoadUint64(r.seq(pos+m)) == pos+m; m++ {
+		}
+		if m == n || (m > 0 && !exact) {
+			if r.claimWrite(pos, m) {
+				break
+			}
+			r.casFailed()
+		} else if int64(atomic.LoadUint64(r.seq(pos+m))-(pos+m)) < 0 {
+			// slot holds previous lap; short.
+			if r.stats != nil {
+				atomic.AddUint64(&r.stats.full, 1)
+			}
+			if r.tracer != nil {
+				r.tracer.drop(pos + m)
+			}
+			m = 0
+			break
+		}
+		r.block(i, pos)
+		i++
+	}
+	if r.fair.Ticket {
+		r.releaseTicket()
+	}
+	for i := uint64(0); i < m; i++ {
+		r.stamp(pos + i)
+		r.publish(pos+i, src[i])
+		if r.wmark != nil {
+			r.wmark.observe(src[i])
+		}
+		if r.tracer != nil {
+			r.tracer.push(pos + i)
+		}
+	}
+	if m > 0 && r.stats != nil {
+		r.stats.observe(r.Len())
+	}
+	if m > 0 && r.press != nil {
+		r.press.update(r.Len())
+	}
+	if m > 0 && r.signal != nil {
+		r.signal.Signal()
+	}
+	return int(m)
+}
+
+// take moves data out of the slot of acquired
+// position `pos` and releases the slot to the
+// producer of next lap.
+func (r *Ring) take(pos uint64) interface{} {
+	var (
+		index uint64      = pos & (r.size - 1)
+		data  interface{} = r.nodes[index]
+	)
+	// drop reference for garbage collection
+	r.nodes[index] = nil
+	yieldPoint()
+	atomic.StoreUint64(r.seq(pos), pos+r.size)
+	atomic.AddUint64(&r.count, ui64NMASK)
+	return data
+}
+
+// seq returns sequence of slot of position `pos`.
+func (r *Ring) seq(pos uint64) *uint64 {
+	return &r.seqs[(pos&(r.size-1))<<r.shift]
+}
+
+// claimWrite advances write-index from `pos` by
+// `n` and returns whether it succeeded. A single
+// producer stores it without competition.
+func (r *Ring) claimWrite(pos, n uint64) bool {
+	if r.mode&cSINGLEPROD != 0 {
+		r.storeCursor(&r.wri, pos+n)
+		return true
+	}
+	return atomic.CompareAndSwapUint64(&r.wri, pos, pos+n)
+}
+
+// claimRead advances read-index from `pos` by `n`
+// and returns whether it succeeded. A single
+// consumer stores it without competition.
+func (r *Ring) claimRead(pos, n uint64) bool {
+	if r.mode&cSINGLECONS != 0 && r.policy != DropOldest {
+		r.storeCursor(&r.rdi, pos+n)
+		return true
+	}
+	return atomic.CompareAndSwapUint64(&r.rdi, pos, pos+n)
+}
+
+// hook records whether an optional feature needs
+// the slow paths of `Push` and `Pop`; setters of
+// such features must call it.
+func (r *Ring) hook() {
+	r.slow = r.sentinel != nil || r.wmark != nil || r.signal != nil || r.stats != nil ||
+		r.tracer != nil || r.lat != nil || r.ttl != nil || r.press != nil || r.fair.Ticket
+}
+
+// readIndex returns read-index without lock bit.
+func (r *Ring) readIndex() uint64 {
+	return atomic.LoadUint64(&r.rdi) &^ cRDLOCK
+}
+
+// writeIndex returns write-index without closed bit.
+func (r *Ring) writeIndex() uint64 {
+	return atomic.LoadUint64(&r.wri) &^ cWRCLOSED
+}
+
+// close marks ring as closed; subsequent pushes
+// fail as if ring was full while pops continue
+// to drain published slots. Once closed, write
+// index is final.
+func (r *Ring) close() {
+	for {
+		pos := atomic.LoadUint64(&r.wri)
+		if pos&cWRCLOSED != 0 || atomic.CompareAndSwapUint64(&r.wri, pos, pos|cWRCLOSED) {
+			return
+		}
+	}
+}
+
+// casFailed records a failed CAS attempt.
+func (r *Ring) casFailed() {
+	atomic.AddUint64(&r.casfail, 1)
+}
+
+// yield records and yields control to scheduler.
+func (r *Ring) yield() {
+	atomic.AddUint64(&r.yields, 1)
+	runtime.Gosched()
+}
+
 // - MARK: Utility section.
 
 // roundP2 rounds the given number `v` to nearest
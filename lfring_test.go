@@ -26,9 +26,12 @@ package lfring
 
 import (
 	"fmt"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+	"unsafe"
 )
 
 // - MARK: Test-structs section.
@@ -129,7 +132,7 @@ func TestRingSerial(t *testing.T) {
 	if lfq.Push(&tstnode{uid: "invalid"}) {
 		t.Fatal("inconsistent state.")
 	}
-	if (lfq.count != lfq.maxrdi) && (lfq.wri != 0 && lfq.count == 8) {
+	if (lfq.count != lfq.wri) && (lfq.wri != 0 && lfq.count == 8) {
 		t.Fatal("asesrtion failed.")
 	}
 	for i := 0; i < rcap; i++ {
@@ -160,3 +163,363 @@ func TestRingSerial(t *testing.T) {
 	}
 	fmt.Printf("(pop)RING: %+v\n", lfq)
 }
+
+func TestRingConsume(t *testing.T) {
+	const rcap = 8
+	var (
+		lfq  *Ring = NewRing(rcap)
+		seen []int
+	)
+	for i := 0; i < rcap; i++ {
+		if !lfq.Push(i) {
+			t.Fatal("inconsistent state, unable to push.")
+		}
+	}
+	// stop early after third item
+	n := lfq.Consume(rcap, func(v interface{}) bool {
+		seen = append(seen, v.(int))
+		return len(seen) < 3
+	})
+	if n != 3 || lfq.Len() != rcap-3 {
+		t.Fatalf("assertion failed, n(%d), len(%d).", n, lfq.Len())
+	}
+	n = lfq.Consume(2, func(v interface{}) bool {
+		seen = append(seen, v.(int))
+		return true
+	})
+	if n != 2 {
+		t.Fatalf("assertion failed, n(%d)!=2.", n)
+	}
+	n = lfq.Consume(rcap, func(v interface{}) bool {
+		seen = append(seen, v.(int))
+		return true
+	})
+	if n != 3 || !lfq.IsEmpty() {
+		t.Fatalf("assertion failed, n(%d), len(%d).", n, lfq.Len())
+	}
+	for i, v := range seen {
+		if v != i {
+			t.Fatal("assertion failed, order violation.")
+		}
+	}
+	for i := 0; i < rcap; i++ {
+		if lfq.nodes[i] != nil {
+			t.Fatal("assertion failed, expected all slots to be nil.")
+		}
+	}
+	if lfq.Consume(rcap, func(interface{}) bool { return true }) != 0 {
+		t.Fatal("inconsistent state, consumed from empty ring.")
+	}
+}
+
+func TestRingConsumePanic(t *testing.T) {
+	var lfq *Ring = NewRing(4)
+	for i := 0; i < 3; i++ {
+		lfq.Push(i)
+	}
+	func() {
+		defer func() { recover() }()
+		lfq.Consume(4, func(v interface{}) bool {
+			if v.(int) == 1 {
+				panic("consumer")
+			}
+			return true
+		})
+	}()
+	// offending item is consumed, head unlocked
+	if v, ok := lfq.Pop(); !ok || v.(int) != 2 {
+		t.Fatalf("assertion failed, expected 2, got %v.", v)
+	}
+}
+
+func TestRingPopIf(t *testing.T) {
+	var (
+		lfq  *Ring = NewRing(4)
+		gate bool
+		pred = func(v interface{}) bool { return gate }
+	)
+	if _, ok := lfq.PopIf(pred); ok {
+		t.Fatal("inconsistent state, popped from empty ring.")
+	}
+	lfq.Push(1)
+	lfq.Push(2)
+	// failing predicate leaves head in place
+	if _, ok := lfq.PopIf(pred); ok || lfq.Len() != 2 {
+		t.Fatal("assertion failed, expected head to stay.")
+	}
+	gate = true
+	if v, ok := lfq.PopIf(pred); !ok || v.(int) != 1 {
+		t.Fatalf("assertion failed, expected 1, got %v.", v)
+	}
+	func() {
+		defer func() { recover() }()
+		lfq.PopIf(func(interface{}) bool { panic("consumer") })
+	}()
+	// head kept and unlocked after panic
+	if v, ok := lfq.Pop(); !ok || v.(int) != 2 {
+		t.Fatalf("assertion failed, expected 2, got %v.", v)
+	}
+}
+
+func TestRingPopIfConcurrent(t *testing.T) {
+	const items = 4000
+	var (
+		lfq  *Ring = NewRing(64)
+		wg   sync.WaitGroup
+		seen [items]int32
+		left int64 = items
+	)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt64(&left) > 0 {
+				v, ok := lfq.PopIf(func(v interface{}) bool { return v.(int) >= 0 })
+				if !ok {
+					runtime.Gosched()
+					continue
+				}
+				atomic.AddInt32(&seen[v.(int)], 1)
+				atomic.AddInt64(&left, -1)
+			}
+		}()
+	}
+	for i := 0; i < items; i++ {
+		for !lfq.Push(i) {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+	for i, n := range seen {
+		if n != 1 {
+			t.Fatalf("assertion failed, item %d popped %d times.", i, n)
+		}
+	}
+}
+
+func TestRingReset(t *testing.T) {
+	var lfq *Ring = NewRing(4)
+	if lfq.Reset() != 0 {
+		t.Fatal("inconsistent state, reset an empty ring.")
+	}
+	for i := 0; i < 6; i++ {
+		lfq.Push(i)
+		if i%2 == 0 {
+			lfq.Pop()
+		}
+	}
+	if n := lfq.Reset(); n != 3 || !lfq.IsEmpty() {
+		t.Fatalf("assertion failed, expected 3 discarded items, got %d.", n)
+	}
+	for i := 0; i < 4; i++ {
+		if lfq.nodes[i] != nil {
+			t.Fatal("assertion failed, expected all slots to be nil.")
+		}
+	}
+	// ring stays usable
+	lfq.Push(7)
+	if v, ok := lfq.Pop(); !ok || v.(int) != 7 {
+		t.Fatalf("assertion failed, expected 7, got %v.", v)
+	}
+}
+
+func TestRingStaleSlot(t *testing.T) {
+	const rcap = 4
+	var (
+		lfq *Ring = NewRing(rcap)
+		pos uint64
+	)
+	lfq.Push(0)
+	// stale consumer loads head of sequence 0
+	// and gets preempted before its CAS.
+	pos = atomic.LoadUint64(&lfq.rdi)
+	if lfq.seqs[0] != pos+1 {
+		t.Fatalf("assertion failed, seq(%d)!=%d.", lfq.seqs[0], pos+1)
+	}
+	// competitors complete a full lap; slot 0
+	// now holds sequence `rcap`.
+	for i := 1; i <= rcap; i++ {
+		if _, ok := lfq.Pop(); !ok {
+			t.Fatal("inconsistent state, unable to pop item.")
+		}
+		lfq.Push(i)
+	}
+	if lfq.seqs[0] != rcap+1 {
+		t.Fatalf("assertion failed, seq(%d)!=%d.", lfq.seqs[0], rcap+1)
+	}
+	// stale CAS fails although slot is
+	// published again (ABA).
+	if atomic.CompareAndSwapUint64(&lfq.rdi, pos, pos+1) {
+		t.Fatal("assertion failed, stale consumer acquired recycled slot.")
+	}
+	if v, ok := lfq.Pop(); !ok || v.(int) != rcap {
+		t.Fatalf("assertion failed, expected %d, got %v.", rcap, v)
+	}
+}
+
+func TestRingWrap(t *testing.T) {
+	const rcap = 4
+	var lfq *Ring = NewRing(rcap)
+	for lap := 0; lap < 3; lap++ {
+		for i := 0; i < rcap; i++ {
+			if !lfq.Push(lap*rcap + i) {
+				t.Fatal("inconsistent state, unable to push.")
+			}
+		}
+		if !lfq.IsFull() || lfq.Push(-1) {
+			t.Fatal("assertion failed, expected full ring.")
+		}
+		for i := 0; i < rcap; i++ {
+			if v, ok := lfq.Pop(); !ok || v.(int) != lap*rcap+i {
+				t.Fatalf("assertion failed, expected %d, got %v.", lap*rcap+i, v)
+			}
+		}
+		if _, ok := lfq.Pop(); ok {
+			t.Fatal("inconsistent state, returned value from empty ring.")
+		}
+	}
+}
+
+func TestRingPadding(t *testing.T) {
+	var (
+		r       Ring
+		offsets []uintptr = []uintptr{
+			unsafe.Offsetof(r.wri),
+			unsafe.Offsetof(r.rdi),
+			unsafe.Offsetof(r.count),
+			unsafe.Offsetof(r.size),
+		}
+	)
+	for i := 1; i < len(offsets); i++ {
+		if offsets[i]-offsets[i-1] < CacheLineSize {
+			t.Fatalf("assertion failed, fields %d and %d share a cache line.", i-1, i)
+		}
+	}
+}
+
+func BenchmarkRingPushPop(b *testing.B) {
+	var (
+		r    *Ring = NewRing(1024)
+		done chan struct{}
+	)
+	done = make(chan struct{})
+	go func() {
+		for i := 0; i < b.N; i++ {
+			for !r.Push(i) {
+				runtime.Gosched()
+			}
+		}
+		close(done)
+	}()
+	for i := 0; i < b.N; i++ {
+		for {
+			if _, ok := r.Pop(); ok {
+				break
+			}
+			runtime.Gosched()
+		}
+	}
+	<-done
+}
+
+func TestRingPopInto(t *testing.T) {
+	var (
+		lfq *Ring         = NewRing(8)
+		dst []interface{} = make([]interface{}, 3)
+	)
+	if lfq.PopInto(dst) != 0 {
+		t.Fatal("inconsistent state, popped from empty ring.")
+	}
+	for i := 0; i < 5; i++ {
+		lfq.Push(i)
+	}
+	for _, want := range [][]int{{0, 1, 2}, {3, 4}} {
+		n := lfq.PopInto(dst)
+		if n != len(want) {
+			t.Fatalf("assertion failed, n(%d)!=%d.", n, len(want))
+		}
+		for i := range want {
+			if dst[i].(int) != want[i] {
+				t.Fatal("assertion failed, order violation.")
+			}
+		}
+	}
+	if !lfq.IsEmpty() {
+		t.Fatalf("assertion failed, len(%d)!=0.", lfq.Len())
+	}
+}
+
+func TestRingClose(t *testing.T) {
+	var r *Ring = NewRing(4)
+	r.Push(1)
+	r.close()
+	if r.Push(2) {
+		t.Fatal("assertion failed, pushed into closed ring.")
+	}
+	r.SetFairness(FairnessPolicy{Ticket: true})
+	if r.Push(2) {
+		t.Fatal("assertion failed, pushed into closed ring.")
+	}
+	if v, ok := r.Pop(); !ok || v.(int) != 1 || r.readIndex() != r.writeIndex() {
+		t.Fatal("assertion failed, expected closed ring to drain.")
+	}
+}
+
+func TestRingPushBatch(t *testing.T) {
+	var (
+		lfq *Ring         = NewRing(4)
+		src []interface{} = []interface{}{0, 1, 2}
+		dst []interface{} = make([]interface{}, 4)
+	)
+	if lfq.PushBatch(src) != 3 || lfq.PushBatch(src) != 1 || lfq.PushBatch(src) != 0 {
+		t.Fatal("assertion failed, expected batches to fill ring.")
+	}
+	if lfq.pushN(src, true) != 0 || lfq.Len() != 4 {
+		t.Fatal("assertion failed, exact batch pushed partially.")
+	}
+	if n := lfq.PopInto(dst); n != 4 {
+		t.Fatalf("assertion failed, n(%d)!=4.", n)
+	}
+	for i, want := range []int{0, 1, 2, 0} {
+		if dst[i].(int) != want {
+			t.Fatal("assertion failed, order violation.")
+		}
+	}
+	lfq.Push(0)
+	if lfq.popN(nil, 2, true) != 0 || lfq.popN(nil, 1, true) != 1 || !lfq.IsEmpty() {
+		t.Fatal("assertion failed, exact batch popped partially.")
+	}
+	lfq.close()
+	if lfq.PushBatch(src) != 0 {
+		t.Fatal("assertion failed, pushed into closed ring.")
+	}
+}
+
+func TestRingCapacity(t *testing.T) {
+	if _, err := NewRingChecked(0); err != ErrCapacityZero {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	if _, err := NewRingChecked(MaxCapacity + 1); err != ErrCapacityLimit {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	if _, err := NewRingChecked(ui64NMASK); err != ErrCapacityLimit {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	// 100 rounds to 128 which exceeds limit
+	if _, err := NewRingChecked(100, WithMaxCapacity(100)); err != ErrCapacityLimit {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	r, err := NewRingChecked(100, WithMaxCapacity(128))
+	if err != nil || r.Cap() != RoundCapacity(100) || r.Cap() != 128 {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	if r.Footprint() != 128*(unsafe.Sizeof(r.nodes[0])+8) {
+		t.Fatalf("assertion failed, footprint(%d).", r.Footprint())
+	}
+	defer func() {
+		if recover() != ErrCapacityZero {
+			t.Fatal("assertion failed, expected NewRing to panic.")
+		}
+	}()
+	NewRing(0)
+}
@@ -0,0 +1,169 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrLimiterWeight is returned when a weight
+	// exceeds limit and can never be acquired.
+	ErrLimiterWeight = errors.New("lfring: weight exceeds limit")
+)
+
+// - MARK: Limiter section.
+
+// Limiter bounds concurrency with a ring pre-filled
+// with tokens: acquiring pops a token and releasing
+// pushes it back. It is a lock-free alternative to
+// semaphore channels. Waiting acquirers follow
+// wait strategy of token ring.
+type Limiter struct {
+	tokens *Ring
+	limit  int
+	fill   []interface{} // `limit` tokens, read-only
+}
+
+// NewLimiter allocates and initializes a new
+// `Limiter` allowing `limit` concurrent holders
+// and returns a pointer to it.
+func NewLimiter(limit int) *Limiter {
+	if limit < 1 {
+		limit = 1
+	}
+	l := &Limiter{tokens: NewRing(uint64(limit)), limit: limit, fill: make([]interface{}, limit)}
+	for i := range l.fill {
+		l.fill[i] = struct{}{}
+	}
+	l.tokens.PushBatch(l.fill)
+	return l
+}
+
+// SetWaitStrategy sets wait strategy of blocked
+// acquirers. It must be called before limiter
+// is shared.
+func (l *Limiter) SetWaitStrategy(w WaitStrategy) {
+	l.tokens.SetWaitStrategy(w)
+}
+
+// Limit returns maximum number of holders.
+func (l *Limiter) Limit() int {
+	return l.limit
+}
+
+// Available returns number of free tokens.
+func (l *Limiter) Available() int {
+	return int(l.tokens.Len())
+}
+
+// TryAcquire takes a token without blocking and
+// returns whether it succeeded.
+func (l *Limiter) TryAcquire() bool {
+	_, ok := l.tokens.Pop()
+	return ok
+}
+
+// Acquire takes a token, waiting until one is
+// released or `ctx` is done.
+func (l *Limiter) Acquire(ctx context.Context) error {
+	return l.acquire(ctx, l.TryAcquire)
+}
+
+// Release returns a token. Releasing more tokens
+// than acquired panics.
+func (l *Limiter) Release() {
+	l.ReleaseN(1)
+}
+
+// TryAcquireN takes `n` tokens at once with a
+// single ring operation without blocking and
+// returns whether it succeeded. On failure, no
+// token is held.
+func (l *Limiter) TryAcquireN(n int) bool {
+	if n <= 0 {
+		return true
+	}
+	return l.tokens.popN(nil, uint64(n), true) == n
+}
+
+// AcquireN takes `n` tokens at once, waiting
+// until they are available or `ctx` is done.
+// Tokens are not held while waiting, hence
+// waiters do not deadlock each other; large
+// counts may starve under sustained load.
+func (l *Limiter) AcquireN(ctx context.Context, n int) error {
+	if n > l.limit {
+		return ErrLimiterWeight
+	}
+	return l.acquire(ctx, func() bool { return l.TryAcquireN(n) })
+}
+
+// ReleaseN returns `n` tokens with a single ring
+// operation. Releasing more tokens than acquired
+// panics.
+func (l *Limiter) ReleaseN(n int) {
+	if n <= 0 {
+		return
+	}
+	// token ring is rounded up to a power of two,
+	// so it can hold more than `limit` tokens.
+	if n > l.limit-l.Available() || l.tokens.pushN(l.fill[:n], true) != n {
+		panic("lfring: limiter released more tokens than acquired")
+	}
+}
+
+// TryAcquireWeighted is `TryAcquireN` with
+// weight `w`.
+func (l *Limiter) TryAcquireWeighted(w int) bool {
+	return l.TryAcquireN(w)
+}
+
+// AcquireWeighted is `AcquireN` with weight `w`.
+func (l *Limiter) AcquireWeighted(ctx context.Context, w int) error {
+	return l.AcquireN(ctx, w)
+}
+
+// ReleaseWeighted is `ReleaseN` with weight `w`.
+func (l *Limiter) ReleaseWeighted(w int) {
+	l.ReleaseN(w)
+}
+
+// acquire calls `try` until it succeeds or `ctx`
+// is done.
+func (l *Limiter) acquire(ctx context.Context, try func() bool) error {
+	for i := 0; ; i++ {
+		if try() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		l.tokens.pause(i)
+	}
+}
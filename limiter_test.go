@@ -0,0 +1,135 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterSerial(t *testing.T) {
+	var l *Limiter = NewLimiter(3)
+	if l.Limit() != 3 || l.Available() != 3 {
+		t.Fatalf("assertion failed, available(%d).", l.Available())
+	}
+	if !l.TryAcquireWeighted(2) || !l.TryAcquire() || l.TryAcquire() {
+		t.Fatal("assertion failed, expected limiter to admit exactly 3.")
+	}
+	l.Release()
+	if l.TryAcquireWeighted(2) || l.Available() != 1 {
+		t.Fatalf("assertion failed, partial weighted acquisition held tokens, available(%d).", l.Available())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.AcquireWeighted(ctx, 2); err != context.DeadlineExceeded {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	if err := l.AcquireWeighted(context.Background(), 4); err != ErrLimiterWeight {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	l.ReleaseWeighted(2)
+	if l.Available() != 3 {
+		t.Fatalf("assertion failed, available(%d)!=3.", l.Available())
+	}
+}
+
+func TestLimiterOverRelease(t *testing.T) {
+	// limit 3 rounds token ring up to 4 slots
+	for name, release := range map[string]func(l *Limiter){
+		"Release":         func(l *Limiter) { l.Release() },
+		"ReleaseN":        func(l *Limiter) { l.ReleaseN(1) },
+		"ReleaseWeighted": func(l *Limiter) { l.ReleaseWeighted(1) },
+	} {
+		l := NewLimiter(3)
+		l.TryAcquire()
+		release(l)
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("assertion failed, %s over limit did not panic.", name)
+				}
+			}()
+			release(l)
+		}()
+		if l.Available() != 3 {
+			t.Fatalf("inconsistent state, %s left %d tokens.", name, l.Available())
+		}
+	}
+}
+
+func TestLimiterBatch(t *testing.T) {
+	var l *Limiter = NewLimiter(4)
+	if !l.TryAcquireN(3) || l.TryAcquireN(2) || l.Available() != 1 {
+		t.Fatalf("assertion failed, available(%d)!=1.", l.Available())
+	}
+	l.ReleaseN(3)
+	if l.Available() != 4 {
+		t.Fatalf("assertion failed, available(%d)!=4.", l.Available())
+	}
+	assertNoAllocs(t, "TryAcquireN+ReleaseN", func() {
+		l.TryAcquireN(4)
+		l.ReleaseN(4)
+	})
+	defer func() {
+		if recover() == nil {
+			t.Fatal("assertion failed, expected over-release to panic.")
+		}
+	}()
+	l.ReleaseN(1)
+}
+
+func TestLimiterConcurrent(t *testing.T) {
+	const limit = 4
+	var (
+		l       *Limiter = NewLimiter(limit)
+		holders int32
+		wg      sync.WaitGroup
+	)
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				w := 1 + i%2
+				if err := l.AcquireN(context.Background(), w); err != nil {
+					t.Errorf("assertion failed, err(%v).", err)
+					return
+				}
+				if n := atomic.AddInt32(&holders, int32(w)); n > limit {
+					t.Errorf("assertion failed, %d holders exceed limit.", n)
+				}
+				atomic.AddInt32(&holders, -int32(w))
+				l.ReleaseN(w)
+			}
+		}()
+	}
+	wg.Wait()
+	if l.Available() != limit {
+		t.Fatalf("assertion failed, available(%d)!=%d.", l.Available(), limit)
+	}
+}
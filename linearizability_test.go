@@ -0,0 +1,272 @@
+//go:build linearizability
+// +build linearizability
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// Defaults
+const (
+	// cLINWORKERS is number of goroutines of a
+	// recorded history.
+	cLINWORKERS = 3
+	// cLINOPS is number of operations per worker;
+	// histories stay below 64 operations, see
+	// `linCheck`.
+	cLINOPS = 6
+	// cLINROUNDS is number of recorded histories
+	// per test.
+	cLINROUNDS = 2000
+)
+
+// - MARK: History section.
+
+// linOp is a completed operation of a history.
+// `call` and `ret` are taken from a shared
+// logical clock, so operations whose intervals
+// overlap are concurrent.
+type linOp struct {
+	push  bool
+	value int
+	ok    bool
+	call  int64
+	ret   int64
+}
+
+// String returns operation in a readable form.
+func (o linOp) String() string {
+	if o.push {
+		return fmt.Sprintf("push(%d)=%v@[%d,%d]", o.value, o.ok, o.call, o.ret)
+	}
+	return fmt.Sprintf("pop()=%d,%v@[%d,%d]", o.value, o.ok, o.call, o.ret)
+}
+
+// linRecord runs `workers` goroutines each doing
+// `ops` random pushes of unique values and pops
+// through `push` and `pop`, and returns the
+// recorded history.
+func linRecord(workers, ops int, seed int64, push func(interface{}) bool, pop func() (interface{}, bool)) []linOp {
+	var (
+		clock   int64
+		wg      sync.WaitGroup
+		history [][]linOp = make([][]linOp, workers)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed + int64(w)))
+			for i := 0; i < ops; i++ {
+				op := linOp{push: rnd.Intn(2) == 0, call: atomic.AddInt64(&clock, 1)}
+				if op.push {
+					op.value = w*ops + i + 1
+					op.ok = push(op.value)
+				} else {
+					var v interface{}
+					if v, op.ok = pop(); op.ok {
+						op.value = v.(int)
+					}
+				}
+				op.ret = atomic.AddInt64(&clock, 1)
+				history[w] = append(history[w], op)
+				if rnd.Intn(4) == 0 {
+					runtime.Gosched()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	var all []linOp
+	for _, h := range history {
+		all = append(all, h...)
+	}
+	return all
+}
+
+// - MARK: Checker section.
+
+// linModel is a sequential bounded FIFO queue.
+// Unless `strict`, failed pushes and empty pops
+// are accepted in any state: a Vyukov ring
+// reports full while a consumer that already
+// claimed the oldest item has yet to release its
+// slot, and empty while an earlier producer has
+// yet to publish, so only successful operations
+// are held to the sequential contract.
+type linModel struct {
+	cap    int
+	strict bool
+}
+
+// step applies `op` to queue `q` and returns the
+// resulting queue, or false when the sequential
+// queue can not produce the recorded outcome.
+func (m linModel) step(q []int, op linOp) ([]int, bool) {
+	switch {
+	case op.push && op.ok:
+		if len(q) >= m.cap {
+			return nil, false
+		}
+		return append(append(make([]int, 0, len(q)+1), q...), op.value), true
+	case op.push:
+		return q, !m.strict || len(q) == m.cap
+	case op.ok:
+		if len(q) == 0 || q[0] != op.value {
+			return nil, false
+		}
+		return q[1:], true
+	}
+	return q, !m.strict || len(q) == 0
+}
+
+// linCheck returns whether `history` is
+// linearizable with respect to `m`. It searches
+// orders of linearization like Wing & Gong with
+// the state caching of porcupine: an operation
+// can be linearized next when no pending
+// operation returned before it was called, and
+// visited (linearized set, queue) pairs are not
+// explored twice.
+func linCheck(m linModel, history []linOp) bool {
+	if len(history) > 64 {
+		panic("lfring: history too long")
+	}
+	ops := append([]linOp(nil), history...)
+	sort.Slice(ops, func(i, j int) bool { return ops[i].call < ops[j].call })
+	var (
+		done    uint64          = uint64(1)<<uint(len(ops)) - 1
+		visited map[string]bool = map[string]bool{}
+		search  func(mask uint64, q []int) bool
+	)
+	if len(ops) == 64 {
+		done = ^uint64(0)
+	}
+	search = func(mask uint64, q []int) bool {
+		if mask == done {
+			return true
+		}
+		key := fmt.Sprint(mask, q)
+		if visited[key] {
+			return false
+		}
+		visited[key] = true
+		// earliest return of pending operations
+		var bound int64 = -1
+		for i, op := range ops {
+			if mask&(1<<uint(i)) == 0 && (bound < 0 || op.ret < bound) {
+				bound = op.ret
+			}
+		}
+		for i, op := range ops {
+			if mask&(1<<uint(i)) != 0 || op.call > bound {
+				continue
+			}
+			if next, ok := m.step(q, op); ok && search(mask|1<<uint(i), next) {
+				return true
+			}
+		}
+		return false
+	}
+	return search(0, nil)
+}
+
+// - MARK: Test section.
+
+func TestLinearizableChecker(t *testing.T) {
+	var m linModel = linModel{cap: 2, strict: true}
+	// sequential push 1, push 2, pop -> 2
+	bad := []linOp{
+		{push: true, value: 1, ok: true, call: 1, ret: 2},
+		{push: true, value: 2, ok: true, call: 3, ret: 4},
+		{value: 2, ok: true, call: 5, ret: 6},
+	}
+	if linCheck(m, bad) {
+		t.Fatal("assertion failed, accepted LIFO history.")
+	}
+	// overlapping pushes may linearize either way
+	bad[1].call = 1
+	if !linCheck(m, bad) {
+		t.Fatal("assertion failed, rejected concurrent history.")
+	}
+	// pushes to a full queue must fail in strict
+	full := []linOp{
+		{push: true, value: 1, ok: true, call: 1, ret: 2},
+		{push: true, value: 2, ok: true, call: 3, ret: 4},
+		{push: true, value: 3, ok: true, call: 5, ret: 6},
+	}
+	if linCheck(m, full) {
+		t.Fatal("assertion failed, accepted overfull queue.")
+	}
+	full[2].ok = false
+	if !linCheck(m, full) {
+		t.Fatal("assertion failed, rejected failed push on full queue.")
+	}
+}
+
+func TestLinearizableRing(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		cap  uint64
+		opts []Option
+	}{
+		{"mpmc", 2, nil},
+		{"mpmc-large", 64, nil},
+		{"padded", 4, []Option{WithPadding()}},
+		{"ticket", 4, []Option{WithFairness(FairnessPolicy{Ticket: true})}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			m := linModel{cap: int(RoundCapacity(c.cap))}
+			for round := 0; round < cLINROUNDS; round++ {
+				r := NewRing(c.cap, c.opts...)
+				history := linRecord(cLINWORKERS, cLINOPS, int64(round), r.Push, r.Pop)
+				if !linCheck(m, history) {
+					t.Fatalf("assertion failed, history is not linearizable: %v.", history)
+				}
+			}
+		})
+	}
+}
+
+func TestLinearizableRingConsume(t *testing.T) {
+	m := linModel{cap: 4}
+	for round := 0; round < cLINROUNDS; round++ {
+		r := NewRing(4)
+		history := linRecord(cLINWORKERS, cLINOPS, int64(round), r.Push, func() (interface{}, bool) {
+			return r.PopIf(func(interface{}) bool { return true })
+		})
+		if !linCheck(m, history) {
+			t.Fatalf("assertion failed, history is not linearizable: %v.", history)
+		}
+	}
+}
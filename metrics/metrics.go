@@ -0,0 +1,196 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+// Package metrics exports gauges and counters of
+// lfring rings through expvar and the Prometheus
+// text exposition format.
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/mitghi/lfring"
+)
+
+// - MARK: Sample section.
+
+// Sample is a snapshot of ring metrics.
+type Sample struct {
+	Name    string // ring name
+	Len     uint64 // current occupancy
+	Cap     uint64 // capacity
+	MaxLen  uint64 // occupancy high-water mark
+	Pushes  uint64 // successful pushes
+	Pops    uint64 // successful pops
+	Dropped uint64 // pushes rejected as full or evicted items
+	Retries uint64 // failed index CAS
+	Waiting uint64 // producers queued for write access
+}
+
+// sample returns snapshot of `r` named `name`.
+func sample(name string, r *lfring.Ring) Sample {
+	s := r.Stats()
+	return Sample{
+		Name:    name,
+		Len:     s.Len,
+		Cap:     r.Cap(),
+		MaxLen:  s.MaxLen,
+		Pushes:  s.Pushes,
+		Pops:    s.Pops,
+		Dropped: s.Full + s.Overwritten,
+		Retries: s.Retries,
+		Waiting: r.Waiting(),
+	}
+}
+
+// - MARK: Registry section.
+
+// Registry is a named set of rings. Drop counts
+// and high-water marks require rings built with
+// `lfring.WithStats`.
+type Registry struct {
+	mu    sync.RWMutex
+	rings map[string]*lfring.Ring
+}
+
+// Default is the registry used by package level
+// functions.
+var Default = NewRegistry()
+
+// NewRegistry allocates and initializes a new
+// `Registry` and returns a pointer to it.
+func NewRegistry() *Registry {
+	return &Registry{rings: make(map[string]*lfring.Ring)}
+}
+
+// Register adds ring `r` as `name`, replacing a
+// ring registered under the same name.
+func (g *Registry) Register(name string, r *lfring.Ring) {
+	g.mu.Lock()
+	g.rings[name] = r
+	g.mu.Unlock()
+}
+
+// Unregister removes ring `name`.
+func (g *Registry) Unregister(name string) {
+	g.mu.Lock()
+	delete(g.rings, name)
+	g.mu.Unlock()
+}
+
+// Samples returns snapshots of registered rings
+// sorted by name.
+func (g *Registry) Samples() []Sample {
+	g.mu.RLock()
+	samples := make([]Sample, 0, len(g.rings))
+	for name, r := range g.rings {
+		samples = append(samples, sample(name, r))
+	}
+	g.mu.RUnlock()
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Name < samples[j].Name })
+	return samples
+}
+
+// Publish exposes registry as expvar variable
+// `name`, a map of ring name to `Sample`. Like
+// `expvar.Publish`, it panics when `name` is
+// already published.
+func (g *Registry) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		samples := g.Samples()
+		vars := make(map[string]Sample, len(samples))
+		for _, s := range samples {
+			vars[s.Name] = s
+		}
+		return vars
+	}))
+}
+
+// WriteTo writes samples in Prometheus text
+// exposition format to `w`.
+func (g *Registry) WriteTo(w io.Writer) (int64, error) {
+	var (
+		samples []Sample = g.Samples()
+		total   int64
+	)
+	for _, m := range families {
+		n, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+		for _, s := range samples {
+			n, err = fmt.Fprintf(w, "%s{ring=%q} %d\n", m.name, s.Name, m.value(s))
+			total += int64(n)
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+// ServeHTTP serves samples in Prometheus text
+// exposition format.
+func (g *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	g.WriteTo(w)
+}
+
+// Register adds ring `r` as `name` to `Default`.
+func Register(name string, r *lfring.Ring) {
+	Default.Register(name, r)
+}
+
+// Unregister removes ring `name` from `Default`.
+func Unregister(name string) {
+	Default.Unregister(name)
+}
+
+// - MARK: Family section.
+
+// family is a metric family derived from samples.
+type family struct {
+	name  string
+	help  string
+	kind  string // gauge or counter
+	value func(Sample) uint64
+}
+
+// families are exported metric families.
+var families = []family{
+	{"lfring_length", "Number of items in ring.", "gauge", func(s Sample) uint64 { return s.Len }},
+	{"lfring_capacity", "Capacity of ring.", "gauge", func(s Sample) uint64 { return s.Cap }},
+	{"lfring_length_max", "Occupancy high-water mark.", "gauge", func(s Sample) uint64 { return s.MaxLen }},
+	{"lfring_waiting_producers", "Producers queued for write access.", "gauge", func(s Sample) uint64 { return s.Waiting }},
+	{"lfring_pushes_total", "Successful pushes.", "counter", func(s Sample) uint64 { return s.Pushes }},
+	{"lfring_pops_total", "Successful pops.", "counter", func(s Sample) uint64 { return s.Pops }},
+	{"lfring_dropped_total", "Pushes rejected as full or evicted items.", "counter", func(s Sample) uint64 { return s.Dropped }},
+	{"lfring_retries_total", "Failed index CAS attempts.", "counter", func(s Sample) uint64 { return s.Retries }},
+}
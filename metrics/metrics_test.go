@@ -0,0 +1,91 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mitghi/lfring"
+)
+
+func TestRegistry(t *testing.T) {
+	var (
+		g *Registry    = NewRegistry()
+		a *lfring.Ring = lfring.NewRing(4, lfring.WithStats())
+		b *lfring.Ring = lfring.NewRing(8)
+	)
+	for i := 0; i < 5; i++ {
+		a.Push(i)
+	}
+	a.Pop()
+	g.Register("b", b)
+	g.Register("a", a)
+	samples := g.Samples()
+	if len(samples) != 2 || samples[0].Name != "a" {
+		t.Fatalf("assertion failed, samples(%+v).", samples)
+	}
+	if s := samples[0]; s.Len != 3 || s.Cap != 4 || s.MaxLen != 4 || s.Pushes != 4 || s.Pops != 1 || s.Dropped != 1 {
+		t.Fatalf("assertion failed, sample(%+v).", s)
+	}
+	g.Unregister("b")
+	if len(g.Samples()) != 1 {
+		t.Fatal("assertion failed, ring not unregistered.")
+	}
+}
+
+func TestRegistryExport(t *testing.T) {
+	var (
+		g   *Registry    = NewRegistry()
+		r   *lfring.Ring = lfring.NewRing(4)
+		rec *httptest.ResponseRecorder
+		buf bytes.Buffer
+	)
+	r.Push(1)
+	g.Register("in", r)
+	rec = httptest.NewRecorder()
+	g.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE lfring_length gauge\n",
+		"lfring_length{ring=\"in\"} 1\n",
+		"lfring_capacity{ring=\"in\"} 4\n",
+		"# TYPE lfring_pushes_total counter\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("assertion failed, missing %q in:\n%s", line, body)
+		}
+	}
+	g.Publish("lfring_test")
+	vars := make(map[string]Sample)
+	buf.WriteString(expvar.Get("lfring_test").String())
+	if err := json.Unmarshal(buf.Bytes(), &vars); err != nil || vars["in"].Len != 1 {
+		t.Fatalf("assertion failed, err(%v), vars(%+v).", err, vars)
+	}
+}
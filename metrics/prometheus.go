@@ -0,0 +1,74 @@
+//go:build prometheus
+// +build prometheus
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// - MARK: Collector section.
+
+// Collector implements `prometheus.Collector`
+// over a registry. It is built with the
+// `prometheus` build tag, so the client library
+// is only required by users who opt in.
+type Collector struct {
+	registry *Registry
+	descs    []*prometheus.Desc
+}
+
+// NewCollector returns a collector of registry
+// `g`, or of `Default` when `g` is nil.
+func NewCollector(g *Registry) *Collector {
+	if g == nil {
+		g = Default
+	}
+	c := &Collector{registry: g, descs: make([]*prometheus.Desc, len(families))}
+	for i, m := range families {
+		c.descs[i] = prometheus.NewDesc(m.name, m.help, []string{"ring"}, nil)
+	}
+	return c
+}
+
+// Describe implements `prometheus.Collector`.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+// Collect implements `prometheus.Collector`.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.registry.Samples() {
+		for i, m := range families {
+			kind := prometheus.GaugeValue
+			if m.kind == "counter" {
+				kind = prometheus.CounterValue
+			}
+			ch <- prometheus.MustNewConstMetric(c.descs[i], kind, float64(m.value(s)), s.Name)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// - MARK: Report section.
+
+// Report is a periodic summary of a ring: its
+// sample plus activity since the previous report,
+// which surfaces slow degradation, e.g. a
+// growing backlog or rising drop rate, of long
+// running rings.
+type Report struct {
+	Sample
+	Interval time.Duration // time since previous report
+	PushRate float64       // pushes per second
+	PopRate  float64       // pops per second
+	NewDrops uint64        // drops since previous report
+}
+
+// String returns report as one logfmt line.
+func (r Report) String() string {
+	return fmt.Sprintf("ring=%q len=%d cap=%d maxlen=%d pushes=%d pops=%d dropped=%d retries=%d waiting=%d interval=%s push_rate=%.1f pop_rate=%.1f new_drops=%d",
+		r.Name, r.Len, r.Cap, r.MaxLen, r.Pushes, r.Pops, r.Dropped, r.Retries, r.Waiting, r.Interval, r.PushRate, r.PopRate, r.NewDrops)
+}
+
+// ReportFunc receives reports, e.g. forwards them
+// to a logger or metrics sink.
+type ReportFunc func(Report)
+
+// LogReports returns a `ReportFunc` writing each
+// report as a line to `w`, e.g. `log.Writer()`.
+// Write errors are ignored.
+func LogReports(w io.Writer) ReportFunc {
+	var mu sync.Mutex
+	return func(r Report) {
+		mu.Lock()
+		fmt.Fprintln(w, r.String())
+		mu.Unlock()
+	}
+}
+
+// Reporter reports every ring of a registry at
+// a fixed interval.
+type Reporter struct {
+	reg  *Registry
+	fn   ReportFunc
+	mu   sync.Mutex        // serializes reports
+	last map[string]Sample // previous samples by name
+	at   time.Time         // time of previous report
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// Report starts a `Reporter` passing a report of
+// every registered ring to `fn` each `interval`.
+// A non-positive `interval` disables the timer,
+// reports are then made by `Tick` only.
+func (g *Registry) Report(interval time.Duration, fn ReportFunc) *Reporter {
+	p := &Reporter{reg: g, fn: fn, last: map[string]Sample{}, at: time.Now(), stop: make(chan struct{}), done: make(chan struct{})}
+	if interval <= 0 {
+		close(p.done)
+		return p
+	}
+	go p.run(interval)
+	return p
+}
+
+// Tick reports every registered ring now.
+func (p *Reporter) Tick() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var (
+		now     time.Time     = time.Now()
+		elapsed time.Duration = now.Sub(p.at)
+		seen    map[string]Sample
+	)
+	seen = make(map[string]Sample, len(p.last))
+	for _, s := range p.reg.Samples() {
+		r := Report{Sample: s, Interval: elapsed}
+		if prev, ok := p.last[s.Name]; ok {
+			if secs := elapsed.Seconds(); secs > 0 {
+				r.PushRate = float64(s.Pushes-prev.Pushes) / secs
+				r.PopRate = float64(s.Pops-prev.Pops) / secs
+			}
+			r.NewDrops = s.Dropped - prev.Dropped
+		} else {
+			r.NewDrops = s.Dropped
+		}
+		seen[s.Name] = s
+		p.fn(r)
+	}
+	p.last, p.at = seen, now
+}
+
+// Stop stops the reporter and waits for a report
+// in progress.
+func (p *Reporter) Stop() {
+	p.once.Do(func() { close(p.stop) })
+	<-p.done
+}
+
+// run reports each `interval` until stopped.
+func (p *Reporter) run(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	defer close(p.done)
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-t.C:
+			p.Tick()
+		}
+	}
+}
+
+// StartReports is `Report` of `Default`.
+func StartReports(interval time.Duration, fn ReportFunc) *Reporter {
+	return Default.Report(interval, fn)
+}
@@ -0,0 +1,99 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitghi/lfring"
+)
+
+func TestReporterTick(t *testing.T) {
+	var (
+		g   *Registry    = NewRegistry()
+		r   *lfring.Ring = lfring.NewRing(2, lfring.WithStats())
+		buf bytes.Buffer
+	)
+	g.Register("in", r)
+	p := g.Report(0, LogReports(&buf))
+	defer p.Stop()
+	r.Push(1)
+	r.Push(2)
+	r.Push(3)
+	p.Tick()
+	r.Pop()
+	r.Push(4)
+	r.Push(5)
+	p.Tick()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("assertion failed, expected 2 lines, got %q.", buf.String())
+	}
+	for i, want := range []string{
+		`ring="in" len=2 cap=2 maxlen=2 pushes=2 pops=0 dropped=1 `,
+		`ring="in" len=2 cap=2 maxlen=2 pushes=3 pops=1 dropped=2 `,
+	} {
+		if !strings.HasPrefix(lines[i], want) {
+			t.Fatalf("assertion failed, expected prefix %q, got %q.", want, lines[i])
+		}
+	}
+	if !strings.HasSuffix(lines[0], "new_drops=1") || !strings.HasSuffix(lines[1], "new_drops=1") {
+		t.Fatalf("assertion failed, unexpected drop deltas %q.", lines)
+	}
+}
+
+func TestReporterInterval(t *testing.T) {
+	var (
+		g       *Registry = NewRegistry()
+		mu      sync.Mutex
+		reports []Report
+	)
+	g.Register("a", lfring.NewRing(4))
+	p := g.Report(time.Millisecond, func(r Report) {
+		mu.Lock()
+		reports = append(reports, r)
+		mu.Unlock()
+	})
+	for i := 0; i < 1000; i++ {
+		mu.Lock()
+		n := len(reports)
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	p.Stop()
+	p.Stop()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) < 2 || reports[1].Name != "a" || reports[1].Interval <= 0 {
+		t.Fatalf("assertion failed, expected periodic reports, got %+v.", reports)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"errors"
+)
+
+// Persistence format, all integers little endian:
+//
+//	header page (cMMAPHDRSIZE bytes)
+//	  0  magic "LFRMMAP\x00"
+//	  8  version   uint32
+//	 12  slot size uint32
+//	 16  slots     uint64, power of two
+//	 24  crc32c of bytes [0, 24)
+//	 64  write index, 72 its check word
+//	128  read index, 136 its check word
+//	192  wake area, see `MmapWakeOffset`
+//	200  consumer lease, see `MmapLeaseOffset`
+//	slots, `slots * slot size` bytes
+//	  0  sequence uint64 (see `Ring`)
+//	  8  length   uint32, cMMAPTOMB for holes
+//	 12  crc32c of record
+//	 16  record bytes
+const (
+	cMMAPMAGIC   = "LFRMMAP\x00"
+	cMMAPVERSION = 1
+	cMMAPHDRSIZE = 4096
+	cMMAPWRI     = 64
+	cMMAPRDI     = 128
+	cMMAPSLOTHDR = 16
+	cMMAPTOMB    = ^uint32(0)
+	// MmapWakeOffset is header offset of two uint32
+	// words, a wake sequence and a waiter count,
+	// reserved for blocking consumers (see package
+	// shm). Ring operations never touch them.
+	MmapWakeOffset = 192
+	// MmapLeaseOffset is header offset of a uint32
+	// consumer lease word, reserved for handing a
+	// ring over between processes (see package
+	// shm). Ring operations never touch it.
+	MmapLeaseOffset = 200
+	// cMMAPCHECK is mixed into cursor check words,
+	// so zeroed words never validate.
+	cMMAPCHECK = 0x9e3779b97f4a7c15
+)
+
+var (
+	// ErrMmapLayout is returned when memory does not
+	// hold a valid mmap ring layout.
+	ErrMmapLayout = errors.New("lfring: invalid mmap ring layout")
+	// ErrMmapUnsupported is returned on platforms
+	// without file mappings and by `purego` builds,
+	// which can not access shared memory atomically.
+	ErrMmapUnsupported = errors.New("lfring: mmap ring not supported")
+)
+
+// - MARK: MmapRing layout section.
+
+// MmapRingSize returns number of bytes needed to
+// hold a ring of `size` slots of `slotsize`
+// bytes. `size` is rounded to power of two.
+func MmapRingSize(size uint64, slotsize int) int64 {
+	return int64(cMMAPHDRSIZE) + int64(roundP2(size))*int64(slotsize)
+}
@@ -0,0 +1,390 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"sync/atomic"
+	"unsafe"
+)
+
+// - MARK: MmapRing section.
+
+// MmapRing is a MPMC ring of byte records whose
+// slots and cursors live in caller provided
+// memory, typically a shared file mapping, so
+// the queue survives process restarts and can be
+// inspected offline. Slots carry sequence numbers
+// like `Ring`; records are checksummed and cursor
+// updates are followed by a check word, which lets
+// `OpenMmapRing` detect torn cursors and rebuild
+// them from slot sequences.
+type MmapRing struct {
+	mem      []byte
+	wri      *uint64
+	rdi      *uint64
+	slots    []byte
+	slotsize uint64
+	size     uint64
+	corrupt  uint64 // records dropped by recovery
+	repaired uint64 // cursor positions moved by recovery
+	unmap    func([]byte) error
+	sync     func([]byte) error
+}
+
+// FormatMmapRing initializes a new empty ring in
+// `mem` with slots of `slotsize` bytes, a multiple
+// of 8 including a 16-byte slot header. Number of
+// slots is the largest power of two fitting.
+func FormatMmapRing(mem []byte, slotsize int) (*MmapRing, error) {
+	if slotsize <= cMMAPSLOTHDR || slotsize%8 != 0 || len(mem) < cMMAPHDRSIZE+slotsize || uintptr(unsafe.Pointer(&mem[0]))&7 != 0 {
+		return nil, ErrMmapLayout
+	}
+	n := uint64(len(mem)-cMMAPHDRSIZE) / uint64(slotsize)
+	if n&(n-1) != 0 {
+		n = roundP2(n) >> 1
+	}
+	if n < 2 {
+		// one slot can not tell free from published
+		return nil, ErrMmapLayout
+	}
+	for i := range mem[:cMMAPHDRSIZE] {
+		mem[i] = 0
+	}
+	copy(mem, cMMAPMAGIC)
+	binary.LittleEndian.PutUint32(mem[8:], cMMAPVERSION)
+	binary.LittleEndian.PutUint32(mem[12:], uint32(slotsize))
+	binary.LittleEndian.PutUint64(mem[16:], n)
+	binary.LittleEndian.PutUint32(mem[24:], crc32.Checksum(mem[:24], crctab))
+	m := newMmapRing(mem, uint64(slotsize), n)
+	for i := uint64(0); i < n; i++ {
+		*m.seq(i) = i
+		binary.LittleEndian.PutUint32(m.slot(i)[8:], 0)
+	}
+	m.storeCursor(m.wri, 0)
+	m.storeCursor(m.rdi, 0)
+	return m, nil
+}
+
+// OpenMmapRing opens the ring stored in `mem` and
+// recovers it: torn cursors are rebuilt from slot
+// sequences, stale ones are repaired against them,
+// and slots claimed by a push which did not
+// complete, or holding corrupt records, are
+// turned into holes skipped by `Pop`. It must not
+// be shared before it returns.
+func OpenMmapRing(mem []byte) (*MmapRing, error) {
+	m, err := parseMmapRing(mem)
+	if err != nil {
+		return nil, err
+	}
+	m.recover()
+	return m, nil
+}
+
+// AttachMmapRing opens the ring stored in `mem`
+// without recovery, e.g. to share a live ring
+// with other processes. Cursors and slots are
+// trusted as they are.
+func AttachMmapRing(mem []byte) (*MmapRing, error) {
+	return parseMmapRing(mem)
+}
+
+// parseMmapRing validates header of `mem` and
+// returns a ring over it.
+func parseMmapRing(mem []byte) (*MmapRing, error) {
+	if len(mem) < cMMAPHDRSIZE || uintptr(unsafe.Pointer(&mem[0]))&7 != 0 || string(mem[:8]) != cMMAPMAGIC {
+		return nil, ErrMmapLayout
+	}
+	var (
+		version  uint32 = binary.LittleEndian.Uint32(mem[8:])
+		slotsize uint64 = uint64(binary.LittleEndian.Uint32(mem[12:]))
+		n        uint64 = binary.LittleEndian.Uint64(mem[16:])
+	)
+	if version != cMMAPVERSION || crc32.Checksum(mem[:24], crctab) != binary.LittleEndian.Uint32(mem[24:]) {
+		return nil, ErrMmapLayout
+	}
+	if slotsize <= cMMAPSLOTHDR || slotsize%8 != 0 || n < 2 || n&(n-1) != 0 || n > (uint64(len(mem))-cMMAPHDRSIZE)/slotsize {
+		return nil, ErrMmapLayout
+	}
+	return newMmapRing(mem, slotsize, n), nil
+}
+
+// newMmapRing returns a ring over validated `mem`.
+func newMmapRing(mem []byte, slotsize, n uint64) *MmapRing {
+	return &MmapRing{
+		mem:      mem,
+		wri:      (*uint64)(unsafe.Pointer(&mem[cMMAPWRI])),
+		rdi:      (*uint64)(unsafe.Pointer(&mem[cMMAPRDI])),
+		slots:    mem[cMMAPHDRSIZE : cMMAPHDRSIZE+n*slotsize],
+		slotsize: slotsize,
+		size:     n,
+	}
+}
+
+// Cap returns number of slots.
+func (m *MmapRing) Cap() uint64 {
+	return m.size
+}
+
+// MaxRecord returns maximum record length.
+func (m *MmapRing) MaxRecord() int {
+	return int(m.slotsize - cMMAPSLOTHDR)
+}
+
+// Len returns number of unread slots, including
+// holes.
+func (m *MmapRing) Len() uint64 {
+	return atomic.LoadUint64(m.wri) - atomic.LoadUint64(m.rdi)
+}
+
+// Corrupt returns number of records dropped by
+// recovery.
+func (m *MmapRing) Corrupt() uint64 {
+	return m.corrupt
+}
+
+// Repaired returns number of positions recovery
+// moved stale cursors by.
+func (m *MmapRing) Repaired() uint64 {
+	return m.repaired
+}
+
+// Push appends record `p` and returns false when
+// ring is full or `p` exceeds `MaxRecord`.
+func (m *MmapRing) Push(p []byte) bool {
+	if len(p) > m.MaxRecord() {
+		return false
+	}
+	for {
+		pos := atomic.LoadUint64(m.wri)
+		dif := int64(atomic.LoadUint64(m.seq(pos)) - pos)
+		if dif < 0 {
+			return false
+		}
+		if dif == 0 && atomic.CompareAndSwapUint64(m.wri, pos, pos+1) {
+			m.check(m.wri)
+			slot := m.slot(pos)
+			copy(slot[cMMAPSLOTHDR:], p)
+			binary.LittleEndian.PutUint32(slot[8:], uint32(len(p)))
+			binary.LittleEndian.PutUint32(slot[12:], crc32.Checksum(p, crctab))
+			atomic.StoreUint64(m.seq(pos), pos+1)
+			return true
+		}
+	}
+}
+
+// Pop removes next record, appends it to `dst`
+// and returns the result, or false when ring is
+// empty. Holes are skipped.
+func (m *MmapRing) Pop(dst []byte) ([]byte, bool) {
+	for {
+		pos := atomic.LoadUint64(m.rdi)
+		dif := int64(atomic.LoadUint64(m.seq(pos)) - (pos + 1))
+		if dif < 0 {
+			return dst, false
+		}
+		if dif == 0 && atomic.CompareAndSwapUint64(m.rdi, pos, pos+1) {
+			m.check(m.rdi)
+			slot := m.slot(pos)
+			n := binary.LittleEndian.Uint32(slot[8:])
+			if n != cMMAPTOMB {
+				dst = append(dst, slot[cMMAPSLOTHDR:cMMAPSLOTHDR+n]...)
+			}
+			atomic.StoreUint64(m.seq(pos), pos+m.size)
+			if n != cMMAPTOMB {
+				return dst, true
+			}
+		}
+	}
+}
+
+// Records visits unread records in order without
+// consuming them, e.g. for offline inspection of
+// a ring that is not in use. `rec` aliases ring
+// memory and is only valid during the call.
+func (m *MmapRing) Records(fn func(pos uint64, rec []byte) bool) {
+	for pos := atomic.LoadUint64(m.rdi); pos != atomic.LoadUint64(m.wri); pos++ {
+		if atomic.LoadUint64(m.seq(pos)) != pos+1 {
+			return
+		}
+		slot := m.slot(pos)
+		if n := binary.LittleEndian.Uint32(slot[8:]); n != cMMAPTOMB && !fn(pos, slot[cMMAPSLOTHDR:cMMAPSLOTHDR+n]) {
+			return
+		}
+	}
+}
+
+// Sync flushes the mapping of a ring returned by
+// `NewMmapRing` to its file; it is a no-op
+// otherwise.
+func (m *MmapRing) Sync() error {
+	if m.sync == nil {
+		return nil
+	}
+	return m.sync(m.mem)
+}
+
+// Close syncs and releases the mapping of a ring
+// returned by `NewMmapRing`; it is a no-op
+// otherwise.
+func (m *MmapRing) Close() error {
+	if m.unmap == nil {
+		return nil
+	}
+	err := m.Sync()
+	unmap := m.unmap
+	m.unmap, m.sync = nil, nil
+	if uerr := unmap(m.mem); err == nil {
+		err = uerr
+	}
+	return err
+}
+
+// recover rebuilds torn cursors and fills holes.
+func (m *MmapRing) recover() {
+	var (
+		wri, rdi uint64
+		ok       bool = m.validCursor(m.wri) && m.validCursor(m.rdi)
+	)
+	if ok {
+		wri, rdi = atomic.LoadUint64(m.wri), atomic.LoadUint64(m.rdi)
+		ok = wri-rdi <= m.size
+	}
+	if ok {
+		wri, rdi = m.repair(wri, rdi)
+	} else {
+		wri, rdi = m.scan()
+	}
+	for pos := rdi; pos != wri; pos++ {
+		slot := m.slot(pos)
+		n := binary.LittleEndian.Uint32(slot[8:])
+		switch {
+		case *m.seq(pos) != pos+1:
+			// claimed, never published
+			n = cMMAPTOMB
+		case n != cMMAPTOMB && (uint64(n) > m.slotsize-cMMAPSLOTHDR || crc32.Checksum(slot[cMMAPSLOTHDR:cMMAPSLOTHDR+n], crctab) != binary.LittleEndian.Uint32(slot[12:])):
+			m.corrupt++
+			n = cMMAPTOMB
+		}
+		binary.LittleEndian.PutUint32(slot[8:], n)
+		*m.seq(pos) = pos + 1
+	}
+	m.storeCursor(m.wri, wri)
+	m.storeCursor(m.rdi, rdi)
+}
+
+// repair reconciles cursors of a stale header,
+// e.g. one flushed before slot pages, with slot
+// sequences: the write cursor moves over
+// published slots and the read cursor over
+// released ones, or back over slots whose pop
+// did not complete, so their records are
+// delivered again.
+func (m *MmapRing) repair(wri, rdi uint64) (uint64, uint64) {
+	w, r := wri, rdi
+	for w-r < m.size && *m.seq(w) == w+1 {
+		w++
+	}
+	for r != w && *m.seq(r) == r+m.size {
+		r++
+	}
+	if r == rdi {
+		for w-r < m.size && *m.seq(r - 1) == r {
+			r--
+		}
+	}
+	m.repaired += w - wri
+	if r > rdi {
+		m.repaired += r - rdi
+	} else {
+		m.repaired += rdi - r
+	}
+	return w, r
+}
+
+// scan derives cursors from slot sequences: a
+// slot either publishes position `seq-1` or is
+// free for position `seq`.
+func (m *MmapRing) scan() (wri, rdi uint64) {
+	var (
+		lo, hi uint64 = ^uint64(0), 0
+		free   uint64 = ^uint64(0)
+		found  bool
+	)
+	for i := uint64(0); i < m.size; i++ {
+		seq := *m.seq(i)
+		if (seq-1)&(m.size-1) == i {
+			// published position `seq-1`
+			if seq-1 < lo {
+				lo = seq - 1
+			}
+			if seq > hi {
+				hi = seq
+			}
+			found = true
+		} else if seq < free {
+			free = seq
+		}
+	}
+	if !found {
+		return free, free
+	}
+	return hi, lo
+}
+
+// validCursor returns whether cursor at `p`
+// matches its check word.
+func (m *MmapRing) validCursor(p *uint64) bool {
+	return *(*uint64)(unsafe.Add(unsafe.Pointer(p), 8)) == *p^cMMAPCHECK
+}
+
+// storeCursor stores cursor `p` and its check word.
+func (m *MmapRing) storeCursor(p *uint64, v uint64) {
+	atomic.StoreUint64(p, v)
+	atomic.StoreUint64((*uint64)(unsafe.Add(unsafe.Pointer(p), 8)), v^cMMAPCHECK)
+}
+
+// check refreshes check word of cursor `p`. It may
+// lag behind concurrent updates, which recovery
+// treats as a torn cursor.
+func (m *MmapRing) check(p *uint64) {
+	atomic.StoreUint64((*uint64)(unsafe.Add(unsafe.Pointer(p), 8)), atomic.LoadUint64(p)^cMMAPCHECK)
+}
+
+// seq returns sequence of slot of position `pos`.
+func (m *MmapRing) seq(pos uint64) *uint64 {
+	return (*uint64)(unsafe.Pointer(&m.slots[(pos&(m.size-1))*m.slotsize]))
+}
+
+// slot returns slot of position `pos`.
+func (m *MmapRing) slot(pos uint64) []byte {
+	off := (pos & (m.size - 1)) * m.slotsize
+	return m.slots[off : off+m.slotsize]
+}
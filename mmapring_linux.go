@@ -0,0 +1,84 @@
+//go:build linux && !purego
+// +build linux,!purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// - MARK: MmapRing mapping section.
+
+// NewMmapRing opens the ring file at `path`, or
+// creates it with `size` slots of `slotsize`
+// bytes when it does not exist or is empty, and
+// maps it shared. An existing file keeps its own
+// geometry. The mapping is released by `Close`.
+func NewMmapRing(path string, size uint64, slotsize int) (*MmapRing, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	length, fresh := fi.Size(), fi.Size() == 0
+	if fresh {
+		length = MmapRingSize(size, slotsize)
+		if err = f.Truncate(length); err != nil {
+			return nil, err
+		}
+	}
+	mem, err := syscall.Mmap(int(f.Fd()), 0, int(length), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	var m *MmapRing
+	if fresh {
+		m, err = FormatMmapRing(mem, slotsize)
+	} else {
+		m, err = OpenMmapRing(mem)
+	}
+	if err != nil {
+		syscall.Munmap(mem)
+		return nil, err
+	}
+	m.unmap, m.sync = syscall.Munmap, msync
+	return m, nil
+}
+
+// msync synchronously flushes mapping `mem`.
+func msync(mem []byte) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&mem[0])), uintptr(len(mem)), syscall.MS_SYNC)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+//go:build linux && !purego
+// +build linux,!purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNewMmapRing(t *testing.T) {
+	var path string = filepath.Join(t.TempDir(), "queue.lfr")
+	m, err := NewMmapRing(path, 8, 64)
+	if err != nil {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	m.Push([]byte("persisted"))
+	if err = m.Close(); err != nil {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	// geometry of existing file wins
+	if m, err = NewMmapRing(path, 2, 32); err != nil || m.Cap() != 8 {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	defer m.Close()
+	if rec, ok := m.Pop(nil); !ok || string(rec) != "persisted" {
+		t.Fatalf("assertion failed, got %q.", rec)
+	}
+}
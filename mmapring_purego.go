@@ -0,0 +1,102 @@
+//go:build purego
+// +build purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+// - MARK: MmapRing section.
+
+// MmapRing is a MPMC ring of byte records in
+// shared memory. Without `unsafe`, bytes can not
+// be accessed as atomic words, hence `purego`
+// builds can not create one: constructors return
+// `ErrMmapUnsupported`.
+type MmapRing struct{}
+
+// FormatMmapRing returns `ErrMmapUnsupported`.
+func FormatMmapRing(mem []byte, slotsize int) (*MmapRing, error) {
+	return nil, ErrMmapUnsupported
+}
+
+// OpenMmapRing returns `ErrMmapUnsupported`.
+func OpenMmapRing(mem []byte) (*MmapRing, error) {
+	return nil, ErrMmapUnsupported
+}
+
+// AttachMmapRing returns `ErrMmapUnsupported`.
+func AttachMmapRing(mem []byte) (*MmapRing, error) {
+	return nil, ErrMmapUnsupported
+}
+
+// Cap returns number of slots.
+func (m *MmapRing) Cap() uint64 {
+	return 0
+}
+
+// MaxRecord returns maximum record length.
+func (m *MmapRing) MaxRecord() int {
+	return 0
+}
+
+// Len returns number of unread slots.
+func (m *MmapRing) Len() uint64 {
+	return 0
+}
+
+// Corrupt returns number of records dropped by
+// recovery.
+func (m *MmapRing) Corrupt() uint64 {
+	return 0
+}
+
+// Repaired returns number of positions recovery
+// moved stale cursors by.
+func (m *MmapRing) Repaired() uint64 {
+	return 0
+}
+
+// Push returns false.
+func (m *MmapRing) Push(p []byte) bool {
+	return false
+}
+
+// Pop returns `dst` and false.
+func (m *MmapRing) Pop(dst []byte) ([]byte, bool) {
+	return dst, false
+}
+
+// Records visits no records.
+func (m *MmapRing) Records(fn func(pos uint64, rec []byte) bool) {}
+
+// Sync returns `ErrMmapUnsupported`.
+func (m *MmapRing) Sync() error {
+	return ErrMmapUnsupported
+}
+
+// Close returns `ErrMmapUnsupported`.
+func (m *MmapRing) Close() error {
+	return ErrMmapUnsupported
+}
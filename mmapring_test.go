@@ -0,0 +1,222 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+// mmapMem returns 8-byte aligned memory for `n`
+// slots of `slotsize` bytes.
+func mmapMem(n, slotsize int) []byte {
+	words := make([]uint64, (cMMAPHDRSIZE+n*slotsize)/8)
+	return unsafe.Slice((*byte)(unsafe.Pointer(&words[0])), len(words)*8)
+}
+
+func TestMmapRing(t *testing.T) {
+	var mem []byte = mmapMem(4, 32)
+	if _, err := FormatMmapRing(mem, 12); err != ErrMmapLayout {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	if _, err := OpenMmapRing(mem); err != ErrMmapLayout {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	m, err := FormatMmapRing(mem, 32)
+	if err != nil || m.Cap() != 4 || m.MaxRecord() != 16 {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+	if m.Push(make([]byte, 17)) {
+		t.Fatal("assertion failed, pushed oversized record.")
+	}
+	for _, rec := range []string{"a", "bb", "ccc", "dddd"} {
+		if !m.Push([]byte(rec)) {
+			t.Fatal("inconsistent state, unable to push.")
+		}
+	}
+	if m.Push([]byte("e")) {
+		t.Fatal("assertion failed, expected full ring.")
+	}
+	if rec, ok := m.Pop(nil); !ok || string(rec) != "a" {
+		t.Fatalf("assertion failed, expected a, got %q.", rec)
+	}
+	// reopen, as after a restart
+	m, err = OpenMmapRing(mem)
+	if err != nil || m.Len() != 3 || m.Corrupt() != 0 {
+		t.Fatalf("assertion failed, err(%v), len(%d).", err, m.Len())
+	}
+	var seen []string
+	m.Records(func(_ uint64, rec []byte) bool {
+		seen = append(seen, string(rec))
+		return true
+	})
+	if len(seen) != 3 || seen[0] != "bb" || seen[2] != "dddd" {
+		t.Fatalf("assertion failed, records(%q).", seen)
+	}
+	for _, want := range seen {
+		if rec, ok := m.Pop(nil); !ok || string(rec) != want {
+			t.Fatalf("assertion failed, expected %s, got %q.", want, rec)
+		}
+	}
+	if _, ok := m.Pop(nil); ok {
+		t.Fatal("inconsistent state, popped from empty ring.")
+	}
+}
+
+func TestMmapRingRecover(t *testing.T) {
+	var mem []byte = mmapMem(4, 32)
+	m, _ := FormatMmapRing(mem, 32)
+	for _, rec := range []string{"a", "b", "c"} {
+		m.Push([]byte(rec))
+	}
+	m.Pop(nil)
+	// crash: torn read index check word, a push
+	// claimed position 3 and never published, and
+	// record of position 2 is corrupted.
+	*(*uint64)(unsafe.Pointer(&mem[cMMAPRDI+8])) = 0
+	*m.wri = 4
+	m.slot(2)[cMMAPSLOTHDR] = 'x'
+	m, err := OpenMmapRing(mem)
+	if err != nil || m.Corrupt() != 1 || m.Len() != 2 {
+		t.Fatalf("assertion failed, err(%v), corrupt(%d), len(%d).", err, m.Corrupt(), m.Len())
+	}
+	if rec, ok := m.Pop(nil); !ok || string(rec) != "b" {
+		t.Fatalf("assertion failed, expected b, got %q.", rec)
+	}
+	// holes are skipped
+	if _, ok := m.Pop(nil); ok || m.Len() != 0 {
+		t.Fatalf("assertion failed, len(%d).", m.Len())
+	}
+	if !m.Push([]byte("d")) {
+		t.Fatal("inconsistent state, unable to push after recovery.")
+	}
+	if rec, ok := m.Pop(nil); !ok || string(rec) != "d" {
+		t.Fatalf("assertion failed, expected d, got %q.", rec)
+	}
+}
+
+func TestMmapRingConcurrent(t *testing.T) {
+	const n = 1000
+	var (
+		m, _ = FormatMmapRing(mmapMem(16, 16+8), 24)
+		wg   sync.WaitGroup
+		sum  uint64
+		got  int
+	)
+	for p := 0; p < 2; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= n; i++ {
+				rec := []byte{byte(i), byte(i >> 8)}
+				for !m.Push(rec) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	for buf := make([]byte, 0, 8); got < 2*n; {
+		rec, ok := m.Pop(buf[:0])
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		sum += uint64(rec[0]) | uint64(rec[1])<<8
+		got++
+	}
+	wg.Wait()
+	if sum != n*(n+1) {
+		t.Fatalf("assertion failed, sum(%d).", sum)
+	}
+}
+
+func TestMmapRingRepair(t *testing.T) {
+	var (
+		mem []byte = mmapMem(8, 32)
+		hdr []byte = make([]byte, cMMAPHDRSIZE)
+	)
+	m, _ := FormatMmapRing(mem, 32)
+	m.Push([]byte("a"))
+	m.Push([]byte("b"))
+	copy(hdr, mem)
+	// crash: slots reached memory, the header
+	// page holding cursors did not.
+	m.Push([]byte("c"))
+	m.Push([]byte("d"))
+	m.Pop(nil)
+	copy(mem, hdr)
+	if m, _ = OpenMmapRing(mem); m.Len() != 3 || m.Repaired() != 3 || m.Corrupt() != 0 {
+		t.Fatalf("assertion failed, len(%d), repaired(%d).", m.Len(), m.Repaired())
+	}
+	// crash: pop of b claimed its position and
+	// never released the slot.
+	*m.seq(1) = 2
+	m.storeCursor(m.rdi, 2)
+	if m, _ = OpenMmapRing(mem); m.Len() != 3 || m.Repaired() != 1 {
+		t.Fatalf("assertion failed, len(%d), repaired(%d).", m.Len(), m.Repaired())
+	}
+	for _, want := range []string{"b", "c", "d"} {
+		if rec, ok := m.Pop(nil); !ok || string(rec) != want {
+			t.Fatalf("assertion failed, expected %s, got %q.", want, rec)
+		}
+	}
+}
+
+func TestMessageRing(t *testing.T) {
+	mr, err := FormatMmapRing(mmapMem(4, 128), 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMessageRing(mr, JSONCodec{New: func() interface{} { return new(codecPoint) }})
+	for i := 0; i < 4; i++ {
+		if ok, err := m.Push(codecPoint{X: i}); !ok || err != nil {
+			t.Fatalf("inconsistent state, unable to push (%v).", err)
+		}
+	}
+	if ok, _ := m.Push(codecPoint{}); ok {
+		t.Fatal("assertion failed, pushed to a full ring.")
+	}
+	if ok, err := m.Push(make(chan int)); ok || err == nil {
+		t.Fatal("assertion failed, expected encoding error.")
+	}
+	for i := 0; i < 4; i++ {
+		v, ok, err := m.Pop()
+		if !ok || err != nil || v.(*codecPoint).X != i {
+			t.Fatalf("assertion failed, expected %d, got %v (%v).", i, v, err)
+		}
+	}
+	if _, ok, _ := m.Pop(); ok {
+		t.Fatal("assertion failed, popped from an empty ring.")
+	}
+	// undecodable records are consumed
+	mr.Push([]byte("{"))
+	if _, ok, err := m.Pop(); !ok || err == nil || mr.Len() != 0 {
+		t.Fatal("assertion failed, expected decoding error.")
+	}
+}
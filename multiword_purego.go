@@ -0,0 +1,88 @@
+//go:build purego
+// +build purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// - MARK: Multi-word section.
+
+// RDCSSCtx performs restricted double-compare
+// single-swap: `a2` is set to `n2` iff
+// `*a1 == o1` and `a2` holds `o2`. It retries
+// with backoff while `a2` is held by a competing
+// operation until `ctx` is done. It returns false
+// without retrying when a value does not match,
+// and `ctx.Err()` when it gave up. Values must be
+// `TagSafe`, otherwise `ErrMisaligned` is returned.
+// Default builds swap an `unsafe.Pointer` word
+// instead of a `Tagged` one.
+func RDCSSCtx[T any](ctx context.Context, a1 *uint64, o1 uint64, a2 *Tagged[T], o2, n2 *T) (bool, error) {
+	if !TagSafe(o2) || !TagSafe(n2) {
+		return false, ErrMisaligned
+	}
+	var swapped bool
+	_, err := casBackoff.RetryCtx(ctx, func() bool {
+		ok, contended := a2.rdcssTry(a1, o1, o2, n2)
+		swapped = ok
+		return !contended
+	})
+	return swapped, err
+}
+
+// KCSSCtx performs k-compare single-swap: `a` is
+// set to `n` iff it holds `o` and
+// `*addrs[i] == olds[i]` for every `i`. Compared
+// words must be monotonic version counters. It
+// retries like `RDCSSCtx` while `a` is held by a
+// competing operation.
+func KCSSCtx[T any](ctx context.Context, a *Tagged[T], o, n *T, addrs []*uint64, olds []uint64) (bool, error) {
+	if !TagSafe(o) || !TagSafe(n) {
+		return false, ErrMisaligned
+	}
+	var swapped bool
+	_, err := casBackoff.RetryCtx(ctx, func() bool {
+		ok, contended := a.kcssTry(o, n, addrs, olds)
+		swapped = ok
+		return !contended
+	})
+	return swapped, err
+}
+
+// collect returns whether every word in `addrs`
+// holds the corresponding value in `olds`.
+func collect(addrs []*uint64, olds []uint64) bool {
+	for i, addr := range addrs {
+		if atomic.LoadUint64(addr) != olds[i] {
+			return false
+		}
+	}
+	return true
+}
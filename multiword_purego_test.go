@@ -0,0 +1,104 @@
+//go:build purego
+// +build purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// - MARK: Test section.
+
+func TestRDCSSCtx(t *testing.T) {
+	var (
+		ctl  uint64 = 1
+		a, b int
+		slot Tagged[int]
+	)
+	slot.Store(&a)
+	// mismatch fails at once, without retries
+	if ok, err := RDCSSCtx(context.Background(), &ctl, 2, &slot, &a, &b); ok || err != nil || slot.Load() != &a {
+		t.Fatalf("assertion failed, expected false without error, got %v, %v.", ok, err)
+	}
+	if ok, err := RDCSSCtx(context.Background(), &ctl, 1, &slot, &b, nil); ok || err != nil || slot.Load() != &a {
+		t.Fatalf("assertion failed, expected false without error, got %v, %v.", ok, err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if ok, err := RDCSSCtx(cancelled, &ctl, 1, &slot, &a, &b); ok || err != context.Canceled || slot.Load() != &a {
+		t.Fatalf("assertion failed, expected cancellation before first attempt, got %v, %v.", ok, err)
+	}
+	// a competitor which never completes holds
+	// the slot until deadline.
+	release := holdTagged(&slot)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if ok, err := RDCSSCtx(ctx, &ctl, 1, &slot, &a, &b); ok || err != context.DeadlineExceeded {
+		t.Fatalf("assertion failed, expected deadline, got %v, %v.", ok, err)
+	}
+	// competitor completes while retrying
+	go func() {
+		time.Sleep(time.Millisecond)
+		release(&a)
+	}()
+	if ok, err := RDCSSCtx(context.Background(), &ctl, 1, &slot, &a, &b); !ok || err != nil {
+		t.Fatalf("assertion failed, expected swap, got %v, %v.", ok, err)
+	}
+	if slot.Load() != &b {
+		t.Fatal("inconsistent state, slot not swapped.")
+	}
+}
+
+func TestKCSSCtx(t *testing.T) {
+	var (
+		v1, v2 uint64 = 3, 7
+		a, b   int
+		word   Tagged[int]
+		addrs  []*uint64 = []*uint64{&v1, &v2}
+	)
+	release := holdTagged(&word)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if ok, err := KCSSCtx(ctx, &word, &a, &b, addrs, []uint64{3, 7}); ok || err != context.DeadlineExceeded {
+		t.Fatalf("assertion failed, expected deadline, got %v, %v.", ok, err)
+	}
+	go func() {
+		time.Sleep(time.Millisecond)
+		release(&a)
+	}()
+	if ok, err := KCSSCtx(context.Background(), &word, &a, &b, addrs, []uint64{3, 8}); ok || err != nil || word.Load() != &a {
+		t.Fatalf("assertion failed, expected counter mismatch, got %v, %v.", ok, err)
+	}
+	if ok, err := KCSSCtx(context.Background(), &word, &b, nil, addrs, []uint64{3, 7}); ok || err != nil || word.Load() != &a {
+		t.Fatalf("assertion failed, expected word mismatch, got %v, %v.", ok, err)
+	}
+	if ok, err := KCSSCtx(context.Background(), &word, &a, &b, addrs, []uint64{3, 7}); !ok || err != nil || word.Load() != &b {
+		t.Fatalf("assertion failed, expected swap, got %v, %v.", ok, err)
+	}
+}
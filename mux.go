@@ -0,0 +1,106 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// - MARK: Mux section.
+
+// Mux lets a consumer wait on several rings at
+// once, like `select` over channels. Each receive
+// starts polling at the ring following the one
+// which served the previous receive, so a busy
+// ring can not starve the others. Between empty
+// rounds it waits according to its wait strategy;
+// with a `Signaler` such as `*Parking`, pass the
+// same strategy to the rings, see
+// `WithWaitStrategy`, so pushes wake the mux. It
+// is safe for concurrent use.
+type Mux struct {
+	// 64bit aligned
+	next  uint64 // ring to poll first
+	rings []*Ring
+	wait  WaitStrategy
+}
+
+// NewMux allocates and initializes a new `Mux`
+// over `rings` waiting by `wait`, or by
+// `DefaultWaitStrategy` when nil.
+func NewMux(wait WaitStrategy, rings ...*Ring) *Mux {
+	if wait == nil {
+		wait = DefaultWaitStrategy
+	}
+	return &Mux{rings: rings, wait: wait}
+}
+
+// TryRecv pops an item of the first non-empty
+// ring in fair order and returns it with index of
+// its ring, or false when all rings are empty.
+func (m *Mux) TryRecv() (interface{}, int, bool) {
+	n := uint64(len(m.rings))
+	if n == 0 {
+		return nil, -1, false
+	}
+	start := atomic.LoadUint64(&m.next)
+	for i := uint64(0); i < n; i++ {
+		k := (start + i) % n
+		if v, ok := m.rings[k].Pop(); ok {
+			atomic.StoreUint64(&m.next, k+1)
+			return v, int(k), true
+		}
+	}
+	return nil, -1, false
+}
+
+// Recv blocks until an item is available and
+// returns it with index of its ring.
+func (m *Mux) Recv() (interface{}, int) {
+	v, i, _ := m.RecvCtx(context.Background())
+	return v, i
+}
+
+// RecvCtx is `Recv` which gives up once `ctx` is
+// done and returns `ctx.Err()`.
+func (m *Mux) RecvCtx(ctx context.Context) (interface{}, int, error) {
+	for n := 0; ; n++ {
+		if v, i, ok := m.TryRecv(); ok {
+			return v, i, nil
+		}
+		if n%cBACKOFFBATCH == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, -1, err
+			}
+		}
+		m.wait.Wait(n)
+	}
+}
+
+// Rings returns multiplexed rings.
+func (m *Mux) Rings() []*Ring {
+	return m.rings
+}
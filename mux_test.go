@@ -0,0 +1,91 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// - MARK: Test section.
+
+func TestMuxFairness(t *testing.T) {
+	var (
+		a *Ring = NewRing(16)
+		b *Ring = NewRing(16)
+		m *Mux  = NewMux(nil, a, b)
+	)
+	if _, _, ok := m.TryRecv(); ok {
+		t.Fatal("assertion failed, expected empty mux.")
+	}
+	for i := 0; i < 8; i++ {
+		a.Push(i)
+	}
+	b.Push(100)
+	b.Push(101)
+	// busy `a` does not starve `b`
+	var from [2]int
+	for i := 0; i < 4; i++ {
+		_, k, ok := m.TryRecv()
+		if !ok {
+			t.Fatal("assertion failed, expected item.")
+		}
+		from[k]++
+	}
+	if from[0] != 2 || from[1] != 2 {
+		t.Fatalf("assertion failed, expected alternating rings, got %v.", from)
+	}
+	if _, _, ok := NewMux(nil).TryRecv(); ok {
+		t.Fatal("assertion failed, expected empty mux.")
+	}
+}
+
+func TestMuxRecv(t *testing.T) {
+	var (
+		p *Parking = NewParking(4, time.Second)
+		a *Ring    = NewRing(4, WithWaitStrategy(p))
+		b *Ring    = NewRing(4, WithWaitStrategy(p))
+		m *Mux     = NewMux(p, a, b)
+	)
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		b.Push("x")
+	}()
+	start := time.Now()
+	v, k := m.Recv()
+	if v != "x" || k != 1 {
+		t.Fatalf("assertion failed, expected x of ring 1, got %v of %d.", v, k)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("assertion failed, expected push to wake mux.")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	m = NewMux(Sleeping{Spins: 1, Sleep: time.Millisecond}, a, b)
+	if _, _, err := m.RecvCtx(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("assertion failed, expected deadline, got %v.", err)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "sync/atomic"
+
+// - MARK: Option section.
+
+const (
+	// cSINGLEPROD is mode bit of a ring pushed to
+	// by a single goroutine.
+	cSINGLEPROD Mode = 1 << 0
+	// cSINGLECONS is mode bit of a ring popped
+	// from by a single goroutine.
+	cSINGLECONS Mode = 1 << 1
+)
+
+// Mode is producer/consumer cardinality of a
+// ring. Single sides advance their index with a
+// plain atomic store instead of a CAS.
+type Mode uint8
+
+const (
+	// MPMC allows multiple producers and multiple
+	// consumers. It is the default.
+	MPMC Mode = 0
+	// MPSC allows multiple producers and a single
+	// consumer.
+	MPSC Mode = cSINGLECONS
+	// SPSC allows a single producer and a single
+	// consumer.
+	SPSC Mode = cSINGLEPROD | cSINGLECONS
+)
+
+// String returns name of mode.
+func (m Mode) String() string {
+	switch m {
+	case MPMC:
+		return "MPMC"
+	case MPSC:
+		return "MPSC"
+	case SPSC:
+		return "SPSC"
+	}
+	return "invalid"
+}
+
+// Option configures a ring built by `NewRing`.
+type Option func(*Ring)
+
+// WithOverwrite makes `Push` evict the oldest
+// item when ring is full instead of failing,
+// same as `WithPolicy(DropOldest)`.
+func WithOverwrite() Option {
+	return WithPolicy(DropOldest)
+}
+
+// WithPolicy sets behavior of `Push` on a full
+// ring, see `Policy`.
+func WithPolicy(p Policy) Option {
+	return func(r *Ring) { r.policy = p }
+}
+
+// WithWaitStrategy sets wait strategy, see
+// `SetWaitStrategy`.
+func WithWaitStrategy(w WaitStrategy) Option {
+	return func(r *Ring) { r.SetWaitStrategy(w) }
+}
+
+// WithFairness sets producer fairness policy,
+// see `SetFairness`.
+func WithFairness(p FairnessPolicy) Option {
+	return func(r *Ring) { r.SetFairness(p) }
+}
+
+// WithPadding places each slot sequence on its
+// own cache line, so producers and consumers of
+// neighbouring slots do not false share. It
+// costs a cache line per slot, sized by
+// `CPU.CacheLineSize`.
+func WithPadding() Option {
+	return func(r *Ring) { r.shift = CPU.seqPadShift() }
+}
+
+// WithStats enables operation statistics, see
+// `Stats`.
+func WithStats() Option {
+	return func(r *Ring) { r.stats = &ringStats{} }
+}
+
+// WithMode sets producer/consumer cardinality.
+// Violating it, e.g. popping from two goroutines
+// of a `MPSC` ring, corrupts the ring. Closing
+// requires a multi-producer mode.
+func WithMode(m Mode) Option {
+	return func(r *Ring) { r.mode = m }
+}
+
+// WithMaxCapacity limits rounded capacity to
+// `n`; `NewRingChecked` fails beyond it.
+func WithMaxCapacity(n uint64) Option {
+	return func(r *Ring) { r.maxcap = n }
+}
+
+// Mode returns producer/consumer cardinality.
+func (r *Ring) Mode() Mode {
+	return r.mode
+}
+
+// Overwrites returns whether `Push` evicts the
+// oldest item when ring is full.
+func (r *Ring) Overwrites() bool {
+	return r.policy == DropOldest
+}
+
+// Policy returns behavior of `Push` on a full
+// ring.
+func (r *Ring) Policy() Policy {
+	return r.policy
+}
+
+// - MARK: Stats section.
+
+// ringStats is optional statistics block of a ring.
+type ringStats struct {
+	_           CacheLinePad
+	full        uint64 // pushes failed on a full ring
+	empty       uint64 // pops failed on an empty ring
+	overwritten uint64 // items evicted by overwrite
+	dropped     uint64 // items discarded by `DropNewest`
+	blocked     uint64 // pushes waiting by `Block`
+	maxlen      uint64 // occupancy high-water mark
+	_           CacheLinePad
+}
+
+// observe raises occupancy high-water mark to
+// `n`.
+func (s *ringStats) observe(n uint64) {
+	for {
+		max := atomic.LoadUint64(&s.maxlen)
+		if int64(n) <= int64(max) || atomic.CompareAndSwapUint64(&s.maxlen, max, n) {
+			return
+		}
+	}
+}
+
+// Stats is a snapshot of ring statistics.
+// `Full`, `Empty`, `Overwritten`, `Dropped`,
+// `Blocked` and `MaxLen` are zero unless enabled
+// by `WithStats`, `Expired` unless enabled by
+// `WithTTL`.
+type Stats struct {
+	Pushes      uint64 // successful pushes
+	Pops        uint64 // successful pops
+	Full        uint64 // pushes failed on a full ring
+	Empty       uint64 // pops failed on an empty ring
+	Overwritten uint64 // items evicted by overwrite
+	Dropped     uint64 // items discarded by `DropNewest`
+	Blocked     uint64 // pushes waiting by `Block`
+	Expired     uint64 // items skipped past deadline
+	Retries     uint64 // failed index CAS, retried
+	Yields      uint64 // sustained waits, see `pause`
+	Len         uint64 // current occupancy
+	MaxLen      uint64 // occupancy high-water mark
+}
+
+// Stats returns a snapshot of ring statistics.
+// Pushes and pops are derived from ring indices,
+// retries and yields are always counted.
+func (r *Ring) Stats() (s Stats) {
+	s.Pops = r.readIndex()
+	s.Pushes = r.writeIndex()
+	s.Retries = atomic.LoadUint64(&r.casfail)
+	s.Yields = atomic.LoadUint64(&r.yields)
+	s.Len = r.Len()
+	if r.stats != nil {
+		s.Full = atomic.LoadUint64(&r.stats.full)
+		s.Empty = atomic.LoadUint64(&r.stats.empty)
+		s.Overwritten = atomic.LoadUint64(&r.stats.overwritten)
+		s.Dropped = atomic.LoadUint64(&r.stats.dropped)
+		s.Blocked = atomic.LoadUint64(&r.stats.blocked)
+		s.MaxLen = atomic.LoadUint64(&r.stats.maxlen)
+	}
+	if r.ttl != nil {
+		s.Expired = atomic.LoadUint64(&r.ttl.expired)
+	}
+	return s
+}
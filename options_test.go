@@ -0,0 +1,160 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestRingOptions(t *testing.T) {
+	var (
+		w Yielding = Yielding{Spins: 4}
+		r *Ring    = NewRing(4, WithWaitStrategy(w), WithFairness(FairnessPolicy{Ticket: true}), WithMode(MPSC))
+	)
+	if r.WaitStrategy() != w || !r.Fairness().Ticket || r.Mode() != MPSC || r.Mode().String() != "MPSC" {
+		t.Fatal("assertion failed, options not applied.")
+	}
+	if r.Overwrites() || r.stats != nil || len(r.seqs) != 4 {
+		t.Fatal("assertion failed, disabled options applied.")
+	}
+}
+
+func TestRingOverwrite(t *testing.T) {
+	var r *Ring = NewRing(4, WithOverwrite(), WithStats())
+	for i := 0; i < 6; i++ {
+		if !r.Push(i) {
+			t.Fatal("assertion failed, overwriting push failed.")
+		}
+	}
+	for i := 2; i < 6; i++ {
+		if v, ok := r.Pop(); !ok || v.(int) != i {
+			t.Fatalf("assertion failed, expected %d, got %v.", i, v)
+		}
+	}
+	r.Pop()
+	if s := r.Stats(); s.Pushes != 6 || s.Pops != 6 || s.Overwritten != 2 || s.Empty != 1 || s.Full != 0 {
+		t.Fatalf("assertion failed, stats(%+v).", s)
+	}
+}
+
+func TestRingSeqPadding(t *testing.T) {
+	var r *Ring = NewRing(4, WithPadding(), WithStats())
+	if len(r.seqs) != 4*CPU.CacheLineSize/8 {
+		t.Fatalf("assertion failed, len(seqs)(%d).", len(r.seqs))
+	}
+	for lap := 0; lap < 3; lap++ {
+		for i := 0; i < 4; i++ {
+			r.Push(i)
+		}
+		if r.Push(-1) {
+			t.Fatal("assertion failed, expected full ring.")
+		}
+		for i := 0; i < 4; i++ {
+			if v, ok := r.Pop(); !ok || v.(int) != i {
+				t.Fatalf("assertion failed, expected %d, got %v.", i, v)
+			}
+		}
+	}
+	if len(r.Audit()) != 0 || r.Stats().Full != 3 {
+		t.Fatal("assertion failed, inconsistent padded ring.")
+	}
+}
+
+func TestRingModes(t *testing.T) {
+	const n = 10000
+	for _, mode := range []Mode{SPSC, MPSC} {
+		var (
+			r         *Ring = NewRing(64, WithMode(mode))
+			producers int   = 1
+			wg        sync.WaitGroup
+		)
+		if mode == MPSC {
+			producers = 4
+		}
+		for p := 0; p < producers; p++ {
+			wg.Add(1)
+			go func(p int) {
+				defer wg.Done()
+				for i := 0; i < n; i++ {
+					for !r.Push(p*n + i) {
+						runtime.Gosched()
+					}
+				}
+			}(p)
+		}
+		last := make([]int, producers)
+		for i := range last {
+			last[i] = -1
+		}
+		for got := 0; got < producers*n; {
+			v, ok := r.Pop()
+			if !ok {
+				runtime.Gosched()
+				continue
+			}
+			p, i := v.(int)/n, v.(int)%n
+			if i <= last[p] {
+				t.Fatalf("assertion failed, %s order violation.", mode)
+			}
+			last[p] = i
+			got++
+		}
+		wg.Wait()
+	}
+}
+
+func TestRingStats(t *testing.T) {
+	var (
+		r   *Ring         = NewRing(4, WithStats())
+		dst []interface{} = make([]interface{}, 4)
+	)
+	for i := 0; i < 5; i++ {
+		r.Push(i)
+	}
+	r.PopInto(dst[:3])
+	r.PushBatch(dst[:3])
+	r.Consume(8, func(interface{}) bool { return true })
+	r.Pop()
+	r.TryPop(4)
+	r.PopInto(dst)
+	s := r.Stats()
+	if s.Pushes != 7 || s.Pops != 7 || s.Len != 0 || s.MaxLen != 4 {
+		t.Fatalf("assertion failed, stats(%+v).", s)
+	}
+	if s.Full != 1 || s.Empty != 3 {
+		t.Fatalf("assertion failed, stats(%+v).", s)
+	}
+	// counters without stats block
+	r = NewRing(4)
+	r.Push(1)
+	r.Pop()
+	if s = r.Stats(); s.Pushes != 1 || s.Pops != 1 || s.MaxLen != 0 || s.Empty != 0 {
+		t.Fatalf("assertion failed, stats(%+v).", s)
+	}
+}
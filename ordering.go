@@ -0,0 +1,90 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	// ErrOrdering is returned by `NewRingChecked`
+	// for `AcqRel` ordering of a `MPMC` ring.
+	ErrOrdering = errors.New("lfring: ordering requires a single side")
+)
+
+// - MARK: Ordering section.
+
+// Ordering is memory ordering of cursor stores of
+// single producer and consumer sides, see
+// `WithMode`. Sides shared by several goroutines
+// advance cursors by CAS, which is always
+// sequentially consistent.
+type Ordering uint8
+
+const (
+	// SeqCst stores cursors sequentially
+	// consistent. It is the default.
+	SeqCst Ordering = iota
+	// AcqRel asks for cursor stores with release
+	// semantics only. The Go memory model has no
+	// release store, and a plain store racing with
+	// atomic loads is a data race, so cursors are
+	// still stored sequentially consistent; the
+	// mode only records that a single side does
+	// not rely on the store-load fence.
+	AcqRel
+)
+
+// String returns name of ordering.
+func (o Ordering) String() string {
+	switch o {
+	case SeqCst:
+		return "seq-cst"
+	case AcqRel:
+		return "acq-rel"
+	}
+	return "invalid"
+}
+
+// WithOrdering sets memory ordering of cursor
+// stores. `AcqRel` requires a mode
+// with a single side, `NewRingChecked` fails for
+// `MPMC`.
+func WithOrdering(o Ordering) Option {
+	return func(r *Ring) { r.order = o }
+}
+
+// Ordering returns memory ordering of cursor
+// stores.
+func (r *Ring) Ordering() Ordering {
+	return r.order
+}
+
+// storeCursor stores cursor `p` of a single side;
+// both orderings store sequentially consistent,
+// see `AcqRel`.
+func (r *Ring) storeCursor(p *uint64, v uint64) {
+	atomic.StoreUint64(p, v)
+}
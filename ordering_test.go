@@ -0,0 +1,69 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestRingOrdering(t *testing.T) {
+	if _, err := NewRingChecked(4, WithOrdering(AcqRel)); err != ErrOrdering {
+		t.Fatalf("assertion failed, expected ErrOrdering, got %v.", err)
+	}
+	const count = 10000
+	var (
+		r  *Ring = NewRing(8, WithMode(SPSC), WithOrdering(AcqRel))
+		wg sync.WaitGroup
+	)
+	if r.Ordering() != AcqRel || r.Ordering().String() != "acq-rel" {
+		t.Fatal("assertion failed, ordering not applied.")
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < count; i++ {
+			for j := 0; !r.Push(i); j++ {
+				r.pause(j)
+			}
+		}
+	}()
+	for i := 0; i < count; i++ {
+		v, ok := r.Pop()
+		for j := 0; !ok; j++ {
+			r.pause(j)
+			v, ok = r.Pop()
+		}
+		if v.(int) != i {
+			t.Fatalf("assertion failed, expected %d, got %v.", i, v)
+		}
+	}
+	wg.Wait()
+	if r.Len() != 0 || r.Stats().Pushes != count {
+		t.Fatalf("assertion failed, len(%d).", r.Len())
+	}
+}
@@ -0,0 +1,144 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "sync"
+
+// - MARK: PacketRing section.
+
+// Packet is a fixed capacity buffer circulating
+// through a `PacketRing`.
+type Packet struct {
+	Data []byte // committed bytes
+	buf  []byte // backing buffer
+}
+
+// PacketRing is a ring of fixed size buffers.
+// Producers reserve a free packet, fill it and
+// commit it; consumers pop packets and recycle
+// them, or take ownership of the backing buffers,
+// e.g. to hand them to cgo or syscalls without
+// copying.
+type PacketRing struct {
+	ring    *Ring     // committed packets
+	free    *Ring     // packets available to producers
+	pool    sync.Pool // spare buffers
+	bufsize int
+}
+
+// NewPacketRing allocates and initializes a new
+// `PacketRing` of `capacity` packets backed by
+// buffers of `bufsize` bytes and returns a
+// pointer to it.
+func NewPacketRing(capacity uint64, bufsize int) *PacketRing {
+	p := &PacketRing{ring: NewRing(capacity), free: NewRing(capacity), bufsize: bufsize}
+	p.pool.New = func() interface{} { return make([]byte, bufsize) }
+	for i := uint64(0); i < p.ring.Cap(); i++ {
+		p.free.Push(&Packet{buf: make([]byte, bufsize)})
+	}
+	return p
+}
+
+// Len returns number of committed packets.
+func (p *PacketRing) Len() uint64 {
+	return p.ring.Len()
+}
+
+// BufSize returns size of packet buffers.
+func (p *PacketRing) BufSize() int {
+	return p.bufsize
+}
+
+// Reserve returns a free packet whose `Data`
+// spans the whole buffer, or false when all
+// packets are in use.
+func (p *PacketRing) Reserve() (*Packet, bool) {
+	v, ok := p.free.Pop()
+	if !ok {
+		return nil, false
+	}
+	pk := v.(*Packet)
+	pk.Data = pk.buf
+	return pk, true
+}
+
+// Commit publishes first `n` bytes of reserved
+// packet `pk`.
+func (p *PacketRing) Commit(pk *Packet, n int) {
+	pk.Data = pk.buf[:n]
+	// packets never outnumber slots
+	p.ring.Push(pk)
+}
+
+// Pop returns next committed packet, or false when
+// none is available. It must be recycled once
+// consumed.
+func (p *PacketRing) Pop() (*Packet, bool) {
+	v, ok := p.ring.Pop()
+	if !ok {
+		return nil, false
+	}
+	return v.(*Packet), true
+}
+
+// Recycle makes popped packet `pk` available to
+// producers.
+func (p *PacketRing) Recycle(pk *Packet) {
+	pk.Data = nil
+	p.free.Push(pk)
+}
+
+// TakeBuffers pops up to `n` committed packets
+// with a single ring operation and transfers
+// ownership of their data to caller. Packets are
+// recycled with fresh buffers from the pool, so
+// producers are not starved while caller holds
+// the taken buffers.
+func (p *PacketRing) TakeBuffers(n int) [][]byte {
+	var (
+		pks  []interface{} = make([]interface{}, n)
+		bufs [][]byte
+	)
+	n = p.ring.PopInto(pks)
+	bufs = make([][]byte, n)
+	for i := 0; i < n; i++ {
+		pk := pks[i].(*Packet)
+		bufs[i] = pk.Data
+		pk.buf = p.pool.Get().([]byte)
+		p.Recycle(pk)
+	}
+	return bufs
+}
+
+// ReturnBuffers returns buffers obtained from
+// `TakeBuffers` to the pool. Buffers must not be
+// used afterwards.
+func (p *PacketRing) ReturnBuffers(bufs [][]byte) {
+	for _, b := range bufs {
+		if cap(b) >= p.bufsize {
+			p.pool.Put(b[:p.bufsize])
+		}
+	}
+}
@@ -0,0 +1,105 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestPacketRing(t *testing.T) {
+	var p *PacketRing = NewPacketRing(2, 16)
+	for i := 0; i < 2; i++ {
+		pk, ok := p.Reserve()
+		if !ok || len(pk.Data) != 16 {
+			t.Fatal("inconsistent state, unable to reserve.")
+		}
+		p.Commit(pk, copy(pk.Data, []byte{'a' + byte(i), '!'}))
+	}
+	if _, ok := p.Reserve(); ok {
+		t.Fatal("assertion failed, reserved more packets than slots.")
+	}
+	bufs := p.TakeBuffers(4)
+	if len(bufs) != 2 || string(bufs[0]) != "a!" || string(bufs[1]) != "b!" {
+		t.Fatalf("assertion failed, bufs(%q).", bufs)
+	}
+	// taken buffers are not reused while held
+	for i := 0; i < 2; i++ {
+		pk, ok := p.Reserve()
+		if !ok {
+			t.Fatal("inconsistent state, packets not replaced.")
+		}
+		if &pk.Data[0] == &bufs[0][0] || &pk.Data[0] == &bufs[1][0] {
+			t.Fatal("assertion failed, taken buffer handed to producer.")
+		}
+		p.Commit(pk, copy(pk.Data, "x"))
+	}
+	if string(bufs[0]) != "a!" {
+		t.Fatal("assertion failed, taken buffer overwritten.")
+	}
+	p.ReturnBuffers(bufs)
+	pk, ok := p.Pop()
+	if !ok || string(pk.Data) != "x" {
+		t.Fatalf("assertion failed, got %v.", pk)
+	}
+	p.Recycle(pk)
+	if p.Len() != 1 {
+		t.Fatalf("assertion failed, len(%d)!=1.", p.Len())
+	}
+}
+
+func TestPacketRingPipe(t *testing.T) {
+	const n = 1000
+	var (
+		p    *PacketRing = NewPacketRing(8, 8)
+		done chan struct{}
+	)
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			pk, ok := p.Reserve()
+			for ; !ok; pk, ok = p.Reserve() {
+				runtime.Gosched()
+			}
+			pk.Data[0] = byte(i)
+			p.Commit(pk, 1)
+		}
+	}()
+	for got := 0; got < n; {
+		bufs := p.TakeBuffers(3)
+		for _, b := range bufs {
+			if b[0] != byte(got) {
+				t.Fatalf("assertion failed, expected %d, got %d.", byte(got), b[0])
+			}
+			got++
+		}
+		p.ReturnBuffers(bufs)
+		if len(bufs) == 0 {
+			runtime.Gosched()
+		}
+	}
+	<-done
+}
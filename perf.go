@@ -0,0 +1,190 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"encoding/binary"
+	"os"
+	"sync/atomic"
+	"unsafe"
+)
+
+// - MARK: Perf section.
+
+// PerfBuffer is a byte ring with the layout of a
+// kernel perf buffer (as used by perf events and
+// eBPF `bpf_perf_event_output`): a control page
+// holding head and tail followed by a power of two
+// sized data area of 8-byte aligned records. Like
+// `Ring`, head and tail are free running indices
+// masked by data size; the kernel advances head
+// and the consumer advances tail, so records are
+// consumed without cgo or syscalls. A buffer has
+// a single consumer.
+type PerfBuffer struct {
+	head    *uint64 // data_head, written by producer
+	tail    *uint64 // data_tail, written by consumer
+	data    []byte  // data area
+	mask    uint64  // data size mask
+	scratch []byte  // reassembly of wrapped records
+	mem     []byte  // backing memory
+	unmap   func([]byte) error
+}
+
+// NewPerfBuffer returns a `PerfBuffer` over `mem`,
+// which holds the control page followed by data
+// pages, e.g. a mapping of a perf event fd. When
+// the control page does not specify data offset
+// and size (kernels before 4.1), data follows the
+// first page.
+func NewPerfBuffer(mem []byte) (*PerfBuffer, error) {
+	var page uint64 = uint64(os.Getpagesize())
+	if uint64(len(mem)) < 2*page || uintptr(unsafe.Pointer(&mem[0]))&7 != 0 {
+		return nil, ErrPerfLayout
+	}
+	var (
+		off  uint64 = *(*uint64)(unsafe.Pointer(&mem[cPERFDATAOFF]))
+		size uint64 = *(*uint64)(unsafe.Pointer(&mem[cPERFDATASZ]))
+	)
+	if off == 0 && size == 0 {
+		off, size = page, uint64(len(mem))-page
+	}
+	if off < cPERFDATASZ+8 || size == 0 || size&(size-1) != 0 || off+size > uint64(len(mem)) {
+		return nil, ErrPerfLayout
+	}
+	return &PerfBuffer{
+		head: (*uint64)(unsafe.Pointer(&mem[cPERFHEAD])),
+		tail: (*uint64)(unsafe.Pointer(&mem[cPERFTAIL])),
+		data: mem[off : off+size],
+		mask: size - 1,
+		mem:  mem,
+	}, nil
+}
+
+// Close releases the mapping of a buffer returned
+// by `MmapPerfBuffer`; it is a no-op otherwise.
+func (b *PerfBuffer) Close() error {
+	if b.unmap == nil {
+		return nil
+	}
+	unmap := b.unmap
+	b.unmap = nil
+	return unmap(b.mem)
+}
+
+// Len returns number of unread bytes.
+func (b *PerfBuffer) Len() uint64 {
+	return atomic.LoadUint64(b.head) - atomic.LoadUint64(b.tail)
+}
+
+// Read visits available records in order and
+// passes each one to `fn`. Visiting stops early
+// when `fn` returns false; the record passed to
+// that call is consumed nonetheless. Tail is
+// committed once, which releases the space to
+// producer. It returns the number of records
+// consumed.
+func (b *PerfBuffer) Read(fn func(PerfRecord) bool) int {
+	var (
+		head uint64 = atomic.LoadUint64(b.head) // acquire
+		tail uint64 = atomic.LoadUint64(b.tail)
+		n    int
+	)
+	for tail+cPERFHDRSIZE <= head {
+		var hdr [cPERFHDRSIZE]byte
+		b.copyOut(hdr[:], tail)
+		rec := PerfRecord{
+			Type: binary.NativeEndian.Uint32(hdr[0:4]),
+			Misc: binary.NativeEndian.Uint16(hdr[4:6]),
+		}
+		size := uint64(binary.NativeEndian.Uint16(hdr[6:8]))
+		if size < cPERFHDRSIZE || tail+size > head {
+			// corrupted or partially visible record
+			break
+		}
+		rec.Data = b.slice(tail+cPERFHDRSIZE, size-cPERFHDRSIZE)
+		tail += size
+		n++
+		if !fn(rec) {
+			break
+		}
+	}
+	atomic.StoreUint64(b.tail, tail) // release
+	return n
+}
+
+// Write appends a record as a kernel producer
+// would and returns false when there is not
+// enough room. It is meant for user-space
+// producers and tests; a buffer fed by the
+// kernel must not be written to. It must not be
+// called concurrently.
+func (b *PerfBuffer) Write(typ uint32, misc uint16, payload []byte) bool {
+	var (
+		size uint64 = (cPERFHDRSIZE + uint64(len(payload)) + 7) &^ 7
+		head uint64 = atomic.LoadUint64(b.head)
+		hdr  [cPERFHDRSIZE]byte
+	)
+	if size > 0xffff || size > uint64(len(b.data))-(head-atomic.LoadUint64(b.tail)) {
+		return false
+	}
+	binary.NativeEndian.PutUint32(hdr[0:4], typ)
+	binary.NativeEndian.PutUint16(hdr[4:6], misc)
+	binary.NativeEndian.PutUint16(hdr[6:8], uint16(size))
+	b.copyIn(head, hdr[:])
+	b.copyIn(head+cPERFHDRSIZE, payload)
+	atomic.StoreUint64(b.head, head+size) // publish
+	return true
+}
+
+// slice returns `n` bytes at index `pos`, copying
+// into scratch space when they wrap around.
+func (b *PerfBuffer) slice(pos, n uint64) []byte {
+	start := pos & b.mask
+	if start+n <= uint64(len(b.data)) {
+		return b.data[start : start+n]
+	}
+	if uint64(cap(b.scratch)) < n {
+		b.scratch = make([]byte, n)
+	}
+	b.scratch = b.scratch[:n]
+	b.copyOut(b.scratch, pos)
+	return b.scratch
+}
+
+// copyOut copies data at index `pos` into `dst`.
+func (b *PerfBuffer) copyOut(dst []byte, pos uint64) {
+	n := copy(dst, b.data[pos&b.mask:])
+	copy(dst[n:], b.data)
+}
+
+// copyIn copies `src` to index `pos`.
+func (b *PerfBuffer) copyIn(pos uint64, src []byte) {
+	n := copy(b.data[pos&b.mask:], src)
+	copy(b.data, src[n:])
+}
@@ -0,0 +1,56 @@
+//go:build linux && !purego
+// +build linux,!purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"os"
+	"syscall"
+)
+
+// - MARK: Perf mapping section.
+
+// MmapPerfBuffer maps the perf buffer of event
+// file descriptor `fd` with `pages` data pages,
+// which must be a power of two. The mapping is
+// released by `Close`.
+func MmapPerfBuffer(fd int, pages int) (*PerfBuffer, error) {
+	if pages <= 0 || pages&(pages-1) != 0 {
+		return nil, ErrPerfLayout
+	}
+	mem, err := syscall.Mmap(fd, 0, (pages+1)*os.Getpagesize(), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewPerfBuffer(mem)
+	if err != nil {
+		syscall.Munmap(mem)
+		return nil, err
+	}
+	b.unmap = syscall.Munmap
+	return b, nil
+}
@@ -0,0 +1,62 @@
+//go:build purego
+// +build purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+// - MARK: Perf section.
+
+// PerfBuffer is a byte ring with the layout of a
+// kernel perf buffer. Its head and tail are words
+// in shared memory, which `purego` builds can not
+// access atomically, hence `NewPerfBuffer` returns
+// `ErrPerfUnsupported`.
+type PerfBuffer struct{}
+
+// NewPerfBuffer returns `ErrPerfUnsupported`.
+func NewPerfBuffer(mem []byte) (*PerfBuffer, error) {
+	return nil, ErrPerfUnsupported
+}
+
+// Close is a no-op.
+func (b *PerfBuffer) Close() error {
+	return nil
+}
+
+// Len returns number of unread bytes.
+func (b *PerfBuffer) Len() uint64 {
+	return 0
+}
+
+// Read visits no records and returns zero.
+func (b *PerfBuffer) Read(fn func(PerfRecord) bool) int {
+	return 0
+}
+
+// Write returns false.
+func (b *PerfBuffer) Write(typ uint32, misc uint16, payload []byte) bool {
+	return false
+}
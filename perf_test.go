@@ -0,0 +1,121 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// newPerfMem returns a zeroed control page
+// followed by `pages` data pages.
+func newPerfMem(pages int) []byte {
+	return make([]byte, (pages+1)*os.Getpagesize())
+}
+
+func TestPerfBufferLayout(t *testing.T) {
+	if _, err := NewPerfBuffer(make([]byte, 100)); err != ErrPerfLayout {
+		t.Fatal("assertion failed, expected layout error.")
+	}
+	if _, err := NewPerfBuffer(make([]byte, 4*os.Getpagesize())); err != ErrPerfLayout {
+		t.Fatal("assertion failed, expected error for non pow2 data size.")
+	}
+	b, err := NewPerfBuffer(newPerfMem(1))
+	if err != nil || uint64(len(b.data)) != uint64(os.Getpagesize()) {
+		t.Fatalf("assertion failed, err(%v).", err)
+	}
+}
+
+func TestPerfBufferWrap(t *testing.T) {
+	var (
+		b, _    = NewPerfBuffer(newPerfMem(1))
+		size    = len(b.data)
+		payload = bytes.Repeat([]byte{0xab}, size/3-cPERFHDRSIZE)
+		got     int
+	)
+	for lap := 0; lap < 10; lap++ {
+		for b.Write(PerfRecordSample, uint16(lap), payload) {
+		}
+		if b.Len() == 0 {
+			t.Fatal("inconsistent state, unable to write.")
+		}
+		b.Read(func(rec PerfRecord) bool {
+			if rec.Type != PerfRecordSample || rec.Misc != uint16(lap) || !bytes.Equal(rec.Data[:len(payload)], payload) {
+				t.Fatalf("assertion failed, corrupted record %+v.", rec)
+			}
+			got++
+			return true
+		})
+		if b.Len() != 0 {
+			t.Fatalf("assertion failed, len(%d)!=0.", b.Len())
+		}
+	}
+	if got < 20 {
+		t.Fatalf("assertion failed, read %d records.", got)
+	}
+}
+
+func TestPerfBufferConcurrent(t *testing.T) {
+	const items = 10000
+	var (
+		b, _ = NewPerfBuffer(newPerfMem(1))
+		wg   sync.WaitGroup
+		exp  uint16
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < items; i++ {
+			payload := bytes.Repeat([]byte{byte(i)}, i%61)
+			for !b.Write(PerfRecordSample, uint16(i), payload) {
+				runtime.Gosched()
+			}
+		}
+	}()
+	for int(exp) < items {
+		n := b.Read(func(rec PerfRecord) bool {
+			if rec.Misc != exp || len(rec.Data) < int(exp)%61 {
+				t.Fatalf("assertion failed, expected record %d, got %d.", exp, rec.Misc)
+			}
+			for _, c := range rec.Data[:int(exp)%61] {
+				if c != byte(exp) {
+					t.Fatalf("assertion failed, corrupted record %d.", exp)
+				}
+			}
+			exp++
+			return true
+		})
+		if n == 0 {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+}
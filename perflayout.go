@@ -0,0 +1,66 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"errors"
+)
+
+// Perf buffer layout, see `perf_event_mmap_page`
+// in linux/perf_event.h.
+const (
+	cPERFHEAD    = 1024 // offset of data_head
+	cPERFTAIL    = 1032 // offset of data_tail
+	cPERFDATAOFF = 1040 // offset of data_offset
+	cPERFDATASZ  = 1048 // offset of data_size
+	cPERFHDRSIZE = 8    // size of perf_event_header
+)
+
+// Perf record types, see `perf_event_type`.
+const (
+	PerfRecordLost   uint32 = 2
+	PerfRecordSample uint32 = 9
+)
+
+var (
+	// ErrPerfLayout is returned when memory does
+	// not hold a valid perf buffer layout.
+	ErrPerfLayout = errors.New("lfring: invalid perf buffer layout")
+	// ErrPerfUnsupported is returned on platforms
+	// without perf events and by `purego` builds,
+	// which can not access shared memory atomically.
+	ErrPerfUnsupported = errors.New("lfring: perf buffer not supported")
+)
+
+// - MARK: Perf record section.
+
+// PerfRecord is a record read from a perf buffer.
+// `Data` aliases buffer memory and is only valid
+// during the visiting callback.
+type PerfRecord struct {
+	Type uint32 // record type
+	Misc uint16 // type specific flags
+	Data []byte // payload following the header
+}
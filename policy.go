@@ -0,0 +1,95 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	// ErrFull is returned by `PushErr` when ring is
+	// full.
+	ErrFull = errors.New("lfring: ring is full")
+	// ErrClosed is returned by `PushErr` when ring
+	// is closed.
+	ErrClosed = errors.New("lfring: ring is closed")
+)
+
+// - MARK: Policy section.
+
+// Policy decides what `Push` does on a full ring.
+// Rings of one type can serve pipelines with
+// different needs, e.g. a lossy metrics feed
+// next to a lossless work queue. Each policy
+// has its counter in `Stats`.
+type Policy uint8
+
+const (
+	// Reject fails the push, counted as `Full`.
+	// It is the default.
+	Reject Policy = iota
+	// DropNewest discards the pushed item and
+	// reports success, counted as `Dropped`, for
+	// producers which must never stall or retry.
+	DropNewest
+	// DropOldest evicts the oldest item to make
+	// room, counted as `Overwritten`. Evicting
+	// producers compete with consumers, hence the
+	// consumer side of `MPSC` and `SPSC` modes
+	// falls back to CAS.
+	DropOldest
+	// Block waits for a free slot using wait
+	// strategy of ring, counted as `Blocked`. A
+	// closed ring still fails the push.
+	Block
+)
+
+// String returns name of policy.
+func (p Policy) String() string {
+	switch p {
+	case Reject:
+		return "reject"
+	case DropNewest:
+		return "drop-newest"
+	case DropOldest:
+		return "drop-oldest"
+	case Block:
+		return "block"
+	}
+	return "invalid"
+}
+
+// PushErr pushes `data` like `Push` and returns
+// `ErrFull` or `ErrClosed` instead of false.
+func (r *Ring) PushErr(data interface{}) error {
+	if r.Push(data) {
+		return nil
+	}
+	if atomic.LoadUint64(&r.wri)&cWRCLOSED != 0 {
+		return ErrClosed
+	}
+	return ErrFull
+}
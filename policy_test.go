@@ -0,0 +1,88 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestRingPolicies(t *testing.T) {
+	for _, p := range []Policy{Reject, DropNewest, DropOldest} {
+		r := NewRing(2, WithPolicy(p), WithStats())
+		for i := 0; i < 3; i++ {
+			r.Push(i)
+		}
+		var (
+			s     Stats = r.Stats()
+			first int
+		)
+		if v, _ := r.Pop(); v != nil {
+			first = v.(int)
+		}
+		switch {
+		case r.Policy() != p:
+			t.Fatalf("assertion failed, policy(%v)!=%v.", r.Policy(), p)
+		case p == Reject && (s.Full != 1 || r.PushErr(3) != nil || r.PushErr(4) != ErrFull):
+			t.Fatalf("assertion failed, %v stats(%+v).", p, s)
+		case p == DropNewest && (s.Dropped != 1 || first != 0 || r.PushErr(3) != nil || r.PushErr(4) != nil):
+			t.Fatalf("assertion failed, %v stats(%+v), first(%d).", p, s, first)
+		case p == DropOldest && (s.Overwritten != 1 || first != 1):
+			t.Fatalf("assertion failed, %v stats(%+v), first(%d).", p, s, first)
+		}
+	}
+	r := NewRing(2)
+	r.close()
+	if r.PushErr(0) != ErrClosed || NewRing(2, WithPolicy(DropNewest)).Policy().String() != "drop-newest" {
+		t.Fatal("assertion failed, expected closed ring.")
+	}
+}
+
+func TestRingPolicyBlock(t *testing.T) {
+	var (
+		r    *Ring     = NewRing(2, WithPolicy(Block), WithStats())
+		done chan bool = make(chan bool)
+	)
+	r.Push(0)
+	r.Push(1)
+	go func() { done <- r.Push(2) }()
+	for r.Stats().Blocked == 0 {
+		runtime.Gosched()
+	}
+	if v, ok := r.Pop(); !ok || v.(int) != 0 || !<-done {
+		t.Fatal("assertion failed, expected blocked push to complete.")
+	}
+	// closing releases blocked producers
+	go func() { done <- r.Push(3) }()
+	for r.Stats().Blocked < 2 {
+		runtime.Gosched()
+	}
+	r.close()
+	if <-done {
+		t.Fatal("assertion failed, push to closed ring succeeded.")
+	}
+}
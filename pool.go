@@ -0,0 +1,134 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+)
+
+// - MARK: Pool section.
+
+// Pool is a fixed-size object pool which uses a
+// ring as lock-free free-list. Unlike `sync.Pool`
+// objects are allocated once by `NewPool` and
+// are never released to GC, so buffers keep
+// their identity and the pool never grows.
+//
+// Leak detection: `Outstanding` counts objects
+// taken but not returned, `Stats` additionally
+// reports failed gets and rejected puts; a put
+// is rejected when the free-list is already
+// full, i.e. on double or foreign puts.
+type Pool struct {
+	// 64bit aligned
+	gets     uint64 // successful gets
+	puts     uint64 // accepted puts
+	misses   uint64 // gets of exhausted pool
+	rejected uint64 // puts of full pool
+	size     uint64
+	free     *Ring
+}
+
+// PoolStats is a snapshot of pool counters.
+type PoolStats struct {
+	Gets        uint64 // successful gets
+	Puts        uint64 // accepted puts
+	Misses      uint64 // gets of exhausted pool
+	Rejected    uint64 // puts of full pool
+	Outstanding uint64 // objects not returned
+}
+
+// NewPool allocates and initializes a new `Pool`
+// of `n` objects made by `alloc`.
+func NewPool(n uint64, alloc func() interface{}) *Pool {
+	p := &Pool{free: NewRing(n), size: n}
+	for i := uint64(0); i < n; i++ {
+		p.free.Push(alloc())
+	}
+	return p
+}
+
+// Get takes an object from pool and returns true
+// when successfull, false is returned when pool
+// is exhausted.
+func (p *Pool) Get() (interface{}, bool) {
+	v, ok := p.free.Pop()
+	if !ok {
+		atomic.AddUint64(&p.misses, 1)
+		return nil, false
+	}
+	atomic.AddUint64(&p.gets, 1)
+	return v, true
+}
+
+// Put returns `v` to pool and returns true when
+// successfull. It returns false when pool holds
+// all of its objects already, which indicates a
+// double or foreign put. Detection is best
+// effort when misuse races with legit puts.
+func (p *Pool) Put(v interface{}) bool {
+	if atomic.LoadUint64(&p.puts) >= atomic.LoadUint64(&p.gets) || !p.free.Push(v) {
+		atomic.AddUint64(&p.rejected, 1)
+		return false
+	}
+	atomic.AddUint64(&p.puts, 1)
+	return true
+}
+
+// Size returns number of objects owned by pool.
+func (p *Pool) Size() uint64 {
+	return p.size
+}
+
+// Available returns number of objects in pool.
+func (p *Pool) Available() uint64 {
+	return p.free.Len()
+}
+
+// Outstanding returns number of objects taken
+// and not yet returned; a steadily growing value
+// indicates a leak.
+func (p *Pool) Outstanding() uint64 {
+	puts := atomic.LoadUint64(&p.puts)
+	gets := atomic.LoadUint64(&p.gets)
+	if puts > gets {
+		return 0
+	}
+	return gets - puts
+}
+
+// Stats returns a snapshot of pool counters.
+func (p *Pool) Stats() PoolStats {
+	s := PoolStats{
+		Puts:     atomic.LoadUint64(&p.puts),
+		Gets:     atomic.LoadUint64(&p.gets),
+		Misses:   atomic.LoadUint64(&p.misses),
+		Rejected: atomic.LoadUint64(&p.rejected),
+	}
+	if s.Gets > s.Puts {
+		s.Outstanding = s.Gets - s.Puts
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestPool(t *testing.T) {
+	var p *Pool = NewPool(3, func() interface{} { return make([]byte, 64) })
+	if p.Size() != 3 || p.Available() != 3 {
+		t.Fatal("assertion failed, expected 3 objects.")
+	}
+	var bufs [][]byte
+	for i := 0; i < 3; i++ {
+		v, ok := p.Get()
+		if !ok {
+			t.Fatal("assertion failed, expected object.")
+		}
+		bufs = append(bufs, v.([]byte))
+	}
+	if _, ok := p.Get(); ok {
+		t.Fatal("assertion failed, expected exhausted pool.")
+	}
+	if p.Outstanding() != 3 {
+		t.Fatalf("inconsistent state, expected 3 outstanding, got %d.", p.Outstanding())
+	}
+	for _, b := range bufs {
+		if !p.Put(b) {
+			t.Fatal("assertion failed, expected accepted put.")
+		}
+	}
+	// double put is rejected although rounded
+	// ring capacity leaves room.
+	if p.Put(bufs[0]) {
+		t.Fatal("assertion failed, expected rejected put.")
+	}
+	s := p.Stats()
+	if s.Gets != 3 || s.Puts != 3 || s.Misses != 1 || s.Rejected != 1 || s.Outstanding != 0 {
+		t.Fatalf("inconsistent state, unexpected stats %+v.", s)
+	}
+}
+
+func TestPoolConcurrent(t *testing.T) {
+	var (
+		wg sync.WaitGroup
+		p  *Pool = NewPool(8, func() interface{} { return new(int) })
+	)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				v, ok := p.Get()
+				if !ok {
+					continue
+				}
+				*v.(*int)++
+				if !p.Put(v) {
+					t.Error("assertion failed, expected accepted put.")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if p.Outstanding() != 0 || p.Available() != 8 {
+		t.Fatal("inconsistent state, expected all objects returned.")
+	}
+	var sum int
+	for i := 0; i < 8; i++ {
+		v, _ := p.Get()
+		sum += *v.(*int)
+	}
+	if uint64(sum) != p.Stats().Gets-8 {
+		t.Fatal("inconsistent state, lost objects.")
+	}
+}
@@ -0,0 +1,75 @@
+//go:build purego
+// +build purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"os"
+	"reflect"
+	"sync/atomic"
+)
+
+// - MARK: Prefault section.
+
+// prefaultNodes touches each page of `s` by
+// storing a slot back in place. The store goes
+// through reflect, since the compiler drops a
+// plain store of a value just loaded.
+func prefaultNodes(s []interface{}) {
+	v := reflect.ValueOf(s)
+	prefaultEach(len(s), int(v.Type().Elem().Size()), func(i int) {
+		e := v.Index(i)
+		e.Set(e)
+	})
+}
+
+// prefaultWords touches each page of `s` with an
+// atomic add of zero.
+func prefaultWords(s []uint64) {
+	prefaultEach(len(s), 8, func(i int) {
+		atomic.AddUint64(&s[i], 0)
+	})
+}
+
+// prefaultEach calls `touch` for one of `n`
+// elements of `size` bytes per page. Stepping by
+// a page from an unaligned base may stop short
+// of the last page, so the last element is
+// always touched.
+func prefaultEach(n, size int, touch func(i int)) {
+	if n == 0 {
+		return
+	}
+	step := os.Getpagesize() / size
+	if step == 0 {
+		step = 1
+	}
+	for i := 0; i < n-1; i += step {
+		touch(i)
+	}
+	touch(n - 1)
+}
@@ -0,0 +1,73 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "testing"
+
+func TestRingPrefault(t *testing.T) {
+	var r *Ring = NewRing(1 << 12)
+	for i := 0; i < 10; i++ {
+		r.Push(i)
+	}
+	r.Prefault()
+	for i := 0; i < 10; i++ {
+		if v, ok := r.Pop(); !ok || v.(int) != i {
+			t.Fatalf("assertion failed, expected %d, got %v.", i, v)
+		}
+	}
+	for i := range r.seqs {
+		if r.seqs[i] != uint64(i) && r.seqs[i] != uint64(i)+r.size {
+			t.Fatalf("assertion failed, slot %d holds sequence %d.", i, r.seqs[i])
+		}
+	}
+	b := NewBroadcast(1 << 12)
+	b.Prefault()
+	if s := b.Subscribe(); !b.Push(1) {
+		t.Fatal("inconsistent state, unable to push.")
+	} else if v, ok := s.Pop(); !ok || v.(int) != 1 {
+		t.Fatalf("assertion failed, expected 1, got %v.", v)
+	}
+}
+
+func BenchmarkRingFirstLap(b *testing.B) {
+	for _, prefault := range []bool{false, true} {
+		name := "cold"
+		if prefault {
+			name = "prefaulted"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				r := NewRing(1 << 16)
+				if prefault {
+					r.Prefault()
+				}
+				b.StartTimer()
+				for r.Push(i) {
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"os"
+	"sync/atomic"
+	"unsafe"
+)
+
+// - MARK: Prefault section.
+
+// prefaultNodes touches each page of `s`.
+func prefaultNodes(s []interface{}) {
+	prefault(unsafe.Pointer(&s[0]), uintptr(len(s))*unsafe.Sizeof(s[0]))
+}
+
+// prefaultWords touches each page of `s`.
+func prefaultWords(s []uint64) {
+	prefault(unsafe.Pointer(&s[0]), uintptr(len(s))*unsafe.Sizeof(s[0]))
+}
+
+// prefault touches each page of `n` bytes at `p`
+// with an atomic add of zero; unlike a read, the
+// write faults in a private page instead of the
+// shared zero page.
+func prefault(p unsafe.Pointer, n uintptr) {
+	prefaultSpan(uintptr(p), n, uintptr(os.Getpagesize()), func(off uintptr) {
+		atomic.AddUint32((*uint32)(unsafe.Add(p, off)), 0)
+	})
+}
+
+// prefaultSpan calls `touch` with the offset of
+// one word in each page overlapping `n` bytes at
+// `base`, for `base` and `n` multiples of 4. Pages
+// are walked from the boundary at or below `base`,
+// so a span starting mid-page still reaches every
+// page, and the word holding byte `n-1` is always
+// touched.
+func prefaultSpan(base, n, page uintptr, touch func(off uintptr)) {
+	if n == 0 {
+		return
+	}
+	last := n - 4
+	for a := base &^ (page - 1); a < base+last; a += page {
+		if a < base {
+			touch(0)
+		} else {
+			touch(a - base)
+		}
+	}
+	touch(last)
+}
@@ -0,0 +1,61 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "testing"
+
+func TestPrefaultSpan(t *testing.T) {
+	const page = 4096
+	for _, c := range []struct {
+		base, n uintptr
+		pages   int
+	}{
+		{page, 2 * page, 2},
+		{page + 8, page, 2},
+		{page + 8, 2*page - 8, 2},
+		{page - 8, 16, 2},
+		{page + 8, 8, 1},
+	} {
+		seen := make(map[uintptr]bool)
+		var last uintptr
+		prefaultSpan(c.base, c.n, page, func(off uintptr) {
+			if off+4 > c.n {
+				t.Fatalf("assertion failed, offset %d out of %d bytes.", off, c.n)
+			}
+			seen[(c.base+off)/page] = true
+			last = off
+		})
+		if len(seen) != c.pages {
+			t.Fatalf("assertion failed, base %d len %d touched %d pages, expected %d.",
+				c.base, c.n, len(seen), c.pages)
+		}
+		if last != c.n-4 {
+			t.Fatalf("assertion failed, last offset %d, expected %d.", last, c.n-4)
+		}
+	}
+}
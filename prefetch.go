@@ -0,0 +1,55 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+package lfring
+
+import "unsafe"
+
+// - MARK: Prefetch section.
+
+const (
+	// cPREFETCHSTRIDE is number of slots per cache
+	// line; storage is prefetched once per line.
+	cPREFETCHSTRIDE = CacheLineSize / 16
+	// cPREFETCHDIST is prefetch distance in slots.
+	cPREFETCHDIST = 4 * cPREFETCHSTRIDE
+	// cPREFETCHMIN is minimum ring size which is
+	// prefetched. Smaller rings stay cache resident
+	// where prefetching only adds instructions.
+	cPREFETCHMIN = 1 << 14
+)
+
+// prefetchAhead hints the cache to load storage of
+// the slot `cPREFETCHDIST` positions ahead of `pos`
+// while batch operations process the current one.
+func (r *Ring) prefetchAhead(pos uint64) {
+	if pos&(cPREFETCHSTRIDE-1) != 0 || r.size < cPREFETCHMIN {
+		return
+	}
+	index := (pos + cPREFETCHDIST) & (r.size - 1)
+	prefetch(unsafe.Pointer(&r.nodes[index]))
+	prefetch(unsafe.Pointer(r.seq(index)))
+}
@@ -0,0 +1,80 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "testing"
+
+func TestPrefetchLargeRing(t *testing.T) {
+	var (
+		r   *Ring         = NewRing(cPREFETCHMIN)
+		dst []interface{} = make([]interface{}, 100)
+		exp int
+	)
+	// wrap around so prefetch crosses ring end
+	for lap := 0; lap < 3; lap++ {
+		for i := 0; i < cPREFETCHMIN-10; i++ {
+			if !r.Push(exp + i) {
+				t.Fatal("inconsistent state, unable to push.")
+			}
+		}
+		for r.Len() > 0 {
+			n := r.PopInto(dst)
+			for _, v := range dst[:n] {
+				if v.(int) != exp {
+					t.Fatalf("assertion failed, expected %d, got %v.", exp, v)
+				}
+				exp++
+			}
+			r.Consume(50, func(v interface{}) bool {
+				if v.(int) != exp {
+					t.Fatalf("assertion failed, expected %d, got %v.", exp, v)
+				}
+				exp++
+				return true
+			})
+		}
+	}
+}
+
+func BenchmarkRingConsumeLarge(b *testing.B) {
+	const batch = 64
+	var (
+		r     *Ring = NewRing(1 << 20)
+		visit func(interface{}) bool
+	)
+	visit = func(interface{}) bool { return true }
+	for i := 0; i < b.N; i += batch {
+		if r.IsEmpty() {
+			b.StopTimer()
+			for j := 0; r.Push(j); j++ {
+			}
+			b.StartTimer()
+		}
+		r.Consume(batch, visit)
+	}
+}
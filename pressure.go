@@ -0,0 +1,164 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "sync/atomic"
+
+// Level is a backpressure level of a ring.
+type Level uint32
+
+// Backpressure levels, in increasing order.
+const (
+	Green  Level = iota // below yellow threshold
+	Yellow              // at or above yellow threshold
+	Red                 // at or above red threshold
+)
+
+// String returns level name.
+func (l Level) String() string {
+	switch l {
+	case Green:
+		return "green"
+	case Yellow:
+		return "yellow"
+	case Red:
+		return "red"
+	}
+	return "unknown"
+}
+
+// - MARK: Pressure section.
+
+// Pressure maps occupancy of a ring to levels, a
+// soft limit ahead of the hard full signal, so
+// producers can degrade gracefully, e.g. shed
+// debug events at yellow and everything but
+// critical ones at red, see `PushLevel`. Level is
+// updated by pushes and pops and held in an
+// atomic word. A `Pressure` belongs to one ring.
+//
+// Thresholds act as high watermarks; a level is
+// left once occupancy drops `Hysteresis` below
+// its threshold, the low watermark, so a ring
+// hovering at a threshold does not flap.
+type Pressure struct {
+	Yellow     uint64 // occupancy entering yellow, 0 never
+	Red        uint64 // occupancy entering red, 0 never
+	Hysteresis uint64 // distance of low watermarks
+	// OnChange is called on every level change,
+	// synchronously on the operating goroutine,
+	// and must be short. May be nil.
+	OnChange func(from, to Level)
+	level    uint32 // current level
+	shed     uint64 // pushes rejected by level
+}
+
+// WithPressure sets backpressure levels of ring,
+// see `SetPressure`.
+func WithPressure(p *Pressure) Option {
+	return func(r *Ring) { r.press = p }
+}
+
+// SetPressure sets backpressure levels of ring;
+// nil disables them. It must be called before
+// ring is shared.
+func (r *Ring) SetPressure(p *Pressure) {
+	r.press = p
+	r.hook()
+}
+
+// Level returns backpressure level of ring,
+// `Green` when levels are disabled.
+func (r *Ring) Level() Level {
+	if r.press == nil {
+		return Green
+	}
+	return r.press.Level()
+}
+
+// Pressure reports whether ring is above `Green`,
+// i.e. producers should shed load.
+func (r *Ring) Pressure() bool {
+	return r.Level() > Green
+}
+
+// PushLevel pushes `data` like `Push` unless
+// ring is above level `max`, e.g. `Green` for
+// debug events and `Yellow` for non-critical
+// ones; critical items use `Push`. It returns
+// false when `data` was shed or ring is full.
+func (r *Ring) PushLevel(data interface{}, max Level) bool {
+	if r.press != nil && r.press.Level() > max {
+		atomic.AddUint64(&r.press.shed, 1)
+		return false
+	}
+	return r.Push(data)
+}
+
+// Level returns current level.
+func (p *Pressure) Level() Level {
+	return Level(atomic.LoadUint32(&p.level))
+}
+
+// Shed returns number of pushes rejected by
+// `PushLevel`.
+func (p *Pressure) Shed() uint64 {
+	return atomic.LoadUint64(&p.shed)
+}
+
+// update sets level for occupancy `n`. Racing
+// updates each report the transition they made.
+func (p *Pressure) update(n uint64) {
+	to := p.levelAt(n)
+	for {
+		from := atomic.LoadUint32(&p.level)
+		if Level(from) == to {
+			return
+		}
+		if to < Level(from) && p.Hysteresis > 0 {
+			// leave only below low watermark.
+			if to = p.levelAt(n + p.Hysteresis); to >= Level(from) {
+				return
+			}
+		}
+		if atomic.CompareAndSwapUint32(&p.level, from, uint32(to)) {
+			if p.OnChange != nil {
+				p.OnChange(Level(from), to)
+			}
+			return
+		}
+	}
+}
+
+// levelAt returns level for occupancy `n`.
+func (p *Pressure) levelAt(n uint64) Level {
+	switch {
+	case p.Red > 0 && n >= p.Red:
+		return Red
+	case p.Yellow > 0 && n >= p.Yellow:
+		return Yellow
+	}
+	return Green
+}
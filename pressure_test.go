@@ -0,0 +1,101 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPressureLevels(t *testing.T) {
+	var (
+		changes [][2]Level
+		p       *Pressure = &Pressure{Yellow: 4, Red: 6, OnChange: func(from, to Level) {
+			changes = append(changes, [2]Level{from, to})
+		}}
+		r *Ring = NewRing(8, WithPressure(p))
+	)
+	for i := 0; i < 4; i++ {
+		if !r.PushLevel(i, Green) {
+			t.Fatalf("inconsistent state, unable to push %d.", i)
+		}
+	}
+	if r.Level() != Yellow || r.Level().String() != "yellow" {
+		t.Fatalf("assertion failed, level(%v)!=yellow.", r.Level())
+	}
+	// debug events are shed, others go through
+	if r.PushLevel(4, Green) || !r.PushLevel(4, Yellow) || p.Shed() != 1 {
+		t.Fatal("assertion failed, expected green-only push to be shed.")
+	}
+	r.PushBatch([]interface{}{5, 6})
+	if r.Level() != Red || r.PushLevel(7, Yellow) || !r.Push(7) {
+		t.Fatalf("assertion failed, level(%v), expected only critical pushes.", r.Level())
+	}
+	r.PopInto(make([]interface{}, 3))
+	r.Consume(1, func(interface{}) bool { return true })
+	if r.Level() != Yellow {
+		t.Fatalf("assertion failed, level(%v)!=yellow.", r.Level())
+	}
+	r.Pop()
+	r.TryPop(1)
+	if r.Level() != Green {
+		t.Fatalf("assertion failed, level(%v)!=green.", r.Level())
+	}
+	want := [][2]Level{{Green, Yellow}, {Yellow, Red}, {Red, Yellow}, {Yellow, Green}}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("assertion failed, expected %v, got %v.", want, changes)
+	}
+	if NewRing(4).Level() != Green {
+		t.Fatal("assertion failed, expected green without levels.")
+	}
+}
+
+func TestPressureHysteresis(t *testing.T) {
+	var (
+		n int
+		p *Pressure = &Pressure{Yellow: 4, Hysteresis: 2, OnChange: func(from, to Level) { n++ }}
+		r *Ring     = NewRing(8, WithPressure(p))
+	)
+	for i := 0; i < 4; i++ {
+		r.Push(i)
+	}
+	if !r.Pressure() {
+		t.Fatal("assertion failed, expected pressure at high watermark.")
+	}
+	// hovering at threshold keeps level
+	for i := 0; i < 3; i++ {
+		r.Pop()
+		r.Push(i)
+	}
+	r.Pop()
+	r.Pop()
+	if !r.Pressure() || n != 1 {
+		t.Fatalf("assertion failed, level(%v), changes(%d), expected yellow.", r.Level(), n)
+	}
+	r.Pop()
+	if r.Pressure() || n != 2 {
+		t.Fatalf("assertion failed, level(%v), changes(%d), expected green below low watermark.", r.Level(), n)
+	}
+}
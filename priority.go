@@ -0,0 +1,136 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "sync/atomic"
+
+// - MARK: PriorityRing section.
+
+// PriorityRing is a ring with one lane per
+// priority behind a single `Push`/`Pop` API, so
+// urgent items, e.g. control messages, overtake
+// bulk data without a second queue and a select
+// loop. Lane 0 has the highest priority. `Pop`
+// drains lanes by strict priority or, for rings
+// built by `NewWeightedRing`, by weighted round
+// robin; either way an empty lane never stalls
+// the others.
+type PriorityRing struct {
+	_     CacheLinePad
+	turn  uint64 // weighted round robin position
+	_     CacheLinePad
+	lanes []*Ring
+	sched []uint8 // lane of each turn, nil for strict
+}
+
+// NewPriorityRing allocates and initializes a new
+// `PriorityRing` of `lanes` lanes, at most 256,
+// each of `capacity` items, drained by strict
+// priority. `opts` configure every lane.
+func NewPriorityRing(capacity uint64, lanes int, opts ...Option) *PriorityRing {
+	if lanes < 1 {
+		lanes = 1
+	}
+	if lanes > 256 {
+		lanes = 256
+	}
+	q := &PriorityRing{lanes: make([]*Ring, lanes)}
+	for i := range q.lanes {
+		q.lanes[i] = NewRing(capacity, opts...)
+	}
+	return q
+}
+
+// NewWeightedRing allocates and initializes a new
+// `PriorityRing` with a lane per weight, drained
+// by weighted round robin: per round, lane `i`
+// yields up to `weights[i]` items. Lanes with
+// weight 0 are only drained when all others are
+// empty.
+func NewWeightedRing(capacity uint64, weights []int, opts ...Option) *PriorityRing {
+	q := NewPriorityRing(capacity, len(weights), opts...)
+	for i := 0; i < len(weights) && i < len(q.lanes); i++ {
+		for w := weights[i]; w > 0; w-- {
+			q.sched = append(q.sched, uint8(i))
+		}
+	}
+	return q
+}
+
+// Push appends `data` to lane `prio`, clamped to
+// lanes of ring, and returns false when the lane
+// is full.
+func (q *PriorityRing) Push(data interface{}, prio int) bool {
+	return q.lanes[q.lane(prio)].Push(data)
+}
+
+// Pop removes next item according to draining
+// policy and returns false when all lanes are
+// empty.
+func (q *PriorityRing) Pop() (interface{}, bool) {
+	if len(q.sched) > 0 {
+		t := atomic.AddUint64(&q.turn, 1) - 1
+		if v, ok := q.lanes[q.sched[t%uint64(len(q.sched))]].Pop(); ok {
+			return v, true
+		}
+	}
+	for _, r := range q.lanes {
+		if v, ok := r.Pop(); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Len returns number of items of all lanes.
+func (q *PriorityRing) Len() uint64 {
+	var n uint64
+	for _, r := range q.lanes {
+		n += r.Len()
+	}
+	return n
+}
+
+// Lanes returns number of lanes.
+func (q *PriorityRing) Lanes() int {
+	return len(q.lanes)
+}
+
+// Lane returns ring of lane `prio`, clamped to
+// lanes of ring.
+func (q *PriorityRing) Lane(prio int) *Ring {
+	return q.lanes[q.lane(prio)]
+}
+
+// lane clamps `prio` to lanes of ring.
+func (q *PriorityRing) lane(prio int) int {
+	switch {
+	case prio < 0:
+		return 0
+	case prio >= len(q.lanes):
+		return len(q.lanes) - 1
+	}
+	return prio
+}
@@ -0,0 +1,75 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "testing"
+
+// - MARK: Test section.
+
+func TestPriorityRingStrict(t *testing.T) {
+	var q *PriorityRing = NewPriorityRing(8, 3)
+	q.Push("bulk", 2)
+	q.Push("bulk", 9)
+	q.Push("normal", 1)
+	q.Push("control", -1)
+	if q.Len() != 4 || q.Lanes() != 3 || q.Lane(5).Len() != 2 {
+		t.Fatalf("assertion failed, len(%d).", q.Len())
+	}
+	for _, want := range []string{"control", "normal", "bulk", "bulk"} {
+		if v, ok := q.Pop(); !ok || v.(string) != want {
+			t.Fatalf("assertion failed, expected %s, got %v.", want, v)
+		}
+	}
+	if _, ok := q.Pop(); ok {
+		t.Fatal("inconsistent state, popped from empty ring.")
+	}
+}
+
+func TestPriorityRingWeighted(t *testing.T) {
+	var (
+		q      *PriorityRing = NewWeightedRing(64, []int{3, 1, 0})
+		counts [3]int
+	)
+	for i := 0; i < 40; i++ {
+		q.Push(0, 0)
+		q.Push(1, 1)
+		q.Push(2, 2)
+	}
+	for i := 0; i < 40; i++ {
+		v, _ := q.Pop()
+		counts[v.(int)]++
+	}
+	if counts != [3]int{30, 10, 0} {
+		t.Fatalf("assertion failed, counts(%v).", counts)
+	}
+	// drained lanes do not stall others
+	for q.Len() > 0 {
+		v, _ := q.Pop()
+		counts[v.(int)]++
+	}
+	if counts != [3]int{40, 40, 40} {
+		t.Fatalf("assertion failed, counts(%v).", counts)
+	}
+}
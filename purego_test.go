@@ -0,0 +1,54 @@
+//go:build purego
+// +build purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "testing"
+
+// - MARK: Test section.
+
+func TestPuregoStubs(t *testing.T) {
+	mem := make([]byte, MmapRingSize(4, 64))
+	if _, err := FormatMmapRing(mem, 64); err != ErrMmapUnsupported {
+		t.Fatalf("assertion failed, expected ErrMmapUnsupported, got %v.", err)
+	}
+	if _, err := OpenMmapRing(mem); err != ErrMmapUnsupported {
+		t.Fatalf("assertion failed, expected ErrMmapUnsupported, got %v.", err)
+	}
+	if _, err := NewMmapRing(t.TempDir()+"/ring", 4, 64); err != ErrMmapUnsupported {
+		t.Fatalf("assertion failed, expected ErrMmapUnsupported, got %v.", err)
+	}
+	if _, err := NewPerfBuffer(mem); err != ErrPerfUnsupported {
+		t.Fatalf("assertion failed, expected ErrPerfUnsupported, got %v.", err)
+	}
+	if _, err := MmapPerfBuffer(-1, 1); err != ErrPerfUnsupported {
+		t.Fatalf("assertion failed, expected ErrPerfUnsupported, got %v.", err)
+	}
+	if _, err := NewUring(4); err != ErrUringUnsupported {
+		t.Fatalf("assertion failed, expected ErrUringUnsupported, got %v.", err)
+	}
+}
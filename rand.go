@@ -0,0 +1,86 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// - MARK: Random section.
+
+// seedSource draws from the runtime generator,
+// which is per-P and uncontended, until it is
+// seeded. Seeded, it is a splitmix64 generator
+// whose state advances by an atomic add, so
+// goroutines share it without locking at the
+// cost of a shared cache line. It implements
+// `rand.Source`.
+type seedSource struct {
+	// 64bit aligned
+	state  uint64 // advanced per draw
+	seed   uint64 // seed set last
+	seeded uint32 // 1 once `SetSeed` was called
+}
+
+// Uint64 implements `rand.Source` interface.
+func (s *seedSource) Uint64() uint64 {
+	if atomic.LoadUint32(&s.seeded) == 0 {
+		return rand.Uint64()
+	}
+	z := atomic.AddUint64(&s.state, 0x9e3779b97f4a7c15)
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+var (
+	// source backs `rnd`; a variable, so it is
+	// 64-bit aligned on 32-bit platforms.
+	source seedSource
+	// rnd draws random choices of the package.
+	rnd *rand.Rand = rand.New(&source)
+)
+
+// SetSeed reseeds random choices of the package,
+// i.e. jitter of `Backoff` and `Sweeper`, audit
+// sampling of `Sentinel` and elimination slots of
+// `Stack`, which otherwise come from the runtime
+// generator. Choices made by a single goroutine, e.g.
+// a test driving a `FakeClock`, repeat exactly
+// for a seed; concurrent goroutines draw the same
+// values in scheduling dependent order.
+func SetSeed(seed uint64) {
+	atomic.StoreUint64(&source.seed, seed)
+	atomic.StoreUint64(&source.state, seed)
+	atomic.StoreUint32(&source.seeded, 1)
+}
+
+// Seed returns seed set last, e.g. to report it
+// along with a failure, or zero before `SetSeed`
+// was called.
+func Seed() uint64 {
+	return atomic.LoadUint64(&source.seed)
+}
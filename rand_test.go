@@ -0,0 +1,85 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestMain seeds random choices of the package
+// from `LFRING_SEED`, or randomly when unset, and
+// reports the seed of a failed run, so it can be
+// reproduced.
+func TestMain(m *testing.M) {
+	seed := rand.Uint64()
+	if v := os.Getenv("LFRING_SEED"); v != "" {
+		var err error
+		if seed, err = strconv.ParseUint(v, 10, 64); err != nil {
+			fmt.Fprintf(os.Stderr, "lfring: invalid LFRING_SEED %q: %v\n", v, err)
+			os.Exit(2)
+		}
+	}
+	SetSeed(seed)
+	code := m.Run()
+	if code != 0 {
+		fmt.Fprintf(os.Stderr, "lfring: rerun with LFRING_SEED=%d\n", seed)
+	}
+	os.Exit(code)
+}
+
+// - MARK: Test section.
+
+func TestSeed(t *testing.T) {
+	defer SetSeed(Seed())
+	var unseeded seedSource
+	if unseeded.Uint64(); unseeded.state != 0 {
+		t.Fatal("assertion failed, unseeded source advanced shared state.")
+	}
+	draw := func() (v []int64) {
+		s := NewSweeper(time.Second, 0.5)
+		for i := 0; i < 8; i++ {
+			v = append(v, int64(rnd.Uint64()>>1), int64(s.next()))
+		}
+		return v
+	}
+	SetSeed(42)
+	first := draw()
+	if Seed() != 42 {
+		t.Fatalf("assertion failed, expected seed 42, got %d.", Seed())
+	}
+	SetSeed(42)
+	if again := draw(); fmt.Sprint(again) != fmt.Sprint(first) {
+		t.Fatalf("assertion failed, seed did not reproduce draws %v, got %v.", first, again)
+	}
+	SetSeed(43)
+	if other := draw(); fmt.Sprint(other) == fmt.Sprint(first) {
+		t.Fatal("assertion failed, different seeds drew equal values.")
+	}
+}
@@ -0,0 +1,246 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// - MARK: Tagged section.
+
+// Tagged is a typed pointer word swapped with
+// RDCSS, e.g. by `ConfigCell`; `purego` builds
+// pass it to `RDCSSCtx` and `KCSSCtx` in place
+// of an untyped word. While an operation is in
+// flight the word holds its tagged descriptor;
+// `Load` and writers wait for it to complete.
+// The zero value holds nil.
+type Tagged[T any] struct {
+	p unsafe.Pointer // *T, or tagged descriptor during a swap
+	_ [0]*T          // ties the word to `T`
+}
+
+// Load returns current pointer.
+func (w *Tagged[T]) Load() *T {
+	return (*T)(loadTagged(&w.p))
+}
+
+// Store replaces current pointer by `p`.
+func (w *Tagged[T]) Store(p *T) {
+	for {
+		if cur := loadTagged(&w.p); atomic.CompareAndSwapPointer(&w.p, cur, unsafe.Pointer(p)) {
+			return
+		}
+	}
+}
+
+// CompareAndSwap replaces current pointer by `n`
+// iff it is `o` and returns whether it did.
+func (w *Tagged[T]) CompareAndSwap(o, n *T) bool {
+	for {
+		cur := loadTagged(&w.p)
+		if cur != unsafe.Pointer(o) {
+			return false
+		}
+		if atomic.CompareAndSwapPointer(&w.p, cur, unsafe.Pointer(n)) {
+			return true
+		}
+	}
+}
+
+// rdcssTry performs `rdcssTry` with the word as
+// data address.
+func (w *Tagged[T]) rdcssTry(a1 *uint64, o1 uint64, o2, n2 *T) (ok, contended bool) {
+	return rdcssTry(a1, o1, &w.p, unsafe.Pointer(o2), unsafe.Pointer(n2))
+}
+
+// loadTagged returns pointer at `addr`, waiting
+// for an operation in progress to complete.
+func loadTagged(addr *unsafe.Pointer) unsafe.Pointer {
+	for i := 0; ; i++ {
+		p := atomic.LoadPointer(addr)
+		if !isDescriptor(p) {
+			return p
+		}
+		yieldPoint()
+		casBackoff.Wait(i)
+	}
+}
+
+// - MARK: Descriptor section.
+
+// rdcssDescriptor describes an ongoing RDCSS
+// operation. While installed, a tagged pointer
+// to the descriptor occupies `a2` and acts as a
+// barrier for competitors.
+type rdcssDescriptor struct {
+	a1  *uint64         // control address
+	o1  uint64          // expected control value
+	a2  *unsafe.Pointer // data address
+	o2  unsafe.Pointer  // expected data value
+	n2  unsafe.Pointer  // new data value
+	gen uint64          // generation, bumped on every release
+}
+
+// descpool recycles descriptors so RDCSS does not
+// heap-allocate on every call. Descriptors are
+// retired through the selected `Reclamation` and
+// only return to the pool once no goroutine can
+// observe them.
+var descpool = sync.Pool{
+	New: func() interface{} { return new(rdcssDescriptor) },
+}
+
+// acquireDescriptor returns a zeroed descriptor
+// from the pool.
+func acquireDescriptor() *rdcssDescriptor {
+	return descpool.Get().(*rdcssDescriptor)
+}
+
+// releaseDescriptor retires `d` through `rc`.
+func releaseDescriptor(rc reclaimer, d *rdcssDescriptor) {
+	rc.retire(unsafe.Pointer(d), freeDescriptor)
+}
+
+// freeDescriptor clears descriptor `p`, bumps its
+// generation to invalidate stale references
+// and puts it back into the pool.
+func freeDescriptor(p unsafe.Pointer) {
+	d := (*rdcssDescriptor)(p)
+	d.a1, d.a2, d.o2, d.n2 = nil, nil, nil, nil
+	d.o1 = 0
+	atomic.AddUint64(&d.gen, 1)
+	descpool.Put(d)
+}
+
+// - MARK: RDCSS section.
+
+// rdcss performs restricted double-compare
+// single-swap: `*a2` is set to `n2` iff
+// `*a1 == o1` and `*a2 == o2`. It returns
+// true when the swap took place. Descriptors
+// are taken from `descpool` and returned once
+// the operation completes.
+func rdcss(a1 *uint64, o1 uint64, a2 *unsafe.Pointer, o2, n2 unsafe.Pointer) bool {
+	ok, _ := rdcssTry(a1, o1, a2, o2, n2)
+	return ok
+}
+
+// RDCSSCtx performs RDCSS like `rdcss`, retrying
+// with backoff while `a2` is held by a competing
+// operation until `ctx` is done. It returns false
+// without retrying when a value does not match,
+// and `ctx.Err()` when it gave up. Values must be
+// `TagSafe`, otherwise `ErrMisaligned` is returned.
+func RDCSSCtx(ctx context.Context, a1 *uint64, o1 uint64, a2 *unsafe.Pointer, o2, n2 unsafe.Pointer) (bool, error) {
+	if !TagSafe(o2) || !TagSafe(n2) {
+		return false, ErrMisaligned
+	}
+	var swapped bool
+	_, err := casBackoff.RetryCtx(ctx, func() bool {
+		ok, contended := rdcssTry(a1, o1, a2, o2, n2)
+		swapped = ok
+		return !contended
+	})
+	return swapped, err
+}
+
+// rdcssTry is `rdcss` which also reports whether
+// it failed to install its descriptor while `a2`
+// was held by a competitor or changed back.
+func rdcssTry(a1 *uint64, o1 uint64, a2 *unsafe.Pointer, o2, n2 unsafe.Pointer) (ok, contended bool) {
+	var (
+		rc  reclaimer        = enterReclaim()
+		d   *rdcssDescriptor = acquireDescriptor()
+		tag unsafe.Pointer
+		gen uint64
+	)
+	d.a1, d.o1, d.a2, d.o2, d.n2 = a1, o1, a2, o2, n2
+	gen = atomic.LoadUint64(&d.gen)
+	tag = tagDescriptor(d)
+	// first stage: install descriptor.
+	if !atomic.CompareAndSwapPointer(a2, o2, tag) {
+		releaseDescriptor(rc, d)
+		cur := atomic.LoadPointer(a2)
+		return false, cur == o2 || isDescriptor(cur)
+	}
+	// descriptor is visible to competitors.
+	yieldPoint()
+	ok = rdcssComplete(d, tag, gen)
+	releaseDescriptor(rc, d)
+	return ok, false
+}
+
+// rdcssComplete performs the second stage of
+// RDCSS by replacing the installed descriptor
+// with either new or old value depending on
+// control address.
+func rdcssComplete(d *rdcssDescriptor, tag unsafe.Pointer, gen uint64) bool {
+	if atomic.LoadUint64(&d.gen) != gen {
+		// descriptor has been recycled; this
+		// indicates a double release.
+		panic("lfring: stale rdcss descriptor")
+	}
+	if atomic.LoadUint64(d.a1) == d.o1 {
+		yieldPoint()
+		atomic.CompareAndSwapPointer(d.a2, tag, d.n2)
+		return true
+	}
+	atomic.CompareAndSwapPointer(d.a2, tag, d.o2)
+	return false
+}
+
+// - MARK: Tag section.
+
+// tagDescriptor returns descriptor address with
+// lowest bit set. Descriptors are word aligned,
+// hence the addition never carries.
+func tagDescriptor(d *rdcssDescriptor) unsafe.Pointer {
+	return unsafe.Pointer(uintptr(unsafe.Pointer(d)) + 1)
+}
+
+// isDescriptor returns whether `p` is a tagged
+// descriptor pointer.
+func isDescriptor(p unsafe.Pointer) bool {
+	return uintptr(p)&(TagAlign-1) != 0
+}
+
+// checkTags verifies descriptors can be tagged
+// in place, see `checkArch`.
+func checkTags() error {
+	for i := 0; i < 4; i++ {
+		if d := new(rdcssDescriptor); !TagSafe(unsafe.Pointer(d)) {
+			return fmt.Errorf("lfring: allocator returned %p, not aligned to %d", d, TagAlign)
+		}
+	}
+	return nil
+}
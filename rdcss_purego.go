@@ -0,0 +1,115 @@
+//go:build purego
+// +build purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// - MARK: Tagged section.
+
+// Tagged is a pointer word swapped by `RDCSSCtx`
+// and `KCSSCtx`. Without pointer tagging, an
+// operation in flight holds the write lock of the
+// word; `Load` and writers wait for it to
+// complete. The zero value holds nil.
+type Tagged[T any] struct {
+	mu sync.RWMutex // held by operations in flight
+	p  *T           // current pointer
+}
+
+// Load returns current pointer.
+func (w *Tagged[T]) Load() *T {
+	w.mu.RLock()
+	p := w.p
+	w.mu.RUnlock()
+	return p
+}
+
+// Store replaces current pointer by `p`.
+func (w *Tagged[T]) Store(p *T) {
+	w.mu.Lock()
+	w.p = p
+	w.mu.Unlock()
+}
+
+// CompareAndSwap replaces current pointer by `n`
+// iff it is `o` and returns whether it did.
+func (w *Tagged[T]) CompareAndSwap(o, n *T) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.p != o {
+		return false
+	}
+	w.p = n
+	return true
+}
+
+// rdcssTry sets the word to `n2` iff `*a1 == o1`
+// and it holds `o2`. It also reports whether the
+// word was held by a competitor.
+func (w *Tagged[T]) rdcssTry(a1 *uint64, o1 uint64, o2, n2 *T) (ok, contended bool) {
+	if !w.mu.TryLock() {
+		return false, true
+	}
+	defer w.mu.Unlock()
+	if w.p != o2 || atomic.LoadUint64(a1) != o1 {
+		return false, false
+	}
+	w.p = n2
+	return true, false
+}
+
+// kcssTry sets the word to `n` iff it holds `o`
+// and every word in `addrs` holds its value in
+// `olds`. It also reports whether the word was
+// held by a competitor.
+func (w *Tagged[T]) kcssTry(o, n *T, addrs []*uint64, olds []uint64) (ok, contended bool) {
+	if len(addrs) != len(olds) {
+		panic("lfring: kcss length mismatch")
+	}
+	if !w.mu.TryLock() {
+		return false, true
+	}
+	defer w.mu.Unlock()
+	// counters are monotonic; two matching
+	// collects imply they held their values
+	// at a common instant while locked.
+	if w.p != o || !collect(addrs, olds) || !collect(addrs, olds) {
+		return false, false
+	}
+	w.p = n
+	return true, false
+}
+
+// checkTags verifies descriptors can be tagged
+// in place; `purego` builds tag nothing.
+func checkTags() error {
+	return nil
+}
@@ -0,0 +1,144 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// - MARK: Test section.
+
+// holdTagged installs a descriptor which never
+// completes in `w`, as a stalled competitor
+// would, and returns a function completing it
+// with `p`.
+func holdTagged[T any](w *Tagged[T]) func(p *T) {
+	atomic.StorePointer(&w.p, tagDescriptor(&rdcssDescriptor{}))
+	return func(p *T) {
+		atomic.StorePointer(&w.p, unsafe.Pointer(p))
+	}
+}
+
+func TestRDCSS(t *testing.T) {
+	var (
+		ctl  uint64 = 1
+		a, b int
+		slot unsafe.Pointer = unsafe.Pointer(&a)
+	)
+	// control mismatch leaves slot unchanged
+	if rdcss(&ctl, 2, &slot, unsafe.Pointer(&a), unsafe.Pointer(&b)) {
+		t.Fatal("assertion failed, expected false.")
+	}
+	if slot != unsafe.Pointer(&a) {
+		t.Fatal("inconsistent state, slot modified.")
+	}
+	// data mismatch leaves slot unchanged
+	if rdcss(&ctl, 1, &slot, unsafe.Pointer(&b), nil) {
+		t.Fatal("assertion failed, expected false.")
+	}
+	if !rdcss(&ctl, 1, &slot, unsafe.Pointer(&a), unsafe.Pointer(&b)) {
+		t.Fatal("assertion failed, expected true.")
+	}
+	if slot != unsafe.Pointer(&b) {
+		t.Fatal("inconsistent state, slot not swapped.")
+	}
+}
+
+func TestRDCSSCtx(t *testing.T) {
+	var (
+		ctl  uint64 = 1
+		a, b int
+		slot unsafe.Pointer   = unsafe.Pointer(&a)
+		held *rdcssDescriptor = &rdcssDescriptor{}
+	)
+	// mismatch fails at once, without retries
+	if ok, err := RDCSSCtx(context.Background(), &ctl, 2, &slot, unsafe.Pointer(&a), unsafe.Pointer(&b)); ok || err != nil {
+		t.Fatalf("assertion failed, expected false without error, got %v, %v.", ok, err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if ok, err := RDCSSCtx(cancelled, &ctl, 1, &slot, unsafe.Pointer(&a), unsafe.Pointer(&b)); ok || err != context.Canceled || slot != unsafe.Pointer(&a) {
+		t.Fatalf("assertion failed, expected cancellation before first attempt, got %v, %v.", ok, err)
+	}
+	// a competitor which never completes holds
+	// the slot until deadline.
+	slot = tagDescriptor(held)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if ok, err := RDCSSCtx(ctx, &ctl, 1, &slot, unsafe.Pointer(&a), unsafe.Pointer(&b)); ok || err != context.DeadlineExceeded {
+		t.Fatalf("assertion failed, expected deadline, got %v, %v.", ok, err)
+	}
+	// competitor completes while retrying
+	go func() {
+		time.Sleep(time.Millisecond)
+		atomic.StorePointer(&slot, unsafe.Pointer(&a))
+	}()
+	if ok, err := RDCSSCtx(context.Background(), &ctl, 1, &slot, unsafe.Pointer(&a), unsafe.Pointer(&b)); !ok || err != nil {
+		t.Fatalf("assertion failed, expected swap, got %v, %v.", ok, err)
+	}
+	if atomic.LoadPointer(&slot) != unsafe.Pointer(&b) {
+		t.Fatal("inconsistent state, slot not swapped.")
+	}
+}
+
+func TestRDCSSPooled(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool is randomized under race detector.")
+	}
+	var (
+		ctl  uint64
+		a    int
+		slot unsafe.Pointer
+	)
+	allocs := testing.AllocsPerRun(100, func() {
+		rdcss(&ctl, 0, &slot, nil, unsafe.Pointer(&a))
+		rdcss(&ctl, 0, &slot, unsafe.Pointer(&a), nil)
+	})
+	if allocs > 0 {
+		t.Fatalf("assertion failed, expected zero allocations, got %v.", allocs)
+	}
+}
+
+func TestRDCSSEpoch(t *testing.T) {
+	SetReclamation(ReclaimEpoch)
+	defer SetReclamation(ReclaimHazard)
+	var (
+		ctl  uint64
+		a    int
+		slot unsafe.Pointer
+	)
+	for i := 0; i < 1000; i++ {
+		if !rdcss(&ctl, 0, &slot, nil, unsafe.Pointer(&a)) || !rdcss(&ctl, 0, &slot, unsafe.Pointer(&a), nil) {
+			t.Fatal("assertion failed, expected true.")
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+
+	"github.com/mitghi/lfring/epoch"
+	"github.com/mitghi/lfring/hazard"
+)
+
+// - MARK: Reclamation section.
+
+// Reclamation is the memory reclamation scheme
+// used for recycled descriptors. `purego` builds
+// have no descriptors and ignore it.
+type Reclamation uint32
+
+const (
+	// ReclaimHazard protects descriptors with
+	// hazard pointers (`lfring/hazard`).
+	ReclaimHazard Reclamation = iota
+	// ReclaimEpoch uses epoch-based reclamation
+	// (`lfring/epoch`); it has lower read-side
+	// overhead but a stalled goroutine delays
+	// every reclamation.
+	ReclaimEpoch
+)
+
+// reclamation is current scheme.
+var reclamation uint32 = uint32(ReclaimHazard)
+
+// SetReclamation selects reclamation scheme. It
+// should be called before any ring is used.
+func SetReclamation(m Reclamation) {
+	atomic.StoreUint32(&reclamation, uint32(m))
+}
+
+// CurrentReclamation returns selected scheme.
+func CurrentReclamation() Reclamation {
+	return Reclamation(atomic.LoadUint32(&reclamation))
+}
+
+// reclaimer is the per-operation handle of the
+// selected scheme.
+type reclaimer struct {
+	rec   *hazard.Record
+	guard *epoch.Guard
+}
+
+// enterReclaim returns a handle of the selected
+// scheme owned by the caller.
+func enterReclaim() reclaimer {
+	if CurrentReclamation() == ReclaimEpoch {
+		return reclaimer{guard: epoch.Default.Enter()}
+	}
+	return reclaimer{rec: hazard.Default.Acquire()}
+}
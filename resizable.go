@@ -0,0 +1,307 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// Defaults
+const (
+	// cSCALESAMPLE is default sampling rate of
+	// operations observed by a `ScalePolicy`.
+	cSCALESAMPLE = 16
+)
+
+var (
+	// ErrResize is returned when `Grow` would not
+	// grow or `Shrink` would not shrink a ring.
+	ErrResize = errors.New("lfring: invalid resize capacity")
+)
+
+// - MARK: Resizable section.
+
+// Resizable is a MPMC ring whose capacity can be
+// changed while in use. Resizing seals current
+// ring and links a successor of the new capacity,
+// like segments of `Unbounded`: producers move on
+// to the successor right away, while consumers
+// drain the sealed ring first and then advance,
+// helping each other forward. Items are not
+// copied, so a resize never blocks or races with
+// operations in flight, and items keep their
+// order. The price is memory: rather than
+// migrating items into the successor, e.g. with
+// RDCSS, a sealed ring stays allocated until it
+// drains, so `Shrink` frees nothing before then
+// and old and new rings coexist meanwhile.
+type Resizable struct {
+	// 64bit aligned
+	resizes uint64 // completed resizes
+	_       CacheLinePad
+	head    atomic.Pointer[generation] // consumers
+	_       CacheLinePad
+	tail    atomic.Pointer[generation] // producers
+	_       CacheLinePad
+	opts    []Option
+	scale   *scaler // capacity policy, nil when disabled
+}
+
+// generation is a ring linked to its successor.
+type generation struct {
+	ring *Ring
+	next atomic.Pointer[generation]
+}
+
+// NewResizable allocates and initializes a new
+// `Resizable` of `capacity` slots, built with
+// `opts`, and returns a pointer to it. Rings are
+// sealed on resize, which requires producers to
+// claim slots by CAS; it panics on `SPSC` mode.
+func NewResizable(capacity uint64, opts ...Option) *Resizable {
+	r := NewRing(capacity, opts...)
+	if r.Mode() == SPSC {
+		panic("lfring: resizable ring requires multiple producers mode")
+	}
+	gen := &generation{ring: r}
+	q := &Resizable{opts: opts}
+	q.head.Store(gen)
+	q.tail.Store(gen)
+	return q
+}
+
+// Cap returns capacity of current ring. While
+// older rings drain, `Len` may exceed it.
+func (q *Resizable) Cap() uint64 {
+	return q.tail.Load().ring.Cap()
+}
+
+// Len returns number of items in all rings.
+func (q *Resizable) Len() uint64 {
+	var n uint64
+	for g := q.head.Load(); g != nil; g = g.next.Load() {
+		n += g.ring.Len()
+	}
+	return n
+}
+
+// Resizes returns number of completed resizes.
+func (q *Resizable) Resizes() uint64 {
+	return atomic.LoadUint64(&q.resizes)
+}
+
+// Push appends `data` to current ring and returns
+// false when it is full.
+func (q *Resizable) Push(data interface{}) bool {
+	for {
+		tail := q.tail.Load()
+		if tail.ring.Push(data) {
+			if q.scale != nil {
+				q.scale.observe(q, tail.ring)
+			}
+			return true
+		}
+		next := tail.next.Load()
+		if next == nil {
+			if q.scale != nil {
+				q.scale.observe(q, tail.ring)
+			}
+			return false
+		}
+		// sealed by a resize; help tail forward.
+		q.tail.CompareAndSwap(tail, next)
+	}
+}
+
+// Pop removes and returns the oldest item with a
+// boolean indicating success status.
+func (q *Resizable) Pop() (interface{}, bool) {
+	for {
+		head := q.head.Load()
+		if v, ok := head.ring.Pop(); ok {
+			if q.scale != nil {
+				q.scale.observe(q, q.tail.Load().ring)
+			}
+			return v, true
+		}
+		next := head.next.Load()
+		if next == nil {
+			if q.scale != nil {
+				q.scale.observe(q, head.ring)
+			}
+			return nil, false
+		}
+		// once sealed, write index of head is
+		// final; items still being published are
+		// waited for.
+		if atomic.LoadUint64(&head.ring.wri)&cWRCLOSED == 0 || head.ring.readIndex() != head.ring.writeIndex() {
+			return nil, false
+		}
+		q.head.CompareAndSwap(head, next)
+	}
+}
+
+// Grow resizes ring to `capacity`, rounded to
+// power of two. It returns `ErrResize` unless the
+// result exceeds current capacity.
+func (q *Resizable) Grow(capacity uint64) error {
+	return q.resize(capacity, true)
+}
+
+// Shrink resizes ring to `capacity`, rounded to
+// power of two. It returns `ErrResize` unless the
+// result is below current capacity. Items stay in
+// the sealed ring, which is freed once they are
+// consumed.
+func (q *Resizable) Shrink(capacity uint64) error {
+	return q.resize(capacity, false)
+}
+
+// resize links a successor of `capacity` to
+// current ring and seals it. Capacity is checked
+// before the successor is allocated, so a resize
+// losing to a concurrent one rarely allocates.
+func (q *Resizable) resize(capacity uint64, grow bool) error {
+	var gen *generation
+	size := roundP2(capacity)
+	for {
+		tail := q.tail.Load()
+		if next := tail.next.Load(); next != nil {
+			// concurrent resize; help and compare
+			// against its capacity.
+			q.tail.CompareAndSwap(tail, next)
+			continue
+		}
+		if c := tail.ring.Cap(); (grow && size <= c) || (!grow && size >= c) {
+			return ErrResize
+		}
+		if gen == nil {
+			r, err := NewRingChecked(capacity, q.opts...)
+			if err != nil {
+				return err
+			}
+			gen = &generation{ring: r}
+			continue
+		}
+		// link before sealing, so producers failing
+		// on the sealed ring find the successor.
+		if !tail.next.CompareAndSwap(nil, gen) {
+			continue
+		}
+		tail.ring.close()
+		q.tail.CompareAndSwap(tail, gen)
+		atomic.AddUint64(&q.resizes, 1)
+		return nil
+	}
+}
+
+// - MARK: Scaling section.
+
+// ScalePolicy resizes a `Resizable` from its
+// occupancy: capacity doubles once occupancy of
+// current ring stayed at or above `High` for
+// `Window` consecutive observations, and halves
+// once it stayed below `Low` as long. Occupancy
+// is `Len` as a fraction of current capacity.
+// One in `Sample` operations is observed, failed
+// pushes and pops included, so pushes and pops
+// mostly skip the shared counters. `Low` must be
+// below half of `High`, so a halved ring does not
+// immediately qualify for growth again.
+type ScalePolicy struct {
+	High   float64 // grow watermark, e.g. 0.75
+	Low    float64 // shrink watermark, e.g. 0.25
+	Window uint64  // consecutive observations, 0 means 1
+	Sample uint64  // sampling rate, 0 means `cSCALESAMPLE`
+	Min    uint64  // capacity floor, 0 means none
+	Max    uint64  // capacity cap, 0 means `MaxCapacity`
+}
+
+// scaler applies a `ScalePolicy`.
+type scaler struct {
+	ScalePolicy
+	above uint64 // consecutive observations above high
+	below uint64 // consecutive observations below low
+}
+
+// SetScalePolicy enables automatic resizing with
+// `p`; nil disables it. It panics when watermarks
+// leave no hysteresis. It must be called before
+// ring is shared.
+func (q *Resizable) SetScalePolicy(p *ScalePolicy) {
+	if p == nil {
+		q.scale = nil
+		return
+	}
+	if p.High <= 0 || p.High > 1 || p.Low < 0 || p.Low*2 >= p.High {
+		panic("lfring: invalid scale policy watermarks")
+	}
+	s := &scaler{ScalePolicy: *p}
+	if s.Window == 0 {
+		s.Window = 1
+	}
+	if s.Sample == 0 {
+		s.Sample = cSCALESAMPLE
+	}
+	if s.Max == 0 || s.Max > MaxCapacity {
+		s.Max = MaxCapacity
+	}
+	q.scale = s
+}
+
+// observe samples an operation on current ring
+// `r` and resizes once a watermark held for
+// window.
+func (s *scaler) observe(q *Resizable, r *Ring) {
+	if s.Sample > 1 && rnd.Uint64()%s.Sample != 0 {
+		return
+	}
+	var (
+		c   uint64  = r.Cap()
+		occ float64 = float64(q.Len()) / float64(c)
+	)
+	switch {
+	case occ >= s.High:
+		atomic.StoreUint64(&s.below, 0)
+		if atomic.AddUint64(&s.above, 1) < s.Window || c >= s.Max {
+			return
+		}
+		atomic.StoreUint64(&s.above, 0)
+		// a racing resize makes this one fail
+		// with `ErrResize`.
+		q.Grow(c << 1)
+	case occ < s.Low:
+		atomic.StoreUint64(&s.above, 0)
+		if atomic.AddUint64(&s.below, 1) < s.Window || c>>1 < s.Min || c == 1 {
+			return
+		}
+		atomic.StoreUint64(&s.below, 0)
+		q.Shrink(c >> 1)
+	default:
+		atomic.StoreUint64(&s.above, 0)
+		atomic.StoreUint64(&s.below, 0)
+	}
+}
@@ -0,0 +1,176 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestResizableSerial(t *testing.T) {
+	var q *Resizable = NewResizable(4)
+	for i := 0; i < 4; i++ {
+		q.Push(i)
+	}
+	if q.Push(4) {
+		t.Fatal("assertion failed, pushed to a full ring.")
+	}
+	if err := q.Grow(3); err != ErrResize {
+		t.Fatalf("assertion failed, expected ErrResize, got %v.", err)
+	}
+	// a rejected resize allocates no ring
+	assertNoAllocs(t, "Grow", func() { q.Grow(4) })
+	assertNoAllocs(t, "Shrink", func() { q.Shrink(8) })
+	if err := q.Grow(16); err != nil || q.Cap() != 16 || q.Resizes() != 1 {
+		t.Fatalf("assertion failed, cap(%d), err(%v).", q.Cap(), err)
+	}
+	for i := 4; i < 20; i++ {
+		if !q.Push(i) {
+			t.Fatalf("inconsistent state, unable to push %d.", i)
+		}
+	}
+	if q.Len() != 20 {
+		t.Fatalf("assertion failed, len(%d)!=20.", q.Len())
+	}
+	// shrunk ring takes items once drained
+	if err := q.Shrink(2); err != nil || q.Cap() != 2 {
+		t.Fatalf("assertion failed, cap(%d), err(%v).", q.Cap(), err)
+	}
+	q.Push(20)
+	for i := 0; i <= 20; i++ {
+		if v, ok := q.Pop(); !ok || v.(int) != i {
+			t.Fatalf("assertion failed, expected %d, got %v.", i, v)
+		}
+	}
+	if _, ok := q.Pop(); ok || q.Len() != 0 {
+		t.Fatal("assertion failed, expected empty ring.")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("assertion failed, expected panic in SPSC mode.")
+		}
+	}()
+	NewResizable(4, WithMode(SPSC))
+}
+
+func TestResizableConcurrent(t *testing.T) {
+	const (
+		producers = 4
+		items     = 2000
+	)
+	var (
+		q    *Resizable = NewResizable(2)
+		wg   sync.WaitGroup
+		last [producers]int
+	)
+	for p := 0; p < producers; p++ {
+		last[p] = -1
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < items; i++ {
+				for !q.Push([2]int{p, i}) {
+					runtime.Gosched()
+				}
+			}
+		}(p)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for c := uint64(4); c <= 256; c <<= 1 {
+			q.Grow(c)
+			runtime.Gosched()
+		}
+		q.Shrink(8)
+	}()
+	for n := 0; n < producers*items; {
+		v, ok := q.Pop()
+		if !ok {
+			runtime.Gosched()
+			continue
+		}
+		item := v.([2]int)
+		if item[1] != last[item[0]]+1 {
+			t.Fatalf("assertion failed, producer %d: %d after %d.", item[0], item[1], last[item[0]])
+		}
+		last[item[0]] = item[1]
+		n++
+	}
+	wg.Wait()
+	if q.Len() != 0 || q.Resizes() != 8 {
+		t.Fatalf("assertion failed, len(%d), resizes(%d).", q.Len(), q.Resizes())
+	}
+}
+
+func TestResizableScale(t *testing.T) {
+	var q *Resizable = NewResizable(4)
+	q.SetScalePolicy(&ScalePolicy{High: 0.75, Low: 0.25, Window: 3, Sample: 1, Min: 2, Max: 16})
+	// a short burst does not resize
+	for i := 0; i < 3; i++ {
+		q.Push(i)
+	}
+	q.Pop()
+	if q.Cap() != 4 {
+		t.Fatalf("assertion failed, cap(%d)!=4.", q.Cap())
+	}
+	// sustained load grows up to max
+	for i := 0; i < 64; i++ {
+		q.Push(i)
+	}
+	if q.Cap() != 16 {
+		t.Fatalf("assertion failed, cap(%d)!=16.", q.Cap())
+	}
+	// sustained idleness shrinks down to min
+	for i := 0; i < 64; i++ {
+		q.Pop()
+	}
+	for i := 0; i < 16; i++ {
+		q.Pop()
+	}
+	if q.Cap() != 2 || q.Len() != 0 {
+		t.Fatalf("assertion failed, cap(%d), len(%d).", q.Cap(), q.Len())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("assertion failed, expected panic without hysteresis.")
+		}
+	}()
+	q.SetScalePolicy(&ScalePolicy{High: 0.5, Low: 0.3})
+}
+
+func TestResizableScaleSampled(t *testing.T) {
+	var q *Resizable = NewResizable(4)
+	// operations which are not sampled leave
+	// capacity alone
+	q.SetScalePolicy(&ScalePolicy{High: 0.5, Low: 0.2, Sample: 1 << 62})
+	for i := 0; i < 64; i++ {
+		q.Push(i)
+	}
+	if q.Cap() != 4 || q.scale.above != 0 {
+		t.Fatalf("assertion failed, cap(%d), above(%d).", q.Cap(), q.scale.above)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "time"
+
+// - MARK: Retry section.
+
+// Defaults
+const (
+	// cRETRYTICK is resolution of delayed redelivery.
+	cRETRYTICK = time.Millisecond
+	// cRETRYBUCKETS is number of timer wheel buckets.
+	cRETRYBUCKETS = 512
+	// cRETRYBUCKETCAP is capacity of each bucket.
+	cRETRYBUCKETCAP = 256
+)
+
+// RetryPolicy describes how managed runners
+// retry items whose handler failed.
+type RetryPolicy struct {
+	// MaxAttempts is total number of handler
+	// invocations per item; values below 1
+	// mean a single attempt.
+	MaxAttempts int
+	// Backoff returns delay before redelivering
+	// an item after `attempt` failures. Nil
+	// redelivers immediately.
+	Backoff func(attempt int) time.Duration
+	// Retryable reports whether `err` is worth
+	// retrying. Nil retries every error.
+	Retryable func(err error) bool
+}
+
+// retryable returns whether an item which failed
+// `attempts` times with `err` should be retried.
+func (p *RetryPolicy) retryable(attempts int, err error) bool {
+	if attempts >= p.MaxAttempts {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// ExponentialBackoff returns a backoff curve
+// starting at `base` and doubling on every attempt
+// up to `max`.
+func ExponentialBackoff(base, max time.Duration) func(int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d <<= 1
+		}
+		if d > max {
+			d = max
+		}
+		return d
+	}
+}
+
+// ConstantBackoff returns a backoff curve which
+// always waits `d`.
+func ConstantBackoff(d time.Duration) func(int) time.Duration {
+	return func(int) time.Duration { return d }
+}
+
+// retryEntry is an item awaiting redelivery.
+type retryEntry struct {
+	item     interface{}
+	attempts int
+}
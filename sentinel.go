@@ -0,0 +1,146 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+)
+
+// Defaults
+const (
+	// cSENTINELRATE is default sampling rate of
+	// sentinel mode, one in `cSENTINELRATE` ops.
+	cSENTINELRATE = 1000000
+)
+
+// - MARK: Sentinel section.
+
+// Anomaly describes a violated ring invariant.
+type Anomaly struct {
+	Check  string // name of failed check
+	Detail string // observed state
+}
+
+// Error implements `error` interface.
+func (a *Anomaly) Error() string {
+	return fmt.Sprintf("lfring: invariant %s violated: %s", a.Check, a.Detail)
+}
+
+// Sentinel is a production-safe soak mode which
+// samples ring operations at random and audits
+// indices, counter and every slot sequence of
+// the ring on sampled operations. Violations are
+// reported to `OnAnomaly`, giving early warning
+// of corruption in long-lived services. Audits
+// run on the sampled goroutine; with the default
+// rate their cost is negligible.
+type Sentinel struct {
+	// 64bit aligned
+	Rate      uint64         // sample one in `Rate` ops, 0 means default
+	samples   uint64         // audits performed
+	anomalies uint64         // violations found
+	OnAnomaly func(*Anomaly) // failure hook, may be nil
+}
+
+// SetSentinel enables sentinel mode with `s`, nil
+// disables it. It must be called before ring is
+// shared.
+func (r *Ring) SetSentinel(s *Sentinel) {
+	if s != nil && s.Rate == 0 {
+		s.Rate = cSENTINELRATE
+	}
+	r.sentinel = s
+	r.hook()
+}
+
+// Stats returns number of audits performed and
+// violations found.
+func (s *Sentinel) Stats() (samples, anomalies uint64) {
+	return atomic.LoadUint64(&s.samples), atomic.LoadUint64(&s.anomalies)
+}
+
+// sample audits ring with probability
+// `1/sentinel.Rate` and reports violations.
+func (r *Ring) sample() {
+	s := r.sentinel
+	if rnd.Uint64()%s.Rate != 0 {
+		return
+	}
+	atomic.AddUint64(&s.samples, 1)
+	for _, a := range r.Audit() {
+		atomic.AddUint64(&s.anomalies, 1)
+		if s.OnAnomaly != nil {
+			s.OnAnomaly(a)
+		}
+	}
+}
+
+// Audit checks ring invariants against a racy
+// view of its state and returns violations. Checks
+// only flag states which are impossible under any
+// interleaving, with a slack of `GOMAXPROCS` for
+// the occupancy counter which is updated after
+// slots are published or released.
+func (r *Ring) Audit() []*Anomaly {
+	var (
+		anomalies []*Anomaly
+		mask      uint64 = r.size - 1
+		slack     int64  = int64(runtime.GOMAXPROCS(0))
+		rdi       uint64
+		wri       uint64
+		count     int64
+	)
+	report := func(check, format string, args ...interface{}) {
+		anomalies = append(anomalies, &Anomaly{Check: check, Detail: fmt.Sprintf(format, args...)})
+	}
+	// read-index never passes write-index
+	rdi = r.readIndex()
+	if wri = r.writeIndex(); rdi > wri {
+		report("order", "rdi(%d) > wri(%d)", rdi, wri)
+	}
+	// at most `size` positions are in flight
+	wri = r.writeIndex()
+	if rdi = r.readIndex(); wri > rdi && wri-rdi > r.size {
+		report("bounds", "wri(%d)-rdi(%d) > size(%d)", wri, rdi, r.size)
+	}
+	count = int64(atomic.LoadUint64(&r.count))
+	if count < -slack || count > int64(r.size)+slack {
+		report("count", "count(%d) outside [0, %d]", count, r.size)
+	}
+	// slot `i` is free for position `pos` or
+	// publishes it, where `pos & mask == i`.
+	for i := uint64(0); i < r.size; i++ {
+		seq := atomic.LoadUint64(r.seq(i))
+		if d := (seq - i) & mask; d > 1 && r.size > 1 {
+			report("slot", "slot(%d) holds foreign sequence(%d)", i, seq)
+		}
+		if seq >= r.writeIndex()+r.size {
+			report("slot", "slot(%d) sequence(%d) ahead of wri", i, seq)
+		}
+	}
+	return anomalies
+}
@@ -0,0 +1,88 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSentinelAudit(t *testing.T) {
+	var (
+		r   *Ring = NewRing(8)
+		got []*Anomaly
+	)
+	for i := 0; i < 5; i++ {
+		r.Push(i)
+	}
+	r.Pop()
+	if a := r.Audit(); len(a) != 0 {
+		t.Fatalf("assertion failed, unexpected anomalies %v.", a)
+	}
+	// corrupt a slot sequence
+	r.seqs[6] = 3
+	r.SetSentinel(&Sentinel{Rate: 1, OnAnomaly: func(a *Anomaly) {
+		got = append(got, a)
+	}})
+	r.Push(5)
+	if len(got) != 1 || got[0].Check != "slot" {
+		t.Fatalf("assertion failed, anomalies %v.", got)
+	}
+	if samples, anomalies := r.sentinel.Stats(); samples != 1 || anomalies != 1 {
+		t.Fatalf("assertion failed, samples(%d), anomalies(%d).", samples, anomalies)
+	}
+}
+
+func TestSentinelConcurrent(t *testing.T) {
+	const items = 20000
+	var (
+		r  *Ring     = NewRing(16)
+		s  *Sentinel = &Sentinel{Rate: 64}
+		wg sync.WaitGroup
+	)
+	s.OnAnomaly = func(a *Anomaly) {
+		t.Errorf("assertion failed, false positive: %v.", a)
+	}
+	r.SetSentinel(s)
+	for p := 0; p < 2; p++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < items; i++ {
+				r.Push(i)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < items; i++ {
+				r.Pop()
+			}
+		}()
+	}
+	wg.Wait()
+	if samples, _ := s.Stats(); samples == 0 {
+		t.Fatal("assertion failed, no operation sampled.")
+	}
+}
@@ -0,0 +1,333 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+)
+
+// - MARK: Sequence section.
+
+// Sequence is a cursor padded onto its own cache
+// line. It holds the number of sequences claimed,
+// published or processed, i.e. the next sequence
+// to be handled.
+type Sequence struct {
+	_     CacheLinePad
+	value uint64
+	_     CacheLinePad
+}
+
+// Get atomically loads sequence value.
+func (s *Sequence) Get() uint64 {
+	return atomic.LoadUint64(&s.value)
+}
+
+// Set atomically stores `v`.
+func (s *Sequence) Set(v uint64) {
+	atomic.StoreUint64(&s.value, v)
+}
+
+// - MARK: Sequencer section.
+
+// Sequencer coordinates access to a ring of
+// `Cap()` slots owned by the caller, LMAX
+// Disruptor style. Producers claim sequences with
+// `Next`, fill slots `Index(seq)` and `Publish`
+// them. Consumers track their own `Sequence` and
+// wait on a `Barrier` made of upstream cursors,
+// which lets dependent stages (e.g. journal ->
+// replicate -> apply) process the same slots
+// in order without copying. Producers never wrap
+// past the slowest gating sequence.
+type Sequencer struct {
+	_      CacheLinePad
+	cursor uint64 // claimed sequences
+	_      CacheLinePad
+	cached uint64 // cached minimum gating sequence
+	_      CacheLinePad
+	pubd   uint64 // contiguously published sequences
+	_      CacheLinePad
+	size   uint64                      // ring size, pow2
+	avail  []uint64                    // per-slot published sequence + 1
+	gating atomic.Pointer[[]*Sequence] // copy-on-write
+	wait   WaitStrategy                // waiting of producers and barriers
+	signal Signaler                    // parking wait strategy, or nil
+}
+
+// NewSequencer allocates and initializes a new
+// `Sequencer` and returns a pointer to it. Note,
+// `capacity` is always rounded to nearest power
+// of two.
+func NewSequencer(capacity uint64) *Sequencer {
+	s := &Sequencer{size: roundP2(capacity), wait: DefaultWaitStrategy}
+	s.avail = make([]uint64, s.size)
+	gating := make([]*Sequence, 0)
+	s.gating.Store(&gating)
+	return s
+}
+
+// SetWaitStrategy sets wait strategy of producers
+// in `Next` and consumers in `Barrier.WaitFor`. It
+// must be called before sequencer is shared.
+func (s *Sequencer) SetWaitStrategy(w WaitStrategy) {
+	s.wait = w
+	s.signal, _ = w.(Signaler)
+}
+
+// Cap returns number of slots.
+func (s *Sequencer) Cap() uint64 {
+	return s.size
+}
+
+// Index returns slot index of sequence `seq`.
+func (s *Sequencer) Index(seq uint64) uint64 {
+	return seq & (s.size - 1)
+}
+
+// Cursor returns number of claimed sequences.
+func (s *Sequencer) Cursor() uint64 {
+	return atomic.LoadUint64(&s.cursor)
+}
+
+// AddGating registers consumer sequences which
+// producers must not overtake. Typically these
+// are the cursors of last stage consumers. A
+// registered sequence should start at `Cursor()`.
+func (s *Sequencer) AddGating(seqs ...*Sequence) {
+	for {
+		old := s.gating.Load()
+		next := make([]*Sequence, 0, len(*old)+len(seqs))
+		next = append(append(next, *old...), seqs...)
+		if s.gating.CompareAndSwap(old, &next) {
+			return
+		}
+	}
+}
+
+// RemoveGating unregisters gating sequence `seq`.
+func (s *Sequencer) RemoveGating(seq *Sequence) {
+	for {
+		old := s.gating.Load()
+		next := make([]*Sequence, 0, len(*old))
+		for _, g := range *old {
+			if g != seq {
+				next = append(next, g)
+			}
+		}
+		if s.gating.CompareAndSwap(old, &next) {
+			return
+		}
+	}
+}
+
+// Next claims `n` consecutive sequences and
+// returns the first one, spinning while the ring
+// has no room. `n` must not exceed `Cap()`.
+func (s *Sequencer) Next(n uint64) uint64 {
+	for i := 0; ; i++ {
+		if lo, ok := s.TryNext(n); ok {
+			return lo
+		}
+		s.wait.Wait(i)
+	}
+}
+
+// TryNext claims `n` consecutive sequences and
+// returns the first one with a boolean which is
+// false when the ring has no room.
+func (s *Sequencer) TryNext(n uint64) (uint64, bool) {
+	for {
+		cur := atomic.LoadUint64(&s.cursor)
+		next := cur + n
+		if next > s.size {
+			// wrap point must not pass slowest
+			// consumer; refresh cache on miss.
+			wrap := next - s.size
+			if wrap > atomic.LoadUint64(&s.cached) {
+				min := s.minGating(cur)
+				atomic.StoreUint64(&s.cached, min)
+				if wrap > min {
+					return 0, false
+				}
+			}
+		}
+		if atomic.CompareAndSwapUint64(&s.cursor, cur, next) {
+			return cur, true
+		}
+	}
+}
+
+// Publish makes `n` sequences starting at `lo`
+// visible to consumers. Producers may publish
+// their claims in any order; consumers still see
+// sequences in order. Slots carry availability
+// flags, so a batch published ahead of a slower
+// earlier one waits only for it, and the
+// producer completing the earliest pending batch
+// exposes every later published one at once.
+func (s *Sequencer) Publish(lo, n uint64) {
+	for seq := lo; seq < lo+n; seq++ {
+		atomic.StoreUint64(&s.avail[seq&(s.size-1)], seq+1)
+	}
+	// only the producer at the head of published
+	// run advances it; later ones are picked up.
+	if lo <= atomic.LoadUint64(&s.pubd) {
+		s.advance()
+	}
+	if s.signal != nil {
+		s.signal.Signal()
+	}
+}
+
+// Published returns number of contiguously
+// published sequences, i.e. the end of the run
+// visible to consumers without dependencies.
+func (s *Sequencer) Published() uint64 {
+	return atomic.LoadUint64(&s.pubd)
+}
+
+// advance moves published run past available
+// slots. A producer storing its flags after the
+// scan sees the run reach its sequence and takes
+// over advancing.
+func (s *Sequencer) advance() {
+	for {
+		var (
+			lo  uint64 = atomic.LoadUint64(&s.pubd)
+			cur uint64 = atomic.LoadUint64(&s.cursor)
+			hi  uint64 = lo
+		)
+		// a slot may already carry a later lap when
+		// producers are not gated; its sequence was
+		// claimed past, so it counts as published.
+		for hi < cur && atomic.LoadUint64(&s.avail[hi&(s.size-1)]) >= hi+1 {
+			hi++
+		}
+		if hi == lo {
+			return
+		}
+		atomic.CompareAndSwapUint64(&s.pubd, lo, hi)
+	}
+}
+
+// IsAvailable returns whether sequence `seq` is
+// published.
+func (s *Sequencer) IsAvailable(seq uint64) bool {
+	return atomic.LoadUint64(&s.avail[seq&(s.size-1)]) == seq+1
+}
+
+// highestPublished returns the first sequence in
+// `[lo, hi)` which is not yet published, or `hi`.
+func (s *Sequencer) highestPublished(lo, hi uint64) uint64 {
+	for seq := lo; seq < hi; seq++ {
+		if !s.IsAvailable(seq) {
+			return seq
+		}
+	}
+	return hi
+}
+
+// minGating returns minimum of gating sequences
+// and `min`.
+func (s *Sequencer) minGating(min uint64) uint64 {
+	for _, g := range *s.gating.Load() {
+		if v := g.Get(); v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// NewBarrier returns a barrier over `deps`. With
+// no dependencies, the barrier tracks published
+// sequences only; otherwise sequences must also
+// be processed by every upstream consumer.
+func (s *Sequencer) NewBarrier(deps ...*Sequence) *Barrier {
+	return &Barrier{seq: s, deps: deps}
+}
+
+// - MARK: Barrier section.
+
+// Barrier lets a consumer wait until sequences are
+// published and processed by its dependencies.
+type Barrier struct {
+	seq     *Sequencer
+	deps    []*Sequence
+	alerted uint32
+}
+
+// WaitFor spins until sequence `seq` is available
+// and returns the end (exclusive) of the available
+// run starting at `seq`, so a consumer can process
+// `[seq, end)` as a batch. It returns false when
+// barrier is alerted.
+func (b *Barrier) WaitFor(seq uint64) (uint64, bool) {
+	for i := 0; ; i++ {
+		if end, ok := b.TryWaitFor(seq); ok {
+			return end, true
+		}
+		if atomic.LoadUint32(&b.alerted) != 0 {
+			return 0, false
+		}
+		b.seq.wait.Wait(i)
+	}
+}
+
+// TryWaitFor is the non-blocking variant of
+// `WaitFor`; it returns false when `seq` is not
+// available yet.
+func (b *Barrier) TryWaitFor(seq uint64) (uint64, bool) {
+	var end uint64 = atomic.LoadUint64(&b.seq.cursor)
+	for _, d := range b.deps {
+		if v := d.Get(); v < end {
+			end = v
+		}
+	}
+	if end <= seq {
+		return 0, false
+	}
+	if pubd := atomic.LoadUint64(&b.seq.pubd); pubd > seq {
+		if pubd < end {
+			end = pubd
+		}
+		return end, true
+	}
+	// sequence past published run, e.g. after a
+	// consumer reset; stop at first gap.
+	end = b.seq.highestPublished(seq, end)
+	return end, end > seq
+}
+
+// Alert wakes up and fails current and future
+// `WaitFor` calls, e.g. on shutdown.
+func (b *Barrier) Alert() {
+	atomic.StoreUint32(&b.alerted, 1)
+}
+
+// ClearAlert resets alert status.
+func (b *Barrier) ClearAlert() {
+	atomic.StoreUint32(&b.alerted, 0)
+}
@@ -0,0 +1,207 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestSequencerSerial(t *testing.T) {
+	var (
+		s   *Sequencer = NewSequencer(4)
+		c   *Sequence  = &Sequence{}
+		b   *Barrier   = s.NewBarrier()
+		end uint64
+		ok  bool
+	)
+	s.AddGating(c)
+	if _, ok = b.TryWaitFor(0); ok {
+		t.Fatal("inconsistent state, waited on unpublished sequence.")
+	}
+	lo := s.Next(3)
+	s.Publish(lo+1, 2)
+	// sequence 0 is claimed but not published
+	if _, ok = b.TryWaitFor(0); ok {
+		t.Fatal("assertion failed, barrier passed a gap.")
+	}
+	s.Publish(lo, 1)
+	if end, ok = b.TryWaitFor(0); !ok || end != 3 {
+		t.Fatalf("assertion failed, end(%d)!=3.", end)
+	}
+	// one free slot left until consumer moves
+	if _, ok = s.TryNext(2); ok {
+		t.Fatal("assertion failed, producer overtook consumer.")
+	}
+	c.Set(end)
+	if lo, ok = s.TryNext(4); !ok || lo != 3 || s.Index(lo) != 3 {
+		t.Fatalf("assertion failed, lo(%d).", lo)
+	}
+	b.Alert()
+	if _, ok = b.WaitFor(3); ok {
+		t.Fatal("assertion failed, alerted barrier returned.")
+	}
+}
+
+func TestSequencerPipeline(t *testing.T) {
+	const (
+		producers = 2
+		items     = 5000
+	)
+	var (
+		s         *Sequencer = NewSequencer(64)
+		buf       []int      = make([]int, s.Cap())
+		journal   *Sequence  = &Sequence{}
+		apply     *Sequence  = &Sequence{}
+		jb        *Barrier   = s.NewBarrier()
+		ab        *Barrier   = s.NewBarrier(journal)
+		journaled []int
+		wg        sync.WaitGroup
+	)
+	s.AddGating(apply)
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < items; i++ {
+				seq := s.Next(1)
+				buf[s.Index(seq)] = int(seq)
+				s.Publish(seq, 1)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for next := uint64(0); next < producers*items; {
+			end, _ := jb.WaitFor(next)
+			for ; next < end; next++ {
+				journaled = append(journaled, buf[s.Index(next)])
+			}
+			journal.Set(end)
+		}
+	}()
+	for next := uint64(0); next < producers*items; {
+		end, _ := ab.WaitFor(next)
+		if end > journal.Get() {
+			t.Fatal("assertion failed, apply overtook journal.")
+		}
+		for ; next < end; next++ {
+			if buf[s.Index(next)] != int(next) {
+				t.Fatalf("assertion failed, slot %d holds %d.", next, buf[s.Index(next)])
+			}
+		}
+		apply.Set(end)
+	}
+	wg.Wait()
+	if len(journaled) != producers*items {
+		t.Fatalf("assertion failed, journaled %d.", len(journaled))
+	}
+}
+
+func TestSequencerPublishOrder(t *testing.T) {
+	var (
+		s *Sequencer = NewSequencer(16)
+		b *Barrier   = s.NewBarrier()
+	)
+	a, bb, c := s.Next(4), s.Next(4), s.Next(4)
+	s.Publish(c, 4)
+	s.Publish(bb, 4)
+	if _, ok := b.TryWaitFor(0); ok || s.Published() != 0 {
+		t.Fatal("assertion failed, barrier passed a slow batch.")
+	}
+	// later batches are visible past their start
+	if end, ok := b.TryWaitFor(bb); !ok || end != c+4 {
+		t.Fatalf("assertion failed, end(%d)!=%d.", end, c+4)
+	}
+	// completing earliest batch exposes all
+	s.Publish(a+1, 3)
+	if s.Published() != 0 {
+		t.Fatalf("assertion failed, published(%d)!=0.", s.Published())
+	}
+	s.Publish(a, 1)
+	if end, ok := b.TryWaitFor(0); !ok || end != 12 || s.Published() != 12 {
+		t.Fatalf("assertion failed, end(%d), published(%d).", end, s.Published())
+	}
+}
+
+func TestSequencerBatches(t *testing.T) {
+	const (
+		producers = 4
+		batches   = 500
+	)
+	var (
+		s    *Sequencer = NewSequencer(64)
+		buf  []uint64   = make([]uint64, s.Cap())
+		c    *Sequence  = &Sequence{}
+		b    *Barrier   = s.NewBarrier()
+		wg   sync.WaitGroup
+		next uint64
+	)
+	s.AddGating(c)
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < batches; i++ {
+				n := uint64(1 + (i+p)%5)
+				lo := s.Next(n)
+				for seq := lo; seq < lo+n; seq++ {
+					buf[s.Index(seq)] = seq
+				}
+				if i%7 == p {
+					runtime.Gosched()
+				}
+				s.Publish(lo, n)
+			}
+		}(p)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		end, ok := b.TryWaitFor(next)
+		if !ok {
+			select {
+			case <-done:
+				if next == s.Cursor() {
+					return
+				}
+			default:
+			}
+			runtime.Gosched()
+			continue
+		}
+		for ; next < end; next++ {
+			if buf[s.Index(next)] != next {
+				t.Fatalf("assertion failed, slot %d holds %d.", next, buf[s.Index(next)])
+			}
+		}
+		c.Set(end)
+	}
+}
@@ -0,0 +1,149 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// - MARK: ShardedRing section.
+
+// ShardedRing stripes items across rings, so
+// producers on different processors touch
+// different cursors instead of contending on
+// one. Producers pick a shard by a per-P hint,
+// see `shardHint`, and spill to other shards
+// when theirs is full; consumers round robin, or
+// drain a shard of their own and steal from the
+// others when it is empty. Items are FIFO per
+// shard only.
+type ShardedRing struct {
+	_      CacheLinePad
+	next   uint64 // round robin position of `Pop`
+	seed   uint64 // next shard of a new hint
+	_      CacheLinePad
+	shards []*Ring
+	hints  sync.Pool // *shardHint
+}
+
+// shardHint is the shard of producers running on
+// a P. `sync.Pool` keeps a cache per P, so a hint
+// taken from it mostly stays with the P, a cheap
+// stand-in for pinning to the processor.
+type shardHint struct {
+	shard int
+}
+
+// NewShardedRing allocates and initializes a new
+// `ShardedRing` of `shards` rings, or one per
+// `GOMAXPROCS` when `shards` is not positive,
+// each of `capacity` items. `opts` configure
+// every shard.
+func NewShardedRing(shards int, capacity uint64, opts ...Option) *ShardedRing {
+	if shards < 1 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	s := &ShardedRing{shards: make([]*Ring, shards)}
+	for i := range s.shards {
+		s.shards[i] = NewRing(capacity, opts...)
+	}
+	s.hints.New = func() interface{} {
+		return &shardHint{shard: int((atomic.AddUint64(&s.seed, 1) - 1) % uint64(len(s.shards)))}
+	}
+	return s
+}
+
+// Push appends `data` to shard of current P or,
+// when it is full, to the next shard with room.
+// It returns false when all shards are full.
+func (s *ShardedRing) Push(data interface{}) bool {
+	h := s.hints.Get().(*shardHint)
+	defer s.hints.Put(h)
+	for i := 0; i < len(s.shards); i++ {
+		if s.shards[h.shard].Push(data) {
+			return true
+		}
+		// move hint off the full shard.
+		if h.shard++; h.shard == len(s.shards) {
+			h.shard = 0
+		}
+	}
+	return false
+}
+
+// Pop removes an item, visiting shards in round
+// robin order, and returns false when all shards
+// are empty.
+func (s *ShardedRing) Pop() (interface{}, bool) {
+	start := int((atomic.AddUint64(&s.next, 1) - 1) % uint64(len(s.shards)))
+	return s.PopShard(start)
+}
+
+// PopShard removes an item of shard `i`, modulo
+// number of shards, stealing from the following
+// shards when it is empty, e.g. for a consumer
+// per shard. It returns false when all shards are
+// empty.
+func (s *ShardedRing) PopShard(i int) (interface{}, bool) {
+	i %= len(s.shards)
+	if i < 0 {
+		i += len(s.shards)
+	}
+	for n := 0; n < len(s.shards); n++ {
+		if v, ok := s.shards[i].Pop(); ok {
+			return v, true
+		}
+		if i++; i == len(s.shards) {
+			i = 0
+		}
+	}
+	return nil, false
+}
+
+// Len returns number of items of all shards.
+func (s *ShardedRing) Len() uint64 {
+	var n uint64
+	for _, r := range s.shards {
+		n += r.Len()
+	}
+	return n
+}
+
+// Cap returns capacity of all shards.
+func (s *ShardedRing) Cap() uint64 {
+	return uint64(len(s.shards)) * s.shards[0].Cap()
+}
+
+// Shards returns number of shards.
+func (s *ShardedRing) Shards() int {
+	return len(s.shards)
+}
+
+// Shard returns ring of shard `i`.
+func (s *ShardedRing) Shard(i int) *Ring {
+	return s.shards[i]
+}
@@ -0,0 +1,97 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestShardedRing(t *testing.T) {
+	var s *ShardedRing = NewShardedRing(4, 2)
+	if s.Shards() != 4 || s.Cap() != 8 || NewShardedRing(0, 2).Shards() < 1 {
+		t.Fatalf("assertion failed, shards(%d), cap(%d).", s.Shards(), s.Cap())
+	}
+	// pushes spill over full shards
+	for i := 0; i < 8; i++ {
+		if !s.Push(i) {
+			t.Fatalf("inconsistent state, unable to push %d.", i)
+		}
+	}
+	if s.Push(8) || s.Len() != 8 {
+		t.Fatalf("assertion failed, len(%d), expected full ring.", s.Len())
+	}
+	// a consumer of one shard steals from others
+	seen := make(map[int]bool)
+	for i := 0; i < 8; i++ {
+		v, ok := s.PopShard(-1)
+		if !ok || seen[v.(int)] {
+			t.Fatalf("assertion failed, popped %v.", v)
+		}
+		seen[v.(int)] = true
+	}
+	if _, ok := s.Pop(); ok {
+		t.Fatal("inconsistent state, popped from empty ring.")
+	}
+}
+
+func TestShardedRingConcurrent(t *testing.T) {
+	const (
+		producers = 4
+		count     = 2000
+	)
+	var (
+		s    *ShardedRing = NewShardedRing(3, 16)
+		wg   sync.WaitGroup
+		seen []int = make([]int, producers*count)
+	)
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				for j := 0; !s.Push(p*count + i); j++ {
+					s.Shard(0).pause(j)
+				}
+			}
+		}(p)
+	}
+	for n := 0; n < producers*count; {
+		if v, ok := s.Pop(); ok {
+			seen[v.(int)]++
+			n++
+			continue
+		}
+		s.Shard(0).pause(cRDSCHDTHRESHOLD - 1)
+	}
+	wg.Wait()
+	for v, n := range seen {
+		if n != 1 {
+			t.Fatalf("assertion failed, item %d seen %d times.", v, n)
+		}
+	}
+}
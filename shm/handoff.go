@@ -0,0 +1,121 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package shm
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+)
+
+// Lease states, low bits of the lease word; the
+// remaining bits count generations, so a sealing
+// consumer sees its successor take over.
+const (
+	cLEASEFREE   = 0
+	cLEASEHELD   = 1
+	cLEASESEALED = 2
+	cLEASEMASK   = 3
+)
+
+// - MARK: Handoff section.
+
+// Acquire claims the consumer lease of the ring,
+// waiting while another process holds it, until
+// it is released or sealed by `Handoff`, or `ctx`
+// is done. The lease is advisory: it orders
+// consumers which take part in a handoff, `Pop`
+// does not check it.
+//
+// Handing a persistent ring to a restarted
+// consumer then goes: the new process opens the
+// ring and calls `Acquire`, the old one stops
+// popping, finishes records it popped and calls
+// `Handoff`, which returns once the new process
+// took over. Unread records stay in the segment
+// throughout, so none are lost.
+func (r *Ring) Acquire(ctx context.Context) error {
+	for {
+		w := atomic.LoadUint32(r.lease)
+		if w&cLEASEMASK != cLEASEHELD {
+			held := (w&^cLEASEMASK + cLEASEMASK + 1) | cLEASEHELD
+			if atomic.CompareAndSwapUint32(r.lease, w, held) {
+				r.held = held
+				// wake sealing predecessor
+				futexWake(r.lease, math.MaxInt32)
+				return nil
+			}
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		futexWait(r.lease, w, bound(ctx))
+	}
+}
+
+// Handoff seals the held consumer lease and waits
+// until a successor acquires it. Caller must stop
+// popping and finish records it popped before.
+// When `ctx` is done first, the lease is taken
+// back and the error of `ctx` returned, so the
+// caller keeps consuming.
+func (r *Ring) Handoff(ctx context.Context) error {
+	sealed := r.held&^cLEASEMASK | cLEASESEALED
+	if r.held == 0 || !atomic.CompareAndSwapUint32(r.lease, r.held, sealed) {
+		return ErrLease
+	}
+	futexWake(r.lease, math.MaxInt32)
+	for atomic.LoadUint32(r.lease) == sealed {
+		if err := ctx.Err(); err != nil {
+			if atomic.CompareAndSwapUint32(r.lease, sealed, r.held) {
+				return err
+			}
+			// successor won the race
+			break
+		}
+		futexWait(r.lease, sealed, bound(ctx))
+	}
+	r.held = 0
+	return nil
+}
+
+// Release gives up the held consumer lease
+// without a successor, e.g. on clean shutdown.
+func (r *Ring) Release() {
+	if r.held == 0 {
+		return
+	}
+	if atomic.CompareAndSwapUint32(r.lease, r.held, r.held&^cLEASEMASK|cLEASEFREE) {
+		futexWake(r.lease, math.MaxInt32)
+	}
+	r.held = 0
+}
+
+// Leased returns whether this handle holds the
+// consumer lease.
+func (r *Ring) Leased() bool {
+	return r.held != 0 && atomic.LoadUint32(r.lease) == r.held
+}
@@ -0,0 +1,180 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+// Package shm shares lfring rings between
+// processes. A ring lives in a POSIX shared
+// memory object or a memfd segment and holds only
+// offsets and sequence numbers, never Go
+// pointers. Any number of processes may map it
+// and push or pop concurrently, so it can replace
+// a socket hop with a lock-free queue.
+package shm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/mitghi/lfring"
+)
+
+var (
+	// ErrName is returned for shared memory object
+	// names which are empty or contain a slash.
+	ErrName = errors.New("shm: invalid name")
+	// ErrUnsupported is returned on platforms
+	// without shared memory support and by
+	// `purego` builds.
+	ErrUnsupported = errors.New("shm: not supported")
+	// ErrLease is returned when handing off a
+	// consumer lease which is not held.
+	ErrLease = errors.New("shm: consumer lease not held")
+)
+
+// - MARK: Ring section.
+
+// Defaults
+const (
+	// cPOLL bounds a single wait of `PopWait`, so
+	// consumers fall back to polling when woken
+	// by nobody, e.g. by producers which push
+	// through a plain `lfring.MmapRing`.
+	cPOLL = 10 * time.Millisecond
+)
+
+// Ring is a `lfring.MmapRing` mapped from a shared
+// memory segment. Records are copied in and out
+// of the segment, see `Push` and `Pop`.
+type Ring struct {
+	*lfring.MmapRing
+	mem     []byte
+	f       *os.File
+	wake    *uint32 // wake sequence, a futex word
+	waiters *uint32 // blocked consumers
+	lease   *uint32 // consumer lease, see `Acquire`
+	held    uint32  // lease word held by this handle, 0 none
+}
+
+// Ring carries structured messages through
+// `lfring.NewMessageRing`.
+var _ lfring.RecordRing = (*Ring)(nil)
+
+// Push appends record `p` like `MmapRing.Push`
+// and wakes consumers blocked in `PopWait`, in
+// this or any other process. Without waiters it
+// costs a single load.
+func (r *Ring) Push(p []byte) bool {
+	if !r.MmapRing.Push(p) {
+		return false
+	}
+	if atomic.LoadUint32(r.waiters) != 0 {
+		atomic.AddUint32(r.wake, 1)
+		futexWake(r.wake, 1)
+	}
+	return true
+}
+
+// PopWait removes next record like `Pop`, but
+// blocks until one is available or `ctx` is
+// done instead of spinning. On linux, consumers
+// sleep on a futex word in the segment and are
+// woken by `Push`; elsewhere, and for producers
+// which do not wake, they poll every few
+// milliseconds.
+func (r *Ring) PopWait(ctx context.Context, dst []byte) ([]byte, error) {
+	for {
+		if out, ok := r.Pop(dst); ok {
+			return out, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return dst, err
+		}
+		d := bound(ctx)
+		// announce waiter before re-checking, so a
+		// producer either sees it or the record.
+		seq := atomic.LoadUint32(r.wake)
+		atomic.AddUint32(r.waiters, 1)
+		out, ok := r.Pop(dst)
+		if !ok && d > 0 {
+			futexWait(r.wake, seq, d)
+		}
+		atomic.AddUint32(r.waiters, ^uint32(0))
+		if ok {
+			return out, nil
+		}
+	}
+}
+
+// File returns the file backing the segment, e.g.
+// to pass it to a child process via
+// `exec.Cmd.ExtraFiles` or over a unix socket.
+func (r *Ring) File() *os.File {
+	return r.f
+}
+
+// Close unmaps the segment and closes its file,
+// releasing a held consumer lease. The segment
+// lives on while other processes have it mapped,
+// and a named object lives until `Unlink`.
+func (r *Ring) Close() error {
+	if r.mem == nil {
+		return nil
+	}
+	r.Release()
+	err := unmap(r.mem)
+	r.mem = nil
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// bound returns duration of a single wait, at
+// most `cPOLL` and no later than deadline of
+// `ctx`.
+func bound(ctx context.Context) time.Duration {
+	d := cPOLL
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline); left < d {
+			d = left
+		}
+	}
+	return d
+}
+
+// validName returns whether `name` is a valid
+// shared memory object name.
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] == '/' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+//go:build linux && !purego
+// +build linux,!purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package shm
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/mitghi/lfring"
+)
+
+// cSHMDIR is where linux keeps POSIX shared
+// memory objects, see shm_overview(7).
+const cSHMDIR = "/dev/shm"
+
+// - MARK: Segment section.
+
+// Create creates the shared memory object `name`
+// holding an empty ring of `size` slots of
+// `slotsize` bytes and maps it. It fails with an
+// error matching `os.ErrExist` when the object
+// exists. The ring is formatted before the object
+// becomes visible, so `Open` never sees it
+// partially initialized.
+func Create(name string, size uint64, slotsize int) (*Ring, error) {
+	if !validName(name) {
+		return nil, ErrName
+	}
+	f, err := os.CreateTemp(cSHMDIR, "."+name+".*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	r, err := format(f, size, slotsize)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err = os.Link(f.Name(), filepath.Join(cSHMDIR, name)); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Open maps the existing shared memory object
+// `name` and attaches to the ring it holds.
+func Open(name string) (*Ring, error) {
+	if !validName(name) {
+		return nil, ErrName
+	}
+	f, err := os.OpenFile(filepath.Join(cSHMDIR, name), os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return attach(f)
+}
+
+// Unlink removes the shared memory object `name`.
+// Processes which mapped it keep using it.
+func Unlink(name string) error {
+	if !validName(name) {
+		return ErrName
+	}
+	return os.Remove(filepath.Join(cSHMDIR, name))
+}
+
+// Memfd creates an anonymous memfd segment holding
+// an empty ring of `size` slots of `slotsize`
+// bytes. `name` is only shown in /proc. Other
+// processes attach with `FromFile` to the
+// descriptor returned by `File`.
+func Memfd(name string, size uint64, slotsize int) (*Ring, error) {
+	if cSYSMEMFD == 0 {
+		return nil, ErrUnsupported
+	}
+	p, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	fd, _, errno := syscall.Syscall(cSYSMEMFD, uintptr(unsafe.Pointer(p)), cMFDCLOEXEC, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	f := os.NewFile(fd, "memfd:"+name)
+	r, err := format(f, size, slotsize)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// FromFile maps segment `f`, e.g. an inherited
+// memfd, and attaches to the ring it holds. The
+// ring owns `f` from then on.
+func FromFile(f *os.File) (*Ring, error) {
+	return attach(f)
+}
+
+// format sizes and maps `f` and formats a ring.
+func format(f *os.File, size uint64, slotsize int) (*Ring, error) {
+	if err := f.Truncate(lfring.MmapRingSize(size, slotsize)); err != nil {
+		return nil, err
+	}
+	mem, err := mmap(f)
+	if err != nil {
+		return nil, err
+	}
+	m, err := lfring.FormatMmapRing(mem, slotsize)
+	if err != nil {
+		unmap(mem)
+		return nil, err
+	}
+	return newRing(m, mem, f), nil
+}
+
+// attach maps `f` and attaches to its ring. It
+// closes `f` on failure.
+func attach(f *os.File) (*Ring, error) {
+	mem, err := mmap(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	m, err := lfring.AttachMmapRing(mem)
+	if err != nil {
+		unmap(mem)
+		f.Close()
+		return nil, err
+	}
+	return newRing(m, mem, f), nil
+}
+
+// mmap maps whole file `f` shared.
+func mmap(f *os.File) ([]byte, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() == 0 {
+		return nil, lfring.ErrMmapLayout
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// unmap releases mapping `mem`.
+func unmap(mem []byte) error {
+	return syscall.Munmap(mem)
+}
+
+// newRing returns a ring over mapping `mem` of
+// file `f`.
+func newRing(m *lfring.MmapRing, mem []byte, f *os.File) *Ring {
+	return &Ring{
+		MmapRing: m,
+		mem:      mem,
+		f:        f,
+		wake:     (*uint32)(unsafe.Pointer(&mem[lfring.MmapWakeOffset])),
+		waiters:  (*uint32)(unsafe.Pointer(&mem[lfring.MmapWakeOffset+4])),
+		lease:    (*uint32)(unsafe.Pointer(&mem[lfring.MmapLeaseOffset])),
+	}
+}
@@ -0,0 +1,237 @@
+//go:build linux && !purego
+// +build linux,!purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package shm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// cHELPERENV makes test binary act as a producer
+// process, see `TestHelperProducer`.
+const cHELPERENV = "LFRING_SHM_HELPER"
+
+func TestCreateOpen(t *testing.T) {
+	if _, err := os.Stat(cSHMDIR); err != nil {
+		t.Skip("no shared memory filesystem")
+	}
+	name := fmt.Sprintf("lfring-test-%d", os.Getpid())
+	w, err := Create(name, 8, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Unlink(name)
+	defer w.Close()
+	if _, err := Create(name, 8, 64); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("assertion failed, expected ErrExist, got %v.", err)
+	}
+	// a second mapping sees the same ring at a
+	// different address.
+	r, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Cap() != 8 || r.MaxRecord() != 48 {
+		t.Fatalf("assertion failed, cap(%d), max record(%d).", r.Cap(), r.MaxRecord())
+	}
+	for i := 0; i < 8; i++ {
+		if !w.Push([]byte(strconv.Itoa(i))) {
+			t.Fatal("inconsistent state, unable to push.")
+		}
+	}
+	if w.Push([]byte("x")) {
+		t.Fatal("assertion failed, pushed to a full ring.")
+	}
+	for i := 0; i < 8; i++ {
+		if v, ok := r.Pop(nil); !ok || string(v) != strconv.Itoa(i) {
+			t.Fatalf("assertion failed, expected %d, got %q.", i, v)
+		}
+	}
+	if w.Len() != 0 {
+		t.Fatalf("assertion failed, len(%d)!=0.", w.Len())
+	}
+	if _, err := Open("a/b"); err != ErrName {
+		t.Fatalf("assertion failed, expected ErrName, got %v.", err)
+	}
+}
+
+func TestMemfdProcess(t *testing.T) {
+	const count = 1000
+	r, err := Memfd("lfring-test", 16, 64)
+	if err == ErrUnsupported {
+		t.Skip("memfd not supported")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProducer$")
+	cmd.Env = append(os.Environ(), cHELPERENV+"="+strconv.Itoa(count))
+	cmd.ExtraFiles = []*os.File{r.File()}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i := 0; i < count; i++ {
+		v, err := r.PopWait(ctx, nil)
+		if err != nil {
+			t.Fatalf("assertion failed, %v after %d records.", err, i)
+		}
+		if string(v) != strconv.Itoa(i) {
+			t.Fatalf("assertion failed, expected %d, got %q.", i, v)
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPopWait(t *testing.T) {
+	r, err := Memfd("lfring-test", 4, 64)
+	if err == ErrUnsupported {
+		t.Skip("memfd not supported")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := r.PopWait(ctx, nil); err != context.DeadlineExceeded {
+		t.Fatalf("assertion failed, expected deadline, got %v.", err)
+	}
+	done := make(chan []byte)
+	go func() {
+		v, err := r.PopWait(context.Background(), nil)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- v
+	}()
+	// wait for consumer to block
+	for atomic.LoadUint32(r.waiters) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	r.Push([]byte("wake"))
+	select {
+	case v := <-done:
+		if string(v) != "wake" {
+			t.Fatalf("assertion failed, expected wake, got %q.", v)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("assertion failed, consumer not woken.")
+	}
+}
+
+// TestHelperProducer pushes records to the ring
+// inherited as descriptor 3 when run as helper.
+func TestHelperProducer(t *testing.T) {
+	count, err := strconv.Atoi(os.Getenv(cHELPERENV))
+	if err != nil {
+		return
+	}
+	r, err := FromFile(os.NewFile(3, "memfd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for i := 0; i < count; i++ {
+		for !r.Push([]byte(strconv.Itoa(i))) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestHandoff(t *testing.T) {
+	const count = 16
+	old, err := Memfd("lfring-test", 16, 64)
+	if err == ErrUnsupported {
+		t.Skip("memfd not supported")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := old.Handoff(ctx); err != ErrLease {
+		t.Fatalf("assertion failed, expected ErrLease, got %v.", err)
+	}
+	if err := old.Acquire(ctx); err != nil || !old.Leased() {
+		t.Fatalf("assertion failed, unable to acquire, %v.", err)
+	}
+	// no successor, lease is taken back
+	short, stop := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer stop()
+	if err := old.Handoff(short); err != context.DeadlineExceeded || !old.Leased() {
+		t.Fatalf("assertion failed, expected lease kept, got %v.", err)
+	}
+	for i := 0; i < count; i++ {
+		old.Push([]byte(strconv.Itoa(i)))
+	}
+	for i := 0; i < count/2; i++ {
+		old.Pop(nil)
+	}
+	// successor reopens the segment
+	f, err := os.OpenFile(fmt.Sprintf("/proc/self/fd/%d", old.File().Fd()), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	succ, err := FromFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer succ.Close()
+	done := make(chan error, 1)
+	go func() { done <- succ.Acquire(ctx) }()
+	if err := old.Handoff(ctx); err != nil || old.Leased() {
+		t.Fatalf("assertion failed, handoff(%v), leased(%v).", err, old.Leased())
+	}
+	if err := <-done; err != nil || !succ.Leased() {
+		t.Fatalf("assertion failed, successor not leased, %v.", err)
+	}
+	for i := count / 2; i < count; i++ {
+		if v, ok := succ.Pop(nil); !ok || string(v) != strconv.Itoa(i) {
+			t.Fatalf("assertion failed, expected %d, got %q.", i, v)
+		}
+	}
+	succ.Release()
+	if succ.Leased() || old.Acquire(ctx) != nil {
+		t.Fatal("assertion failed, expected released lease.")
+	}
+}
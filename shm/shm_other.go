@@ -0,0 +1,66 @@
+//go:build !linux || purego
+// +build !linux purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package shm
+
+import "os"
+
+// Create returns `ErrUnsupported` on platforms
+// other than linux and in `purego` builds.
+func Create(name string, size uint64, slotsize int) (*Ring, error) {
+	return nil, ErrUnsupported
+}
+
+// Open returns `ErrUnsupported` on platforms
+// other than linux and in `purego` builds.
+func Open(name string) (*Ring, error) {
+	return nil, ErrUnsupported
+}
+
+// Unlink returns `ErrUnsupported` on platforms
+// other than linux and in `purego` builds.
+func Unlink(name string) error {
+	return ErrUnsupported
+}
+
+// Memfd returns `ErrUnsupported` on platforms
+// other than linux and in `purego` builds.
+func Memfd(name string, size uint64, slotsize int) (*Ring, error) {
+	return nil, ErrUnsupported
+}
+
+// FromFile returns `ErrUnsupported` on platforms
+// other than linux and in `purego` builds.
+func FromFile(f *os.File) (*Ring, error) {
+	return nil, ErrUnsupported
+}
+
+// unmap is never called on platforms other than
+// linux or in `purego` builds.
+func unmap(mem []byte) error {
+	return ErrUnsupported
+}
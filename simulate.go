@@ -0,0 +1,179 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Defaults
+const (
+	// cSIMITEMS is number of arrivals simulated by
+	// `Simulate`.
+	cSIMITEMS = 100000
+	// cSIMSEED seeds simulations, so results are
+	// reproducible.
+	cSIMSEED = 1
+)
+
+// - MARK: Distribution section.
+
+// Distribution draws random durations, e.g. times
+// between arrivals or service times of a
+// simulation, see `Simulate`.
+type Distribution interface {
+	Sample(rnd *rand.Rand) time.Duration
+}
+
+// DistFunc is a `Distribution` defined by a
+// function.
+type DistFunc func(rnd *rand.Rand) time.Duration
+
+// Sample implements `Distribution` interface.
+func (f DistFunc) Sample(rnd *rand.Rand) time.Duration {
+	return f(rnd)
+}
+
+// Constant returns a distribution which always
+// draws `d`, e.g. a fixed rate producer.
+func Constant(d time.Duration) Distribution {
+	return DistFunc(func(*rand.Rand) time.Duration { return d })
+}
+
+// Exponential returns an exponential distribution
+// of mean `mean`; as times between arrivals it
+// models independent, i.e. Poisson, arrivals.
+func Exponential(mean time.Duration) Distribution {
+	return DistFunc(func(rnd *rand.Rand) time.Duration {
+		return time.Duration(rnd.ExpFloat64() * float64(mean))
+	})
+}
+
+// Uniform returns a distribution drawing uniformly
+// from [`min`, `max`].
+func Uniform(min, max time.Duration) Distribution {
+	return DistFunc(func(rnd *rand.Rand) time.Duration {
+		return min + time.Duration(rnd.Int64N(int64(max-min)+1))
+	})
+}
+
+// - MARK: Simulation section.
+
+// SimResult is the predicted behavior of a ring
+// configuration, see `Simulate`.
+type SimResult struct {
+	Arrivals    uint64          // items offered by producers
+	Delivered   uint64          // items popped by consumer
+	Dropped     uint64          // items refused under `Reject` or `DropNewest`
+	Evicted     uint64          // items evicted under `DropOldest`
+	Blocked     uint64          // pushes waiting under `Block`
+	BlockTime   time.Duration   // total time producers waited
+	MaxLen      uint64          // occupancy high-water mark
+	Utilization float64         // fraction of time consumer was busy
+	Latency     LatencySnapshot // time-in-queue of delivered items
+}
+
+// LossRate returns fraction of arrivals which
+// were not delivered.
+func (s SimResult) LossRate() float64 {
+	if s.Arrivals == 0 {
+		return 0
+	}
+	return float64(s.Dropped+s.Evicted) / float64(s.Arrivals)
+}
+
+// Simulate predicts drops and time-in-queue of a
+// ring of `capacity`, rounded like `NewRing`, and
+// full ring `policy` fed by producers with times
+// between arrivals drawn from `arrival` and
+// drained by a consumer with service times drawn
+// from `service`, so capacity and policy can be
+// chosen before load testing. It runs a discrete
+// event simulation of a single queue: an item
+// leaves the ring when consumer pops it, i.e.
+// once the previous item is served. A service
+// time divided by number of consumers
+// approximates several consumers. Results are
+// deterministic.
+func Simulate(arrival, service Distribution, capacity uint64, policy Policy) SimResult {
+	var (
+		res   SimResult
+		lat   latency
+		gen   *rand.Rand    = rand.New(rand.NewPCG(cSIMSEED, cSIMSEED))
+		size  int           = int(RoundCapacity(capacity))
+		queue []int64       = make([]int64, 0, size) // push times, ns
+		now   int64                                  // time of arrival, ns
+		free  int64                                  // time consumer becomes idle, ns
+		busy  time.Duration                          // total service time
+	)
+	// drain pops items consumer takes until `t`.
+	drain := func(t int64) {
+		for len(queue) > 0 && free <= t {
+			start := free
+			if queue[0] > start {
+				start = queue[0]
+			}
+			lat.record(start - queue[0])
+			d := service.Sample(gen)
+			free, busy = start+int64(d), busy+d
+			queue = queue[1:]
+			res.Delivered++
+		}
+	}
+	for i := 0; i < cSIMITEMS; i++ {
+		now += int64(arrival.Sample(gen))
+		res.Arrivals++
+		drain(now)
+		if len(queue) == size {
+			switch policy {
+			case DropOldest:
+				queue = queue[1:]
+				res.Evicted++
+			case Block:
+				// producer waits for next pop, which
+				// delays its later arrivals too.
+				res.Blocked++
+				res.BlockTime += time.Duration(free - now)
+				now = free
+				drain(now)
+			default:
+				res.Dropped++
+				continue
+			}
+		}
+		queue = append(queue, now)
+		if n := uint64(len(queue)); n > res.MaxLen {
+			res.MaxLen = n
+		}
+	}
+	drain(math.MaxInt64)
+	if free > 0 {
+		res.Utilization = float64(busy) / float64(free)
+	}
+	res.Latency = lat.snapshot()
+	return res
+}
@@ -0,0 +1,90 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// - MARK: Test section.
+
+func TestSimulateUnderload(t *testing.T) {
+	s := Simulate(Constant(time.Millisecond), Constant(time.Millisecond/2), 4, Reject)
+	if s.Arrivals != cSIMITEMS || s.Delivered != s.Arrivals || s.LossRate() != 0 || s.MaxLen != 1 {
+		t.Fatalf("assertion failed, unexpected result %+v.", s)
+	}
+	if s.Latency.Max != 0 || math.Abs(s.Utilization-0.5) > 0.01 {
+		t.Fatalf("assertion failed, max(%v), utilization(%f).", s.Latency.Max, s.Utilization)
+	}
+}
+
+func TestSimulateOverload(t *testing.T) {
+	// consumer serves half of arrivals.
+	arrival, service := Constant(time.Millisecond), Constant(2*time.Millisecond)
+	for _, p := range []Policy{Reject, DropNewest, DropOldest} {
+		s := Simulate(arrival, service, 8, p)
+		if math.Abs(s.LossRate()-0.5) > 0.01 || s.Delivered+s.Dropped+s.Evicted != s.Arrivals {
+			t.Fatalf("assertion failed, %s lost %f.", p, s.LossRate())
+		}
+		if s.MaxLen != 8 {
+			t.Fatalf("assertion failed, %s max length %d.", p, s.MaxLen)
+		}
+	}
+	// evicting keeps items fresh.
+	reject, evict := Simulate(arrival, service, 8, Reject), Simulate(arrival, service, 8, DropOldest)
+	if evict.Latency.Mean() >= reject.Latency.Mean() {
+		t.Fatalf("assertion failed, evicting mean %v, rejecting mean %v.", evict.Latency.Mean(), reject.Latency.Mean())
+	}
+	s := Simulate(arrival, service, 8, Block)
+	if s.LossRate() != 0 || s.Delivered != s.Arrivals || s.Blocked == 0 || s.BlockTime == 0 {
+		t.Fatalf("assertion failed, blocking result %+v.", s)
+	}
+}
+
+func TestSimulateQueueingTheory(t *testing.T) {
+	// M/M/1/K with K = capacity plus item in
+	// service loses (1-ρ)ρ^K / (1-ρ^(K+1)).
+	const (
+		rho  = 0.9
+		size = 4
+	)
+	var (
+		k    float64 = size + 1
+		want float64 = (1 - rho) * math.Pow(rho, k) / (1 - math.Pow(rho, k+1))
+		s    SimResult
+	)
+	s = Simulate(Exponential(time.Millisecond), Exponential(time.Duration(rho*float64(time.Millisecond))), size, Reject)
+	if got := s.LossRate(); math.Abs(got-want) > 0.01 {
+		t.Fatalf("assertion failed, expected loss %f, got %f.", want, got)
+	}
+	if p50, p99 := s.Latency.Quantile(0.5), s.Latency.Quantile(0.99); p50 > p99 || p99 == 0 {
+		t.Fatalf("inconsistent state, p50(%v), p99(%v).", p50, p99)
+	}
+	if Simulate(Uniform(0, 2*time.Millisecond), Constant(time.Millisecond/2), 16, Reject).Dropped != 0 {
+		t.Fatal("assertion failed, unexpected drops.")
+	}
+}
@@ -0,0 +1,202 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+)
+
+// Defaults
+const (
+	// cSLOTHDR is size of slot header, i.e. the
+	// sequence word preceding each record.
+	cSLOTHDR = 8
+)
+
+// - MARK: SlotRing section.
+
+// SlotRing is a MPMC ring storing fixed-size
+// records inline in its slots, e.g. 32-byte
+// structs, instead of `interface{}` values, so
+// pushes do not allocate and records sit next to
+// their sequence. Slots are `SlotStride` bytes
+// apart; like `MmapRing`, the slot of a position
+// is found by scaling its masked index by stride,
+// so one implementation serves every record size.
+type SlotRing struct {
+	_       CacheLinePad
+	wri     uint64
+	_       CacheLinePad
+	rdi     uint64
+	_       CacheLinePad
+	buf     []uint64 // backing store, see `alloc`
+	slots   []byte   // `size * stride` bytes of slots
+	recsize uintptr
+	stride  uintptr
+	size    uint64
+}
+
+// SlotStride returns distance between slots of
+// records of `recsize` bytes: record plus slot
+// header rounded up to 8 bytes.
+func SlotStride(recsize int) uintptr {
+	return (uintptr(recsize) + cSLOTHDR + 7) &^ 7
+}
+
+// NewSlotRing allocates and initializes a new
+// `SlotRing` of `capacity` records of `recsize`
+// bytes and returns a pointer to it. Capacity is
+// rounded to power of two, at least two. It
+// panics when `recsize` is not positive or
+// capacity is invalid, see `NewRingChecked`.
+func NewSlotRing(capacity uint64, recsize int) *SlotRing {
+	if recsize <= 0 {
+		panic("lfring: invalid record size")
+	}
+	if capacity == 0 {
+		panic(ErrCapacityZero)
+	}
+	if capacity > MaxCapacity {
+		panic(ErrCapacityLimit)
+	}
+	s := &SlotRing{recsize: uintptr(recsize), stride: SlotStride(recsize), size: roundP2(capacity)}
+	if s.size < 2 {
+		// one slot can not tell free from published
+		s.size = 2
+	}
+	s.alloc()
+	for i := uint64(0); i < s.size; i++ {
+		*s.seq(i) = i
+	}
+	return s
+}
+
+// Cap returns number of slots.
+func (s *SlotRing) Cap() uint64 {
+	return s.size
+}
+
+// RecordSize returns record size in bytes.
+func (s *SlotRing) RecordSize() int {
+	return int(s.recsize)
+}
+
+// Len returns number of records in ring.
+func (s *SlotRing) Len() uint64 {
+	return atomic.LoadUint64(&s.wri) - atomic.LoadUint64(&s.rdi)
+}
+
+// Push copies record `p` into next free slot,
+// zero padding short records, and returns false
+// when ring is full or `p` exceeds `RecordSize`.
+func (s *SlotRing) Push(p []byte) bool {
+	if uintptr(len(p)) > s.recsize {
+		return false
+	}
+	return s.PushFunc(func(rec []byte) {
+		n := copy(rec, p)
+		for i := n; i < len(rec); i++ {
+			rec[i] = 0
+		}
+	})
+}
+
+// PushFunc lets `fn` fill next free slot in
+// place, e.g. encode a struct directly into the
+// ring, and returns false when ring is full.
+// `rec` holds stale bytes of an earlier record
+// and is only valid during the call. When `fn`
+// panics, the slot is published as filled so
+// far, so the ring does not stall.
+func (s *SlotRing) PushFunc(fn func(rec []byte)) bool {
+	for {
+		pos := atomic.LoadUint64(&s.wri)
+		dif := int64(atomic.LoadUint64(s.seq(pos)) - pos)
+		if dif < 0 {
+			return false
+		}
+		if dif == 0 && atomic.CompareAndSwapUint64(&s.wri, pos, pos+1) {
+			s.fill(pos, fn)
+			return true
+		}
+	}
+}
+
+// Pop removes next record, appends it to `dst`
+// and returns the result, or false when ring is
+// empty.
+func (s *SlotRing) Pop(dst []byte) ([]byte, bool) {
+	ok := s.PopFunc(func(rec []byte) {
+		dst = append(dst, rec...)
+	})
+	return dst, ok
+}
+
+// PopFunc passes next record to `fn` in place,
+// e.g. to decode a struct, and removes it. It
+// returns false when ring is empty. `rec` aliases
+// ring memory and is only valid during the call.
+// When `fn` panics, the record is removed.
+func (s *SlotRing) PopFunc(fn func(rec []byte)) bool {
+	for {
+		pos := atomic.LoadUint64(&s.rdi)
+		dif := int64(atomic.LoadUint64(s.seq(pos)) - (pos + 1))
+		if dif < 0 {
+			return false
+		}
+		if dif == 0 && atomic.CompareAndSwapUint64(&s.rdi, pos, pos+1) {
+			s.drain(pos, fn)
+			return true
+		}
+	}
+}
+
+// fill passes slot of claimed position `pos` to
+// `fn` and publishes it, also when `fn` panics.
+func (s *SlotRing) fill(pos uint64, fn func(rec []byte)) {
+	defer atomic.StoreUint64(s.seq(pos), pos+1)
+	fn(s.record(pos))
+}
+
+// drain passes record of claimed position `pos`
+// to `fn` and releases its slot, also when `fn`
+// panics.
+func (s *SlotRing) drain(pos uint64, fn func(rec []byte)) {
+	defer atomic.StoreUint64(s.seq(pos), pos+s.size)
+	fn(s.record(pos))
+}
+
+// offset returns byte offset of slot of position
+// `pos`.
+func (s *SlotRing) offset(pos uint64) uintptr {
+	return uintptr(pos&(s.size-1)) * s.stride
+}
+
+// record returns record bytes of slot of
+// position `pos`.
+func (s *SlotRing) record(pos uint64) []byte {
+	off := s.offset(pos) + cSLOTHDR
+	return s.slots[off : off+s.recsize : off+s.recsize]
+}
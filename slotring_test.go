@@ -0,0 +1,171 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// - MARK: Test section.
+
+// slotRecord is an inline 32-byte test record.
+type slotRecord struct {
+	ID, A, B, C uint64
+}
+
+func (r *slotRecord) put(p []byte) {
+	binary.LittleEndian.PutUint64(p[0:], r.ID)
+	binary.LittleEndian.PutUint64(p[8:], r.A)
+	binary.LittleEndian.PutUint64(p[16:], r.B)
+	binary.LittleEndian.PutUint64(p[24:], r.C)
+}
+
+func (r *slotRecord) get(p []byte) {
+	r.ID = binary.LittleEndian.Uint64(p[0:])
+	r.A = binary.LittleEndian.Uint64(p[8:])
+	r.B = binary.LittleEndian.Uint64(p[16:])
+	r.C = binary.LittleEndian.Uint64(p[24:])
+}
+
+func TestSlotStride(t *testing.T) {
+	for _, c := range []struct {
+		recsize int
+		stride  uintptr
+	}{{1, 16}, {8, 16}, {13, 24}, {32, 40}, {56, 64}} {
+		if s := SlotStride(c.recsize); s != c.stride {
+			t.Fatalf("assertion failed, stride of %d: expected %d, got %d.", c.recsize, c.stride, s)
+		}
+	}
+}
+
+func TestSlotRing(t *testing.T) {
+	var s *SlotRing = NewSlotRing(3, 5)
+	if s.Cap() != 4 || s.RecordSize() != 5 {
+		t.Fatal("assertion failed, unexpected geometry.")
+	}
+	if s.Push([]byte("toolong")) {
+		t.Fatal("assertion failed, pushed oversized record.")
+	}
+	for _, p := range []string{"abcde", "xy", "12345", "q"} {
+		if !s.Push([]byte(p)) {
+			t.Fatal("inconsistent state, unable to push.")
+		}
+	}
+	if s.Push([]byte("z")) || s.Len() != 4 {
+		t.Fatal("assertion failed, pushed to a full ring.")
+	}
+	for _, want := range []string{"abcde", "xy\x00\x00\x00", "12345", "q\x00\x00\x00\x00"} {
+		p, ok := s.Pop(nil)
+		if !ok || !bytes.Equal(p, []byte(want)) {
+			t.Fatalf("assertion failed, expected %q, got %q.", want, p)
+		}
+	}
+	if _, ok := s.Pop(nil); ok {
+		t.Fatal("assertion failed, popped from an empty ring.")
+	}
+	if NewSlotRing(1, 8).Cap() != 2 {
+		t.Fatal("assertion failed, expected at least two slots.")
+	}
+}
+
+func TestSlotRingPanic(t *testing.T) {
+	var s *SlotRing = NewSlotRing(2, 4)
+	mustPanic := func(fn func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("assertion failed, expected callback panic.")
+			}
+		}()
+		fn()
+	}
+	mustPanic(func() {
+		s.PushFunc(func(rec []byte) {
+			copy(rec, "ab")
+			panic("encode")
+		})
+	})
+	// slot was published, the ring moves on
+	if !s.Push([]byte("cd")) || s.Len() != 2 {
+		t.Fatalf("inconsistent state, len(%d)!=2.", s.Len())
+	}
+	mustPanic(func() {
+		s.PopFunc(func([]byte) { panic("decode") })
+	})
+	if p, ok := s.Pop(nil); !ok || string(p[:2]) != "cd" {
+		t.Fatalf("assertion failed, expected cd, got %q.", p)
+	}
+	// released slots are reused
+	for i := 0; i < 2; i++ {
+		if !s.Push([]byte("ef")) {
+			t.Fatal("inconsistent state, unable to push.")
+		}
+	}
+}
+
+func TestSlotRingInline(t *testing.T) {
+	const (
+		producers = 4
+		items     = 2000
+	)
+	var (
+		s    *SlotRing = NewSlotRing(64, 32)
+		wg   sync.WaitGroup
+		seen [producers * items]int
+	)
+	for w := 0; w < producers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < items; i++ {
+				rec := slotRecord{ID: uint64(w*items + i), A: 1, B: 2, C: 3}
+				for !s.PushFunc(rec.put) {
+					runtime.Gosched()
+				}
+			}
+		}(w)
+	}
+	for n := 0; n < producers*items; {
+		var rec slotRecord
+		if !s.PopFunc(rec.get) {
+			runtime.Gosched()
+			continue
+		}
+		if rec.A != 1 || rec.B != 2 || rec.C != 3 {
+			t.Fatalf("assertion failed, torn record %+v.", rec)
+		}
+		seen[rec.ID]++
+		n++
+	}
+	wg.Wait()
+	for id, n := range seen {
+		if n != 1 {
+			t.Fatalf("assertion failed, record %d popped %d times.", id, n)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+//go:build !purego
+// +build !purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "unsafe"
+
+// - MARK: SlotRing section.
+
+// alloc allocates slots as a byte view of `buf`,
+// which keeps them 8-byte aligned, so sequences
+// sit inline in slot headers next to records.
+func (s *SlotRing) alloc() {
+	s.buf = make([]uint64, s.size*uint64(s.stride)/8)
+	s.slots = unsafe.Slice((*byte)(unsafe.Pointer(&s.buf[0])), len(s.buf)*8)
+}
+
+// seq returns sequence of slot of position `pos`.
+func (s *SlotRing) seq(pos uint64) *uint64 {
+	return (*uint64)(unsafe.Pointer(&s.slots[s.offset(pos)]))
+}
@@ -0,0 +1,91 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "sync/atomic"
+
+// - MARK: Snapshot section.
+
+// Snapshot returns a copy of unconsumed items in
+// order, e.g. for debugging dumps or state
+// transfer. It is consistent: the result is the
+// ring contents at one instant, i.e. the items a
+// consumer would pop next, up to the first slot
+// not yet published when the copy was taken.
+//
+// Like `Consume`, head is locked by lock bit of
+// read-index for the duration of the copy, so
+// competing consumers wait for at most `Len`
+// slot reads. Producers are never blocked; items
+// pushed meanwhile may or may not be included,
+// but never out of order.
+func (r *Ring) Snapshot() []interface{} {
+	var (
+		i   int
+		pos uint64
+	)
+	for {
+		pos = atomic.LoadUint64(&r.rdi)
+		if pos&cRDLOCK == 0 {
+			// acquire head.
+			if atomic.CompareAndSwapUint64(&r.rdi, pos, pos|cRDLOCK) {
+				break
+			}
+			r.casFailed()
+		}
+		r.block(i, pos)
+		i++
+	}
+	defer atomic.StoreUint64(&r.rdi, pos)
+	var (
+		end   uint64        = r.writeIndex()
+		items []interface{} = make([]interface{}, 0, end-pos)
+	)
+	// published slots of locked head can not be
+	// taken or overwritten until unlocked.
+	for p := pos; p < end && atomic.LoadUint64(r.seq(p)) == p+1; p++ {
+		items = append(items, r.nodes[p&(r.size-1)])
+	}
+	return items
+}
+
+// Range calls `fn` for unconsumed items in order
+// until it returns false, without dequeuing them,
+// e.g. for monitoring code inspecting contents.
+// Items are copied by `Snapshot` first, so `fn`
+// runs without holding the head; consistency is
+// best-effort in that visited items may have been
+// consumed by the time `fn` sees them. It returns
+// number of visited items.
+func (r *Ring) Range(fn func(interface{}) bool) int {
+	var n int
+	for _, item := range r.Snapshot() {
+		n++
+		if !fn(item) {
+			break
+		}
+	}
+	return n
+}
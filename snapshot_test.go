@@ -0,0 +1,117 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestSnapshot(t *testing.T) {
+	var lfq *Ring = NewRing(4)
+	if len(lfq.Snapshot()) != 0 {
+		t.Fatal("assertion failed, expected empty snapshot.")
+	}
+	for i := 0; i < 6; i++ {
+		lfq.Push(i)
+		if i%2 == 0 {
+			lfq.Pop()
+		}
+	}
+	s := lfq.Snapshot()
+	if len(s) != 3 || s[0] != 3 || s[1] != 4 || s[2] != 5 {
+		t.Fatalf("assertion failed, expected [3 4 5], got %v.", s)
+	}
+	// snapshot leaves ring intact
+	if v, ok := lfq.Pop(); !ok || v != 3 || lfq.Len() != 2 {
+		t.Fatalf("inconsistent state, expected 3, got %v.", v)
+	}
+}
+
+func TestRange(t *testing.T) {
+	var (
+		lfq  *Ring = NewRing(8)
+		seen []int
+	)
+	for i := 0; i < 5; i++ {
+		lfq.Push(i)
+	}
+	lfq.Pop()
+	n := lfq.Range(func(v interface{}) bool {
+		seen = append(seen, v.(int))
+		// consuming meanwhile does not deadlock
+		lfq.Pop()
+		return len(seen) < 3
+	})
+	if n != 3 || len(seen) != 3 || seen[0] != 1 || seen[2] != 3 {
+		t.Fatalf("assertion failed, expected [1 2 3], got %v.", seen)
+	}
+	if lfq.Len() != 1 || lfq.Range(func(interface{}) bool { return true }) != 1 {
+		t.Fatal("inconsistent state, expected one item left.")
+	}
+}
+
+func TestSnapshotConcurrent(t *testing.T) {
+	const items = 5000
+	var (
+		lfq  *Ring = NewRing(32)
+		wg   sync.WaitGroup
+		done uint32
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < items; i++ {
+			for !lfq.Push(i) {
+				runtime.Gosched()
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for n := 0; n < items; {
+			if _, ok := lfq.Pop(); ok {
+				n++
+				continue
+			}
+			runtime.Gosched()
+		}
+		atomic.StoreUint32(&done, 1)
+	}()
+	for atomic.LoadUint32(&done) == 0 {
+		s := lfq.Snapshot()
+		for i := 1; i < len(s); i++ {
+			if s[i].(int) != s[i-1].(int)+1 {
+				t.Fatalf("assertion failed, inconsistent snapshot %v.", s)
+			}
+		}
+		runtime.Gosched()
+	}
+	wg.Wait()
+}
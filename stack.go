@@ -0,0 +1,165 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// Defaults
+const (
+	// cELIMSPINS is number of spins a pusher waits
+	// in the elimination array for a popper.
+	cELIMSPINS = 64
+)
+
+// - MARK: Stack section.
+
+// Stack is a lock-free LIFO stack (Treiber stack),
+// e.g. for pools of buffers where the most
+// recently returned, cache-hot one should be
+// reused first. Nodes are allocated per push and
+// never recycled, so the garbage collector rules
+// out ABA without tagged pointers. Under heavy
+// contention an optional elimination array lets
+// a push and a pop which both failed their CAS
+// exchange the item directly, off the top word.
+type Stack struct {
+	// 64bit aligned
+	_     CacheLinePad
+	count int64  // approximate number of items
+	elims uint64 // exchanges through elimination
+	_     CacheLinePad
+	top   atomic.Pointer[stackNode]
+	_     CacheLinePad
+	slots []elimSlot // elimination array, nil when disabled
+}
+
+// stackNode is an item of a `Stack`.
+type stackNode struct {
+	next *stackNode
+	data interface{}
+}
+
+// elimSlot holds a node offered by a pusher.
+type elimSlot struct {
+	p atomic.Pointer[stackNode]
+	_ [CacheLineSize - 8]byte
+}
+
+// NewStack allocates and initializes a new `Stack`
+// with an elimination array of `elimination`
+// slots; zero disables elimination.
+func NewStack(elimination int) *Stack {
+	s := &Stack{}
+	if elimination > 0 {
+		s.slots = make([]elimSlot, elimination)
+	}
+	return s
+}
+
+// Push pushes `data` on top of stack.
+func (s *Stack) Push(data interface{}) {
+	n := &stackNode{data: data}
+	for {
+		top := s.top.Load()
+		n.next = top
+		if s.top.CompareAndSwap(top, n) {
+			atomic.AddInt64(&s.count, 1)
+			return
+		}
+		if s.slots != nil && s.offer(n) {
+			return
+		}
+	}
+}
+
+// Pop removes the item on top of stack and returns
+// false when stack is empty.
+func (s *Stack) Pop() (interface{}, bool) {
+	for {
+		n := s.top.Load()
+		if n == nil {
+			return nil, false
+		}
+		if s.top.CompareAndSwap(n, n.next) {
+			atomic.AddInt64(&s.count, -1)
+			return n.data, true
+		}
+		if s.slots != nil {
+			if n := s.take(); n != nil {
+				return n.data, true
+			}
+		}
+	}
+}
+
+// Len returns number of items; it is approximate
+// while stack is in use.
+func (s *Stack) Len() int {
+	if n := atomic.LoadInt64(&s.count); n > 0 {
+		return int(n)
+	}
+	return 0
+}
+
+// Eliminated returns number of push and pop pairs
+// which exchanged items through the elimination
+// array.
+func (s *Stack) Eliminated() uint64 {
+	return atomic.LoadUint64(&s.elims)
+}
+
+// offer offers node `n` in a random slot and
+// returns whether a popper took it.
+func (s *Stack) offer(n *stackNode) bool {
+	slot := &s.slots[rnd.IntN(len(s.slots))]
+	if !slot.p.CompareAndSwap(nil, n) {
+		return false
+	}
+	for i := 0; i < cELIMSPINS; i++ {
+		if slot.p.Load() != n {
+			return true
+		}
+		if i == cELIMSPINS/2 {
+			runtime.Gosched()
+		}
+	}
+	// withdraw; failing means it was taken.
+	return !slot.p.CompareAndSwap(n, nil)
+}
+
+// take takes a node offered in a random slot or
+// returns nil.
+func (s *Stack) take() *stackNode {
+	slot := &s.slots[rnd.IntN(len(s.slots))]
+	n := slot.p.Load()
+	if n == nil || !slot.p.CompareAndSwap(n, nil) {
+		return nil
+	}
+	atomic.AddUint64(&s.elims, 1)
+	return n
+}
@@ -0,0 +1,103 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestStack(t *testing.T) {
+	var s *Stack = NewStack(0)
+	if _, ok := s.Pop(); ok {
+		t.Fatal("inconsistent state, popped from empty stack.")
+	}
+	for i := 0; i < 4; i++ {
+		s.Push(i)
+	}
+	if s.Len() != 4 {
+		t.Fatalf("assertion failed, len(%d)!=4.", s.Len())
+	}
+	for i := 3; i >= 0; i-- {
+		if v, ok := s.Pop(); !ok || v.(int) != i {
+			t.Fatalf("assertion failed, expected %d, got %v.", i, v)
+		}
+	}
+}
+
+func TestStackElimination(t *testing.T) {
+	const (
+		workers = 4
+		count   = 5000
+	)
+	var (
+		s    *Stack = NewStack(2)
+		wg   sync.WaitGroup
+		seen []int32 = make([]int32, workers*count)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				s.Push(w*count + i)
+				if v, ok := s.Pop(); ok {
+					atomic.AddInt32(&seen[v.(int)], 1)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	for {
+		v, ok := s.Pop()
+		if !ok {
+			break
+		}
+		atomic.AddInt32(&seen[v.(int)], 1)
+	}
+	for v, n := range seen {
+		if n != 1 {
+			t.Fatalf("assertion failed, item %d popped %d times.", v, n)
+		}
+	}
+	// exchange through elimination directly
+	e := NewStack(1)
+	n := &stackNode{data: "x"}
+	go func() {
+		for !e.offer(n) {
+			runtime.Gosched()
+		}
+	}()
+	for e.take() == nil {
+		runtime.Gosched()
+	}
+	if e.Eliminated() != 1 {
+		t.Fatal("assertion failed, expected eliminated pair.")
+	}
+}
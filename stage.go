@@ -0,0 +1,326 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// - MARK: Stage section.
+
+// Handler processes an item and returns the
+// result to be forwarded to the next stage.
+type Handler func(item interface{}) (interface{}, error)
+
+// StageError is pushed to stage's error ring
+// when handler fails.
+type StageError struct {
+	Stage string      // stage name
+	Item  interface{} // offending item
+	Err   error       // handler error
+}
+
+// Error implements `error` interface.
+func (e *StageError) Error() string {
+	return fmt.Sprintf("lfring: stage %s: %v", e.Stage, e.Err)
+}
+
+// Unwrap returns handler error.
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is the error reported for an item
+// whose handler panicked.
+type PanicError struct {
+	Value interface{} // recovered value
+	Stack []byte      // stack of panicking goroutine
+}
+
+// Error implements `error` interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("lfring: handler panic: %v", e.Value)
+}
+
+// DeadLetter is pushed to stage's dead-letter
+// ring when handler fails on an item for the
+// configured number of attempts.
+type DeadLetter struct {
+	StageError           // last failure
+	Attempts   int       // number of failed attempts
+	Time       time.Time // time of last failure
+}
+
+// Stage is a pipeline stage which pops items
+// from input ring, processes them with handler
+// and pushes results to output ring. Handler
+// failures are pushed as `*StageError` to error
+// ring, so failures are observable rather than
+// silently dropped.
+type Stage struct {
+	// 64bit aligned
+	processed uint64 // successfully handled items
+	failed    uint64 // handler failures
+	dropped   uint64 // errors lost due to missing or full error ring
+	dead      uint64 // dead-lettered items
+	retried   uint64 // redeliveries
+	panics    uint64 // recovered handler panics
+	maxpanics uint64 // panics before crash, 0 never
+	name      string
+	in        *Ring
+	out       *Ring
+	errs      *Ring
+	fn        Handler
+	dlq       *Ring       // dead-letter ring
+	attempts  int         // attempts before dead-lettering
+	retry     RetryPolicy // retry policy
+	wheel     *timerWheel // delayed redelivery
+}
+
+// NewStage allocates and initializes a new
+// `Stage` and returns a pointer to it. `out`
+// may be nil for sink stages and `errs` may be
+// nil when failures are only counted.
+func NewStage(name string, in *Ring, fn Handler, out *Ring, errs *Ring) *Stage {
+	return &Stage{name: name, in: in, out: out, errs: errs, fn: fn}
+}
+
+// Name returns stage name.
+func (s *Stage) Name() string {
+	return s.name
+}
+
+// SetDeadLetter configures `dlq` as dead-letter
+// ring. Items are handled up to `attempts` times
+// and moved to `dlq` as `*DeadLetter` when every
+// attempt fails; a retry policy with
+// `MaxAttempts` set takes precedence, regardless
+// of call order. It must be called before stage
+// is run.
+func (s *Stage) SetDeadLetter(dlq *Ring, attempts int) {
+	s.dlq = dlq
+	s.attempts = attempts
+}
+
+// SetRetryPolicy sets retry policy of stage. Items
+// with a backoff delay are redelivered through a
+// timer wheel, so the stage keeps processing other
+// items meanwhile. Zero `MaxAttempts` keeps the
+// limit of `SetDeadLetter`. It must be called
+// before stage is run.
+func (s *Stage) SetRetryPolicy(p RetryPolicy) {
+	s.retry = p
+	if p.Backoff != nil && s.wheel == nil {
+		s.wheel = newTimerWheel(cRETRYTICK, cRETRYBUCKETS, cRETRYBUCKETCAP, s.in.clock.Now())
+	}
+}
+
+// SetPanicThreshold makes stage crash, i.e.
+// re-panic with the `*PanicError`, once `n`
+// handler panics were recovered. Zero keeps it
+// alive forever. Panicking items are reported as
+// failures and moved to dead-letter ring when
+// configured; they are never retried. It must be
+// called before stage is run.
+func (s *Stage) SetPanicThreshold(n uint64) {
+	s.maxpanics = n
+}
+
+// Panics returns number of recovered handler
+// panics.
+func (s *Stage) Panics() uint64 {
+	return atomic.LoadUint64(&s.panics)
+}
+
+// Pending returns number of items awaiting
+// delayed redelivery.
+func (s *Stage) Pending() uint64 {
+	if s.wheel == nil {
+		return 0
+	}
+	return s.wheel.len()
+}
+
+// Retried returns number of redeliveries.
+func (s *Stage) Retried() uint64 {
+	return atomic.LoadUint64(&s.retried)
+}
+
+// DeadLettered returns number of items moved to
+// dead-letter ring.
+func (s *Stage) DeadLettered() uint64 {
+	return atomic.LoadUint64(&s.dead)
+}
+
+// Step redelivers due retries, processes up to
+// `max` items and returns the number of items
+// handled. Results are pushed to output ring,
+// spinning while it is full.
+func (s *Stage) Step(max int) int {
+	var n int
+	if s.wheel != nil && s.wheel.len() > 0 {
+		n += s.wheel.advance(s.in.clock.Now(), func(v interface{}) {
+			e := v.(*retryEntry)
+			atomic.AddUint64(&s.retried, 1)
+			s.handle(e.item, e.attempts)
+		})
+	}
+	for n < max {
+		item, ok := s.in.Pop()
+		if !ok {
+			break
+		}
+		n++
+		s.handle(item, 0)
+	}
+	return n
+}
+
+// Run processes items until `stop` is closed.
+func (s *Stage) Run(stop <-chan struct{}) {
+	for idle := 0; ; {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if s.Step(cRDSCHDTHRESHOLD) > 0 {
+			idle = 0
+			continue
+		}
+		// wait on input according to its
+		// wait strategy.
+		s.in.pause(idle)
+		idle++
+	}
+}
+
+// Stats returns processed, failed and dropped
+// counters.
+func (s *Stage) Stats() (processed, failed, dropped uint64) {
+	return atomic.LoadUint64(&s.processed), atomic.LoadUint64(&s.failed), atomic.LoadUint64(&s.dropped)
+}
+
+// retryable returns whether an item which failed
+// `attempts` times with `err` should be retried,
+// bounded by dead-letter attempts unless retry
+// policy sets its own.
+func (s *Stage) retryable(attempts int, err error) bool {
+	p := s.retry
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = s.attempts
+	}
+	return p.retryable(attempts, err)
+}
+
+// handle runs handler on `item` which previously
+// failed `attempts` times and routes the result.
+func (s *Stage) handle(item interface{}, attempts int) {
+	var (
+		result interface{}
+		err    error
+	)
+	for {
+		result, err = s.call(item)
+		attempts++
+		if pe, ok := err.(*PanicError); ok {
+			s.panicked(item, pe, attempts)
+			return
+		}
+		if err == nil || !s.retryable(attempts, err) {
+			break
+		}
+		if s.retry.Backoff != nil {
+			at := s.in.clock.Now().Add(s.retry.Backoff(attempts))
+			if s.wheel.schedule(at, &retryEntry{item: item, attempts: attempts}) {
+				return
+			}
+		}
+		atomic.AddUint64(&s.retried, 1)
+	}
+	if err != nil {
+		s.fail(item, err)
+		if s.dlq != nil {
+			s.deadLetter(item, err, attempts)
+		}
+		return
+	}
+	atomic.AddUint64(&s.processed, 1)
+	if s.out == nil {
+		return
+	}
+	for i := 0; !s.out.Push(result); i++ {
+		s.out.pause(i)
+	}
+}
+
+// call runs handler on `item` and recovers its
+// panic as `*PanicError`.
+func (s *Stage) call(item interface{}) (result interface{}, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			result, err = nil, &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return s.fn(item)
+}
+
+// panicked reports handler panic on `item` and
+// crashes once panic threshold is reached.
+func (s *Stage) panicked(item interface{}, pe *PanicError, attempts int) {
+	n := atomic.AddUint64(&s.panics, 1)
+	s.fail(item, pe)
+	if s.dlq != nil {
+		s.deadLetter(item, pe, attempts)
+	}
+	if s.maxpanics > 0 && n >= s.maxpanics {
+		panic(pe)
+	}
+}
+
+// fail reports handler failure of `item`.
+func (s *Stage) fail(item interface{}, err error) {
+	atomic.AddUint64(&s.failed, 1)
+	if s.errs == nil || !s.errs.Push(&StageError{Stage: s.name, Item: item, Err: err}) {
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// deadLetter moves `item` to dead-letter ring.
+func (s *Stage) deadLetter(item interface{}, err error, attempts int) {
+	dl := &DeadLetter{
+		StageError: StageError{Stage: s.name, Item: item, Err: err},
+		Attempts:   attempts,
+		Time:       s.in.clock.Now(),
+	}
+	if s.dlq.Push(dl) {
+		atomic.AddUint64(&s.dead, 1)
+		return
+	}
+	atomic.AddUint64(&s.dropped, 1)
+}
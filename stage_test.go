@@ -0,0 +1,235 @@
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStage(t *testing.T) {
+	var (
+		in    *Ring = NewRing(8)
+		out   *Ring = NewRing(8)
+		errs  *Ring = NewRing(8)
+		odd   error = errors.New("odd")
+		stage *Stage
+	)
+	stage = NewStage("double", in, func(v interface{}) (interface{}, error) {
+		if v.(int)%2 == 1 {
+			return nil, odd
+		}
+		return v.(int) * 2, nil
+	}, out, errs)
+	for i := 0; i < 4; i++ {
+		in.Push(i)
+	}
+	if n := stage.Step(8); n != 4 {
+		t.Fatalf("assertion failed, n(%d)!=4.", n)
+	}
+	for _, want := range []int{0, 4} {
+		if v, ok := out.Pop(); !ok || v.(int) != want {
+			t.Fatalf("assertion failed, expected %d, got %v.", want, v)
+		}
+	}
+	for _, want := range []int{1, 3} {
+		v, ok := errs.Pop()
+		if !ok {
+			t.Fatal("inconsistent state, missing stage error.")
+		}
+		serr := v.(*StageError)
+		if serr.Stage != "double" || serr.Item.(int) != want || !errors.Is(serr, odd) {
+			t.Fatalf("assertion failed, unexpected error %+v.", serr)
+		}
+	}
+	if p, f, d := stage.Stats(); p != 2 || f != 2 || d != 0 {
+		t.Fatalf("assertion failed, stats(%d, %d, %d).", p, f, d)
+	}
+}
+
+func TestStageDeadLetter(t *testing.T) {
+	var (
+		in    *Ring = NewRing(8)
+		dlq   *Ring = NewRing(8)
+		calls int
+		stage *Stage
+	)
+	stage = NewStage("flaky", in, func(v interface{}) (interface{}, error) {
+		calls++
+		if v.(int) == 1 && calls < 3 {
+			return nil, errors.New("transient")
+		}
+		if v.(int) == 2 {
+			return nil, errors.New("permanent")
+		}
+		return v, nil
+	}, nil, nil)
+	stage.SetDeadLetter(dlq, 3)
+	in.Push(1)
+	in.Push(2)
+	stage.Step(2)
+	v, ok := dlq.Pop()
+	if !ok {
+		t.Fatal("inconsistent state, missing dead letter.")
+	}
+	dl := v.(*DeadLetter)
+	if dl.Item.(int) != 2 || dl.Attempts != 3 || dl.Stage != "flaky" {
+		t.Fatalf("assertion failed, unexpected dead letter %+v.", dl)
+	}
+	if _, ok := dlq.Pop(); ok {
+		t.Fatal("assertion failed, recovered item dead-lettered.")
+	}
+	if stage.DeadLettered() != 1 {
+		t.Fatalf("assertion failed, dead(%d)!=1.", stage.DeadLettered())
+	}
+}
+
+func TestStagePanic(t *testing.T) {
+	var (
+		in    *Ring = NewRing(8)
+		out   *Ring = NewRing(8)
+		dlq   *Ring = NewRing(8)
+		stage *Stage
+	)
+	stage = NewStage("boom", in, func(v interface{}) (interface{}, error) {
+		if v.(int) < 0 {
+			panic("negative")
+		}
+		return v, nil
+	}, out, nil)
+	stage.SetDeadLetter(dlq, 3)
+	stage.SetPanicThreshold(2)
+	for _, v := range []int{1, -1, 2} {
+		in.Push(v)
+	}
+	if n := stage.Step(8); n != 3 || out.Len() != 2 || stage.Panics() != 1 {
+		t.Fatalf("assertion failed, n(%d), out(%d), panics(%d).", n, out.Len(), stage.Panics())
+	}
+	v, ok := dlq.Pop()
+	if !ok {
+		t.Fatal("inconsistent state, missing dead letter.")
+	}
+	dl := v.(*DeadLetter)
+	pe, ok := dl.Err.(*PanicError)
+	if !ok || dl.Item.(int) != -1 || dl.Attempts != 1 || pe.Value != "negative" || len(pe.Stack) == 0 {
+		t.Fatalf("assertion failed, unexpected dead letter %+v.", dl)
+	}
+	// second panic reaches threshold
+	in.Push(-2)
+	defer func() {
+		if v := recover(); v == nil || v.(*PanicError).Value != "negative" || stage.Panics() != 2 {
+			t.Fatalf("assertion failed, expected stage to crash, got %v.", v)
+		}
+	}()
+	stage.Step(8)
+}
+
+func TestStageRetryPolicy(t *testing.T) {
+	var (
+		in        *Ring = NewRing(8)
+		dlq       *Ring = NewRing(8)
+		out       *Ring = NewRing(8)
+		fatal     error = errors.New("fatal")
+		transient error = errors.New("transient")
+		calls     map[int]int
+		stage     *Stage
+	)
+	calls = make(map[int]int)
+	stage = NewStage("retry", in, func(v interface{}) (interface{}, error) {
+		calls[v.(int)]++
+		switch {
+		case v.(int) == 1:
+			return nil, fatal
+		case v.(int) == 2 && calls[2] < 3:
+			return nil, transient
+		}
+		return v, nil
+	}, out, nil)
+	stage.SetDeadLetter(dlq, 5)
+	stage.SetRetryPolicy(RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     ConstantBackoff(time.Millisecond),
+		Retryable:   func(err error) bool { return err != fatal },
+	})
+	in.Push(1)
+	in.Push(2)
+	stage.Step(2)
+	if stage.Pending() != 1 {
+		t.Fatalf("assertion failed, pending(%d)!=1.", stage.Pending())
+	}
+	// non-retryable error goes to dlq at once
+	if v, ok := dlq.Pop(); !ok || v.(*DeadLetter).Attempts != 1 {
+		t.Fatal("assertion failed, expected fatal item in dead-letter ring.")
+	}
+	deadline := time.Now().Add(time.Second)
+	for stage.Pending() > 0 && time.Now().Before(deadline) {
+		stage.Step(1)
+	}
+	if v, ok := out.Pop(); !ok || v.(int) != 2 {
+		t.Fatal("assertion failed, expected redelivered item in output ring.")
+	}
+	if calls[2] != 3 || stage.Retried() != 2 {
+		t.Fatalf("assertion failed, calls(%d), retried(%d).", calls[2], stage.Retried())
+	}
+}
+
+func TestStageDeadLetterOrder(t *testing.T) {
+	for _, deadFirst := range []bool{true, false} {
+		var (
+			in    *Ring = NewRing(8)
+			dlq   *Ring = NewRing(8)
+			stage *Stage
+		)
+		stage = NewStage("order", in, func(v interface{}) (interface{}, error) {
+			return nil, errors.New("permanent")
+		}, nil, nil)
+		retry := RetryPolicy{Retryable: func(error) bool { return true }}
+		if deadFirst {
+			stage.SetDeadLetter(dlq, 3)
+			stage.SetRetryPolicy(retry)
+		} else {
+			stage.SetRetryPolicy(retry)
+			stage.SetDeadLetter(dlq, 3)
+		}
+		in.Push(1)
+		stage.Step(1)
+		if v, ok := dlq.Pop(); !ok || v.(*DeadLetter).Attempts != 3 {
+			t.Fatalf("assertion failed, dead letter first(%v), got %+v.", deadFirst, v)
+		}
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	fn := ExponentialBackoff(time.Millisecond, 10*time.Millisecond)
+	for attempt, want := range []time.Duration{1, 1, 2, 4, 8, 10, 10} {
+		if attempt == 0 {
+			continue
+		}
+		if got := fn(attempt); got != want*time.Millisecond {
+			t.Fatalf("assertion failed, attempt %d: %v!=%v.", attempt, got, want*time.Millisecond)
+		}
+	}
+}
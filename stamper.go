@@ -0,0 +1,131 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// - MARK: Stamper section.
+
+// Stamper issues unique, globally ordered 64-bit
+// stamps, e.g. to order items of partitioned
+// rings, without a single contended counter. It
+// keeps a tick counter per stripe and encodes
+// the stripe in low bits of stamps, so stripes
+// never collide. Every `every` ticks a stripe
+// publishes its tick, and stripes lagging behind
+// the highest published tick jump ahead; hence a
+// stamp taken after another is below it by at
+// most `every` ticks, and `every` of 1 orders
+// stamps strictly at the cost of contention.
+// Stamps are increasing per stripe but not
+// dense.
+type Stamper struct {
+	_       CacheLinePad
+	hi      uint64 // highest published tick
+	_       CacheLinePad
+	stripes []stampStripe
+	shift   uint   // log2 of number of stripes
+	every   uint64 // reconcile period in ticks
+}
+
+// stampStripe is tick counter of a stripe.
+type stampStripe struct {
+	tick uint64
+	_    [CacheLineSize - 8]byte
+}
+
+// NewStamper allocates and initializes a new
+// `Stamper` with `stripes` stripes, rounded to
+// power of two, reconciling every `every` ticks.
+func NewStamper(stripes int, every uint64) *Stamper {
+	if stripes < 1 {
+		stripes = 1
+	}
+	if every < 1 {
+		every = 1
+	}
+	n := roundP2(uint64(stripes))
+	return &Stamper{
+		stripes: make([]stampStripe, n),
+		shift:   uint(bits.TrailingZeros64(n)),
+		every:   every,
+	}
+}
+
+// Stripes returns number of stripes.
+func (s *Stamper) Stripes() int {
+	return len(s.stripes)
+}
+
+// Next returns next stamp of stripe `stripe`,
+// taken modulo number of stripes, e.g. index of
+// a partition or of a producer.
+func (s *Stamper) Next(stripe int) uint64 {
+	var (
+		i  uint64       = uint64(stripe) & uint64(len(s.stripes)-1)
+		st *stampStripe = &s.stripes[i]
+		k  uint64       = atomic.AddUint64(&st.tick, 1)
+	)
+	if h := atomic.LoadUint64(&s.hi); k <= h {
+		// lagging behind other stripes.
+		k = s.jump(st, h)
+	}
+	if k%s.every == 0 {
+		s.publish(k)
+	}
+	return k<<s.shift | i
+}
+
+// Stripe returns stripe which issued `stamp`.
+func (s *Stamper) Stripe(stamp uint64) int {
+	return int(stamp & uint64(len(s.stripes)-1))
+}
+
+// jump moves tick of stripe `st` past `h` and
+// returns the tick taken.
+func (s *Stamper) jump(st *stampStripe, h uint64) uint64 {
+	for {
+		cur := atomic.LoadUint64(&st.tick)
+		if cur > h {
+			return atomic.AddUint64(&st.tick, 1)
+		}
+		if atomic.CompareAndSwapUint64(&st.tick, cur, h+1) {
+			return h + 1
+		}
+	}
+}
+
+// publish raises highest published tick to `k`.
+func (s *Stamper) publish(k uint64) {
+	for {
+		h := atomic.LoadUint64(&s.hi)
+		if k <= h || atomic.CompareAndSwapUint64(&s.hi, h, k) {
+			return
+		}
+	}
+}
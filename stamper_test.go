@@ -0,0 +1,99 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestStamperUnique(t *testing.T) {
+	const (
+		producers = 6
+		count     = 5000
+	)
+	var (
+		s    *Stamper = NewStamper(3, 16)
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen map[uint64]bool = make(map[uint64]bool, producers*count)
+	)
+	if s.Stripes() != 4 {
+		t.Fatalf("assertion failed, stripes(%d)!=4.", s.Stripes())
+	}
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			var (
+				last  uint64
+				local []uint64 = make([]uint64, 0, count)
+			)
+			for i := 0; i < count; i++ {
+				v := s.Next(p)
+				if s.Stripe(v) != p%4 || (p < 4 && v <= last) {
+					t.Errorf("assertion failed, stamp(%d) after %d on stripe %d.", v, last, p)
+					return
+				}
+				last = v
+				local = append(local, v)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, v := range local {
+				if seen[v] {
+					t.Errorf("assertion failed, duplicate stamp(%d).", v)
+				}
+				seen[v] = true
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+func TestStamperOrder(t *testing.T) {
+	// busy stripe 0 drags idle stripe 1 along
+	var (
+		s    *Stamper = NewStamper(2, 8)
+		last uint64
+	)
+	for i := 0; i < 100; i++ {
+		last = s.Next(0)
+	}
+	if v := s.Next(1); v+8<<1 < last {
+		t.Fatalf("assertion failed, stamp(%d) lags %d by more than a period.", v, last)
+	}
+	// period of one orders strictly
+	s = NewStamper(4, 1)
+	for i := 0; i < 100; i++ {
+		v := s.Next(i)
+		if v <= last && i > 0 {
+			t.Fatalf("assertion failed, stamp(%d)<=%d.", v, last)
+		}
+		last = v
+	}
+}
@@ -0,0 +1,247 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrMember is returned when joining a member
+	// twice or leaving an unknown member.
+	ErrMember = errors.New("lfring: invalid member")
+)
+
+// - MARK: Sticky section.
+
+// AssignFunc is called for every member whose
+// partitions changed with the partitions it
+// gained and lost.
+type AssignFunc func(member string, added, removed []int)
+
+// Sticky assigns partition rings, e.g. shards of
+// a `ShardedRing`, to consumers. Each partition
+// is owned by one member and stays with it across
+// membership changes unless balance requires a
+// move; rebalancing moves as few partitions as
+// possible, so stateful consumers keep their
+// local caches warm. Membership changes are
+// serialized, lookups and pops are lock-free.
+type Sticky struct {
+	// 64bit aligned
+	moved    uint64                     // partitions moved between members
+	mu       sync.Mutex                 // serializes rebalancing
+	rings    []*Ring                    // partitions
+	onChange AssignFunc                 // assignment-change callback, or nil
+	cur      atomic.Pointer[assignment] // copy-on-write
+}
+
+// assignment is an immutable partition assignment.
+type assignment struct {
+	members []string           // in join order
+	owner   []string           // owner per partition, "" unowned
+	parts   map[string][]int   // owned partitions per member
+	next    map[string]*uint64 // round robin position per member
+}
+
+// NewSticky allocates and initializes a new
+// `Sticky` over partitions `rings` calling
+// `onChange`, unless nil, on every assignment
+// change. Callbacks run while rebalancing and
+// must not join or leave members.
+func NewSticky(rings []*Ring, onChange AssignFunc) *Sticky {
+	s := &Sticky{rings: rings, onChange: onChange}
+	a := &assignment{owner: make([]string, len(rings)), parts: map[string][]int{}, next: map[string]*uint64{}}
+	s.cur.Store(a)
+	return s
+}
+
+// Join adds `member` and rebalances. It returns
+// `ErrMember` when already a member.
+func (s *Sticky) Join(member string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.load()
+	if _, ok := old.parts[member]; ok {
+		return ErrMember
+	}
+	s.rebalance(old, append(append(make([]string, 0, len(old.members)+1), old.members...), member))
+	return nil
+}
+
+// Leave removes `member` and rebalances its
+// partitions over remaining members. It returns
+// `ErrMember` when not a member.
+func (s *Sticky) Leave(member string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.load()
+	if _, ok := old.parts[member]; !ok {
+		return ErrMember
+	}
+	members := make([]string, 0, len(old.members))
+	for _, m := range old.members {
+		if m != member {
+			members = append(members, m)
+		}
+	}
+	s.rebalance(old, members)
+	return nil
+}
+
+// Members returns current members.
+func (s *Sticky) Members() []string {
+	return append([]string(nil), s.load().members...)
+}
+
+// Assigned returns partitions owned by `member`.
+func (s *Sticky) Assigned(member string) []int {
+	return append([]int(nil), s.load().parts[member]...)
+}
+
+// Owner returns owner of partition `i`, or "".
+func (s *Sticky) Owner(i int) string {
+	return s.load().owner[i]
+}
+
+// Moved returns number of partitions moved from
+// one member to another by rebalancing.
+func (s *Sticky) Moved() uint64 {
+	return atomic.LoadUint64(&s.moved)
+}
+
+// Pop removes an item of a partition owned by
+// `member`, visiting them in round robin order,
+// and returns it with its partition. It returns
+// false when they are empty or `member` owns
+// none.
+func (s *Sticky) Pop(member string) (interface{}, int, bool) {
+	a := s.load()
+	parts := a.parts[member]
+	if len(parts) == 0 {
+		return nil, -1, false
+	}
+	start := atomic.AddUint64(a.next[member], 1) - 1
+	for i := range parts {
+		p := parts[(start+uint64(i))%uint64(len(parts))]
+		if v, ok := s.rings[p].Pop(); ok {
+			return v, p, true
+		}
+	}
+	return nil, -1, false
+}
+
+// load returns current assignment.
+func (s *Sticky) load() *assignment {
+	return s.cur.Load()
+}
+
+// rebalance assigns partitions to `members`
+// starting from `old` and publishes the result.
+// Partitions of departed members and partitions
+// above quota of a member are released, then
+// handed to members below quota. Members owning
+// most partitions keep the larger quotas, which
+// minimizes moves.
+func (s *Sticky) rebalance(old *assignment, members []string) {
+	a := &assignment{members: members, owner: make([]string, len(s.rings)), parts: map[string][]int{}, next: map[string]*uint64{}}
+	for _, m := range members {
+		a.parts[m] = nil
+		a.next[m] = new(uint64)
+	}
+	if len(members) > 0 {
+		var (
+			free  []int                             // unowned partitions
+			quota map[string]int = map[string]int{} // partitions per member
+			order []string       = append([]string(nil), members...)
+		)
+		sort.SliceStable(order, func(i, j int) bool {
+			return len(old.parts[order[i]]) > len(old.parts[order[j]])
+		})
+		for i, m := range order {
+			quota[m] = len(s.rings) / len(members)
+			if i < len(s.rings)%len(members) {
+				quota[m]++
+			}
+		}
+		for p, m := range old.owner {
+			if _, ok := a.parts[m]; ok && len(a.parts[m]) < quota[m] {
+				a.assign(m, p)
+				continue
+			}
+			free = append(free, p)
+		}
+		for _, m := range members {
+			for len(a.parts[m]) < quota[m] {
+				a.assign(m, free[0])
+				free = free[1:]
+			}
+			sort.Ints(a.parts[m])
+		}
+	}
+	s.cur.Store(a)
+	s.notify(old, a)
+}
+
+// assign makes `m` owner of partition `p`.
+func (a *assignment) assign(m string, p int) {
+	a.owner[p] = m
+	a.parts[m] = append(a.parts[m], p)
+}
+
+// notify counts moves from `old` to `a` and calls
+// assignment-change callback for changed members.
+func (s *Sticky) notify(old, a *assignment) {
+	for p := range a.owner {
+		if old.owner[p] != "" && a.owner[p] != "" && old.owner[p] != a.owner[p] {
+			atomic.AddUint64(&s.moved, 1)
+		}
+	}
+	if s.onChange == nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, m := range append(append([]string(nil), old.members...), a.members...) {
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		var added, removed []int
+		for p := range a.owner {
+			switch {
+			case a.owner[p] == m && old.owner[p] != m:
+				added = append(added, p)
+			case a.owner[p] != m && old.owner[p] == m:
+				removed = append(removed, p)
+			}
+		}
+		if len(added) > 0 || len(removed) > 0 {
+			s.onChange(m, added, removed)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"reflect"
+	"testing"
+)
+
+// - MARK: Test section.
+
+func TestStickyRebalance(t *testing.T) {
+	var (
+		changes map[string][2][]int = map[string][2][]int{}
+		s       *Sticky
+	)
+	s = NewSticky(NewShardedRing(6, 4).shards, func(m string, added, removed []int) {
+		changes[m] = [2][]int{added, removed}
+	})
+	if err := s.Join("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Join("a"); err != ErrMember {
+		t.Fatalf("assertion failed, expected ErrMember, got %v.", err)
+	}
+	if !reflect.DeepEqual(s.Assigned("a"), []int{0, 1, 2, 3, 4, 5}) {
+		t.Fatalf("assertion failed, unexpected assignment %v.", s.Assigned("a"))
+	}
+	changes = map[string][2][]int{}
+	s.Join("b")
+	if !reflect.DeepEqual(changes["a"], [2][]int{nil, {3, 4, 5}}) || !reflect.DeepEqual(changes["b"], [2][]int{{3, 4, 5}, nil}) {
+		t.Fatalf("assertion failed, unexpected changes %v.", changes)
+	}
+	s.Join("c")
+	if s.Moved() != 5 {
+		t.Fatalf("assertion failed, expected 5 moves, got %d.", s.Moved())
+	}
+	for _, m := range s.Members() {
+		if len(s.Assigned(m)) != 2 {
+			t.Fatalf("assertion failed, expected 2 partitions of %s.", m)
+		}
+	}
+	// partitions of remaining members stay put.
+	a, c := s.Assigned("a"), s.Assigned("c")
+	if err := s.Leave("b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Leave("b"); err != ErrMember {
+		t.Fatalf("assertion failed, expected ErrMember, got %v.", err)
+	}
+	for _, p := range a {
+		if s.Owner(p) != "a" {
+			t.Fatalf("assertion failed, partition %d left a.", p)
+		}
+	}
+	for _, p := range c {
+		if s.Owner(p) != "c" {
+			t.Fatalf("assertion failed, partition %d left c.", p)
+		}
+	}
+	if len(s.Assigned("a")) != 3 || len(s.Assigned("c")) != 3 || s.Moved() != 7 {
+		t.Fatal("inconsistent state, unbalanced assignment.")
+	}
+	s.Leave("a")
+	s.Leave("c")
+	for p := 0; p < 6; p++ {
+		if s.Owner(p) != "" {
+			t.Fatal("inconsistent state, expected unowned partitions.")
+		}
+	}
+}
+
+func TestStickyPop(t *testing.T) {
+	var (
+		r *ShardedRing = NewShardedRing(4, 4)
+		s *Sticky      = NewSticky(r.shards, nil)
+	)
+	s.Join("a")
+	s.Join("b")
+	for i := 0; i < 4; i++ {
+		r.Shard(i).Push(i)
+	}
+	if _, _, ok := s.Pop("x"); ok {
+		t.Fatal("assertion failed, expected nothing for non-member.")
+	}
+	var got []int
+	for {
+		v, p, ok := s.Pop("b")
+		if !ok {
+			break
+		}
+		if s.Owner(p) != "b" || v.(int) != p {
+			t.Fatal("assertion failed, popped foreign partition.")
+		}
+		got = append(got, p)
+	}
+	if len(got) != 2 || r.Len() != 2 {
+		t.Fatalf("inconsistent state, expected 2 items of b, got %v.", got)
+	}
+}
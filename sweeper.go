@@ -0,0 +1,168 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// - MARK: Sweeper section.
+
+// Sweeper enforces retention of rings in the
+// background instead of relying on pops: at
+// jittered intervals it reaps expired items of
+// rings built `WithTTL`, which otherwise stay
+// queued until popped or reaped, and drops
+// subscribers of broadcast rings lagging more
+// than a retention bound behind producers, so
+// slots they hold back are released to
+// producers. Jitter keeps sweepers of many rings
+// from running in lockstep.
+type Sweeper struct {
+	interval time.Duration
+	jitter   float64
+	mu       sync.Mutex // guards rings and casts
+	rings    []*Ring
+	casts    []sweptBroadcast
+	sweeps   uint64 // completed sweeps
+	reaped   uint64 // expired items reaped
+	dropped  uint64 // lagging subscribers dropped
+}
+
+// sweptBroadcast is a broadcast ring of a sweeper
+// with its retention bound.
+type sweptBroadcast struct {
+	b         *Broadcast
+	retention uint64
+}
+
+// SweeperStats is a snapshot of sweeper
+// statistics.
+type SweeperStats struct {
+	Sweeps  uint64 // completed sweeps
+	Reaped  uint64 // expired items reaped
+	Dropped uint64 // lagging subscribers dropped
+}
+
+// NewSweeper allocates and initializes a new
+// `Sweeper` sweeping every `interval`, varied
+// randomly by up to the fraction `jitter` of it,
+// and returns a pointer to it. `jitter` is
+// clamped to [0, 1].
+func NewSweeper(interval time.Duration, jitter float64) *Sweeper {
+	if jitter < 0 {
+		jitter = 0
+	} else if jitter > 1 {
+		jitter = 1
+	}
+	return &Sweeper{interval: interval, jitter: jitter}
+}
+
+// Ring adds ring `r` whose expired items are
+// reaped, see `ReapExpired`. Rings without
+// `WithTTL` are ignored.
+func (s *Sweeper) Ring(r *Ring) {
+	if r.ttl == nil {
+		return
+	}
+	s.mu.Lock()
+	s.rings = append(s.rings, r)
+	s.mu.Unlock()
+}
+
+// Broadcast adds broadcast ring `b` whose
+// subscribers are dropped, see `Drop`, once
+// they lag more than `retention` items behind
+// producers.
+func (s *Sweeper) Broadcast(b *Broadcast, retention uint64) {
+	s.mu.Lock()
+	s.casts = append(s.casts, sweptBroadcast{b, retention})
+	s.mu.Unlock()
+}
+
+// Sweep performs one sweep over rings and
+// returns number of reaped items and dropped
+// subscribers.
+func (s *Sweeper) Sweep() (reaped, dropped int) {
+	s.mu.Lock()
+	rings, casts := s.rings, s.casts
+	s.mu.Unlock()
+	for _, r := range rings {
+		reaped += r.ReapExpired()
+	}
+	for _, c := range casts {
+		for _, sub := range c.b.Lagging(c.retention) {
+			if !sub.Dropped() {
+				sub.Drop()
+				dropped++
+			}
+		}
+	}
+	atomic.AddUint64(&s.sweeps, 1)
+	atomic.AddUint64(&s.reaped, uint64(reaped))
+	atomic.AddUint64(&s.dropped, uint64(dropped))
+	return reaped, dropped
+}
+
+// Run sweeps at jittered intervals until `stop`
+// is closed.
+func (s *Sweeper) Run(stop <-chan struct{}) {
+	t := time.NewTimer(s.next())
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		s.Sweep()
+		t.Reset(s.next())
+	}
+}
+
+// Stats returns a snapshot of sweeper
+// statistics.
+func (s *Sweeper) Stats() SweeperStats {
+	return SweeperStats{
+		Sweeps:  atomic.LoadUint64(&s.sweeps),
+		Reaped:  atomic.LoadUint64(&s.reaped),
+		Dropped: atomic.LoadUint64(&s.dropped),
+	}
+}
+
+// next returns delay until next sweep, i.e.
+// interval varied by up to +/- jitter.
+func (s *Sweeper) next() time.Duration {
+	d := s.interval
+	if span := int64(float64(d) * s.jitter); span > 0 {
+		d += time.Duration(rnd.Int64N(2*span+1) - span)
+	}
+	if d <= 0 {
+		d = 1
+	}
+	return d
+}
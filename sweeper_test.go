@@ -0,0 +1,92 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"testing"
+	"time"
+)
+
+// - MARK: Test section.
+
+func TestSweeper(t *testing.T) {
+	var (
+		s    *Sweeper   = NewSweeper(time.Millisecond, 0.5)
+		r    *Ring      = NewRing(8, WithTTL(nil))
+		b    *Broadcast = NewBroadcast(8)
+		past time.Time  = time.Now().Add(-time.Second)
+	)
+	s.Ring(r)
+	s.Ring(NewRing(8)) // ignored, no TTL
+	s.Broadcast(b, 2)
+	slow, fast := b.Subscribe(), b.Subscribe()
+	r.PushDeadline("stale", past)
+	r.PushDeadline("stale", past)
+	r.PushTTL("fresh", time.Hour)
+	for i := 0; i < 3; i++ {
+		b.Push(i)
+	}
+	fast.Consume(3, func(interface{}) bool { return true })
+	if reaped, dropped := s.Sweep(); reaped != 2 || dropped != 1 {
+		t.Fatalf("assertion failed, reaped(%d), dropped(%d).", reaped, dropped)
+	}
+	if !slow.Dropped() || fast.Dropped() || r.Len() != 1 {
+		t.Fatal("inconsistent state, sweep did not enforce retention.")
+	}
+	// dropped subscribers are not counted again.
+	if reaped, dropped := s.Sweep(); reaped != 0 || dropped != 0 {
+		t.Fatalf("assertion failed, reaped(%d), dropped(%d).", reaped, dropped)
+	}
+	if st := s.Stats(); st != (SweeperStats{Sweeps: 2, Reaped: 2, Dropped: 1}) {
+		t.Fatalf("assertion failed, stats(%+v).", st)
+	}
+}
+
+func TestSweeperRun(t *testing.T) {
+	var (
+		s    *Sweeper      = NewSweeper(time.Millisecond, 1)
+		r    *Ring         = NewRing(8, WithTTL(nil))
+		stop chan struct{} = make(chan struct{})
+		done chan struct{} = make(chan struct{})
+	)
+	s.Ring(r)
+	r.PushDeadline("stale", time.Now().Add(-time.Second))
+	go func() {
+		s.Run(stop)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Stats().Reaped == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("assertion failed, sweeper did not reap.")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(stop)
+	<-done
+	if r.Len() != 0 {
+		t.Fatal("inconsistent state, expected empty ring.")
+	}
+}
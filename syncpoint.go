@@ -0,0 +1,107 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// - MARK: SyncPoint section.
+
+// SyncPoint is a cross-ring sync point. It
+// captures write-index of every ring when made
+// and is passed once each ring has drained past
+// it, i.e. every item pushed, or being pushed,
+// before `NewSyncPoint` returned has been popped
+// and released its slot. Items pushed later do
+// not hold it back. This lets a topology flush
+// everything in flight before a snapshot or a
+// shutdown. Note, the name `Barrier` belongs to
+// `Sequencer`.
+type SyncPoint struct {
+	rings  []*Ring
+	points []uint64 // captured write-index per ring
+	done   []uint64 // positions verified as consumed
+}
+
+// NewSyncPoint allocates and initializes a new
+// `SyncPoint` over `rings` at their current
+// write-index.
+func NewSyncPoint(rings ...*Ring) *SyncPoint {
+	s := &SyncPoint{rings: rings, points: make([]uint64, len(rings)), done: make([]uint64, len(rings))}
+	for i, r := range rings {
+		s.points[i] = r.writeIndex()
+		if s.points[i] > r.size {
+			// earlier laps are consumed, their
+			// slots were reused.
+			s.done[i] = s.points[i] - r.size
+		}
+	}
+	return s
+}
+
+// Passed returns whether all rings drained past
+// the sync point.
+func (s *SyncPoint) Passed() bool {
+	for i := range s.rings {
+		if !s.drained(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// Wait blocks until all rings drained past the
+// sync point or `ctx` is done, in which case
+// `ctx.Err()` is returned. Nothing is popped by
+// `Wait`; consumers must keep running.
+func (s *SyncPoint) Wait(ctx context.Context) error {
+	_, err := casBackoff.RetryCtx(ctx, s.Passed)
+	return err
+}
+
+// drained returns whether ring `i` drained past
+// its captured position. A position is consumed
+// once its slot moved past the published state,
+// so consumers finishing out of order are
+// awaited, not only read-index. Verified
+// positions are remembered to keep polls cheap.
+func (s *SyncPoint) drained(i int) bool {
+	r, point := s.rings[i], s.points[i]
+	if r.readIndex() < point {
+		return false
+	}
+	for {
+		pos := atomic.LoadUint64(&s.done[i])
+		if pos >= point {
+			return true
+		}
+		if atomic.LoadUint64(r.seq(pos))-pos <= 1 {
+			return false
+		}
+		atomic.CompareAndSwapUint64(&s.done[i], pos, pos+1)
+	}
+}
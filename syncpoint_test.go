@@ -0,0 +1,94 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// - MARK: Test section.
+
+func TestSyncPoint(t *testing.T) {
+	var (
+		a *Ring = NewRing(4)
+		b *Ring = NewRing(4)
+	)
+	for i := 0; i < 3; i++ {
+		a.Push(i)
+		b.Push(i)
+	}
+	s := NewSyncPoint(a, b)
+	if s.Passed() {
+		t.Fatal("assertion failed, expected pending sync point.")
+	}
+	for i := 0; i < 3; i++ {
+		a.Pop()
+	}
+	// items pushed after sync point don't count.
+	a.Push(3)
+	a.Push(4)
+	if s.Passed() {
+		t.Fatal("assertion failed, expected pending sync point.")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("assertion failed, expected deadline, got %v.", err)
+	}
+	go func() {
+		for i := 0; i < 3; i++ {
+			time.Sleep(time.Millisecond)
+			b.Pop()
+		}
+	}()
+	if err := s.Wait(context.Background()); err != nil {
+		t.Fatalf("assertion failed, expected nil, got %v.", err)
+	}
+	if b.Len() != 0 || a.Len() != 2 {
+		t.Fatal("inconsistent state, unexpected ring lengths.")
+	}
+}
+
+func TestSyncPointWrapped(t *testing.T) {
+	var r *Ring = NewRing(4)
+	for i := 0; i < 10; i++ {
+		r.Push(i)
+		r.Pop()
+	}
+	r.Push(10)
+	s := NewSyncPoint(r)
+	if s.Passed() {
+		t.Fatal("assertion failed, expected pending sync point.")
+	}
+	r.Pop()
+	if !s.Passed() {
+		t.Fatal("assertion failed, expected passed sync point.")
+	}
+	if !NewSyncPoint().Passed() {
+		t.Fatal("assertion failed, expected empty sync point to pass.")
+	}
+}
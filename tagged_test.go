@@ -0,0 +1,58 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"testing"
+	"time"
+)
+
+// - MARK: Test section.
+
+func TestTagged(t *testing.T) {
+	var (
+		w    Tagged[int]
+		a, b int
+	)
+	if w.Load() != nil {
+		t.Fatal("assertion failed, expected nil zero value.")
+	}
+	w.Store(&a)
+	if w.CompareAndSwap(&b, nil) || w.Load() != &a {
+		t.Fatal("assertion failed, swapped mismatching pointer.")
+	}
+	if !w.CompareAndSwap(&a, &b) || w.Load() != &b {
+		t.Fatal("assertion failed, expected swap.")
+	}
+	// readers wait out an operation in flight.
+	release := holdTagged(&w)
+	go func() {
+		time.Sleep(time.Millisecond)
+		release(&a)
+	}()
+	if w.Load() != &a {
+		t.Fatal("inconsistent state, observed operation in flight.")
+	}
+}
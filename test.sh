@@ -1,3 +1,9 @@
 #!/bin/bash
 
 go test -race .
+go test -tags=linearizability -run Linearizable .
+go test -tags=interleave -run 'Explorer|Interleave' .
+go test -run '^$' -fuzz FuzzRingConcurrent -fuzztime 30s .
+go test -run '^$' -fuzz FuzzRingSequential -fuzztime 30s .
+GOARCH=386 go test ./...
+go test -tags=purego ./...
@@ -0,0 +1,184 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// - MARK: Timer-wheel section.
+
+// timerEntry is an item scheduled on a timer wheel.
+type timerEntry struct {
+	at    time.Time   // deadline
+	value interface{} // payload
+}
+
+// timerWheel is a hashed timer wheel whose buckets
+// are rings; scheduling and expiry are lock-free.
+// Deadlines are rounded up to `tick` resolution.
+type timerWheel struct {
+	tick     time.Duration
+	start    time.Time
+	buckets  []*Ring
+	overflow *Unbounded // entries not yet due whose bucket was full
+	cursor   uint64     // next tick to expire
+	pending  uint64     // scheduled entries
+}
+
+// newTimerWheel allocates and initializes a new
+// `timerWheel` with `nbuckets` buckets each holding
+// up to `bucketcap` entries.
+func newTimerWheel(tick time.Duration, nbuckets, bucketcap uint64, now time.Time) *timerWheel {
+	w := &timerWheel{
+		tick:     tick,
+		start:    now,
+		buckets:  make([]*Ring, roundP2(nbuckets)),
+		overflow: NewUnboundedSize(bucketcap),
+	}
+	for i := range w.buckets {
+		w.buckets[i] = NewRing(bucketcap)
+	}
+	return w
+}
+
+// ticks returns number of ticks elapsed at `t`,
+// rounded up when `ceil` is true.
+func (w *timerWheel) ticks(t time.Time, ceil bool) uint64 {
+	d := t.Sub(w.start)
+	if d <= 0 {
+		return 0
+	}
+	if ceil {
+		d += w.tick - 1
+	}
+	return uint64(d / w.tick)
+}
+
+// schedule puts `v` on the wheel to expire at `at`
+// and returns false when its bucket is full.
+// Entries whose bucket has just been passed expire
+// one lap later.
+func (w *timerWheel) schedule(at time.Time, v interface{}) bool {
+	var (
+		t      uint64 = w.ticks(at, true)
+		cursor uint64 = atomic.LoadUint64(&w.cursor)
+	)
+	if t < cursor {
+		t = cursor
+	}
+	// count entry first, so `advance` does not
+	// skip its tick as idle.
+	atomic.AddUint64(&w.pending, 1)
+	if !w.buckets[t&uint64(len(w.buckets)-1)].Push(&timerEntry{at: at, value: v}) {
+		atomic.AddUint64(&w.pending, ^uint64(0))
+		return false
+	}
+	return true
+}
+
+// advance expires entries due at `now` and passes
+// their payloads to `fn`. Each tick is claimed by
+// exactly one caller; idle ticks are skipped in a
+// single step, see `skip`. Entries not yet due are
+// pushed back to their bucket, or carried over to
+// `overflow` when a concurrent `schedule` filled
+// it. It returns number of expired entries.
+func (w *timerWheel) advance(now time.Time, fn func(interface{})) int {
+	var (
+		n   int
+		end uint64 = w.ticks(now, false)
+	)
+	// overflow is checked on every call; entries
+	// carried over below are not due before
+	// next call.
+	for i := w.overflow.Len(); i > 0; i-- {
+		v, ok := w.overflow.Pop()
+		if !ok {
+			break
+		}
+		e := v.(*timerEntry)
+		if e.at.After(now) {
+			w.overflow.Push(e)
+			continue
+		}
+		atomic.AddUint64(&w.pending, ^uint64(0))
+		fn(e.value)
+		n++
+	}
+	for {
+		cursor := atomic.LoadUint64(&w.cursor)
+		if cursor > end {
+			return n
+		}
+		if next := w.skip(cursor, end); next != cursor {
+			atomic.CompareAndSwapUint64(&w.cursor, cursor, next)
+			continue
+		}
+		if !atomic.CompareAndSwapUint64(&w.cursor, cursor, cursor+1) {
+			continue
+		}
+		bucket := w.buckets[cursor&uint64(len(w.buckets)-1)]
+		// entries of later laps are pushed back;
+		// bound the loop by current length.
+		for i := bucket.Len(); i > 0; i-- {
+			v, ok := bucket.Pop()
+			if !ok {
+				break
+			}
+			e := v.(*timerEntry)
+			if e.at.After(now) {
+				if !bucket.Push(e) {
+					w.overflow.Push(e)
+				}
+				continue
+			}
+			atomic.AddUint64(&w.pending, ^uint64(0))
+			fn(e.value)
+			n++
+		}
+	}
+}
+
+// skip returns tick `advance` may move `cursor`
+// to instead of claiming every tick up to `end`:
+// past `end` when nothing is scheduled, else one
+// lap before it, since a lap visits each bucket
+// and due entries expire regardless of lap.
+func (w *timerWheel) skip(cursor, end uint64) uint64 {
+	if atomic.LoadUint64(&w.pending) == 0 {
+		return end + 1
+	}
+	if lap := uint64(len(w.buckets)); end+1-cursor > lap {
+		return end + 1 - lap
+	}
+	return cursor
+}
+
+// len returns number of scheduled entries.
+func (w *timerWheel) len() uint64 {
+	return atomic.LoadUint64(&w.pending)
+}
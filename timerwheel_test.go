@@ -0,0 +1,126 @@
+/*
+* MIT License
+*
+* Copyright (c) 2017 Milad (Mike) Taghavi <mitghi[at]me/gmail.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimerWheel(t *testing.T) {
+	var (
+		start time.Time   = time.Unix(0, 0)
+		w     *timerWheel = newTimerWheel(time.Millisecond, 4, 8, start)
+		got   []int
+		fn    func(interface{})
+	)
+	fn = func(v interface{}) { got = append(got, v.(int)) }
+	// rounded up to the same tick
+	w.schedule(start.Add(1500*time.Microsecond), 1)
+	w.schedule(start.Add(2*time.Millisecond), 2)
+	// lands in the same bucket one lap later
+	w.schedule(start.Add(6*time.Millisecond), 6)
+	if n := w.advance(start.Add(1*time.Millisecond), fn); n != 0 {
+		t.Fatalf("assertion failed, expired %d entries early.", n)
+	}
+	if n := w.advance(start.Add(2*time.Millisecond), fn); n != 2 || got[0] != 1 || got[1] != 2 {
+		t.Fatalf("assertion failed, n(%d), got(%v).", n, got)
+	}
+	if w.len() != 1 {
+		t.Fatalf("assertion failed, pending(%d)!=1.", w.len())
+	}
+	if n := w.advance(start.Add(6*time.Millisecond), fn); n != 1 || got[2] != 6 {
+		t.Fatalf("assertion failed, n(%d), got(%v).", n, got)
+	}
+	// past deadlines expire on next advance
+	w.schedule(start, 0)
+	if n := w.advance(start.Add(7*time.Millisecond), fn); n != 1 || got[3] != 0 {
+		t.Fatalf("assertion failed, n(%d), got(%v).", n, got)
+	}
+}
+
+func TestTimerWheelIdle(t *testing.T) {
+	var (
+		start time.Time   = time.Unix(0, 0)
+		w     *timerWheel = newTimerWheel(time.Millisecond, 4, 8, start)
+		now   time.Time   = start.Add(time.Hour)
+		got   []int
+		fn    func(interface{})
+	)
+	fn = func(v interface{}) { got = append(got, v.(int)) }
+	// an empty wheel jumps past idle ticks
+	if n := w.advance(now, fn); n != 0 || w.cursor != w.ticks(now, false)+1 {
+		t.Fatalf("assertion failed, n(%d), cursor(%d).", n, w.cursor)
+	}
+	w.schedule(now.Add(time.Millisecond), 1)
+	w.schedule(now.Add(time.Hour), 2)
+	// a pending entry bounds the walk to one lap
+	now = now.Add(time.Minute)
+	if n := w.advance(now, fn); n != 1 || got[0] != 1 || w.cursor != w.ticks(now, false)+1 {
+		t.Fatalf("assertion failed, n(%d), got(%v), cursor(%d).", n, got, w.cursor)
+	}
+	if w.len() != 1 {
+		t.Fatalf("assertion failed, pending(%d)!=1.", w.len())
+	}
+	if n := w.advance(now.Add(time.Hour), fn); n != 1 || got[1] != 2 {
+		t.Fatalf("assertion failed, n(%d), got(%v).", n, got)
+	}
+}
+
+func TestTimerWheelFullBucket(t *testing.T) {
+	var (
+		start time.Time   = time.Unix(0, 0)
+		w     *timerWheel = newTimerWheel(time.Millisecond, 4, 2, start)
+		got   []int
+		fn    func(interface{})
+		once  bool
+	)
+	fn = func(v interface{}) { got = append(got, v.(int)) }
+	w.schedule(start.Add(5*time.Millisecond), 5)
+	// a racing producer fills the bucket while
+	// the entry is out of it
+	w.buckets[1].SetTracer(&Tracer{OnPop: func(uint64, time.Time) {
+		if !once {
+			once = true
+			w.schedule(start.Add(9*time.Millisecond), 9)
+			w.schedule(start.Add(9*time.Millisecond), 9)
+		}
+	}})
+	if n := w.advance(start.Add(time.Millisecond), fn); n != 0 || len(got) != 0 {
+		t.Fatalf("assertion failed, expired early, n(%d), got(%v).", n, got)
+	}
+	if w.overflow.Len() != 1 || w.len() != 3 {
+		t.Fatalf("inconsistent state, overflow(%d), pending(%d).", w.overflow.Len(), w.len())
+	}
+	if n := w.advance(start.Add(4*time.Millisecond), fn); n != 0 {
+		t.Fatalf("assertion failed, expired early, got(%v).", got)
+	}
+	if n := w.advance(start.Add(5*time.Millisecond), fn); n != 1 || got[0] != 5 {
+		t.Fatalf("assertion failed, n(%d), got(%v).", n, got)
+	}
+	if n := w.advance(start.Add(9*time.Millisecond), fn); n != 2 || w.len() != 0 {
+		t.Fatalf("assertion failed, n(%d), pending(%d).", n, w.len())
+	}
+}
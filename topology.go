@@ -0,0 +1,285 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Topology node kinds.
+const (
+	KindRing       = "ring"
+	KindStage      = "stage"
+	KindJoin       = "join"
+	KindGroup      = "group"
+	KindMember     = "member"
+	KindBroadcast  = "broadcast"
+	KindSubscriber = "subscriber"
+)
+
+// - MARK: Topology section.
+
+// Topology is a registry of stages, joins,
+// groups and broadcasts which make up a ring
+// topology. Rings are discovered through the
+// components attached to them and may be named
+// with `Ring`; unnamed rings are numbered in
+// order of discovery. It is safe for concurrent
+// use.
+type Topology struct {
+	mu    sync.Mutex
+	names map[*Ring]string
+	parts []topologyPart
+}
+
+// topologyPart is a registered component.
+type topologyPart struct {
+	kind string
+	name string
+	v    interface{}
+}
+
+// TopologyNode is a vertex of a `TopologyGraph`
+// with a snapshot of its live metrics.
+type TopologyNode struct {
+	ID      string            `json:"id"`
+	Kind    string            `json:"kind"`
+	Name    string            `json:"name"`
+	Metrics map[string]uint64 `json:"metrics,omitempty"`
+}
+
+// TopologyEdge is a directed edge of a
+// `TopologyGraph`, following the flow of items.
+type TopologyEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"`
+}
+
+// TopologyGraph is a snapshot of a `Topology`.
+type TopologyGraph struct {
+	Nodes []TopologyNode `json:"nodes"`
+	Edges []TopologyEdge `json:"edges"`
+}
+
+// NewTopology allocates and initializes a new
+// `Topology` and returns a pointer to it.
+func NewTopology() *Topology {
+	return &Topology{names: make(map[*Ring]string)}
+}
+
+// Ring names ring `r` in exported graphs.
+func (t *Topology) Ring(name string, r *Ring) *Topology {
+	t.mu.Lock()
+	t.names[r] = name
+	t.mu.Unlock()
+	return t
+}
+
+// Stage registers stage `s` under its name.
+func (t *Topology) Stage(s *Stage) *Topology {
+	return t.add(KindStage, s.Name(), s)
+}
+
+// Join registers join `j` as `name`.
+func (t *Topology) Join(name string, j *Join) *Topology {
+	return t.add(KindJoin, name, j)
+}
+
+// Group registers consumer group `g` as `name`.
+func (t *Topology) Group(name string, g *Group) *Topology {
+	return t.add(KindGroup, name, g)
+}
+
+// Broadcast registers broadcast ring `b` as
+// `name`.
+func (t *Topology) Broadcast(name string, b *Broadcast) *Topology {
+	return t.add(KindBroadcast, name, b)
+}
+
+// add appends a component of `kind`.
+func (t *Topology) add(kind, name string, v interface{}) *Topology {
+	t.mu.Lock()
+	t.parts = append(t.parts, topologyPart{kind: kind, name: name, v: v})
+	t.mu.Unlock()
+	return t
+}
+
+// ExportTopology returns a graph of registered
+// components, the rings connecting them and a
+// snapshot of their metrics. Metrics are read
+// without stopping producers or consumers, so
+// they are only consistent per counter.
+func (t *Topology) ExportTopology() *TopologyGraph {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var (
+		g     *TopologyGraph   = &TopologyGraph{}
+		rings map[*Ring]string = make(map[*Ring]string)
+	)
+	// ring returns id of `r`, adding its node on
+	// first sight; nil rings have no node.
+	ring := func(r *Ring) string {
+		if r == nil {
+			return ""
+		}
+		if id, ok := rings[r]; ok {
+			return id
+		}
+		id := fmt.Sprintf("ring%d", len(rings))
+		rings[r] = id
+		name, ok := t.names[r]
+		if !ok {
+			name = id
+		}
+		g.Nodes = append(g.Nodes, TopologyNode{ID: id, Kind: KindRing, Name: name, Metrics: ringMetrics(r)})
+		return id
+	}
+	edge := func(from, to, label string) {
+		if from != "" && to != "" {
+			g.Edges = append(g.Edges, TopologyEdge{From: from, To: to, Label: label})
+		}
+	}
+	for i, p := range t.parts {
+		id := fmt.Sprintf("%s%d", p.kind, i)
+		node := TopologyNode{ID: id, Kind: p.kind, Name: p.name, Metrics: make(map[string]uint64)}
+		switch v := p.v.(type) {
+		case *Stage:
+			node.Metrics["processed"], node.Metrics["failed"], node.Metrics["dropped"] = v.Stats()
+			node.Metrics["dead"] = v.DeadLettered()
+			node.Metrics["retried"] = v.Retried()
+			node.Metrics["panics"] = v.Panics()
+			node.Metrics["pending"] = v.Pending()
+			g.Nodes = append(g.Nodes, node)
+			edge(ring(v.in), id, "in")
+			edge(id, ring(v.out), "out")
+			edge(id, ring(v.errs), "errors")
+			edge(id, ring(v.dlq), "dead-letter")
+		case *Join:
+			node.Metrics["matched"], node.Metrics["expired"] = v.Stats()
+			g.Nodes = append(g.Nodes, node)
+			edge(ring(v.left), id, "left")
+			edge(ring(v.right), id, "right")
+			edge(id, ring(v.out), "out")
+		case *Group:
+			members := v.Members()
+			node.Metrics["members"] = uint64(len(members))
+			node.Metrics["redeliver"] = v.redeliver.Len()
+			g.Nodes = append(g.Nodes, node)
+			edge(ring(v.ring), id, "in")
+			for j, m := range members {
+				mid := fmt.Sprintf("%s.%s%d", id, KindMember, j)
+				g.Nodes = append(g.Nodes, TopologyNode{
+					ID:      mid,
+					Kind:    KindMember,
+					Name:    m.Name(),
+					Metrics: map[string]uint64{"inflight": uint64(m.InFlight())},
+				})
+				edge(id, mid, "")
+			}
+		case *Broadcast:
+			subs := v.Subscribers()
+			node.Metrics["cap"] = v.Cap()
+			node.Metrics["cursor"] = v.seq.Cursor()
+			node.Metrics["subscribers"] = uint64(len(subs))
+			g.Nodes = append(g.Nodes, node)
+			for j, s := range subs {
+				sid := fmt.Sprintf("%s.%s%d", id, KindSubscriber, j)
+				g.Nodes = append(g.Nodes, TopologyNode{
+					ID:      sid,
+					Kind:    KindSubscriber,
+					Name:    s.Name(),
+					Metrics: map[string]uint64{"offset": s.Offset(), "lag": s.Lag()},
+				})
+				edge(id, sid, "")
+			}
+		}
+	}
+	return g
+}
+
+// ringMetrics returns a snapshot of `r` metrics.
+func ringMetrics(r *Ring) map[string]uint64 {
+	s := r.Stats()
+	return map[string]uint64{
+		"len":         s.Len,
+		"cap":         r.Cap(),
+		"maxlen":      s.MaxLen,
+		"pushes":      s.Pushes,
+		"pops":        s.Pops,
+		"full":        s.Full,
+		"empty":       s.Empty,
+		"overwritten": s.Overwritten,
+		"retries":     s.Retries,
+		"waiting":     r.Waiting(),
+	}
+}
+
+// - MARK: Export section.
+
+// WriteJSON writes graph as JSON to `w`.
+func (g *TopologyGraph) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(g)
+}
+
+// WriteDOT writes graph in Graphviz DOT format to
+// `w`. Rings are drawn as boxes and metrics are
+// part of node labels.
+func (g *TopologyGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph lfring {\n\trankdir=LR;\n")
+	for _, n := range g.Nodes {
+		shape := "ellipse"
+		switch n.Kind {
+		case KindRing, KindBroadcast:
+			shape = "box"
+		}
+		label := n.Kind + " " + n.Name
+		keys := make([]string, 0, len(n.Metrics))
+		for k := range n.Metrics {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			label += fmt.Sprintf("\n%s=%d", k, n.Metrics[k])
+		}
+		fmt.Fprintf(&b, "\t%q [shape=%s, label=%q];\n", n.ID, shape, label)
+	}
+	for _, e := range g.Edges {
+		if e.Label == "" {
+			fmt.Fprintf(&b, "\t%q -> %q;\n", e.From, e.To)
+			continue
+		}
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q];\n", e.From, e.To, e.Label)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,107 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTopologyExport(t *testing.T) {
+	var (
+		in    *Ring      = NewRing(8)
+		mid   *Ring      = NewRing(8)
+		errs  *Ring      = NewRing(8)
+		bc    *Broadcast = NewBroadcast(8)
+		topo  *Topology  = NewTopology()
+		parse *Stage
+		sink  *Stage
+	)
+	parse = NewStage("parse", in, func(v interface{}) (interface{}, error) {
+		if v.(int) < 0 {
+			return nil, errors.New("negative")
+		}
+		return v, nil
+	}, mid, errs)
+	sink = NewStage("sink", mid, func(v interface{}) (interface{}, error) { return nil, nil }, nil, nil)
+	bc.Named("audit")
+	topo.Ring("in", in).Ring("errors", errs).Stage(parse).Stage(sink).Broadcast("events", bc)
+	for _, v := range []int{1, -1, 2} {
+		in.Push(v)
+	}
+	parse.Step(3)
+	bc.Push(1)
+
+	g := topo.ExportTopology()
+	nodes := make(map[string]TopologyNode)
+	for _, n := range g.Nodes {
+		nodes[n.Kind+":"+n.Name] = n
+	}
+	if len(g.Nodes) != 7 || len(g.Edges) != 5 {
+		t.Fatalf("assertion failed, nodes(%d), edges(%d).", len(g.Nodes), len(g.Edges))
+	}
+	if n := nodes["stage:parse"]; n.Metrics["processed"] != 2 || n.Metrics["failed"] != 1 {
+		t.Fatalf("assertion failed, parse metrics(%v).", n.Metrics)
+	}
+	if n := nodes["ring:in"]; n.Metrics["pops"] != 3 || n.Metrics["cap"] != 8 {
+		t.Fatalf("assertion failed, in metrics(%v).", n.Metrics)
+	}
+	if n, ok := nodes["ring:ring1"]; !ok || n.Metrics["len"] != 2 {
+		t.Fatalf("assertion failed, expected unnamed middle ring, got %v.", nodes)
+	}
+	if n := nodes["subscriber:audit"]; n.Metrics["lag"] != 1 {
+		t.Fatalf("assertion failed, audit metrics(%v).", n.Metrics)
+	}
+	// middle ring is shared by both stages
+	var shared int
+	for _, e := range g.Edges {
+		if e.From == "ring1" || e.To == "ring1" {
+			shared++
+		}
+	}
+	if shared != 2 {
+		t.Fatalf("assertion failed, edges(%v).", g.Edges)
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	if !strings.HasPrefix(dot, "digraph") || !strings.Contains(dot, `"ring0" -> "stage0" [label="in"];`) {
+		t.Fatalf("assertion failed, unexpected dot output:\n%s", dot)
+	}
+	buf.Reset()
+	if err := g.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var back TopologyGraph
+	if err := json.Unmarshal(buf.Bytes(), &back); err != nil || len(back.Nodes) != len(g.Nodes) || back.Edges[0] != g.Edges[0] {
+		t.Fatalf("assertion failed, json round trip(%v).", err)
+	}
+}
@@ -0,0 +1,109 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "time"
+
+// - MARK: Tracer section.
+
+// Tracer receives enqueue and dequeue events of a
+// ring with the ring position involved and the
+// event time, e.g. to measure queueing delay as
+// pop time minus push time of a position. Nil
+// hooks are skipped; a ring without tracer pays a
+// single nil check per operation. Hooks run
+// synchronously on the operating goroutine and
+// must be short.
+type Tracer struct {
+	// OnPush is called after position `seq`
+	// was published.
+	OnPush func(seq uint64, at time.Time)
+	// OnPop is called after position `seq`
+	// was taken.
+	OnPop func(seq uint64, at time.Time)
+	// OnDrop is called when a push at position
+	// `seq` is rejected as ring is full or closed,
+	// or when overwrite evicts position `seq`.
+	OnDrop func(seq uint64, at time.Time)
+	// OnBlocked is called when a goroutine
+	// starts waiting on position `seq`, i.e. a
+	// contended read position or fairness ticket.
+	OnBlocked func(seq uint64, at time.Time)
+	// Clock is time source of events, nil for
+	// `DefaultClock`.
+	Clock Clock
+}
+
+// WithTracer sets tracer of ring, see `SetTracer`.
+func WithTracer(t *Tracer) Option {
+	return func(r *Ring) { r.tracer = t }
+}
+
+// SetTracer sets tracer of ring; nil disables
+// tracing. It must be called before ring is
+// shared.
+func (r *Ring) SetTracer(t *Tracer) {
+	r.tracer = t
+	r.hook()
+}
+
+// now returns current time of tracer clock.
+func (t *Tracer) now() time.Time {
+	if t.Clock == nil {
+		return DefaultClock.Now()
+	}
+	return t.Clock.Now()
+}
+
+// push reports published position `seq`.
+func (t *Tracer) push(seq uint64) {
+	if t.OnPush != nil {
+		t.OnPush(seq, t.now())
+	}
+}
+
+// pop reports taken position `seq`.
+func (t *Tracer) pop(seq uint64) {
+	if t.OnPop != nil {
+		t.OnPop(seq, t.now())
+	}
+}
+
+// drop reports dropped position `seq`.
+func (t *Tracer) drop(seq uint64) {
+	if t.OnDrop != nil {
+		t.OnDrop(seq, t.now())
+	}
+}
+
+// block waits after `n` unsuccessful attempts on
+// position `seq`, reporting the first wait. Lock
+// bit of `seq` is not reported.
+func (r *Ring) block(n int, seq uint64) {
+	if n == 0 && r.tracer != nil && r.tracer.OnBlocked != nil {
+		r.tracer.OnBlocked(seq&^cRDLOCK, r.tracer.now())
+	}
+	r.pause(n)
+}
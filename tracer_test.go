@@ -0,0 +1,117 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tsttrace records traced positions per event.
+type tsttrace struct {
+	blocked         uint64 // 64bit aligned
+	push, pop, drop []uint64
+}
+
+func (tt *tsttrace) tracer() *Tracer {
+	return &Tracer{
+		OnPush:    func(seq uint64, _ time.Time) { tt.push = append(tt.push, seq) },
+		OnPop:     func(seq uint64, _ time.Time) { tt.pop = append(tt.pop, seq) },
+		OnDrop:    func(seq uint64, _ time.Time) { tt.drop = append(tt.drop, seq) },
+		OnBlocked: func(seq uint64, _ time.Time) { atomic.StoreUint64(&tt.blocked, seq+1) },
+	}
+}
+
+func equalSeqs(a []uint64, b ...uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRingTracer(t *testing.T) {
+	var (
+		tt  tsttrace
+		r   *Ring         = NewRing(2, WithTracer(tt.tracer()))
+		dst []interface{} = make([]interface{}, 2)
+	)
+	r.Push(0)
+	r.Push(1)
+	r.Push(2)
+	r.Pop()
+	// batch is cut short, nothing is dropped
+	r.PushBatch([]interface{}{3, 4})
+	r.PopInto(dst)
+	if !equalSeqs(tt.push, 0, 1, 2) || !equalSeqs(tt.pop, 0, 1, 2) || !equalSeqs(tt.drop, 2) {
+		t.Fatalf("assertion failed, trace(%+v).", tt)
+	}
+	// overwrite drops evicted position
+	tt = tsttrace{}
+	r = NewRing(2, WithOverwrite(), WithTracer(tt.tracer()))
+	for i := 0; i < 3; i++ {
+		r.Push(i)
+	}
+	if !equalSeqs(tt.drop, 0) || !equalSeqs(tt.push, 0, 1, 2) {
+		t.Fatalf("assertion failed, trace(%+v).", tt)
+	}
+}
+
+func TestRingTracerBlocked(t *testing.T) {
+	var (
+		tt   tsttrace
+		r    *Ring = NewRing(2, WithTracer(tt.tracer()))
+		done chan struct{}
+	)
+	r.Push(0)
+	// lock head like `Consume` does
+	atomic.StoreUint64(&r.rdi, cRDLOCK)
+	done = make(chan struct{})
+	go func() {
+		r.Pop()
+		close(done)
+	}()
+	for atomic.LoadUint64(&tt.blocked) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	atomic.StoreUint64(&r.rdi, 0)
+	<-done
+	if tt.blocked != 1 || !equalSeqs(tt.pop, 0) {
+		t.Fatalf("assertion failed, trace(%+v).", tt)
+	}
+}
+
+func TestRingTracerDisabledAllocs(t *testing.T) {
+	var r *Ring = NewRing(4, WithTracer(&Tracer{}))
+	assertNoAllocs(t, "Push+Pop", func() {
+		r.Push(r)
+		r.Pop()
+	})
+}
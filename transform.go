@@ -0,0 +1,107 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+// - MARK: Transform section.
+
+// Transform is a symmetric record transformation
+// applied by `MessageRing` at the ring boundary,
+// e.g. compression, encryption or redaction, so
+// producers and consumers stay unchanged. It
+// must be safe for concurrent use.
+type Transform interface {
+	// Encode returns transformed record `p`.
+	Encode(p []byte) ([]byte, error)
+	// Decode restores record `p` transformed by
+	// `Encode`. `p` is only valid during the call.
+	Decode(p []byte) ([]byte, error)
+}
+
+// TransformFuncs adapts a pair of functions to
+// `Transform` interface. A nil function leaves
+// records as they are, e.g. one-way redaction
+// only sets `Enc`.
+type TransformFuncs struct {
+	Enc func(p []byte) ([]byte, error)
+	Dec func(p []byte) ([]byte, error)
+}
+
+// Encode implements `Transform` interface.
+func (f TransformFuncs) Encode(p []byte) ([]byte, error) {
+	if f.Enc == nil {
+		return p, nil
+	}
+	return f.Enc(p)
+}
+
+// Decode implements `Transform` interface.
+func (f TransformFuncs) Decode(p []byte) ([]byte, error) {
+	if f.Dec == nil {
+		return p, nil
+	}
+	return f.Dec(p)
+}
+
+// FlateTransform compresses records with
+// `compress/flate` at `Level`; zero selects
+// `flate.DefaultCompression`.
+type FlateTransform struct {
+	Level int
+}
+
+// Encode implements `Transform` interface.
+func (f FlateTransform) Encode(p []byte) ([]byte, error) {
+	var (
+		buf   bytes.Buffer
+		level int = f.Level
+	)
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+	w, err := flate.NewWriter(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(p); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode implements `Transform` interface.
+func (f FlateTransform) Decode(p []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(p))
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,98 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// - MARK: Test section.
+
+// xorTransform is a toy cipher for tests.
+func xorTransform(key byte) Transform {
+	xor := func(p []byte) ([]byte, error) {
+		out := make([]byte, len(p))
+		for i := range p {
+			out[i] = p[i] ^ key
+		}
+		return out, nil
+	}
+	return TransformFuncs{Enc: xor, Dec: xor}
+}
+
+func TestMessageRingTransform(t *testing.T) {
+	var (
+		b   *ByteRing    = NewByteRing(1024)
+		m   *MessageRing = NewMessageRing(b.Messages(), RawCodec, FlateTransform{}, xorTransform(0x5a))
+		msg []byte       = bytes.Repeat([]byte("lfring "), 32)
+	)
+	if ok, err := m.Push(msg); !ok || err != nil {
+		t.Fatalf("inconsistent state, unable to push (%v).", err)
+	}
+	// record is compressed first, then encrypted.
+	p, ok := b.Messages().Pop(nil)
+	if !ok || len(p) >= len(msg) {
+		t.Fatal("assertion failed, expected compressed record.")
+	}
+	if _, err := (FlateTransform{}).Decode(p); err == nil {
+		t.Fatal("assertion failed, expected encrypted record.")
+	}
+	b.Messages().Push(p)
+	v, ok, err := m.Pop()
+	if !ok || err != nil || !bytes.Equal(v.([]byte), msg) {
+		t.Fatalf("assertion failed, expected round trip, got %q (%v).", v, err)
+	}
+	// undecodable records are consumed
+	b.Messages().Push([]byte("garbage"))
+	if _, ok, err := m.Pop(); !ok || err == nil {
+		t.Fatal("assertion failed, expected transform error.")
+	}
+}
+
+func TestMessageRingRedact(t *testing.T) {
+	var (
+		errRedact error = errors.New("redact failed")
+		redact    Transform
+	)
+	redact = TransformFuncs{Enc: func(p []byte) ([]byte, error) {
+		if bytes.Contains(p, []byte("panic")) {
+			return nil, errRedact
+		}
+		return bytes.ReplaceAll(p, []byte("secret"), []byte("******")), nil
+	}}
+	m := NewMessageRing(NewByteRing(256).Messages(), RawCodec, redact)
+	if ok, err := m.Push("my secret"); !ok || err != nil {
+		t.Fatalf("inconsistent state, unable to push (%v).", err)
+	}
+	if ok, err := m.Push("panic"); ok || err != errRedact {
+		t.Fatalf("assertion failed, expected transform error, got %v.", err)
+	}
+	v, ok, err := m.Pop()
+	if !ok || err != nil || string(v.([]byte)) != "my ******" {
+		t.Fatalf("assertion failed, expected redacted record, got %q (%v).", v, err)
+	}
+}
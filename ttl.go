@@ -0,0 +1,135 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// - MARK: TTL section.
+
+// expiry holds item deadlines of a ring. Push
+// clears the deadline of a slot, `PushDeadline`
+// sets it before publication, and pops read it
+// before releasing the slot.
+type expiry struct {
+	base      time.Time // monotonic time origin
+	deadlines []int64   // deadline per slot, ns since base, 0 none
+	expired   uint64    // items skipped past deadline
+	fn        func(item interface{})
+}
+
+// WithTTL enables item deadlines, see
+// `PushDeadline`. `Pop` and `TryPop` skip items
+// past their deadline and pass them to
+// `onExpired`, which may be nil; batch pops, e.g.
+// `PopInto` and `Consume`, deliver them as usual.
+// It costs a clock read per pop of an item with
+// a deadline.
+func WithTTL(onExpired func(item interface{})) Option {
+	return func(r *Ring) { r.ttl = &expiry{fn: onExpired} }
+}
+
+// PushDeadline pushes `data` like `Push`, to
+// expire at `deadline`. Without `WithTTL` it
+// ignores the deadline.
+func (r *Ring) PushDeadline(data interface{}, deadline time.Time) bool {
+	if r.ttl == nil {
+		return r.Push(data)
+	}
+	pos, ok := r.acquireWrite()
+	if !ok {
+		return r.dropped()
+	}
+	r.stamp(pos)
+	d := int64(deadline.Sub(r.ttl.base))
+	if d <= 0 {
+		// before origin, expired anyway
+		d = 1
+	}
+	atomic.StoreInt64(&r.ttl.deadlines[pos&(r.size-1)], d)
+	r.publish(pos, data)
+	r.published(pos, data)
+	return true
+}
+
+// PushTTL pushes `data` like `Push`, to expire
+// after `ttl`, see `PushDeadline`.
+func (r *Ring) PushTTL(data interface{}, ttl time.Duration) bool {
+	return r.PushDeadline(data, r.clock.Now().Add(ttl))
+}
+
+// ReapExpired pops expired items from head of
+// ring, passing them to the callback of
+// `WithTTL`, and returns their number. It stops
+// at the first live item, so expired items
+// queued behind it stay until popped or reaped
+// later; with uniform TTLs, deadlines grow with
+// positions anyway.
+func (r *Ring) ReapExpired() int {
+	var n int
+	if r.ttl == nil {
+		return 0
+	}
+	for {
+		pos := atomic.LoadUint64(&r.rdi)
+		if atomic.LoadUint64(r.seq(pos)) != pos+1 || !r.expired(pos) {
+			break
+		}
+		if !r.claimRead(pos, 1) {
+			r.casFailed()
+			continue
+		}
+		r.age(pos)
+		r.reap(pos)
+		n++
+	}
+	if n > 0 && r.press != nil {
+		r.press.update(r.Len())
+	}
+	return n
+}
+
+// expired returns whether item of acquired or
+// published position `pos` is past its deadline.
+func (r *Ring) expired(pos uint64) bool {
+	d := atomic.LoadInt64(&r.ttl.deadlines[pos&(r.size-1)])
+	return d != 0 && int64(r.clock.Now().Sub(r.ttl.base)) >= d
+}
+
+// reap takes expired item of acquired position
+// `pos` and reports it.
+func (r *Ring) reap(pos uint64) {
+	data := r.take(pos)
+	r.freed()
+	atomic.AddUint64(&r.ttl.expired, 1)
+	if r.tracer != nil {
+		r.tracer.drop(pos)
+	}
+	if r.ttl.fn != nil {
+		r.ttl.fn(data)
+	}
+}
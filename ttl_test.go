@@ -0,0 +1,113 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"testing"
+	"time"
+)
+
+// - MARK: Test section.
+
+func TestRingTTL(t *testing.T) {
+	var (
+		expired []interface{}
+		r       *Ring     = NewRing(8, WithTTL(func(item interface{}) { expired = append(expired, item) }))
+		past    time.Time = time.Now().Add(-time.Second)
+	)
+	r.PushDeadline("stale", past)
+	r.Push("live")
+	r.PushTTL("fresh", time.Hour)
+	r.PushDeadline("stale", past)
+	r.PushTTL("fresh", time.Hour)
+	for _, want := range []string{"live", "fresh", "fresh"} {
+		if v, ok := r.Pop(); !ok || v.(string) != want {
+			t.Fatalf("assertion failed, expected %s, got %v.", want, v)
+		}
+	}
+	if s := r.Stats(); s.Expired != 2 || len(expired) != 2 || r.Len() != 0 {
+		t.Fatalf("assertion failed, stats(%+v), expired(%v).", s, expired)
+	}
+	// slots reused by plain pushes do not expire
+	for i := 0; i < 8; i++ {
+		r.Push(i)
+	}
+	if v, ok := r.TryPop(4); !ok || v.(int) != 0 || r.Stats().Expired != 2 {
+		t.Fatalf("assertion failed, expected 0, got %v.", v)
+	}
+}
+
+func TestRingReapExpired(t *testing.T) {
+	var (
+		r    *Ring     = NewRing(8, WithTTL(nil))
+		past time.Time = time.Now().Add(-time.Second)
+	)
+	for i := 0; i < 3; i++ {
+		r.PushDeadline(i, past)
+	}
+	r.PushTTL(3, time.Hour)
+	r.PushDeadline(4, past)
+	if n := r.ReapExpired(); n != 3 || r.Len() != 2 {
+		t.Fatalf("assertion failed, reaped(%d), len(%d).", n, r.Len())
+	}
+	if v, ok := r.Pop(); !ok || v.(int) != 3 {
+		t.Fatalf("assertion failed, expected 3, got %v.", v)
+	}
+	if _, ok := r.Pop(); ok || r.Stats().Expired != 4 {
+		t.Fatal("assertion failed, expected expired item skipped.")
+	}
+	if NewRing(2).ReapExpired() != 0 || !NewRing(2).PushTTL(0, 0) {
+		t.Fatal("assertion failed, expected deadlines ignored.")
+	}
+}
+
+func TestRingConsumeExpired(t *testing.T) {
+	var (
+		expired []interface{}
+		got     []interface{}
+		r       *Ring     = NewRing(8, WithTTL(func(item interface{}) { expired = append(expired, item) }))
+		past    time.Time = time.Now().Add(-time.Second)
+	)
+	r.PushDeadline(0, past)
+	r.Push(1)
+	r.PushDeadline(2, past)
+	r.PushTTL(3, time.Hour)
+	r.Push(4)
+	// expired items neither reach `fn` nor
+	// count towards `max`.
+	n := r.Consume(2, func(item interface{}) bool {
+		got = append(got, item)
+		return true
+	})
+	if n != 2 || len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Fatalf("assertion failed, consumed(%d), got(%v).", n, got)
+	}
+	if len(expired) != 2 || r.Stats().Expired != 2 || r.Len() != 1 {
+		t.Fatalf("assertion failed, expired(%v), len(%d).", expired, r.Len())
+	}
+	if v, ok := r.Pop(); !ok || v != 4 {
+		t.Fatalf("assertion failed, expected 4, got %v.", v)
+	}
+}
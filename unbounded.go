@@ -0,0 +1,147 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync/atomic"
+)
+
+// Defaults
+const (
+	// cSEGMENTSIZE is default capacity of an
+	// unbounded queue segment.
+	cSEGMENTSIZE = 1024
+)
+
+// - MARK: Unbounded section.
+
+// Unbounded is an unbounded MPMC queue composed of
+// linked ring segments (LCRQ style). When the tail
+// segment fills it is closed and a new segment is
+// appended; consumers move on to the next segment
+// once the head segment is closed and drained.
+// Items stay in ring storage, so queue keeps the
+// cache behavior of the ring.
+type Unbounded struct {
+	_       CacheLinePad
+	head    atomic.Pointer[segment] // consumers
+	_       CacheLinePad
+	tail    atomic.Pointer[segment] // producers
+	_       CacheLinePad
+	count   uint64 // occupancy counter
+	_       CacheLinePad
+	segsize uint64 // segment capacity
+	segs    uint64 // allocated segments
+}
+
+// segment is a ring linked to its successor.
+type segment struct {
+	ring *Ring
+	next atomic.Pointer[segment]
+}
+
+// NewUnbounded allocates and initializes a new
+// `Unbounded` queue with default segment size and
+// returns a pointer to it.
+func NewUnbounded() *Unbounded {
+	return NewUnboundedSize(cSEGMENTSIZE)
+}
+
+// NewUnboundedSize allocates and initializes a new
+// `Unbounded` queue whose segments hold `segsize`
+// items, rounded to nearest power of two.
+func NewUnboundedSize(segsize uint64) *Unbounded {
+	q := &Unbounded{segsize: roundP2(segsize), segs: 1}
+	seg := &segment{ring: NewRing(q.segsize)}
+	q.head.Store(seg)
+	q.tail.Store(seg)
+	return q
+}
+
+// Len returns number of items in queue.
+func (q *Unbounded) Len() uint64 {
+	return atomic.LoadUint64(&q.count)
+}
+
+// Segments returns number of segments allocated
+// since queue creation.
+func (q *Unbounded) Segments() uint64 {
+	return atomic.LoadUint64(&q.segs)
+}
+
+// Push appends `data` to tail segment, allocating
+// a new segment when it is full. It never fails.
+func (q *Unbounded) Push(data interface{}) {
+	for {
+		tail := q.tail.Load()
+		if tail.ring.Push(data) {
+			atomic.AddUint64(&q.count, 1)
+			return
+		}
+		next := tail.next.Load()
+		if next == nil {
+			// seal tail so late producers can not
+			// reorder items behind the successor.
+			tail.ring.close()
+			seg := &segment{ring: NewRing(q.segsize)}
+			seg.ring.Push(data)
+			if tail.next.CompareAndSwap(nil, seg) {
+				atomic.AddUint64(&q.segs, 1)
+				q.tail.CompareAndSwap(tail, seg)
+				atomic.AddUint64(&q.count, 1)
+				return
+			}
+			// competitor appended first; discard
+			// `seg` and retry on its segment.
+			next = tail.next.Load()
+		}
+		// help lagging tail forward
+		q.tail.CompareAndSwap(tail, next)
+	}
+}
+
+// Pop removes and returns the oldest item with a
+// boolean indicating success status. Like
+// `Ring.Pop`, it may report empty while a
+// producer is still publishing its item.
+func (q *Unbounded) Pop() (interface{}, bool) {
+	for {
+		head := q.head.Load()
+		if v, ok := head.ring.Pop(); ok {
+			atomic.AddUint64(&q.count, ui64NMASK)
+			return v, true
+		}
+		next := head.next.Load()
+		if next == nil {
+			return nil, false
+		}
+		// successor exists, hence head is closed
+		// and its write index is final.
+		if head.ring.readIndex() != head.ring.writeIndex() {
+			return nil, false
+		}
+		q.head.CompareAndSwap(head, next)
+	}
+}
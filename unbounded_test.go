@@ -0,0 +1,94 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestUnboundedSerial(t *testing.T) {
+	const n = 100
+	var q *Unbounded = NewUnboundedSize(8)
+	if _, ok := q.Pop(); ok {
+		t.Fatal("inconsistent state, returned value from empty queue.")
+	}
+	for i := 0; i < n; i++ {
+		q.Push(i)
+	}
+	if q.Len() != n || q.Segments() < n/8 {
+		t.Fatalf("assertion failed, len(%d), segments(%d).", q.Len(), q.Segments())
+	}
+	for i := 0; i < n; i++ {
+		if v, ok := q.Pop(); !ok || v.(int) != i {
+			t.Fatalf("assertion failed, expected %d, got %v.", i, v)
+		}
+	}
+	if _, ok := q.Pop(); ok || q.Len() != 0 {
+		t.Fatal("inconsistent state, returned value from empty queue.")
+	}
+}
+
+func TestUnboundedConcurrent(t *testing.T) {
+	const (
+		producers = 4
+		items     = 5000
+	)
+	var (
+		q    *Unbounded = NewUnboundedSize(16)
+		wg   sync.WaitGroup
+		last [producers]int
+		got  int
+	)
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < items; i++ {
+				q.Push([2]int{p, i})
+			}
+		}(p)
+	}
+	for i := range last {
+		last[i] = -1
+	}
+	for got < producers*items {
+		v, ok := q.Pop()
+		if !ok {
+			continue
+		}
+		item := v.([2]int)
+		// per-producer order is preserved
+		if item[1] != last[item[0]]+1 {
+			t.Fatalf("assertion failed, producer %d: %d after %d.", item[0], item[1], last[item[0]])
+		}
+		last[item[0]] = item[1]
+		got++
+	}
+	wg.Wait()
+	if q.Len() != 0 {
+		t.Fatalf("assertion failed, len(%d)!=0.", q.Len())
+	}
+}
@@ -0,0 +1,146 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	// ErrUringUnsupported is returned when io_uring
+	// is not available on the platform, and by
+	// `purego` builds, which can not map its queues.
+	ErrUringUnsupported = errors.New("lfring: io_uring not supported")
+)
+
+// - MARK: Uring section.
+
+// SQE is an io_uring submission queue entry, see
+// `struct io_uring_sqe` in linux/io_uring.h.
+type SQE struct {
+	Opcode      uint8
+	Flags       uint8
+	IOPrio      uint16
+	Fd          int32
+	Off         uint64
+	Addr        uint64
+	Len         uint32
+	OpFlags     uint32
+	UserData    uint64
+	BufIndex    uint16
+	Personality uint16
+	SpliceFdIn  int32
+	Addr3       uint64
+	_           uint64
+}
+
+// CQE is an io_uring completion queue entry, see
+// `struct io_uring_cqe`.
+type CQE struct {
+	UserData uint64
+	Res      int32
+	Flags    uint32
+}
+
+// uringQueue is a queue shared with the kernel.
+// Like `Ring`, it uses free running head and tail
+// indices masked by a power of two size; one side
+// only advances head, the other only tail.
+type uringQueue struct {
+	head    *uint32
+	tail    *uint32
+	mask    uint32
+	entries uint32
+}
+
+// Uring bridges io_uring queues and rings:
+// completions are drained into a `Ring` and
+// submissions are fed from another one, so kernel
+// I/O can be processed with the same lock-free
+// pipelines (`Stage`, `Group`, ...). A `Uring` must
+// be driven by a single goroutine.
+type Uring struct {
+	fd      int
+	sq      uringQueue
+	sqarray []uint32 // sq index array
+	sqes    []SQE
+	cq      uringQueue
+	cqes    []CQE
+	pending uint32 // fed but not yet submitted sqes
+	mems    [][]byte
+}
+
+// DrainCompletions moves available completions
+// into `dst` as `CQE` values until `dst` is full
+// and returns the number of moved completions.
+func (u *Uring) DrainCompletions(dst *Ring) int {
+	var (
+		head uint32 = atomic.LoadUint32(u.cq.head)
+		tail uint32 = atomic.LoadUint32(u.cq.tail) // acquire
+		n    int
+	)
+	for ; head != tail; head++ {
+		if !dst.Push(u.cqes[head&u.cq.mask]) {
+			break
+		}
+		n++
+	}
+	// release consumed entries to kernel
+	atomic.StoreUint32(u.cq.head, head)
+	return n
+}
+
+// FeedSubmissions pops `*SQE` items from `src` into
+// the submission queue until it is full and returns
+// the number of queued entries. Entries are passed
+// to the kernel by `Submit`.
+func (u *Uring) FeedSubmissions(src *Ring) int {
+	var (
+		tail uint32 = atomic.LoadUint32(u.sq.tail)
+		n    int
+	)
+	for tail-atomic.LoadUint32(u.sq.head) < u.sq.entries {
+		v, ok := src.Pop()
+		if !ok {
+			break
+		}
+		index := tail & u.sq.mask
+		u.sqes[index] = *v.(*SQE)
+		u.sqarray[index] = index
+		tail++
+		n++
+	}
+	// publish entries to kernel
+	atomic.StoreUint32(u.sq.tail, tail)
+	u.pending += uint32(n)
+	return n
+}
+
+// Pending returns number of fed entries which are
+// not submitted yet.
+func (u *Uring) Pending() uint32 {
+	return u.pending
+}
@@ -0,0 +1,143 @@
+//go:build linux && !purego
+// +build linux,!purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// io_uring syscalls and mmap offsets, see
+// linux/io_uring.h.
+const (
+	cSYSURINGSETUP = 425
+	cSYSURINGENTER = 426
+	cURINGOFFSQ    = 0
+	cURINGOFFCQ    = 0x8000000
+	cURINGOFFSQES  = 0x10000000
+	cURINGGETEVENT = 1 // IORING_ENTER_GETEVENTS
+)
+
+// uringParams mirrors `struct io_uring_params`.
+type uringParams struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFd         uint32
+	resv         [3]uint32
+	sqOff        [10]uint32 // head, tail, mask, entries, flags, dropped, array, resv1, user_addr
+	cqOff        [10]uint32 // head, tail, mask, entries, overflow, cqes, flags, resv1, user_addr
+}
+
+// NewUring sets up an io_uring instance with at
+// least `entries` submission entries and maps its
+// queues.
+func NewUring(entries uint32) (*Uring, error) {
+	var p uringParams
+	fd, _, errno := syscall.Syscall(cSYSURINGSETUP, uintptr(entries), uintptr(unsafe.Pointer(&p)), 0)
+	if errno != 0 {
+		if errno == syscall.ENOSYS {
+			return nil, ErrUringUnsupported
+		}
+		return nil, errno
+	}
+	u := &Uring{fd: int(fd)}
+	sqmem, err := u.mmap(cURINGOFFSQ, int(p.sqOff[6]+p.sqEntries*4))
+	if err != nil {
+		return nil, err
+	}
+	cqmem, err := u.mmap(cURINGOFFCQ, int(p.cqOff[5]+p.cqEntries*uint32(unsafe.Sizeof(CQE{}))))
+	if err != nil {
+		return nil, err
+	}
+	sqes, err := u.mmap(cURINGOFFSQES, int(p.sqEntries*uint32(unsafe.Sizeof(SQE{}))))
+	if err != nil {
+		return nil, err
+	}
+	u.sq = uringQueue{
+		head:    uint32At(sqmem, p.sqOff[0]),
+		tail:    uint32At(sqmem, p.sqOff[1]),
+		mask:    *uint32At(sqmem, p.sqOff[2]),
+		entries: *uint32At(sqmem, p.sqOff[3]),
+	}
+	u.sqarray = unsafe.Slice(uint32At(sqmem, p.sqOff[6]), p.sqEntries)
+	u.sqes = unsafe.Slice((*SQE)(unsafe.Pointer(&sqes[0])), p.sqEntries)
+	u.cq = uringQueue{
+		head:    uint32At(cqmem, p.cqOff[0]),
+		tail:    uint32At(cqmem, p.cqOff[1]),
+		mask:    *uint32At(cqmem, p.cqOff[2]),
+		entries: *uint32At(cqmem, p.cqOff[3]),
+	}
+	u.cqes = unsafe.Slice((*CQE)(unsafe.Pointer(&cqmem[p.cqOff[5]])), p.cqEntries)
+	return u, nil
+}
+
+// Submit passes fed entries to the kernel and
+// waits for at least `wait` completions. It
+// returns the number of submitted entries.
+func (u *Uring) Submit(wait uint32) (int, error) {
+	var flags uintptr
+	if wait > 0 {
+		flags = cURINGGETEVENT
+	}
+	n, _, errno := syscall.Syscall6(cSYSURINGENTER, uintptr(u.fd), uintptr(u.pending), uintptr(wait), flags, 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	u.pending -= uint32(n)
+	return int(n), nil
+}
+
+// Close unmaps queues and closes the instance.
+func (u *Uring) Close() error {
+	for _, mem := range u.mems {
+		syscall.Munmap(mem)
+	}
+	u.mems = nil
+	return syscall.Close(u.fd)
+}
+
+// mmap maps `size` bytes of queue memory at
+// `off`, closing the instance on failure.
+func (u *Uring) mmap(off int64, size int) ([]byte, error) {
+	mem, err := syscall.Mmap(u.fd, off, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE)
+	if err != nil {
+		u.Close()
+		return nil, err
+	}
+	u.mems = append(u.mems, mem)
+	return mem, nil
+}
+
+// uint32At returns a pointer to the word at `off`.
+func uint32At(mem []byte, off uint32) *uint32 {
+	return (*uint32)(unsafe.Pointer(&mem[off]))
+}
@@ -0,0 +1,66 @@
+//go:build linux && !purego
+// +build linux,!purego
+
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "testing"
+
+func TestUringNop(t *testing.T) {
+	const items = 64
+	var (
+		src  *Ring = NewRing(items)
+		dst  *Ring = NewRing(items)
+		seen map[uint64]bool
+	)
+	u, err := NewUring(8)
+	if err != nil {
+		t.Skipf("io_uring unavailable: %v.", err)
+	}
+	defer u.Close()
+	for i := 0; i < items; i++ {
+		// zero opcode is IORING_OP_NOP
+		src.Push(&SQE{UserData: uint64(i)})
+	}
+	for dst.Len() < items {
+		u.FeedSubmissions(src)
+		if _, err := u.Submit(1); err != nil {
+			t.Fatalf("assertion failed, submit: %v.", err)
+		}
+		u.DrainCompletions(dst)
+	}
+	seen = make(map[uint64]bool)
+	for v, ok := dst.Pop(); ok; v, ok = dst.Pop() {
+		cqe := v.(CQE)
+		if cqe.Res != 0 || seen[cqe.UserData] {
+			t.Fatalf("assertion failed, unexpected completion %+v.", cqe)
+		}
+		seen[cqe.UserData] = true
+	}
+	if len(seen) != items {
+		t.Fatalf("assertion failed, completed %d.", len(seen))
+	}
+}
@@ -0,0 +1,77 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import "testing"
+
+// newFakeUring returns a `Uring` over plain memory
+// with `entries` slots per queue, where the test
+// plays the kernel.
+func newFakeUring(entries uint32) *Uring {
+	var words [4]uint32 // sq head, sq tail, cq head, cq tail
+	return &Uring{
+		sq:      uringQueue{head: &words[0], tail: &words[1], mask: entries - 1, entries: entries},
+		sqarray: make([]uint32, entries),
+		sqes:    make([]SQE, entries),
+		cq:      uringQueue{head: &words[2], tail: &words[3], mask: entries - 1, entries: entries},
+		cqes:    make([]CQE, entries),
+	}
+}
+
+func TestUringFake(t *testing.T) {
+	var (
+		u   *Uring = newFakeUring(4)
+		src *Ring  = NewRing(8)
+		dst *Ring  = NewRing(2)
+	)
+	for i := 0; i < 6; i++ {
+		src.Push(&SQE{UserData: uint64(i)})
+	}
+	if n := u.FeedSubmissions(src); n != 4 || u.Pending() != 4 || src.Len() != 2 {
+		t.Fatalf("assertion failed, fed(%d), pending(%d).", n, u.Pending())
+	}
+	// kernel consumes two entries and completes them
+	for i := uint32(0); i < 2; i++ {
+		sqe := u.sqes[u.sqarray[i]]
+		u.cqes[*u.cq.tail&u.cq.mask] = CQE{UserData: sqe.UserData, Res: int32(i)}
+		*u.cq.tail++
+		*u.sq.head++
+	}
+	if n := u.FeedSubmissions(src); n != 2 || *u.sq.tail != 6 {
+		t.Fatalf("assertion failed, fed(%d), tail(%d).", n, *u.sq.tail)
+	}
+	dst.Push(CQE{})
+	// destination ring has room for one
+	if n := u.DrainCompletions(dst); n != 1 || *u.cq.head != 1 {
+		t.Fatalf("assertion failed, drained(%d), head(%d).", n, *u.cq.head)
+	}
+	dst.Pop()
+	if v, _ := dst.Pop(); v.(CQE).UserData != 0 {
+		t.Fatalf("assertion failed, unexpected completion %v.", v)
+	}
+	if n := u.DrainCompletions(dst); n != 1 || *u.cq.head != 2 {
+		t.Fatalf("assertion failed, drained(%d), head(%d).", n, *u.cq.head)
+	}
+}
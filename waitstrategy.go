@@ -0,0 +1,185 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// - MARK: Wait strategy section.
+
+// WaitStrategy decides how a goroutine waits
+// between unsuccessful attempts, e.g. while a
+// competitor holds the head or a consumer finds
+// its input empty. Latency sensitive users want
+// to busy spin while others prefer low CPU usage.
+type WaitStrategy interface {
+	// Wait is called after `n` consecutive
+	// unsuccessful attempts, starting at zero.
+	Wait(n int)
+}
+
+// Signaler is implemented by wait strategies
+// which park goroutines; rings signal them when
+// items are pushed and, with `Block` policy,
+// when items are popped.
+type Signaler interface {
+	Signal()
+}
+
+var (
+	// DefaultWaitStrategy yields to scheduler every
+	// `cRDSCHDTHRESHOLD` spins.
+	DefaultWaitStrategy WaitStrategy = Yielding{Spins: cRDSCHDTHRESHOLD}
+)
+
+// BusySpin never gives up the processor; it has
+// the lowest latency and burns a core per waiter.
+type BusySpin struct{}
+
+// Wait implements `WaitStrategy` interface.
+func (BusySpin) Wait(n int) {}
+
+// Yielding spins and yields control to scheduler
+// every `Spins` attempts.
+type Yielding struct {
+	Spins int
+}
+
+// Wait implements `WaitStrategy` interface.
+func (y Yielding) Wait(n int) {
+	if y.Spins <= 1 || (n+1)%y.Spins == 0 {
+		runtime.Gosched()
+	}
+}
+
+// Sleeping spins for `Spins` attempts, yields for
+// as many more and then sleeps for `Sleep`. It
+// trades latency for low CPU usage.
+type Sleeping struct {
+	Spins int
+	Sleep time.Duration
+}
+
+// Wait implements `WaitStrategy` interface.
+func (s Sleeping) Wait(n int) {
+	switch {
+	case n < s.Spins:
+	case n < 2*s.Spins:
+		runtime.Gosched()
+	default:
+		time.Sleep(s.Sleep)
+	}
+}
+
+// Parking spins for `Spins` attempts and then
+// parks the goroutine until signaled or until
+// `Timeout` elapses. A signal racing with a
+// goroutine about to park is not lost for longer
+// than `Timeout`.
+type Parking struct {
+	Spins   int
+	Timeout time.Duration
+	waiters int32         // parked goroutines
+	mu      sync.Mutex    // guards ch
+	ch      chan struct{} // closed on signal
+}
+
+// NewParking allocates and initializes a new
+// `Parking` strategy and returns a pointer to it.
+func NewParking(spins int, timeout time.Duration) *Parking {
+	return &Parking{Spins: spins, Timeout: timeout}
+}
+
+// Wait implements `WaitStrategy` interface.
+func (p *Parking) Wait(n int) {
+	if n < p.Spins {
+		return
+	}
+	p.mu.Lock()
+	if p.ch == nil {
+		p.ch = make(chan struct{})
+	}
+	ch := p.ch
+	atomic.AddInt32(&p.waiters, 1)
+	p.mu.Unlock()
+	t := time.NewTimer(p.Timeout)
+	select {
+	case <-ch:
+	case <-t.C:
+	}
+	t.Stop()
+	atomic.AddInt32(&p.waiters, -1)
+}
+
+// Signal implements `Signaler` interface; it
+// wakes all parked goroutines.
+func (p *Parking) Signal() {
+	if atomic.LoadInt32(&p.waiters) == 0 {
+		return
+	}
+	p.mu.Lock()
+	if p.ch != nil {
+		close(p.ch)
+		p.ch = nil
+	}
+	p.mu.Unlock()
+}
+
+// - MARK: Ring section.
+
+// SetWaitStrategy sets wait strategy of ring. It
+// must be called before ring is shared.
+func (r *Ring) SetWaitStrategy(w WaitStrategy) {
+	r.wait = w
+	r.signal, _ = w.(Signaler)
+	r.hook()
+}
+
+// WaitStrategy returns wait strategy of ring.
+func (r *Ring) WaitStrategy() WaitStrategy {
+	return r.wait
+}
+
+// freed wakes producers parked on a full ring
+// of `Block` policy once slots were released.
+func (r *Ring) freed() {
+	if r.signal != nil && r.policy == Block {
+		r.signal.Signal()
+	}
+}
+
+// pause waits after `n` consecutive unsuccessful
+// attempts according to wait strategy. Sustained
+// waits are recorded as yields.
+func (r *Ring) pause(n int) {
+	if (n+1)%cRDSCHDTHRESHOLD == 0 {
+		atomic.AddUint64(&r.yields, 1)
+	}
+	r.wait.Wait(n)
+}
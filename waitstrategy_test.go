@@ -0,0 +1,153 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWaitStrategies(t *testing.T) {
+	for name, w := range map[string]WaitStrategy{
+		"busyspin": BusySpin{},
+		"yielding": Yielding{Spins: 10},
+		"sleeping": Sleeping{Spins: 10, Sleep: time.Microsecond},
+		"backoff":  Backoff{Cap: 64},
+		"parking":  NewParking(10, time.Millisecond),
+	} {
+		if _, ok := w.(BusySpin); ok && runtime.GOMAXPROCS(0) < 4 {
+			// spinners starve each other until
+			// preempted.
+			continue
+		}
+		var (
+			in  *Ring = NewRing(4)
+			out *Ring = NewRing(4)
+			wg  sync.WaitGroup
+		)
+		in.SetWaitStrategy(w)
+		out.SetWaitStrategy(w)
+		stage := NewStage(name, in, func(v interface{}) (interface{}, error) { return v, nil }, out, nil)
+		stop := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stage.Run(stop)
+		}()
+		go func() {
+			for i := 0; i < 200; i++ {
+				for j := 0; !in.Push(i); j++ {
+					in.pause(j)
+				}
+			}
+		}()
+		for i, idle := 0, 0; i < 200; idle++ {
+			v, ok := out.Pop()
+			if !ok {
+				out.pause(idle)
+				continue
+			}
+			idle = 0
+			if v.(int) != i {
+				t.Fatalf("assertion failed, %s: expected %d, got %v.", name, i, v)
+			}
+			i++
+		}
+		close(stop)
+		wg.Wait()
+	}
+}
+
+func TestParkingSignal(t *testing.T) {
+	var (
+		p    *Parking      = NewParking(0, time.Minute)
+		r    *Ring         = NewRing(4)
+		done chan struct{} = make(chan struct{})
+	)
+	r.SetWaitStrategy(p)
+	go func() {
+		for i := 0; ; i++ {
+			if _, ok := r.Pop(); ok {
+				close(done)
+				return
+			}
+			r.pause(i)
+		}
+	}()
+	// wait until consumer parks
+	for i := 0; atomicWaiters(p) == 0; i++ {
+		if i > 1e6 {
+			t.Fatal("assertion failed, consumer never parked.")
+		}
+		time.Sleep(time.Microsecond)
+	}
+	r.Push(1)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("assertion failed, parked consumer not woken by push.")
+	}
+}
+
+func TestParkingBlockedProducer(t *testing.T) {
+	var (
+		p    *Parking      = NewParking(0, time.Minute)
+		r    *Ring         = NewRing(2, WithPolicy(Block), WithWaitStrategy(p))
+		done chan struct{} = make(chan struct{})
+	)
+	r.Push(1)
+	r.Push(2)
+	go func() {
+		r.Push(3)
+		close(done)
+	}()
+	// wait until producer parks on full ring
+	for i := 0; atomicWaiters(p) == 0; i++ {
+		if i > 1e6 {
+			t.Fatal("assertion failed, producer never parked.")
+		}
+		time.Sleep(time.Microsecond)
+	}
+	if v, ok := r.Pop(); !ok || v.(int) != 1 {
+		t.Fatalf("assertion failed, expected 1, got %v.", v)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("assertion failed, parked producer not woken by pop.")
+	}
+	if r.Len() != 2 {
+		t.Fatalf("inconsistent state, len(%d)!=2.", r.Len())
+	}
+}
+
+// atomicWaiters returns number of goroutines
+// parked on `p`.
+func atomicWaiters(p *Parking) int32 {
+	return atomic.LoadInt32(&p.waiters)
+}
@@ -0,0 +1,149 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// - MARK: Watermark section.
+
+// Timestamped is implemented by items carrying an
+// event time.
+type Timestamped interface {
+	EventTime() time.Time
+}
+
+// Stamped wraps an item with its event time.
+type Stamped struct {
+	Item interface{}
+	Time time.Time
+}
+
+// EventTime implements `Timestamped` interface.
+func (s Stamped) EventTime() time.Time {
+	return s.Time
+}
+
+// Watermark tracks event-time progress of a
+// stream as the maximum event time seen minus
+// allowed lateness. Items older than watermark
+// are late; windows ending before it can be
+// closed by consumers. It is updated atomically
+// and may be shared by several rings.
+type Watermark struct {
+	lateness int64 // allowed lateness, ns
+	max      int64 // max event time, unix ns
+}
+
+// NewWatermark allocates and initializes a new
+// `Watermark` allowing `lateness` out-of-orderness
+// and returns a pointer to it.
+func NewWatermark(lateness time.Duration) *Watermark {
+	return &Watermark{lateness: int64(lateness), max: math.MinInt64}
+}
+
+// SetWatermark makes ring observe event time of
+// `Timestamped` items pushed successfully; nil
+// disables tracking. It must be called before
+// ring is shared.
+func (r *Ring) SetWatermark(w *Watermark) {
+	r.wmark = w
+	r.hook()
+}
+
+// Watermark returns watermark of ring or nil.
+func (r *Ring) Watermark() *Watermark {
+	return r.wmark
+}
+
+// Observe advances watermark with event time `t`.
+func (w *Watermark) Observe(t time.Time) {
+	w.advance(t.UnixNano())
+}
+
+// ObserveBatch advances watermark with the latest
+// event time of `batch`, touching the shared word
+// at most once.
+func (w *Watermark) ObserveBatch(batch []interface{}) {
+	var max int64 = math.MinInt64
+	for _, item := range batch {
+		if ts, ok := item.(Timestamped); ok {
+			if t := ts.EventTime().UnixNano(); t > max {
+				max = t
+			}
+		}
+	}
+	if max != math.MinInt64 {
+		w.advance(max)
+	}
+}
+
+// Current returns watermark, the zero time when
+// no event has been observed.
+func (w *Watermark) Current() time.Time {
+	max := atomic.LoadInt64(&w.max)
+	if max == math.MinInt64 {
+		return time.Time{}
+	}
+	return time.Unix(0, max-w.lateness)
+}
+
+// MaxEventTime returns the latest observed event
+// time, the zero time when none.
+func (w *Watermark) MaxEventTime() time.Time {
+	max := atomic.LoadInt64(&w.max)
+	if max == math.MinInt64 {
+		return time.Time{}
+	}
+	return time.Unix(0, max)
+}
+
+// IsLate returns whether event time `t` is behind
+// watermark.
+func (w *Watermark) IsLate(t time.Time) bool {
+	max := atomic.LoadInt64(&w.max)
+	return max != math.MinInt64 && t.UnixNano() < max-w.lateness
+}
+
+// observe advances watermark with event time of
+// `item` when it is `Timestamped`.
+func (w *Watermark) observe(item interface{}) {
+	if ts, ok := item.(Timestamped); ok {
+		w.advance(ts.EventTime().UnixNano())
+	}
+}
+
+// advance raises max event time to `t`.
+func (w *Watermark) advance(t int64) {
+	for {
+		max := atomic.LoadInt64(&w.max)
+		if t <= max || atomic.CompareAndSwapInt64(&w.max, max, t) {
+			return
+		}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+* MIT License
+*
+* Copyright (c) 2018 Mike Taghavi <mitghi[at]me.com>
+*
+* Permission is hereby granted, free of charge, to any person obtaining a copy
+* of this software and associated documentation files (the "Software"), to deal
+* in the Software without restriction, including without limitation the rights
+* to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+* copies of the Software, and to permit persons to whom the Software is
+* furnished to do so, subject to the following conditions:
+*
+* The above copyright notice and this permission notice shall be included in all
+* copies or substantial portions of the Software.
+*
+* THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+* IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+* FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+* AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+* LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+* OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+* SOFTWARE.
+ */
+
+package lfring
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatermark(t *testing.T) {
+	var (
+		r    *Ring      = NewRing(8)
+		w    *Watermark = NewWatermark(time.Second)
+		base time.Time  = time.Unix(1000, 0)
+	)
+	r.SetWatermark(w)
+	if !w.Current().IsZero() || w.IsLate(base) {
+		t.Fatal("assertion failed, expected empty watermark.")
+	}
+	r.Push(Stamped{Item: 1, Time: base.Add(5 * time.Second)})
+	// out of order event does not move watermark
+	r.Push(Stamped{Item: 2, Time: base.Add(3 * time.Second)})
+	r.Push("untimed")
+	if !w.Current().Equal(base.Add(4*time.Second)) || !w.MaxEventTime().Equal(base.Add(5*time.Second)) {
+		t.Fatalf("assertion failed, watermark(%v).", w.Current())
+	}
+	if !w.IsLate(base.Add(3*time.Second)) || w.IsLate(base.Add(4*time.Second)) {
+		t.Fatal("assertion failed, lateness misclassified.")
+	}
+	w.ObserveBatch([]interface{}{Stamped{Time: base.Add(9 * time.Second)}, Stamped{Time: base.Add(7 * time.Second)}, 3})
+	if !w.Current().Equal(base.Add(8 * time.Second)) {
+		t.Fatalf("assertion failed, watermark(%v).", w.Current())
+	}
+}
+
+func TestWatermarkConcurrent(t *testing.T) {
+	var (
+		w    *Watermark = NewWatermark(0)
+		base time.Time  = time.Unix(1000, 0)
+		wg   sync.WaitGroup
+	)
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				w.Observe(base.Add(time.Duration(i*4+p) * time.Millisecond))
+			}
+		}(p)
+	}
+	wg.Wait()
+	if !w.Current().Equal(base.Add(3999 * time.Millisecond)) {
+		t.Fatalf("assertion failed, watermark(%v).", w.Current())
+	}
+}